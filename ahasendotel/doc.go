@@ -0,0 +1,33 @@
+// Package ahasendotel instruments the AhaSend Go SDK with OpenTelemetry.
+//
+// It lives in its own module so that applications which do not use
+// OpenTelemetry do not inherit the dependency. Install it on a client with
+// WithTracing:
+//
+//	client := api.NewAPIClient(
+//		api.WithAPIKey(apiKey),
+//		ahasendotel.WithTracing(),
+//	)
+//
+// Every APIClient.Execute call becomes a span named after the method and path
+// template, such as "AhaSend POST /v2/accounts/{account_id}/messages", with a
+// client span for each attempt beneath it, so retries are visible. Trace
+// context is injected into the headers of each attempt. Spans carry the HTTP
+// semantic-convention attributes together with the account ID, endpoint type
+// and idempotency key of the request.
+//
+// The following metrics are recorded:
+//
+//   - ahasend.client.request.duration: duration of Execute calls, retries
+//     and rate-limit waits included
+//   - http.client.request.duration: duration of each attempt
+//   - ahasend.client.retries: attempts after the first
+//   - ahasend.client.rate_limit.wait: time spent waiting on the client-side
+//     rate limiter
+//   - ahasend.client.errors: failed Execute calls, by api.ErrorType
+//
+// The global TracerProvider, MeterProvider and TextMapPropagator are used
+// unless others are given with WithTracerProvider, WithMeterProvider and
+// WithPropagators. Query strings are left out of url.full, since they can
+// contain recipient addresses.
+package ahasendotel
@@ -0,0 +1,33 @@
+module github.com/AhaSend/ahasend-go/ahasendotel
+
+go 1.25.0
+
+require (
+	github.com/AhaSend/ahasend-go v0.1.0
+	github.com/stretchr/testify v1.11.1
+	go.opentelemetry.io/otel v1.44.0
+	go.opentelemetry.io/otel/metric v1.44.0
+	go.opentelemetry.io/otel/sdk v1.44.0
+	go.opentelemetry.io/otel/sdk/metric v1.44.0
+	go.opentelemetry.io/otel/trace v1.44.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.2.1 // indirect
+	golang.org/x/sys v0.45.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The SDK is developed in the same repository; build against the local copy.
+// Consumers ignore this replace and get the required SDK version, so the two
+// modules are released together: before tagging vX.Y.Z and ahasendotel/vX.Y.Z
+// on the same commit, the require above must name vX.Y.Z, the first SDK
+// release with api.RequestTracer. The release workflow refuses an SDK tag
+// this module does not require.
+replace github.com/AhaSend/ahasend-go => ../
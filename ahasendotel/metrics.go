@@ -0,0 +1,70 @@
+package ahasendotel
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// durationBuckets are the explicit bucket boundaries, in seconds, that the
+// HTTP semantic conventions recommend for request durations
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// instruments are the metrics a Tracer records
+type instruments struct {
+	requestDuration metric.Float64Histogram
+	attemptDuration metric.Float64Histogram
+	retries         metric.Int64Counter
+	rateLimitWait   metric.Float64Histogram
+	errors          metric.Int64Counter
+}
+
+// newInstruments creates the instruments on meter. An instrument that cannot
+// be created is reported to the global error handler and replaced with a
+// no-op, so a broken metrics pipeline never stops requests from being sent.
+func newInstruments(meter metric.Meter) *instruments {
+	fallback := noop.Meter{}
+	m := &instruments{}
+	var err error
+
+	if m.requestDuration, err = meter.Float64Histogram("ahasend.client.request.duration",
+		metric.WithDescription("Duration of AhaSend API calls, including retries and rate-limit waits"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	); err != nil {
+		otel.Handle(err)
+		m.requestDuration, _ = fallback.Float64Histogram("")
+	}
+	if m.attemptDuration, err = meter.Float64Histogram("http.client.request.duration",
+		metric.WithDescription("Duration of HTTP client requests"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	); err != nil {
+		otel.Handle(err)
+		m.attemptDuration, _ = fallback.Float64Histogram("")
+	}
+	if m.retries, err = meter.Int64Counter("ahasend.client.retries",
+		metric.WithDescription("Number of AhaSend API request attempts after the first"),
+		metric.WithUnit("{retry}"),
+	); err != nil {
+		otel.Handle(err)
+		m.retries, _ = fallback.Int64Counter("")
+	}
+	if m.rateLimitWait, err = meter.Float64Histogram("ahasend.client.rate_limit.wait",
+		metric.WithDescription("Time AhaSend API calls spent waiting on the client-side rate limiter"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	); err != nil {
+		otel.Handle(err)
+		m.rateLimitWait, _ = fallback.Float64Histogram("")
+	}
+	if m.errors, err = meter.Int64Counter("ahasend.client.errors",
+		metric.WithDescription("Number of failed AhaSend API calls, by error type"),
+		metric.WithUnit("{error}"),
+	); err != nil {
+		otel.Handle(err)
+		m.errors, _ = fallback.Int64Counter("")
+	}
+
+	return m
+}
@@ -0,0 +1,259 @@
+package ahasendotel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies this package to tracer and meter providers
+const instrumentationName = "github.com/AhaSend/ahasend-go/ahasendotel"
+
+// AhaSend-specific attribute keys
+const (
+	AccountIDKey      = attribute.Key("ahasend.account_id")
+	EndpointTypeKey   = attribute.Key("ahasend.endpoint_type")
+	IdempotencyKeyKey = attribute.Key("ahasend.idempotency_key")
+	RequestIDKey      = attribute.Key("ahasend.request_id")
+)
+
+// Option configures a Tracer
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+}
+
+// WithTracerProvider sets the TracerProvider spans are created with
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the MeterProvider metrics are recorded with
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject trace context into
+// outgoing requests
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// Tracer is an api.RequestTracer that records OpenTelemetry spans and metrics
+type Tracer struct {
+	tracer      trace.Tracer
+	propagators propagation.TextMapPropagator
+	metrics     *instruments
+}
+
+// NewTracer returns a Tracer configured by opts
+func NewTracer(opts ...Option) *Tracer {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagators:    otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &Tracer{
+		tracer:      c.tracerProvider.Tracer(instrumentationName, trace.WithInstrumentationVersion(api.Version)),
+		propagators: c.propagators,
+		metrics:     newInstruments(c.meterProvider.Meter(instrumentationName, metric.WithInstrumentationVersion(api.Version))),
+	}
+}
+
+// WithTracing instruments an API client with a Tracer configured by opts
+func WithTracing(opts ...Option) api.ClientOption {
+	return api.WithRequestTracer(NewTracer(opts...))
+}
+
+// StartRequest implements api.RequestTracer
+func (t *Tracer) StartRequest(ctx context.Context, info api.RequestInfo) (context.Context, api.RequestSpan) {
+	common := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(info.Method),
+		semconv.URLTemplate(info.PathTemplate),
+		EndpointTypeKey.String(info.EndpointType.String()),
+	}
+
+	attrs := append([]attribute.KeyValue{}, common...)
+	attrs = append(attrs, serverAttributes(info.URL)...)
+	if info.AccountID != "" {
+		attrs = append(attrs, AccountIDKey.String(info.AccountID))
+	}
+	if info.IdempotencyKey != "" {
+		attrs = append(attrs, IdempotencyKeyKey.String(info.IdempotencyKey))
+	}
+
+	ctx, span := t.tracer.Start(ctx, "AhaSend "+info.Method+" "+info.PathTemplate,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, &requestSpan{
+		tracer: t,
+		ctx:    ctx,
+		span:   span,
+		info:   info,
+		common: common,
+		start:  time.Now(),
+	}
+}
+
+// requestSpan follows one Execute call
+type requestSpan struct {
+	tracer *Tracer
+	ctx    context.Context
+	span   trace.Span
+	info   api.RequestInfo
+
+	// common are the attributes shared by the span and its metrics
+	common []attribute.KeyValue
+	start  time.Time
+}
+
+func (s *requestSpan) RateLimited(wait time.Duration) {
+	s.tracer.metrics.rateLimitWait.Record(s.ctx, wait.Seconds(),
+		metric.WithAttributes(EndpointTypeKey.String(s.info.EndpointType.String())))
+	if wait > 0 {
+		s.span.AddEvent("rate limited", trace.WithAttributes(
+			attribute.Float64("ahasend.rate_limit.wait", wait.Seconds()),
+		))
+	}
+}
+
+func (s *requestSpan) StartAttempt(req *http.Request, attempt int) func(*http.Response, error) {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLFull(redactURL(req.URL)),
+		semconv.URLTemplate(s.info.PathTemplate),
+	}
+	attrs = append(attrs, serverAttributes(req.URL)...)
+	if attempt > 1 {
+		attrs = append(attrs, semconv.HTTPRequestResendCount(attempt-1))
+		s.tracer.metrics.retries.Add(s.ctx, 1, metric.WithAttributes(s.common...))
+	}
+
+	ctx, span := s.tracer.tracer.Start(s.ctx, req.Method+" "+s.info.PathTemplate,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	s.tracer.propagators.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	start := time.Now()
+
+	return func(resp *http.Response, err error) {
+		outcome := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLTemplate(s.info.PathTemplate),
+		}
+		outcome = append(outcome, serverAttributes(req.URL)...)
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			outcome = append(outcome, semconv.ErrorTypeKey.String(errorType(err)))
+		case resp != nil:
+			outcome = append(outcome, semconv.HTTPResponseStatusCode(resp.StatusCode))
+			if resp.StatusCode >= 400 {
+				span.SetStatus(codes.Error, "")
+				outcome = append(outcome, semconv.ErrorTypeKey.String(strconv.Itoa(resp.StatusCode)))
+			}
+			if requestID := resp.Header.Get("X-Request-Id"); requestID != "" {
+				span.SetAttributes(RequestIDKey.String(requestID))
+			}
+		}
+		span.SetAttributes(outcome...)
+		span.End()
+		s.tracer.metrics.attemptDuration.Record(s.ctx, time.Since(start).Seconds(), metric.WithAttributes(outcome...))
+	}
+}
+
+func (s *requestSpan) End(resp *http.Response, err error) {
+	attrs := append([]attribute.KeyValue{}, s.common...)
+	if resp != nil {
+		attrs = append(attrs, semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if requestID := resp.Header.Get("X-Request-Id"); requestID != "" {
+			s.span.SetAttributes(RequestIDKey.String(requestID))
+		}
+	}
+	if err != nil {
+		errType := errorType(err)
+		attrs = append(attrs, semconv.ErrorTypeKey.String(errType))
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+		s.tracer.metrics.errors.Add(s.ctx, 1, metric.WithAttributes(attrs...))
+	}
+	s.span.SetAttributes(attrs...)
+	s.span.End()
+	s.tracer.metrics.requestDuration.Record(s.ctx, time.Since(s.start).Seconds(), metric.WithAttributes(attrs...))
+}
+
+// errorType classifies an error by its api.ErrorType
+func errorType(err error) string {
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) && apiErr.Type != "" {
+		return string(apiErr.Type)
+	}
+	var netErr *api.NetworkError
+	if errors.As(err, &netErr) {
+		return string(api.ErrorTypeNetwork)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return string(api.ErrorTypeNetwork)
+	}
+	return string(api.ErrorTypeUnknown)
+}
+
+// serverAttributes returns the server.address and server.port of u
+func serverAttributes(u *url.URL) []attribute.KeyValue {
+	if u == nil {
+		return nil
+	}
+	attrs := []attribute.KeyValue{semconv.ServerAddress(u.Hostname())}
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		}
+	}
+	if n, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.ServerPort(n))
+	}
+	return attrs
+}
+
+// redactURL returns u without its query string or user info
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	safe := *u
+	safe.User = nil
+	safe.RawQuery = ""
+	safe.ForceQuery = false
+	return safe.String()
+}
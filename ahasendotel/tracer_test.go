@@ -0,0 +1,183 @@
+package ahasendotel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/ahasendtest"
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type harness struct {
+	server *ahasendtest.Server
+	client *api.APIClient
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+func newHarness(t *testing.T, retry api.RetryConfig) *harness {
+	t.Helper()
+
+	h := &harness{
+		server: ahasendtest.NewServer(),
+		spans:  tracetest.NewSpanRecorder(),
+		reader: sdkmetric.NewManualReader(),
+	}
+	t.Cleanup(h.server.Close)
+
+	tracing := WithTracing(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(h.spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(h.reader))),
+		WithPropagators(propagation.TraceContext{}),
+	)
+	h.client = h.server.Client(api.WithRetryConfig(retry), api.WithRateLimit(true), tracing)
+	return h
+}
+
+func (h *harness) metrics(t *testing.T) map[string]metricdata.Metrics {
+	t.Helper()
+
+	var data metricdata.ResourceMetrics
+	require.NoError(t, h.reader.Collect(context.Background(), &data))
+	metrics := map[string]metricdata.Metrics{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+	return metrics
+}
+
+func attributeValue(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracerRecordsRequestSpanWithAttemptChildren(t *testing.T) {
+	h := newHarness(t, api.RetryConfig{Enabled: true, MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffStrategy: api.BackoffConstant})
+	h.server.FailNext(1, http.StatusServiceUnavailable)
+
+	_, _, err := h.client.MessagesAPI.CreateMessage(context.Background(), h.server.AccountID(), requests.CreateMessageRequest{
+		From:        common.SenderAddress{Email: "noreply@example.com"},
+		Recipients:  []common.Recipient{{Email: "ada@example.net"}},
+		Subject:     "Welcome",
+		TextContent: ahasend.String("Hello"),
+	}, api.WithIdempotencyKey("welcome-ada"))
+	require.NoError(t, err)
+
+	spans := h.spans.Ended()
+	require.Len(t, spans, 3)
+	first, second, parent := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "AhaSend POST /v2/accounts/{account_id}/messages", parent.Name())
+	assert.Equal(t, trace.SpanKindInternal, parent.SpanKind())
+	assert.Equal(t, codes.Unset, parent.Status().Code)
+	attrs := parent.Attributes()
+	value, _ := attributeValue(attrs, AccountIDKey)
+	assert.Equal(t, h.server.AccountID().String(), value.AsString())
+	value, _ = attributeValue(attrs, EndpointTypeKey)
+	assert.Equal(t, "send_message", value.AsString())
+	value, _ = attributeValue(attrs, IdempotencyKeyKey)
+	assert.Equal(t, "welcome-ada", value.AsString())
+	value, _ = attributeValue(attrs, "http.response.status_code")
+	assert.Equal(t, int64(http.StatusAccepted), value.AsInt64())
+
+	for _, child := range []sdktrace.ReadOnlySpan{first, second} {
+		assert.Equal(t, "POST /v2/accounts/{account_id}/messages", child.Name())
+		assert.Equal(t, trace.SpanKindClient, child.SpanKind())
+		assert.Equal(t, parent.SpanContext().SpanID(), child.Parent().SpanID())
+	}
+	value, _ = attributeValue(first.Attributes(), "http.response.status_code")
+	assert.Equal(t, int64(http.StatusServiceUnavailable), value.AsInt64())
+	assert.Equal(t, codes.Error, first.Status().Code)
+	_, resent := attributeValue(first.Attributes(), "http.request.resend_count")
+	assert.False(t, resent)
+	value, _ = attributeValue(second.Attributes(), "http.request.resend_count")
+	assert.Equal(t, int64(1), value.AsInt64())
+
+	received := h.server.Requests()
+	require.Len(t, received, 2)
+	for i, child := range []sdktrace.ReadOnlySpan{first, second} {
+		carrier := propagation.HeaderCarrier(received[i].Header)
+		propagated := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+		assert.Equal(t, child.SpanContext().TraceID(), propagated.TraceID())
+		assert.Equal(t, child.SpanContext().SpanID(), propagated.SpanID(), "each attempt carries its own span")
+	}
+
+	metrics := h.metrics(t)
+	retries := metrics["ahasend.client.retries"].Data.(metricdata.Sum[int64])
+	require.Len(t, retries.DataPoints, 1)
+	assert.Equal(t, int64(1), retries.DataPoints[0].Value)
+	attempts := metrics["http.client.request.duration"].Data.(metricdata.Histogram[float64])
+	var count uint64
+	for _, dp := range attempts.DataPoints {
+		count += dp.Count
+	}
+	assert.Equal(t, uint64(2), count)
+	requestDuration := metrics["ahasend.client.request.duration"].Data.(metricdata.Histogram[float64])
+	require.Len(t, requestDuration.DataPoints, 1)
+	assert.Equal(t, uint64(1), requestDuration.DataPoints[0].Count)
+	wait := metrics["ahasend.client.rate_limit.wait"].Data.(metricdata.Histogram[float64])
+	require.Len(t, wait.DataPoints, 1)
+	_, hasErrors := metrics["ahasend.client.errors"]
+	assert.False(t, hasErrors)
+}
+
+func TestTracerRecordsErrorType(t *testing.T) {
+	h := newHarness(t, api.RetryConfig{Enabled: false})
+
+	_, _, err := h.client.DomainsAPI.GetDomain(context.Background(), h.server.AccountID(), "missing.example.com")
+	var apiErr *api.APIError
+	require.True(t, errors.As(err, &apiErr))
+
+	spans := h.spans.Ended()
+	require.Len(t, spans, 2)
+	parent := spans[1]
+	assert.Equal(t, codes.Error, parent.Status().Code)
+	value, _ := attributeValue(parent.Attributes(), "error.type")
+	assert.Equal(t, string(api.ErrorTypeNotFound), value.AsString())
+
+	errorsMetric := h.metrics(t)["ahasend.client.errors"].Data.(metricdata.Sum[int64])
+	require.Len(t, errorsMetric.DataPoints, 1)
+	point := errorsMetric.DataPoints[0]
+	assert.Equal(t, int64(1), point.Value)
+	errType, _ := point.Attributes.Value("error.type")
+	assert.Equal(t, "not_found", errType.AsString())
+}
+
+func TestRedactURLDropsQueryAndUserInfo(t *testing.T) {
+	h := newHarness(t, api.RetryConfig{Enabled: false})
+
+	_, _, err := h.client.MessagesAPI.GetMessages(context.Background(), h.server.AccountID(), requests.GetMessagesParams{
+		Sender:    ahasend.String("noreply@example.com"),
+		Recipient: ahasend.String("ada@example.net"),
+	})
+	require.NoError(t, err)
+
+	spans := h.spans.Ended()
+	require.Len(t, spans, 2)
+	value, ok := attributeValue(spans[0].Attributes(), "url.full")
+	require.True(t, ok)
+	assert.NotContains(t, value.AsString(), "ada@example.net")
+	assert.Contains(t, value.AsString(), "/v2/accounts/"+h.server.AccountID().String()+"/messages")
+}
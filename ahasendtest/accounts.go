@@ -0,0 +1,54 @@
+package ahasendtest
+
+import (
+	"net/http"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+)
+
+func (s *Server) getAccount(c *call) (int, interface{}) {
+	if !c.key.allows("accounts:read") {
+		return forbidden()
+	}
+	return http.StatusOK, s.account
+}
+
+func (s *Server) updateAccount(c *call) (int, interface{}) {
+	if !c.key.allows("accounts:write") {
+		return forbidden()
+	}
+	var req requests.UpdateAccountRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+
+	if req.Name != nil {
+		s.account.Name = *req.Name
+	}
+	if req.Website != nil {
+		s.account.Website = req.Website
+	}
+	if req.About != nil {
+		s.account.About = req.About
+	}
+	if req.TrackOpens != nil {
+		s.account.TrackOpens = req.TrackOpens
+	}
+	if req.TrackClicks != nil {
+		s.account.TrackClicks = req.TrackClicks
+	}
+	if req.RejectBadRecipients != nil {
+		s.account.RejectBadRecipients = req.RejectBadRecipients
+	}
+	if req.RejectMistypedRecipients != nil {
+		s.account.RejectMistypedRecipients = req.RejectMistypedRecipients
+	}
+	if req.MessageMetadataRetention != nil {
+		s.account.MessageMetadataRetention = req.MessageMetadataRetention
+	}
+	if req.MessageDataRetention != nil {
+		s.account.MessageDataRetention = req.MessageDataRetention
+	}
+	s.account.UpdatedAt = s.clock()
+	return http.StatusOK, s.account
+}
@@ -0,0 +1,387 @@
+package ahasendtest
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// plainScopes are the scopes that do not take a domain
+var plainScopes = []string{
+	"domains:read",
+	"domains:write",
+	"accounts:read",
+	"accounts:write",
+	"accounts:billing",
+	"accounts:members:read",
+	"accounts:members:add",
+	"accounts:members:update",
+	"accounts:members:remove",
+	"suppressions:read",
+	"suppressions:write",
+	"suppressions:delete",
+	"suppressions:wipe",
+	"api-keys:read",
+	"api-keys:write",
+	"api-keys:delete",
+	"sub-accounts:read",
+	"sub-accounts:write",
+	"sub-accounts:delete",
+	"sub-accounts:suspend",
+	"sub-accounts:usage",
+	"sub-account-api-keys:read",
+	"sub-account-api-keys:write",
+	"sub-account-api-keys:delete",
+}
+
+// domainScopes are the scopes granted either for every domain, with an
+// ":all" suffix, or for a single domain, with a ":{example.com}" suffix
+var domainScopes = []string{
+	"messages:send",
+	"messages:cancel",
+	"messages:read",
+	"domains:delete",
+	"webhooks:read",
+	"webhooks:write",
+	"webhooks:delete",
+	"routes:read",
+	"routes:write",
+	"routes:delete",
+	"smtp-credentials:read",
+	"smtp-credentials:write",
+	"smtp-credentials:delete",
+	"statistics-transactional:read",
+}
+
+// rootScopes grants everything, for the key returned by Server.APIKey
+var rootScopes = func() []string {
+	scopes := append([]string{}, plainScopes...)
+	for _, scope := range domainScopes {
+		scopes = append(scopes, scope+":all")
+	}
+	return scopes
+}()
+
+// validScope reports whether scope is one the API knows
+func validScope(scope string) bool {
+	for _, plain := range plainScopes {
+		if scope == plain {
+			return true
+		}
+	}
+	for _, base := range domainScopes {
+		rest, ok := cutPrefix(scope, base+":")
+		if !ok {
+			continue
+		}
+		if rest == "all" {
+			return true
+		}
+		if strings.HasPrefix(rest, "{") && strings.HasSuffix(rest, "}") && len(rest) > 2 {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKey is a stored API key with its secret
+type apiKey struct {
+	responses.APIKey
+	secret string
+}
+
+// grants returns whether the key holds scope for every domain, and the
+// domains it holds scope for otherwise. A scope that does not take a domain
+// counts as granted for every domain.
+func (k *apiKey) grants(scope string) (all bool, domains map[string]bool) {
+	domains = make(map[string]bool)
+	for _, granted := range k.Scopes {
+		if granted.Scope == scope || granted.Scope == scope+":all" {
+			return true, nil
+		}
+		if rest, ok := cutPrefix(granted.Scope, scope+":{"); ok && strings.HasSuffix(rest, "}") {
+			domains[strings.ToLower(strings.TrimSuffix(rest, "}"))] = true
+		}
+	}
+	return false, domains
+}
+
+// allows reports whether the key holds scope for all of the given domains.
+// With no domains, only a scope granted for every domain is enough.
+func (k *apiKey) allows(scope string, domains ...string) bool {
+	all, granted := k.grants(scope)
+	if all {
+		return true
+	}
+	if len(domains) == 0 {
+		return false
+	}
+	for _, domain := range domains {
+		if !granted[strings.ToLower(domain)] {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsAny reports whether the key holds scope for at least one domain,
+// which is enough to call a list endpoint whose results are then filtered
+func (k *apiKey) allowsAny(scope string) bool {
+	all, granted := k.grants(scope)
+	return all || len(granted) > 0
+}
+
+// allowsIP reports whether the key's IP allow list admits the request
+func (k *apiKey) allowsIP(r *http.Request) bool {
+	if len(k.IPAllowList) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range k.IPAllowList {
+		if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate finds the API key for a request. When there is none, it
+// returns the status and message to respond with.
+func (s *Server) authenticate(r *http.Request) (*apiKey, int, string) {
+	secret, ok := cutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.TrimSpace(secret) == "" {
+		return nil, http.StatusUnauthorized, "missing or malformed bearer token"
+	}
+	for _, key := range s.apiKeys {
+		if key.secret == secret {
+			if !key.allowsIP(r) {
+				return nil, http.StatusForbidden, "request IP is not in the API key's IP allow list"
+			}
+			return key, 0, ""
+		}
+	}
+	return nil, http.StatusUnauthorized, "invalid API key"
+}
+
+// AddAPIKey creates an API key with the given scopes and returns its secret,
+// for tests of code running with a restricted key. It panics if a scope is
+// not one the API knows.
+func (s *Server) AddAPIKey(label string, scopes ...string) string {
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			panic("ahasendtest: invalid scope " + scope)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addAPIKey(label, scopes)
+}
+
+// addAPIKey stores a new API key and returns its secret
+func (s *Server) addAPIKey(label string, scopes []string) string {
+	now := s.clock()
+	key := &apiKey{
+		APIKey: responses.APIKey{
+			Object:      "api_key",
+			ID:          uuid.New(),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			AccountID:   s.accountID,
+			Label:       label,
+			PublicKey:   "aha-pk-" + randomString(32),
+			IPAllowList: []string{},
+		},
+		secret: "aha-sk-" + randomString(64),
+	}
+	s.setScopes(key, scopes)
+	s.apiKeys = append(s.apiKeys, key)
+	return key.secret
+}
+
+// setScopes replaces the scopes of a key
+func (s *Server) setScopes(key *apiKey, scopes []string) {
+	now := s.clock()
+	key.Scopes = make([]responses.APIKeyScope, 0, len(scopes))
+	for _, scope := range scopes {
+		granted := responses.APIKeyScope{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			APIKeyID:  key.ID,
+			Scope:     scope,
+		}
+		if start := strings.Index(scope, ":{"); start >= 0 {
+			if domain := s.findDomain(strings.Trim(scope[start+1:], "{}")); domain != nil {
+				id := domain.ID
+				granted.DomainID = &id
+			}
+		}
+		key.Scopes = append(key.Scopes, granted)
+	}
+}
+
+// normalizeIPAllowList canonicalizes IP allow list entries to CIDR blocks
+func normalizeIPAllowList(entries []string) ([]string, bool) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if ip := net.ParseIP(entry); ip != nil {
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, false
+		}
+		if ones, _ := network.Mask.Size(); ones == 0 {
+			return nil, false
+		}
+		if canonical := network.String(); !seen[canonical] {
+			seen[canonical] = true
+			normalized = append(normalized, canonical)
+		}
+	}
+	return normalized, len(normalized) <= 100
+}
+
+func (s *Server) findAPIKey(id string) *apiKey {
+	for _, key := range s.apiKeys {
+		if key.ID.String() == id {
+			return key
+		}
+	}
+	return nil
+}
+
+func (s *Server) getAPIKeys(c *call) (int, interface{}) {
+	if !c.key.allows("api-keys:read") {
+		return forbidden()
+	}
+	keys := make([]responses.APIKey, 0, len(s.apiKeys))
+	for _, key := range s.apiKeys {
+		keys = append(keys, key.APIKey)
+	}
+	return paginate(c, keys, func(key responses.APIKey) string { return key.ID.String() })
+}
+
+func (s *Server) createAPIKey(c *call) (int, interface{}) {
+	if !c.key.allows("api-keys:write") {
+		return forbidden()
+	}
+	var req requests.CreateAPIKeyRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if strings.TrimSpace(req.Label) == "" {
+		return errorf(http.StatusBadRequest, "label is required")
+	}
+	if len(req.Scopes) == 0 {
+		return errorf(http.StatusBadRequest, "at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return errorf(http.StatusBadRequest, "invalid scope %q", scope)
+		}
+	}
+	ipAllowList, ok := normalizeIPAllowList(req.IPAllowList)
+	if !ok {
+		return errorf(http.StatusBadRequest, "invalid ip_allow_list")
+	}
+
+	secret := s.addAPIKey(req.Label, req.Scopes)
+	key := s.apiKeys[len(s.apiKeys)-1]
+	key.IPAllowList = ipAllowList
+
+	created := key.APIKey
+	created.SecretKey = &secret
+	return http.StatusCreated, created
+}
+
+func (s *Server) getAPIKey(c *call) (int, interface{}) {
+	if !c.key.allows("api-keys:read") {
+		return forbidden()
+	}
+	key := s.findAPIKey(c.params["key_id"])
+	if key == nil {
+		return errorf(http.StatusNotFound, "API key not found")
+	}
+	return http.StatusOK, key.APIKey
+}
+
+func (s *Server) updateAPIKey(c *call) (int, interface{}) {
+	if !c.key.allows("api-keys:write") {
+		return forbidden()
+	}
+	key := s.findAPIKey(c.params["key_id"])
+	if key == nil {
+		return errorf(http.StatusNotFound, "API key not found")
+	}
+	var req requests.UpdateAPIKeyRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if req.Label == nil && req.Scopes == nil && req.IPAllowList == nil {
+		return errorf(http.StatusBadRequest, "at least one field must be provided")
+	}
+
+	if req.Label != nil && strings.TrimSpace(*req.Label) == "" {
+		return errorf(http.StatusBadRequest, "label must not be empty")
+	}
+	if req.Scopes != nil {
+		if len(*req.Scopes) == 0 {
+			return errorf(http.StatusBadRequest, "at least one scope is required")
+		}
+		for _, scope := range *req.Scopes {
+			if !validScope(scope) {
+				return errorf(http.StatusBadRequest, "invalid scope %q", scope)
+			}
+		}
+	}
+	var ipAllowList []string
+	if req.IPAllowList != nil {
+		var ok bool
+		if ipAllowList, ok = normalizeIPAllowList(*req.IPAllowList); !ok {
+			return errorf(http.StatusBadRequest, "invalid ip_allow_list")
+		}
+	}
+
+	if req.Label != nil {
+		key.Label = *req.Label
+	}
+	if req.Scopes != nil {
+		s.setScopes(key, *req.Scopes)
+	}
+	if req.IPAllowList != nil {
+		key.IPAllowList = ipAllowList
+	}
+	key.UpdatedAt = s.clock()
+	return http.StatusOK, key.APIKey
+}
+
+func (s *Server) deleteAPIKey(c *call) (int, interface{}) {
+	if !c.key.allows("api-keys:delete") {
+		return forbidden()
+	}
+	for i, key := range s.apiKeys {
+		if key.ID.String() == c.params["key_id"] {
+			s.apiKeys = append(s.apiKeys[:i], s.apiKeys[i+1:]...)
+			return success("API key deleted successfully")
+		}
+	}
+	return errorf(http.StatusNotFound, "API key not found")
+}
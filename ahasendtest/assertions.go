@@ -0,0 +1,79 @@
+package ahasendtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// SentMessages returns every message the server accepted, one per recipient,
+// oldest first. Messages to suppressed recipients were not accepted and are
+// not included.
+func (s *Server) SentMessages() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.advance()
+	messages := make([]SentMessage, 0, len(s.messages))
+	for _, m := range s.messages {
+		messages = append(messages, m.clone())
+	}
+	return messages
+}
+
+// MessagesTo returns the messages accepted for a recipient, oldest first
+func (s *Server) MessagesTo(recipient string) []SentMessage {
+	var messages []SentMessage
+	for _, m := range s.SentMessages() {
+		if strings.EqualFold(m.To.Email, recipient) {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+// AssertMessageSent fails the test unless a message to recipient with the
+// given subject was accepted, and returns the most recent such message
+func (s *Server) AssertMessageSent(t testing.TB, recipient, subject string) SentMessage {
+	t.Helper()
+
+	messages := s.MessagesTo(recipient)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Subject == subject {
+			return messages[i]
+		}
+	}
+	t.Errorf("ahasendtest: no message to %s with subject %q was sent%s", recipient, subject, describeSent(s.SentMessages()))
+	return SentMessage{}
+}
+
+// AssertNoMessageSent fails the test if any message to recipient was accepted
+func (s *Server) AssertNoMessageSent(t testing.TB, recipient string) {
+	t.Helper()
+
+	if messages := s.MessagesTo(recipient); len(messages) > 0 {
+		t.Errorf("ahasendtest: expected no message to %s, but %d were sent%s", recipient, len(messages), describeSent(messages))
+	}
+}
+
+// AssertMessageCount fails the test unless exactly n messages were accepted
+func (s *Server) AssertMessageCount(t testing.TB, n int) {
+	t.Helper()
+
+	if messages := s.SentMessages(); len(messages) != n {
+		t.Errorf("ahasendtest: expected %d sent messages, got %d%s", n, len(messages), describeSent(messages))
+	}
+}
+
+// describeSent lists messages for a failure message
+func describeSent(messages []SentMessage) string {
+	if len(messages) == 0 {
+		return "; no messages were sent"
+	}
+	var b strings.Builder
+	b.WriteString("; sent messages:")
+	for _, m := range messages {
+		fmt.Fprintf(&b, "\n  to %s: %q (%s)", m.To.Email, m.Subject, m.Status)
+	}
+	return b.String()
+}
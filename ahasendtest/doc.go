@@ -0,0 +1,24 @@
+// Package ahasendtest provides an in-process fake of the AhaSend v2 API for
+// tests that should not depend on the real service or on the Prism mock.
+//
+// A Server keeps real state in memory, so the requests a test makes affect
+// the responses it gets afterwards: a message sent with CreateMessage shows up
+// in GetMessages, a suppression stops later sends to that recipient, domains
+// carry DNS records that can be marked verified, API keys are only allowed
+// what their scopes grant, and list endpoints paginate with working cursors.
+//
+//	server := ahasendtest.NewServer()
+//	defer server.Close()
+//
+//	client := server.Client()
+//	_, _, err := client.MessagesAPI.CreateMessage(ctx, server.AccountID(), request)
+//	...
+//	server.AssertMessageSent(t, "user@example.com", "Welcome")
+//
+// Faults let a test exercise the unhappy paths of its own code: AddFault
+// makes matching requests fail, respond slowly or be rate limited.
+//
+// The server implements the ping, account, API key, domain, message,
+// suppression, route, webhook and SMTP credential endpoints. Account member,
+// sub-account and statistics endpoints respond with 501 Not Implemented.
+package ahasendtest
@@ -0,0 +1,278 @@
+package ahasendtest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// newDNSRecords returns the records a new domain has to publish
+func newDNSRecords(domain string) []responses.DNSRecord {
+	label := func(s string) *string { return &s }
+	return []responses.DNSRecord{
+		{
+			Type:     "TXT",
+			Label:    label("SPF"),
+			Host:     domain,
+			Content:  "v=spf1 include:spf.ahasend.com ~all",
+			Required: true,
+		},
+		{
+			Type:     "CNAME",
+			Label:    label("DKIM"),
+			Host:     "aha-dkim._domainkey." + domain,
+			Content:  "dkim.ahasend.com",
+			Required: true,
+		},
+		{
+			Type:     "CNAME",
+			Label:    label("Return path"),
+			Host:     "aha-rp." + domain,
+			Content:  "rp.ahasend.com",
+			Required: true,
+		},
+		{
+			Type:     "TXT",
+			Label:    label("DMARC"),
+			Host:     "_dmarc." + domain,
+			Content:  "v=DMARC1; p=none;",
+			Required: false,
+		},
+	}
+}
+
+// validDomainName reports whether name looks like a domain name
+func validDomainName(name string) bool {
+	if len(name) > 253 || !strings.Contains(name, ".") {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// domainOf returns the lowercased domain part of an email address
+func domainOf(email string) string {
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		return strings.ToLower(email[at+1:])
+	}
+	return ""
+}
+
+func (s *Server) findDomain(name string) *responses.Domain {
+	for _, domain := range s.domains {
+		if strings.EqualFold(domain.Domain, name) {
+			return domain
+		}
+	}
+	return nil
+}
+
+// AddDomain adds an unverified domain, as CreateDomain does, and returns it
+func (s *Server) AddDomain(name string) (responses.Domain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domain, err := s.addDomain(requests.CreateDomainRequest{Domain: name})
+	if err != nil {
+		return responses.Domain{}, err
+	}
+	copied := *domain
+	copied.DNSRecords = append([]responses.DNSRecord{}, domain.DNSRecords...)
+	return copied, nil
+}
+
+// VerifyDomain marks every DNS record of a domain as published, so that the
+// next DNS check finds the domain valid. It returns false if the domain does
+// not exist.
+func (s *Server) VerifyDomain(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domain := s.findDomain(name)
+	if domain == nil {
+		return false
+	}
+	for i := range domain.DNSRecords {
+		domain.DNSRecords[i].Propagated = true
+	}
+	s.checkDNS(domain)
+	return true
+}
+
+// addDomain stores a new domain
+func (s *Server) addDomain(req requests.CreateDomainRequest) (*responses.Domain, error) {
+	name := strings.ToLower(strings.TrimSpace(req.Domain))
+	if !validDomainName(name) {
+		return nil, fmt.Errorf("invalid domain %q", req.Domain)
+	}
+	if s.findDomain(name) != nil {
+		return nil, fmt.Errorf("domain %s already exists", name)
+	}
+
+	now := s.clock()
+	domain := &responses.Domain{
+		Object:                   "domain",
+		ID:                       uuid.New(),
+		CreatedAt:                now,
+		UpdatedAt:                now,
+		Domain:                   name,
+		AccountID:                s.accountID,
+		DNSRecords:               newDNSRecords(name),
+		TrackingSubdomain:        req.TrackingSubdomain,
+		ReturnPathSubdomain:      req.ReturnPathSubdomain,
+		SubscriptionSubdomain:    req.SubscriptionSubdomain,
+		MediaSubdomain:           req.MediaSubdomain,
+		DKIMRotationIntervalDays: req.DKIMRotationIntervalDays,
+	}
+	s.domains = append(s.domains, domain)
+
+	// Domain scopes granted before the domain existed now resolve to it
+	for _, key := range s.apiKeys {
+		for i, scope := range key.Scopes {
+			if scope.DomainID == nil && strings.HasSuffix(scope.Scope, ":{"+name+"}") {
+				id := domain.ID
+				key.Scopes[i].DomainID = &id
+			}
+		}
+	}
+	return domain, nil
+}
+
+// checkDNS records a DNS check of a domain. The domain is valid once every
+// required record has been published.
+func (s *Server) checkDNS(domain *responses.Domain) {
+	now := s.clock()
+	valid := true
+	for _, record := range domain.DNSRecords {
+		if record.Required && !record.Propagated {
+			valid = false
+		}
+	}
+	domain.DNSValid = valid
+	domain.LastDNSCheckAt = &now
+	domain.UpdatedAt = now
+}
+
+func (s *Server) getDomains(c *call) (int, interface{}) {
+	if !c.key.allows("domains:read") {
+		return forbidden()
+	}
+
+	var dnsValid *bool
+	if value := c.r.URL.Query().Get("dns_valid"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return errorf(http.StatusBadRequest, "invalid dns_valid %q", value)
+		}
+		dnsValid = &parsed
+	}
+
+	domains := make([]responses.Domain, 0, len(s.domains))
+	for _, domain := range s.domains {
+		if dnsValid == nil || domain.DNSValid == *dnsValid {
+			domains = append(domains, *domain)
+		}
+	}
+	return paginate(c, domains, func(domain responses.Domain) string { return domain.ID.String() })
+}
+
+func (s *Server) createDomain(c *call) (int, interface{}) {
+	if !c.key.allows("domains:write") {
+		return forbidden()
+	}
+	var req requests.CreateDomainRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if existing := s.findDomain(strings.TrimSpace(req.Domain)); existing != nil {
+		return errorf(http.StatusConflict, "domain %s already exists", existing.Domain)
+	}
+	domain, err := s.addDomain(req)
+	if err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	return http.StatusCreated, *domain
+}
+
+func (s *Server) getDomain(c *call) (int, interface{}) {
+	if !c.key.allows("domains:read") {
+		return forbidden()
+	}
+	domain := s.findDomain(c.params["domain"])
+	if domain == nil {
+		return errorf(http.StatusNotFound, "domain not found")
+	}
+	return http.StatusOK, *domain
+}
+
+func (s *Server) updateDomain(c *call) (int, interface{}) {
+	if !c.key.allows("domains:write") {
+		return forbidden()
+	}
+	domain := s.findDomain(c.params["domain"])
+	if domain == nil {
+		return errorf(http.StatusNotFound, "domain not found")
+	}
+	var req requests.UpdateDomainRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+
+	if req.TrackingSubdomain != nil {
+		domain.TrackingSubdomain = req.TrackingSubdomain
+	}
+	if req.ReturnPathSubdomain != nil {
+		domain.ReturnPathSubdomain = req.ReturnPathSubdomain
+	}
+	if req.SubscriptionSubdomain != nil {
+		domain.SubscriptionSubdomain = req.SubscriptionSubdomain
+	}
+	if req.MediaSubdomain != nil {
+		domain.MediaSubdomain = req.MediaSubdomain
+	}
+	if req.DKIMRotationIntervalDays != nil {
+		domain.DKIMRotationIntervalDays = req.DKIMRotationIntervalDays
+	}
+	domain.UpdatedAt = s.clock()
+	return http.StatusOK, *domain
+}
+
+func (s *Server) deleteDomain(c *call) (int, interface{}) {
+	name := c.params["domain"]
+	if !c.key.allows("domains:delete", name) {
+		return forbidden()
+	}
+	for i, domain := range s.domains {
+		if strings.EqualFold(domain.Domain, name) {
+			s.domains = append(s.domains[:i], s.domains[i+1:]...)
+			return success("Domain deleted successfully")
+		}
+	}
+	return errorf(http.StatusNotFound, "domain not found")
+}
+
+func (s *Server) checkDomainDNS(c *call) (int, interface{}) {
+	if !c.key.allows("domains:read") && !c.key.allows("domains:write") {
+		return forbidden()
+	}
+	domain := s.findDomain(c.params["domain"])
+	if domain == nil {
+		return errorf(http.StatusNotFound, "domain not found")
+	}
+	s.checkDNS(domain)
+	return http.StatusOK, *domain
+}
@@ -0,0 +1,152 @@
+package ahasendtest
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+)
+
+// Fault changes how the server answers matching requests, so that tests can
+// exercise error handling, timeouts and rate limiting
+type Fault struct {
+	// Method restricts the fault to one HTTP method. Empty matches any method.
+	Method string
+
+	// Path restricts the fault to request paths matching a path.Match
+	// pattern, such as "/v2/accounts/*/messages". Empty matches any path.
+	Path string
+
+	// Latency delays the response. A request whose context ends first gets
+	// no response.
+	Latency time.Duration
+
+	// Status, when set, is returned instead of handling the request. A zero
+	// Status handles the request normally after Latency.
+	Status int
+
+	// Message is the error message in the response body. It defaults to the
+	// status text.
+	Message string
+
+	// RetryAfter sets the Retry-After header, rounded up to whole seconds
+	RetryAfter time.Duration
+
+	// Header adds headers to the response
+	Header http.Header
+
+	// Times limits how many requests the fault affects. Zero means every
+	// matching request until the fault is cleared.
+	Times int
+}
+
+// matches reports whether the fault applies to a request
+func (f *Fault) matches(r *http.Request) bool {
+	if f.Method != "" && f.Method != r.Method {
+		return false
+	}
+	if f.Path != "" {
+		if ok, err := path.Match(f.Path, r.URL.Path); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// apply delays the request and returns the fault's response. It returns false
+// when the request should then be handled normally.
+func (f *Fault) apply(r *http.Request) (response, bool) {
+	if f.Latency > 0 {
+		timer := time.NewTimer(f.Latency)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return response{status: http.StatusServiceUnavailable}, true
+		case <-timer.C:
+		}
+	}
+	if f.Status == 0 {
+		return response{}, false
+	}
+
+	message := f.Message
+	if message == "" {
+		message = http.StatusText(f.Status)
+	}
+	resp := jsonResponse(f.Status, common.ErrorResponse{Message: message})
+	resp.header = f.Header.Clone()
+	if f.RetryAfter > 0 {
+		if resp.header == nil {
+			resp.header = http.Header{}
+		}
+		seconds := int((f.RetryAfter + time.Second - 1) / time.Second)
+		resp.header.Set("Retry-After", strconv.Itoa(seconds))
+	}
+	return resp, true
+}
+
+// AddFault adds a fault. Every matching fault delays a request by its
+// Latency, up to and including the first one that sets a Status.
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// FailNext makes the next n requests fail with status
+func (s *Server) FailNext(n int, status int) {
+	s.AddFault(Fault{Status: status, Times: n})
+}
+
+// RateLimitNext makes the next n requests fail with 429 Too Many Requests
+// and the given Retry-After
+func (s *Server) RateLimitNext(n int, retryAfter time.Duration) {
+	s.AddFault(Fault{
+		Status:     http.StatusTooManyRequests,
+		Message:    "rate limit exceeded",
+		RetryAfter: retryAfter,
+		Times:      n,
+	})
+}
+
+// SetLatency delays every response by d, in addition to any other fault
+func (s *Server) SetLatency(d time.Duration) {
+	s.AddFault(Fault{Latency: d})
+}
+
+// ClearFaults removes every fault
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// takeFaults returns the faults that apply to a request, counting the
+// request against their Times
+func (s *Server) takeFaults(r *http.Request) []Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var applied []Fault
+	remaining := make([]*Fault, 0, len(s.faults))
+	done := false
+	for _, f := range s.faults {
+		if done || !f.matches(r) {
+			remaining = append(remaining, f)
+			continue
+		}
+		applied = append(applied, *f)
+		done = f.Status != 0
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				continue
+			}
+		}
+		remaining = append(remaining, f)
+	}
+	s.faults = remaining
+	return applied
+}
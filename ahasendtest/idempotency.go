@@ -0,0 +1,47 @@
+package ahasendtest
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// storedResponse is the outcome of a request made with an Idempotency-Key,
+// kept so that a retry with the same key gets the same answer
+type storedResponse struct {
+	method string
+	path   string
+	body   []byte
+	resp   response
+}
+
+// handleIdempotent handles a request carrying an Idempotency-Key the way the
+// API does: the first request with a key executes, a repeat of the same
+// request replays its response with Idempotent-Replayed: true, and reusing
+// the key for a different request is rejected with 422. Server errors are
+// not stored, so a retry after one executes again.
+//
+// Stored responses never expire; requests are handled one at a time, so the
+// API's 409 for a key whose first request is still executing cannot occur
+// here unless it is injected with a Fault.
+func (s *Server) handleIdempotent(key string, rt *route, c *call) response {
+	if stored, ok := s.idempotency[key]; ok {
+		if stored.method != c.r.Method || stored.path != c.r.URL.Path || !bytes.Equal(stored.body, c.body) {
+			return jsonResponse(errorf(http.StatusUnprocessableEntity,
+				"idempotency key was already used for a different request"))
+		}
+		replay := stored.resp
+		replay.header = http.Header{"Idempotent-Replayed": {"true"}}
+		return replay
+	}
+
+	resp := jsonResponse(rt.handle(s, c))
+	if resp.status < http.StatusInternalServerError {
+		s.idempotency[key] = &storedResponse{
+			method: c.r.Method,
+			path:   c.r.URL.Path,
+			body:   append([]byte(nil), c.body...),
+			resp:   resp,
+		}
+	}
+	return resp
+}
@@ -0,0 +1,524 @@
+package ahasendtest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// Message statuses the server assigns. A message is delivered as soon as it
+// is accepted unless it is scheduled; SetMessageStatus simulates any other
+// outcome.
+const (
+	StatusDelivered = common.MessageStatusDelivered
+	StatusScheduled = common.MessageStatusScheduled
+	StatusCancelled = common.MessageStatusCancelled
+)
+
+// sandboxStatuses maps a sandbox_result to the status of the message
+var sandboxStatuses = map[string]common.MessageStatus{
+	"deliver":  common.MessageStatusSandboxDelivered,
+	"bounce":   common.MessageStatusSandboxBounced,
+	"defer":    common.MessageStatusSandboxDeferred,
+	"fail":     common.MessageStatusSandboxFailed,
+	"suppress": common.MessageStatusSandboxSuppressed,
+}
+
+// SentMessage is a message the server accepted, as sent to one recipient
+type SentMessage struct {
+	// ID is the message ID returned by CreateMessage, such as
+	// "<uuid@example.com>"
+	ID          string
+	From        common.SenderAddress
+	To          common.Recipient
+	ReplyTo     *common.SenderAddress
+	Subject     string
+	TextContent *string
+	HTMLContent *string
+	AMPContent  *string
+	Attachments []common.Attachment
+	Headers     map[string]string
+	// Substitutions are the request's global substitutions merged with the
+	// recipient's own, which take precedence
+	Substitutions map[string]interface{}
+	Tags          []string
+	Sandbox       bool
+	Schedule      *common.MessageSchedule
+	Status        common.MessageStatus
+	CreatedAt     time.Time
+}
+
+// message is a stored message
+type message struct {
+	SentMessage
+	id          uuid.UUID
+	domainID    uuid.UUID
+	updatedAt   time.Time
+	deliveredAt *time.Time
+}
+
+// summary returns the message as the API reports it
+func (m *message) summary(accountID uuid.UUID) responses.Message {
+	tags := m.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return responses.Message{
+		Object:           "message",
+		ID:               m.id,
+		MessageID:        m.ID,
+		CreatedAt:        m.CreatedAt,
+		UpdatedAt:        m.updatedAt,
+		SentAt:           m.deliveredAt,
+		DeliveredAt:      m.deliveredAt,
+		RetainUntil:      m.CreatedAt.Add(30 * 24 * time.Hour),
+		Subject:          m.Subject,
+		Tags:             tags,
+		Sender:           m.From.Email,
+		Recipient:        m.To.Email,
+		Direction:        "outbound",
+		Status:           m.Status,
+		DeliveryAttempts: []responses.DeliveryEvent{},
+		DomainID:         m.domainID,
+		AccountID:        accountID,
+	}
+}
+
+// details returns the message with its parsed content, as GetMessage
+// reports it
+func (m *message) details(accountID uuid.UUID) responses.Message {
+	result := m.summary(accountID)
+
+	parsed := &responses.ContentParsed{
+		Parts:       []responses.ContentPart{},
+		Attachments: []responses.ContentAttachment{},
+		Headers:     map[string][]string{"Subject": {m.Subject}, "Message-Id": {m.ID}},
+	}
+	if m.TextContent != nil {
+		parsed.Parts = append(parsed.Parts, responses.ContentPart{ContentType: "text/plain", Content: *m.TextContent})
+	}
+	if m.HTMLContent != nil {
+		parsed.Parts = append(parsed.Parts, responses.ContentPart{ContentType: "text/html", Content: *m.HTMLContent})
+	}
+	for _, attachment := range m.Attachments {
+		contentID := ""
+		if attachment.ContentID != nil {
+			contentID = *attachment.ContentID
+		}
+		parsed.Attachments = append(parsed.Attachments, responses.ContentAttachment{
+			Filename:    attachment.FileName,
+			ContentType: attachment.ContentType,
+			Content:     attachment.Data,
+			ContentID:   contentID,
+		})
+	}
+	for name, value := range m.Headers {
+		parsed.Headers[name] = []string{value}
+	}
+	result.ContentParsed = parsed
+	return result
+}
+
+// clone returns a copy of the message's fields that does not share maps or
+// slices with the stored message
+func (m *message) clone() SentMessage {
+	sent := m.SentMessage
+	sent.Attachments = append([]common.Attachment(nil), m.Attachments...)
+	sent.Tags = append([]string(nil), m.Tags...)
+	if m.Headers != nil {
+		sent.Headers = make(map[string]string, len(m.Headers))
+		for name, value := range m.Headers {
+			sent.Headers[name] = value
+		}
+	}
+	if m.Substitutions != nil {
+		sent.Substitutions = make(map[string]interface{}, len(m.Substitutions))
+		for name, value := range m.Substitutions {
+			sent.Substitutions[name] = value
+		}
+	}
+	return sent
+}
+
+// draft is what the message creation endpoints have in common
+type draft struct {
+	from          common.SenderAddress
+	replyTo       *common.SenderAddress
+	subject       string
+	text          *string
+	html          *string
+	amp           *string
+	attachments   []common.Attachment
+	headers       map[string]string
+	substitutions map[string]interface{}
+	tags          []string
+	sandbox       bool
+	sandboxResult string
+	schedule      *common.MessageSchedule
+}
+
+func (s *Server) createMessage(c *call) (int, interface{}) {
+	var req requests.CreateMessageRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if len(req.Recipients) == 0 {
+		return errorf(http.StatusBadRequest, "at least one recipient is required")
+	}
+
+	d := draft{
+		from:          req.From,
+		replyTo:       req.ReplyTo,
+		subject:       req.Subject,
+		text:          req.TextContent,
+		html:          req.HtmlContent,
+		amp:           req.AmpContent,
+		attachments:   req.Attachments,
+		headers:       req.Headers,
+		substitutions: req.Substitutions,
+		tags:          req.Tags,
+		sandbox:       req.Sandbox != nil && *req.Sandbox,
+		schedule:      req.Schedule,
+	}
+	if req.SandboxResult != nil {
+		d.sandboxResult = *req.SandboxResult
+	}
+	return s.send(c, d, req.Recipients)
+}
+
+func (s *Server) createConversationMessage(c *call) (int, interface{}) {
+	var req requests.CreateConversationMessageRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if len(req.To) == 0 {
+		return errorf(http.StatusBadRequest, "at least one to address is required")
+	}
+
+	var recipients []common.Recipient
+	for _, addresses := range [][]common.SenderAddress{req.To, req.CC, req.BCC} {
+		for _, address := range addresses {
+			recipients = append(recipients, common.Recipient{Email: address.Email, Name: address.Name})
+		}
+	}
+
+	d := draft{
+		from:        req.From,
+		replyTo:     req.ReplyTo,
+		subject:     req.Subject,
+		text:        req.TextContent,
+		html:        req.HtmlContent,
+		amp:         req.AmpContent,
+		attachments: req.Attachments,
+		headers:     req.Headers,
+		tags:        req.Tags,
+		sandbox:     req.Sandbox != nil && *req.Sandbox,
+		schedule:    req.Schedule,
+	}
+	if req.SandboxResult != nil {
+		d.sandboxResult = *req.SandboxResult
+	}
+	return s.send(c, d, recipients)
+}
+
+// send stores one message per recipient. Suppressed recipients are not sent
+// to and get an error status in the response.
+func (s *Server) send(c *call, d draft, recipients []common.Recipient) (int, interface{}) {
+	if !strings.Contains(d.from.Email, "@") {
+		return errorf(http.StatusBadRequest, "from.email is required")
+	}
+	if strings.TrimSpace(d.subject) == "" {
+		return errorf(http.StatusBadRequest, "subject is required")
+	}
+	if d.text == nil && d.html == nil {
+		return errorf(http.StatusBadRequest, "either text_content or html_content is required")
+	}
+	if d.sandboxResult != "" {
+		if _, ok := sandboxStatuses[d.sandboxResult]; !ok {
+			return errorf(http.StatusBadRequest, "invalid sandbox_result %q", d.sandboxResult)
+		}
+	}
+	for _, recipient := range recipients {
+		if !strings.Contains(recipient.Email, "@") {
+			return errorf(http.StatusBadRequest, "invalid recipient email %q", recipient.Email)
+		}
+	}
+
+	now := s.clock()
+	scheduled := false
+	if d.schedule != nil && d.schedule.FirstAttempt != nil {
+		if !d.schedule.FirstAttempt.After(now) {
+			return errorf(http.StatusBadRequest, "schedule.first_attempt must be in the future")
+		}
+		scheduled = true
+	}
+
+	senderDomain := domainOf(d.from.Email)
+	if !c.key.allows("messages:send", senderDomain) {
+		return forbidden()
+	}
+	domain := s.findDomain(senderDomain)
+	if s.strictDomains && (domain == nil || !domain.DNSValid) {
+		return errorf(http.StatusBadRequest, "sender domain %s is not verified", senderDomain)
+	}
+	var domainID uuid.UUID
+	if domain != nil {
+		domainID = domain.ID
+	}
+
+	status := StatusDelivered
+	switch {
+	case scheduled:
+		status = StatusScheduled
+	case d.sandbox:
+		status = sandboxStatuses["deliver"]
+		if d.sandboxResult != "" {
+			status = sandboxStatuses[d.sandboxResult]
+		}
+	}
+
+	result := responses.CreateMessageResponse{Object: "list", Data: []responses.CreateSingleMessageResponse{}}
+	for _, recipient := range recipients {
+		single := responses.CreateSingleMessageResponse{
+			Object:    "message",
+			Recipient: common.Recipient{Email: recipient.Email, Name: recipient.Name, Substitutions: recipient.Substitutions},
+		}
+		if d.schedule != nil {
+			single.Schedule = &responses.MessageSchedule{FirstAttempt: d.schedule.FirstAttempt, Expires: d.schedule.Expires}
+		}
+
+		if !d.sandbox && s.suppressed(recipient.Email, senderDomain) {
+			reason := fmt.Sprintf("recipient %s is suppressed", recipient.Email)
+			single.Status = "error"
+			single.Error = &reason
+			result.Data = append(result.Data, single)
+			continue
+		}
+
+		id := uuid.New()
+		m := &message{
+			SentMessage: SentMessage{
+				ID:            fmt.Sprintf("<%s@%s>", id, senderDomain),
+				From:          d.from,
+				To:            recipient,
+				ReplyTo:       d.replyTo,
+				Subject:       d.subject,
+				TextContent:   d.text,
+				HTMLContent:   d.html,
+				AMPContent:    d.amp,
+				Attachments:   d.attachments,
+				Headers:       d.headers,
+				Substitutions: mergeSubstitutions(d.substitutions, recipient.Substitutions),
+				Tags:          d.tags,
+				Sandbox:       d.sandbox,
+				Schedule:      d.schedule,
+				Status:        status,
+				CreatedAt:     now,
+			},
+			id:        id,
+			domainID:  domainID,
+			updatedAt: now,
+		}
+		if !scheduled {
+			m.deliveredAt = &now
+		}
+		s.messages = append(s.messages, m)
+
+		single.ID = &m.ID
+		single.Status = "queued"
+		if scheduled {
+			single.Status = "scheduled"
+		}
+		result.Data = append(result.Data, single)
+	}
+	return http.StatusAccepted, result
+}
+
+// mergeSubstitutions merges global and per-recipient substitutions, with the
+// recipient's values taking precedence
+func mergeSubstitutions(global, recipient map[string]interface{}) map[string]interface{} {
+	if len(global) == 0 && len(recipient) == 0 {
+		return nil
+	}
+	merged := make(map[string]interface{}, len(global)+len(recipient))
+	for name, value := range global {
+		merged[name] = value
+	}
+	for name, value := range recipient {
+		merged[name] = value
+	}
+	return merged
+}
+
+// advance delivers scheduled messages whose first attempt is due
+func (s *Server) advance() {
+	now := s.clock()
+	for _, m := range s.messages {
+		if m.Status != StatusScheduled || m.Schedule == nil || m.Schedule.FirstAttempt == nil {
+			continue
+		}
+		if !m.Schedule.FirstAttempt.After(now) {
+			deliveredAt := *m.Schedule.FirstAttempt
+			m.Status = StatusDelivered
+			m.deliveredAt = &deliveredAt
+			m.updatedAt = now
+		}
+	}
+}
+
+// findMessage finds a message by its API ID, either the generated Message-ID
+// such as "<uuid@example.com>" or its bare UUID
+func (s *Server) findMessage(apiID string) *message {
+	value := strings.TrimSuffix(strings.TrimPrefix(apiID, "<"), ">")
+	if at := strings.Index(value, "@"); at >= 0 {
+		value = value[:at]
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil
+	}
+	for _, m := range s.messages {
+		if m.id == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// SetMessageStatus changes the status of a message, to simulate what
+// happened to it after it was sent, such as "Bounced" or "Failed". It
+// returns false if there is no message with the given ID.
+func (s *Server) SetMessageStatus(id string, status common.MessageStatus) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.findMessage(id)
+	if m == nil {
+		return false
+	}
+	m.Status = status
+	m.updatedAt = s.clock()
+	return true
+}
+
+func (s *Server) getMessages(c *call) (int, interface{}) {
+	all, readable := c.key.grants("messages:read")
+	if !all && len(readable) == 0 {
+		return forbidden()
+	}
+
+	query := c.r.URL.Query()
+	var fromTime, toTime time.Time
+	for name, target := range map[string]*time.Time{"from_time": &fromTime, "to_time": &toTime} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return errorf(http.StatusBadRequest, "invalid %s %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+	statuses := splitList(query.Get("status"))
+	for _, status := range statuses {
+		if !common.MessageStatus(status).IsFilterable() {
+			return errorf(http.StatusBadRequest, "invalid status %q", status)
+		}
+	}
+	tags := splitList(query.Get("tags"))
+
+	matches := func(m *message) bool {
+		if !all && !readable[domainOf(m.From.Email)] {
+			return false
+		}
+		if len(statuses) > 0 && !containsFold(statuses, string(m.Status)) {
+			return false
+		}
+		for _, tag := range tags {
+			if !containsFold(m.Tags, tag) {
+				return false
+			}
+		}
+		if sender := query.Get("sender"); sender != "" && !strings.EqualFold(sender, m.From.Email) {
+			return false
+		}
+		if recipient := query.Get("recipient"); recipient != "" && !strings.EqualFold(recipient, m.To.Email) {
+			return false
+		}
+		if subject := query.Get("subject"); subject != "" && subject != m.Subject {
+			return false
+		}
+		if header := query.Get("message_id_header"); header != "" && header != m.ID {
+			return false
+		}
+		if !fromTime.IsZero() && m.CreatedAt.Before(fromTime) {
+			return false
+		}
+		if !toTime.IsZero() && !m.CreatedAt.Before(toTime) {
+			return false
+		}
+		return true
+	}
+
+	// Newest first, as the API lists messages
+	summaries := []responses.Message{}
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if matches(s.messages[i]) {
+			summaries = append(summaries, s.messages[i].summary(s.accountID))
+		}
+	}
+	return paginate(c, summaries, func(m responses.Message) string { return m.ID.String() })
+}
+
+func (s *Server) getMessage(c *call) (int, interface{}) {
+	m := s.findMessage(c.params["message_id"])
+	if m == nil {
+		return errorf(http.StatusNotFound, "message not found")
+	}
+	if !c.key.allows("messages:read", domainOf(m.From.Email)) {
+		return forbidden()
+	}
+	return http.StatusOK, m.details(s.accountID)
+}
+
+func (s *Server) cancelMessage(c *call) (int, interface{}) {
+	m := s.findMessage(c.params["message_id"])
+	if m == nil {
+		return errorf(http.StatusNotFound, "message not found")
+	}
+	if !c.key.allows("messages:cancel", domainOf(m.From.Email)) {
+		return forbidden()
+	}
+	if m.Status != StatusScheduled {
+		return errorf(http.StatusBadRequest, "only scheduled messages can be cancelled")
+	}
+	m.Status = StatusCancelled
+	m.updatedAt = s.clock()
+	return success("Message cancelled successfully")
+}
+
+// splitList splits a comma-separated query parameter
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// containsFold reports whether items contains value, ignoring case
+func containsFold(items []string, value string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
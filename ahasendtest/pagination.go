@@ -0,0 +1,88 @@
+package ahasendtest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 100
+)
+
+// paginate returns the page of items selected by the request's limit, after,
+// before and cursor query parameters. Cursors are item IDs: next_cursor is
+// the last item of the page and previous_cursor the first, so paging stays
+// stable while items are added or removed elsewhere in the list.
+func paginate[T any](c *call, items []T, id func(T) string) (int, interface{}) {
+	query := c.r.URL.Query()
+
+	limit := defaultPageSize
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			return errorf(http.StatusBadRequest, "limit must be between 1 and %d", maxPageSize)
+		}
+		limit = parsed
+	}
+
+	after, before := query.Get("after"), query.Get("before")
+	if after == "" {
+		after = query.Get("cursor")
+	}
+	if after != "" && before != "" {
+		return errorf(http.StatusBadRequest, "after and before are mutually exclusive")
+	}
+
+	indexOf := func(cursor string) int {
+		for i, item := range items {
+			if id(item) == cursor {
+				return i
+			}
+		}
+		return -1
+	}
+
+	start, end := 0, len(items)
+	switch {
+	case after != "":
+		i := indexOf(after)
+		if i < 0 {
+			return errorf(http.StatusBadRequest, "invalid cursor")
+		}
+		start = i + 1
+		if start+limit < end {
+			end = start + limit
+		}
+	case before != "":
+		i := indexOf(before)
+		if i < 0 {
+			return errorf(http.StatusBadRequest, "invalid cursor")
+		}
+		end = i
+		if end-limit > 0 {
+			start = end - limit
+		}
+	default:
+		if limit < end {
+			end = limit
+		}
+	}
+
+	page := common.PaginatedResponse[T]{
+		Object: "list",
+		Data:   append([]T{}, items[start:end]...),
+	}
+	if end < len(items) && end > start {
+		next := id(items[end-1])
+		page.Pagination.HasMore = true
+		page.Pagination.NextCursor = &next
+	}
+	if start > 0 && end > start {
+		previous := id(items[start])
+		page.Pagination.PreviousCursor = &previous
+	}
+	return http.StatusOK, page
+}
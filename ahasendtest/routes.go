@@ -0,0 +1,144 @@
+package ahasendtest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+func (s *Server) findRoute(id string) *responses.Route {
+	for _, route := range s.routes {
+		if route.ID.String() == id {
+			return route
+		}
+	}
+	return nil
+}
+
+func (s *Server) getRoutes(c *call) (int, interface{}) {
+	domain := strings.ToLower(c.r.URL.Query().Get("domain"))
+	if domain == "" && !c.key.allows("routes:read") {
+		return errorf(http.StatusForbidden, "domain is required when using a domain-scoped API key")
+	}
+	if domain != "" && !c.key.allows("routes:read", domain) {
+		return forbidden()
+	}
+
+	routes := []responses.Route{}
+	for _, route := range s.routes {
+		if domain == "" || domainOf(route.Recipient) == domain {
+			routes = append(routes, *route)
+		}
+	}
+	return paginate(c, routes, func(route responses.Route) string { return route.ID.String() })
+}
+
+func (s *Server) createRoute(c *call) (int, interface{}) {
+	var req requests.CreateRouteRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.URL) == "" {
+		return errorf(http.StatusBadRequest, "name and url are required")
+	}
+	if !strings.Contains(req.Recipient, "@") {
+		return errorf(http.StatusBadRequest, "invalid recipient %q", req.Recipient)
+	}
+	if !c.key.allows("routes:write", domainOf(req.Recipient)) {
+		return forbidden()
+	}
+
+	now := s.clock()
+	route := &responses.Route{
+		Object:           "route",
+		ID:               uuid.New(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		Name:             req.Name,
+		URL:              req.URL,
+		Recipient:        req.Recipient,
+		Attachments:      req.Attachments,
+		Headers:          req.Headers,
+		GroupByMessageID: req.GroupByMessageId,
+		StripReplies:     req.StripReplies,
+		Secret:           "aha-whsec-" + randomString(32),
+		Enabled:          req.Enabled == nil || *req.Enabled,
+	}
+	s.routes = append(s.routes, route)
+	return http.StatusCreated, *route
+}
+
+func (s *Server) getRoute(c *call) (int, interface{}) {
+	route := s.findRoute(c.params["route_id"])
+	if route == nil {
+		return errorf(http.StatusNotFound, "route not found")
+	}
+	if !c.key.allows("routes:read", domainOf(route.Recipient)) {
+		return forbidden()
+	}
+	return http.StatusOK, *route
+}
+
+func (s *Server) updateRoute(c *call) (int, interface{}) {
+	route := s.findRoute(c.params["route_id"])
+	if route == nil {
+		return errorf(http.StatusNotFound, "route not found")
+	}
+	if !c.key.allows("routes:write", domainOf(route.Recipient)) {
+		return forbidden()
+	}
+	var req requests.UpdateRouteRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if req.Recipient != nil {
+		if !strings.Contains(*req.Recipient, "@") {
+			return errorf(http.StatusBadRequest, "invalid recipient %q", *req.Recipient)
+		}
+		if !c.key.allows("routes:write", domainOf(*req.Recipient)) {
+			return forbidden()
+		}
+		route.Recipient = *req.Recipient
+	}
+
+	if req.Name != nil {
+		route.Name = *req.Name
+	}
+	if req.URL != nil {
+		route.URL = *req.URL
+	}
+	if req.Attachments != nil {
+		route.Attachments = *req.Attachments
+	}
+	if req.Headers != nil {
+		route.Headers = *req.Headers
+	}
+	if req.GroupByMessageId != nil {
+		route.GroupByMessageID = *req.GroupByMessageId
+	}
+	if req.StripReplies != nil {
+		route.StripReplies = *req.StripReplies
+	}
+	if req.Enabled != nil {
+		route.Enabled = *req.Enabled
+	}
+	route.UpdatedAt = s.clock()
+	return http.StatusOK, *route
+}
+
+func (s *Server) deleteRoute(c *call) (int, interface{}) {
+	for i, route := range s.routes {
+		if route.ID.String() != c.params["route_id"] {
+			continue
+		}
+		if !c.key.allows("routes:delete", domainOf(route.Recipient)) {
+			return forbidden()
+		}
+		s.routes = append(s.routes[:i], s.routes[i+1:]...)
+		return success("Route deleted successfully")
+	}
+	return errorf(http.StatusNotFound, "route not found")
+}
@@ -0,0 +1,437 @@
+package ahasendtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// Server is a fake AhaSend API server backed by in-memory state. It is safe
+// for concurrent use.
+type Server struct {
+	// URL is the base URL of the server, of the form http://ipaddr:port
+	URL string
+
+	httpServer    *httptest.Server
+	accountID     uuid.UUID
+	apiKey        string
+	strictDomains bool
+	now           func() time.Time
+
+	mu              sync.Mutex
+	account         responses.Account
+	apiKeys         []*apiKey
+	domains         []*responses.Domain
+	messages        []*message
+	suppressions    []*responses.Suppression
+	routes          []*responses.Route
+	webhooks        []*responses.Webhook
+	smtpCredentials []*responses.SMTPCredential
+	idempotency     map[string]*storedResponse
+	faults          []*Fault
+	requests        []Request
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithAccountID sets the ID of the account the server hosts. By default a
+// random ID is used.
+func WithAccountID(id uuid.UUID) Option {
+	return func(s *Server) {
+		s.accountID = id
+	}
+}
+
+// WithStrictDomains makes the server reject messages whose sender domain has
+// not been added and verified, as the real API does. By default any sender
+// domain is accepted.
+func WithStrictDomains() Option {
+	return func(s *Server) {
+		s.strictDomains = true
+	}
+}
+
+// WithClock sets the function the server uses to tell the time, for tests of
+// scheduled messages and suppression expiry
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// NewServer starts a fake AhaSend API server. The caller should call Close
+// when finished, to shut it down.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		accountID:   uuid.New(),
+		now:         time.Now,
+		idempotency: make(map[string]*storedResponse),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+
+	now := s.clock()
+	s.account = responses.Account{
+		Object:    "account",
+		ID:        s.accountID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      "Test Account",
+		OwnerID:   uuid.New(),
+	}
+	s.apiKey = s.addAPIKey("Test key", rootScopes)
+
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// Close shuts down the server and blocks until all outstanding requests on
+// it have completed
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// AccountID returns the ID of the account the server hosts
+func (s *Server) AccountID() uuid.UUID {
+	return s.accountID
+}
+
+// APIKey returns the secret of an API key that has every scope
+func (s *Server) APIKey() string {
+	return s.apiKey
+}
+
+// Client returns an API client that talks to the server with the key returned
+// by APIKey. Options are applied after the server's, so they can replace the
+// key or the retry configuration.
+func (s *Server) Client(opts ...api.ClientOption) *api.APIClient {
+	serverURL, err := url.Parse(s.URL)
+	if err != nil {
+		panic(fmt.Sprintf("ahasendtest: invalid server URL %q: %v", s.URL, err))
+	}
+
+	base := []api.ClientOption{
+		api.WithAPIKey(s.apiKey),
+		api.WithHTTPClient(s.httpServer.Client()),
+		func(cfg *api.Configuration) {
+			cfg.Host = serverURL.Host
+			cfg.Scheme = serverURL.Scheme
+		},
+	}
+	return api.NewAPIClient(append(base, opts...)...)
+}
+
+// Request is a request the server received
+type Request struct {
+	Method     string
+	Path       string
+	Query      url.Values
+	Header     http.Header
+	Body       []byte
+	StatusCode int
+}
+
+// Requests returns every request the server has received, oldest first
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// clock returns the current time in UTC
+func (s *Server) clock() time.Time {
+	return s.now().UTC()
+}
+
+// call is a request matched to a route, with its authenticated API key
+type call struct {
+	r      *http.Request
+	key    *apiKey
+	params map[string]string
+	body   []byte
+}
+
+// decode unmarshals the request body into v
+func (c *call) decode(v interface{}) error {
+	if len(bytes.TrimSpace(c.body)) == 0 {
+		return fmt.Errorf("request body is required")
+	}
+	if err := json.Unmarshal(c.body, v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// route maps a method and path template to the handler implementing it
+type route struct {
+	method     string
+	template   string
+	idempotent bool
+	handle     func(s *Server, c *call) (int, interface{})
+}
+
+var endpoints = []route{
+	{http.MethodGet, "/v2/ping", false, (*Server).ping},
+
+	{http.MethodGet, "/v2/accounts/{account_id}", false, (*Server).getAccount},
+	{http.MethodPut, "/v2/accounts/{account_id}", false, (*Server).updateAccount},
+
+	{http.MethodGet, "/v2/accounts/{account_id}/api-keys", false, (*Server).getAPIKeys},
+	{http.MethodPost, "/v2/accounts/{account_id}/api-keys", true, (*Server).createAPIKey},
+	{http.MethodGet, "/v2/accounts/{account_id}/api-keys/{key_id}", false, (*Server).getAPIKey},
+	{http.MethodPut, "/v2/accounts/{account_id}/api-keys/{key_id}", false, (*Server).updateAPIKey},
+	{http.MethodDelete, "/v2/accounts/{account_id}/api-keys/{key_id}", false, (*Server).deleteAPIKey},
+
+	{http.MethodGet, "/v2/accounts/{account_id}/domains", false, (*Server).getDomains},
+	{http.MethodPost, "/v2/accounts/{account_id}/domains", true, (*Server).createDomain},
+	{http.MethodGet, "/v2/accounts/{account_id}/domains/{domain}", false, (*Server).getDomain},
+	{http.MethodPut, "/v2/accounts/{account_id}/domains/{domain}", false, (*Server).updateDomain},
+	{http.MethodDelete, "/v2/accounts/{account_id}/domains/{domain}", false, (*Server).deleteDomain},
+	{http.MethodPost, "/v2/accounts/{account_id}/domains/{domain}/check-dns", false, (*Server).checkDomainDNS},
+
+	{http.MethodGet, "/v2/accounts/{account_id}/messages", false, (*Server).getMessages},
+	{http.MethodPost, "/v2/accounts/{account_id}/messages", true, (*Server).createMessage},
+	{http.MethodPost, "/v2/accounts/{account_id}/messages/conversation", true, (*Server).createConversationMessage},
+	{http.MethodGet, "/v2/accounts/{account_id}/messages/{message_id}", false, (*Server).getMessage},
+	{http.MethodDelete, "/v2/accounts/{account_id}/messages/{message_id}/cancel", false, (*Server).cancelMessage},
+
+	{http.MethodGet, "/v2/accounts/{account_id}/suppressions", false, (*Server).getSuppressions},
+	{http.MethodPost, "/v2/accounts/{account_id}/suppressions", true, (*Server).createSuppression},
+	{http.MethodDelete, "/v2/accounts/{account_id}/suppressions", false, (*Server).deleteSuppression},
+	{http.MethodDelete, "/v2/accounts/{account_id}/suppressions/all", false, (*Server).deleteAllSuppressions},
+
+	{http.MethodGet, "/v2/accounts/{account_id}/routes", false, (*Server).getRoutes},
+	{http.MethodPost, "/v2/accounts/{account_id}/routes", true, (*Server).createRoute},
+	{http.MethodGet, "/v2/accounts/{account_id}/routes/{route_id}", false, (*Server).getRoute},
+	{http.MethodPut, "/v2/accounts/{account_id}/routes/{route_id}", false, (*Server).updateRoute},
+	{http.MethodDelete, "/v2/accounts/{account_id}/routes/{route_id}", false, (*Server).deleteRoute},
+
+	{http.MethodGet, "/v2/accounts/{account_id}/webhooks", false, (*Server).getWebhooks},
+	{http.MethodPost, "/v2/accounts/{account_id}/webhooks", true, (*Server).createWebhook},
+	{http.MethodGet, "/v2/accounts/{account_id}/webhooks/{webhook_id}", false, (*Server).getWebhook},
+	{http.MethodPut, "/v2/accounts/{account_id}/webhooks/{webhook_id}", false, (*Server).updateWebhook},
+	{http.MethodDelete, "/v2/accounts/{account_id}/webhooks/{webhook_id}", false, (*Server).deleteWebhook},
+
+	{http.MethodGet, "/v2/accounts/{account_id}/smtp-credentials", false, (*Server).getSMTPCredentials},
+	{http.MethodPost, "/v2/accounts/{account_id}/smtp-credentials", true, (*Server).createSMTPCredential},
+	{http.MethodGet, "/v2/accounts/{account_id}/smtp-credentials/{smtp_credential_id}", false, (*Server).getSMTPCredential},
+	{http.MethodDelete, "/v2/accounts/{account_id}/smtp-credentials/{smtp_credential_id}", false, (*Server).deleteSMTPCredential},
+}
+
+// unimplementedPrefixes are the parts of the API the server does not fake.
+// Requests to them get 501 Not Implemented rather than 404, so a test can
+// tell a missing feature from a wrong path.
+var unimplementedPrefixes = []string{"members", "sub-accounts", "statistics"}
+
+// match finds the route for a request. When the path matches a route but the
+// method does not, it returns the methods that would have matched.
+func match(r *http.Request) (*route, map[string]string, []string) {
+	segments := strings.Split(r.URL.EscapedPath(), "/")
+
+	var allowed []string
+	for i := range endpoints {
+		params, ok := matchTemplate(endpoints[i].template, segments)
+		if !ok {
+			continue
+		}
+		if endpoints[i].method != r.Method {
+			allowed = append(allowed, endpoints[i].method)
+			continue
+		}
+		return &endpoints[i], params, nil
+	}
+	return nil, nil, allowed
+}
+
+// matchTemplate matches escaped path segments against a route template,
+// returning the unescaped path parameters
+func matchTemplate(template string, segments []string) (map[string]string, bool) {
+	parts := strings.Split(template, "/")
+	if len(parts) != len(segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			value, err := url.PathUnescape(segments[i])
+			if err != nil || value == "" {
+				return nil, false
+			}
+			params[strings.Trim(part, "{}")] = value
+			continue
+		}
+		if part != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// unimplemented reports whether a path belongs to a part of the API the
+// server does not fake
+func unimplemented(path string) bool {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 4 || segments[0] != "v2" || segments[1] != "accounts" {
+		return false
+	}
+	for _, prefix := range unimplementedPrefixes {
+		if segments[3] == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// response is a handled request, ready to be written
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		body = nil
+	}
+
+	resp := s.handle(r, body)
+
+	for key, values := range resp.header {
+		w.Header()[key] = values
+	}
+	if resp.body != nil {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(resp.status)
+	_, _ = w.Write(resp.body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		Header:     r.Header.Clone(),
+		Body:       body,
+		StatusCode: resp.status,
+	})
+	s.mu.Unlock()
+}
+
+// handle produces the response to a request: an injected fault if one
+// matches, or the result of the route's handler
+func (s *Server) handle(r *http.Request, body []byte) response {
+	for _, fault := range s.takeFaults(r) {
+		if resp, ok := fault.apply(r); ok {
+			return resp
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.advance()
+
+	rt, params, allowed := match(r)
+	if rt == nil {
+		if unimplemented(r.URL.Path) {
+			return jsonResponse(errorf(http.StatusNotImplemented, "%s %s is not implemented by ahasendtest", r.Method, r.URL.Path))
+		}
+		if len(allowed) > 0 {
+			resp := jsonResponse(errorf(http.StatusMethodNotAllowed, "method not allowed"))
+			resp.header = http.Header{"Allow": {strings.Join(allowed, ", ")}}
+			return resp
+		}
+		return jsonResponse(errorf(http.StatusNotFound, "not found"))
+	}
+
+	key, status, message := s.authenticate(r)
+	if key == nil {
+		return jsonResponse(errorf(status, message))
+	}
+	if accountID, ok := params["account_id"]; ok && accountID != s.accountID.String() {
+		return jsonResponse(errorf(http.StatusForbidden, "API key does not have access to account %s", accountID))
+	}
+	now := s.clock()
+	key.LastUsedAt = &now
+
+	c := &call{r: r, key: key, params: params, body: body}
+	if rt.idempotent {
+		if idempotencyKey := r.Header.Get("Idempotency-Key"); idempotencyKey != "" {
+			return s.handleIdempotent(idempotencyKey, rt, c)
+		}
+	}
+	return jsonResponse(rt.handle(s, c))
+}
+
+// jsonResponse encodes a handler's result
+func jsonResponse(status int, v interface{}) response {
+	body, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		body, _ = json.Marshal(common.ErrorResponse{Message: err.Error()})
+	}
+	return response{status: status, body: body}
+}
+
+// errorf returns an error response with a formatted message
+func errorf(status int, format string, args ...interface{}) (int, interface{}) {
+	return status, common.ErrorResponse{Message: fmt.Sprintf(format, args...)}
+}
+
+// success returns a 200 response with a confirmation message
+func success(message string) (int, interface{}) {
+	return http.StatusOK, common.SuccessResponse{Message: message}
+}
+
+// forbidden is the response to a request the API key's scopes do not allow
+func forbidden() (int, interface{}) {
+	return errorf(http.StatusForbidden, "insufficient permissions for the required scope")
+}
+
+func (s *Server) ping(c *call) (int, interface{}) {
+	return success("pong")
+}
+
+// randomString returns n random alphanumeric characters
+func randomString(n int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("ahasendtest: failed to read random bytes: %v", err))
+	}
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
+
+// cutPrefix returns s without prefix and whether s started with it
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
@@ -0,0 +1,298 @@
+package ahasendtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, opts ...Option) (*Server, *api.APIClient) {
+	t.Helper()
+
+	server := NewServer(opts...)
+	t.Cleanup(server.Close)
+	return server, server.Client(api.WithRetryConfig(api.RetryConfig{Enabled: false}))
+}
+
+func welcomeMessage(recipients ...string) requests.CreateMessageRequest {
+	request := requests.CreateMessageRequest{
+		From:        common.SenderAddress{Email: "noreply@example.com"},
+		Subject:     "Welcome",
+		TextContent: ahasend.String("Hello {{ name }}"),
+	}
+	for _, recipient := range recipients {
+		request.Recipients = append(request.Recipients, common.Recipient{Email: recipient})
+	}
+	return request
+}
+
+func apiErrorOf(t *testing.T, err error) *api.APIError {
+	t.Helper()
+
+	var apiErr *api.APIError
+	require.True(t, errors.As(err, &apiErr), "expected an APIError, got %v", err)
+	return apiErr
+}
+
+func TestSentMessagesAreListed(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	request := welcomeMessage("ada@example.net", "grace@example.net")
+	request.Substitutions = map[string]interface{}{"name": "friend"}
+	request.Recipients[0].Substitutions = map[string]interface{}{"name": "Ada"}
+	request.Tags = []string{"onboarding"}
+
+	created, httpResp, err := client.MessagesAPI.CreateMessage(ctx, server.AccountID(), request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, httpResp.StatusCode)
+	require.Len(t, created.Data, 2)
+	assert.Equal(t, "queued", created.Data[0].Status)
+	require.NotNil(t, created.Data[0].ID)
+	assert.True(t, strings.HasSuffix(*created.Data[0].ID, "@example.com>"))
+
+	sent := server.AssertMessageSent(t, "ada@example.net", "Welcome")
+	assert.Equal(t, "Ada", sent.Substitutions["name"], "recipient substitutions take precedence")
+	server.AssertNoMessageSent(t, "linus@example.net")
+	server.AssertMessageCount(t, 2)
+
+	list, _, err := client.MessagesAPI.GetMessages(ctx, server.AccountID(), requests.GetMessagesParams{
+		Recipient: ahasend.String("grace@example.net"),
+	})
+	require.NoError(t, err)
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, StatusDelivered, list.Data[0].Status)
+	assert.Equal(t, []string{"onboarding"}, list.Data[0].Tags)
+
+	message, _, err := client.MessagesAPI.GetMessageByAPIID(ctx, server.AccountID(), *created.Data[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.net", message.Recipient)
+	require.NotNil(t, message.ContentParsed)
+	assert.Equal(t, "Hello {{ name }}", message.ContentParsed.Parts[0].Content)
+
+	assert.True(t, server.SetMessageStatus(*created.Data[0].ID, "Bounced"))
+	bounced, _, err := client.MessagesAPI.GetMessages(ctx, server.AccountID(), requests.GetMessagesParams{
+		Status: ahasend.String("Bounced,Failed"),
+	})
+	require.NoError(t, err)
+	require.Len(t, bounced.Data, 1)
+	assert.Equal(t, "ada@example.net", bounced.Data[0].Recipient)
+
+	// Like the API, the status filter does not take response-only statuses.
+	// The client refuses to send one, so the request is made by hand.
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/accounts/"+server.AccountID().String()+"/messages?status=Scheduled", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+server.APIKey())
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestSuppressionsBlockSends(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _, err := client.SuppressionsAPI.CreateSuppression(ctx, server.AccountID(), requests.CreateSuppressionRequest{
+		Email:     "gone@example.net",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Reason:    ahasend.String("hard bounce"),
+	})
+	require.NoError(t, err)
+
+	created, _, err := client.MessagesAPI.CreateMessage(ctx, server.AccountID(), welcomeMessage("gone@example.net", "here@example.net"))
+	require.NoError(t, err)
+	require.Len(t, created.Data, 2)
+	assert.Equal(t, "error", created.Data[0].Status)
+	assert.Nil(t, created.Data[0].ID)
+	require.NotNil(t, created.Data[0].Error)
+	assert.Equal(t, "queued", created.Data[1].Status)
+	server.AssertNoMessageSent(t, "gone@example.net")
+
+	_, _, err = client.SuppressionsAPI.DeleteSuppression(ctx, server.AccountID(), "gone@example.net", nil)
+	require.NoError(t, err)
+	_, _, err = client.MessagesAPI.CreateMessage(ctx, server.AccountID(), welcomeMessage("gone@example.net"))
+	require.NoError(t, err)
+	server.AssertMessageSent(t, "gone@example.net", "Welcome")
+}
+
+func TestDomainVerification(t *testing.T) {
+	server, client := newTestServer(t, WithStrictDomains())
+	ctx := context.Background()
+
+	domain, httpResp, err := client.DomainsAPI.CreateDomain(ctx, server.AccountID(), requests.CreateDomainRequest{Domain: "example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+	assert.False(t, domain.DNSValid)
+	assert.NotEmpty(t, domain.DNSRecords)
+
+	_, _, err = client.DomainsAPI.CreateDomain(ctx, server.AccountID(), requests.CreateDomainRequest{Domain: "example.com"})
+	assert.Equal(t, http.StatusConflict, apiErrorOf(t, err).StatusCode)
+
+	_, _, err = client.MessagesAPI.CreateMessage(ctx, server.AccountID(), welcomeMessage("ada@example.net"))
+	assert.Equal(t, http.StatusBadRequest, apiErrorOf(t, err).StatusCode, "unverified sender domains are rejected")
+
+	require.True(t, server.VerifyDomain("example.com"))
+	checked, _, err := client.DomainsAPI.CheckDomainDNS(ctx, server.AccountID(), "example.com")
+	require.NoError(t, err)
+	assert.True(t, checked.DNSValid)
+	assert.NotNil(t, checked.LastDNSCheckAt)
+
+	_, _, err = client.MessagesAPI.CreateMessage(ctx, server.AccountID(), welcomeMessage("ada@example.net"))
+	require.NoError(t, err)
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	restricted := server.Client(
+		api.WithAPIKey(server.AddAPIKey("Sender", "messages:send:{example.com}")),
+		api.WithRetryConfig(api.RetryConfig{Enabled: false}),
+	)
+
+	_, _, err := restricted.MessagesAPI.CreateMessage(ctx, server.AccountID(), welcomeMessage("ada@example.net"))
+	require.NoError(t, err)
+
+	other := welcomeMessage("ada@example.net")
+	other.From.Email = "noreply@other.example"
+	_, _, err = restricted.MessagesAPI.CreateMessage(ctx, server.AccountID(), other)
+	assert.Equal(t, http.StatusForbidden, apiErrorOf(t, err).StatusCode)
+
+	_, _, err = restricted.DomainsAPI.GetDomains(ctx, server.AccountID(), nil, nil)
+	assert.Equal(t, http.StatusForbidden, apiErrorOf(t, err).StatusCode)
+
+	invalid := server.Client(api.WithAPIKey("aha-sk-unknown"), api.WithRetryConfig(api.RetryConfig{Enabled: false}))
+	_, _, err = invalid.UtilityAPI.Ping(ctx)
+	assert.Equal(t, http.StatusUnauthorized, apiErrorOf(t, err).StatusCode)
+}
+
+func TestPagination(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	for _, email := range []string{"a@example.net", "b@example.net", "c@example.net", "d@example.net", "e@example.net"} {
+		_, _, err := client.SuppressionsAPI.CreateSuppression(ctx, server.AccountID(), requests.CreateSuppressionRequest{
+			Email:     email,
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+	}
+
+	params := requests.GetSuppressionsParams{}
+	params.Limit = ahasend.Int32(2)
+	first, _, err := client.SuppressionsAPI.GetSuppressions(ctx, server.AccountID(), params)
+	require.NoError(t, err)
+	require.Len(t, first.Data, 2)
+	assert.True(t, first.Pagination.HasMore)
+	assert.Nil(t, first.Pagination.PreviousCursor)
+
+	params.After = first.Pagination.NextCursor
+	second, _, err := client.SuppressionsAPI.GetSuppressions(ctx, server.AccountID(), params)
+	require.NoError(t, err)
+	require.Len(t, second.Data, 2)
+	assert.Equal(t, "c@example.net", second.Data[0].Email)
+	require.NotNil(t, second.Pagination.PreviousCursor)
+
+	params.After = nil
+	params.Before = second.Pagination.PreviousCursor
+	back, _, err := client.SuppressionsAPI.GetSuppressions(ctx, server.AccountID(), params)
+	require.NoError(t, err)
+	assert.Equal(t, first.Data, back.Data)
+
+	params.Before = nil
+	all, err := client.SuppressionsAPI.GetSuppressionsPager(ctx, server.AccountID(), params).All()
+	require.NoError(t, err)
+	assert.Len(t, all, 5)
+}
+
+func TestFaults(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	server.RateLimitNext(1, 3*time.Second)
+	_, _, err := client.UtilityAPI.Ping(ctx)
+	apiErr := apiErrorOf(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, 3, apiErr.RetryAfter)
+
+	_, _, err = client.UtilityAPI.Ping(ctx)
+	require.NoError(t, err, "the fault only applied once")
+
+	server.AddFault(Fault{Method: http.MethodPost, Path: "/v2/accounts/*/messages", Status: http.StatusServiceUnavailable})
+	_, _, err = client.MessagesAPI.CreateMessage(ctx, server.AccountID(), welcomeMessage("ada@example.net"))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErrorOf(t, err).StatusCode)
+	_, _, err = client.UtilityAPI.Ping(ctx)
+	require.NoError(t, err, "faults only apply to matching requests")
+	server.AssertMessageCount(t, 0)
+	server.ClearFaults()
+
+	server.SetLatency(200 * time.Millisecond)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, _, err = client.UtilityAPI.Ping(timeoutCtx)
+	assert.Error(t, err)
+
+	requests := server.Requests()
+	require.NotEmpty(t, requests)
+	assert.Equal(t, http.StatusTooManyRequests, requests[0].StatusCode)
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	request := welcomeMessage("ada@example.net")
+	first, _, err := client.MessagesAPI.CreateMessage(ctx, server.AccountID(), request, api.WithIdempotencyKey("welcome-ada"))
+	require.NoError(t, err)
+	replayed, httpResp, err := client.MessagesAPI.CreateMessage(ctx, server.AccountID(), request, api.WithIdempotencyKey("welcome-ada"))
+	require.NoError(t, err)
+	assert.Equal(t, "true", httpResp.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Data[0].ID, replayed.Data[0].ID)
+	server.AssertMessageCount(t, 1)
+
+	request.Subject = "Something else"
+	_, _, err = client.MessagesAPI.CreateMessage(ctx, server.AccountID(), request, api.WithIdempotencyKey("welcome-ada"))
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErrorOf(t, err).StatusCode)
+}
+
+func TestScheduledMessages(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	server, client := newTestServer(t, WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	firstAttempt := now.Add(time.Hour)
+	request := welcomeMessage("ada@example.net", "grace@example.net")
+	request.Schedule = &common.MessageSchedule{FirstAttempt: &firstAttempt}
+	created, _, err := client.MessagesAPI.CreateMessage(ctx, server.AccountID(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "scheduled", created.Data[0].Status)
+
+	_, _, err = client.MessagesAPI.CancelMessage(ctx, server.AccountID(), *created.Data[0].ID)
+	require.NoError(t, err)
+	_, _, err = client.MessagesAPI.CancelMessage(ctx, server.AccountID(), *created.Data[0].ID)
+	assert.Equal(t, http.StatusBadRequest, apiErrorOf(t, err).StatusCode, "only scheduled messages can be cancelled")
+
+	now = now.Add(2 * time.Hour)
+	messages := server.SentMessages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, StatusCancelled, messages[0].Status)
+	assert.Equal(t, StatusDelivered, messages[1].Status, "scheduled messages are delivered when due")
+}
+
+func TestUnimplementedEndpoints(t *testing.T) {
+	server, client := newTestServer(t)
+
+	_, _, err := client.AccountsAPI.GetAccountMembers(context.Background(), server.AccountID())
+	assert.Equal(t, http.StatusNotImplemented, apiErrorOf(t, err).StatusCode)
+}
@@ -0,0 +1,100 @@
+package ahasendtest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+func (s *Server) findSMTPCredential(id string) *responses.SMTPCredential {
+	for _, credential := range s.smtpCredentials {
+		if credential.ID.String() == id {
+			return credential
+		}
+	}
+	return nil
+}
+
+// withoutPassword returns a credential as it is reported after creation
+func withoutPassword(credential *responses.SMTPCredential) responses.SMTPCredential {
+	copied := *credential
+	copied.Password = ""
+	return copied
+}
+
+func (s *Server) getSMTPCredentials(c *call) (int, interface{}) {
+	if !c.key.allowsAny("smtp-credentials:read") {
+		return forbidden()
+	}
+	credentials := []responses.SMTPCredential{}
+	for _, credential := range s.smtpCredentials {
+		if c.key.allows("smtp-credentials:read", scopeDomains(credential.Scope, credential.Domains)...) {
+			credentials = append(credentials, withoutPassword(credential))
+		}
+	}
+	return paginate(c, credentials, func(credential responses.SMTPCredential) string { return credential.ID.String() })
+}
+
+func (s *Server) createSMTPCredential(c *call) (int, interface{}) {
+	var req requests.CreateSMTPCredentialRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return errorf(http.StatusBadRequest, "name is required")
+	}
+	domains := req.Domains
+	if domains == nil || req.Scope == "global" {
+		domains = []string{}
+	}
+	if message, ok := validateScope(req.Scope, domains); !ok {
+		return errorf(http.StatusBadRequest, "%s", message)
+	}
+	if !c.key.allows("smtp-credentials:write", scopeDomains(req.Scope, domains)...) {
+		return forbidden()
+	}
+
+	now := s.clock()
+	credential := &responses.SMTPCredential{
+		Object:    "smtp_credential",
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      req.Name,
+		Username:  "aha-smtp-" + randomString(16),
+		Password:  randomString(32),
+		Sandbox:   req.Sandbox,
+		Scope:     req.Scope,
+		Domains:   domains,
+	}
+	s.smtpCredentials = append(s.smtpCredentials, credential)
+	return http.StatusCreated, *credential
+}
+
+func (s *Server) getSMTPCredential(c *call) (int, interface{}) {
+	credential := s.findSMTPCredential(c.params["smtp_credential_id"])
+	if credential == nil {
+		return errorf(http.StatusNotFound, "SMTP credential not found")
+	}
+	if !c.key.allows("smtp-credentials:read", scopeDomains(credential.Scope, credential.Domains)...) {
+		return forbidden()
+	}
+	return http.StatusOK, withoutPassword(credential)
+}
+
+func (s *Server) deleteSMTPCredential(c *call) (int, interface{}) {
+	for i, credential := range s.smtpCredentials {
+		if credential.ID.String() != c.params["smtp_credential_id"] {
+			continue
+		}
+		if !c.key.allows("smtp-credentials:delete", scopeDomains(credential.Scope, credential.Domains)...) {
+			return forbidden()
+		}
+		s.smtpCredentials = append(s.smtpCredentials[:i], s.smtpCredentials[i+1:]...)
+		return success("SMTP credential deleted successfully")
+	}
+	return errorf(http.StatusNotFound, "SMTP credential not found")
+}
@@ -0,0 +1,166 @@
+package ahasendtest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// suppressed reports whether email has an unexpired suppression that applies
+// to messages from senderDomain. A suppression without a domain applies to
+// every sender domain.
+func (s *Server) suppressed(email, senderDomain string) bool {
+	now := s.clock()
+	for _, suppression := range s.suppressions {
+		if !strings.EqualFold(suppression.Email, email) || !suppression.ExpiresAt.After(now) {
+			continue
+		}
+		if suppression.Domain == "" || strings.EqualFold(suppression.Domain, senderDomain) {
+			return true
+		}
+	}
+	return false
+}
+
+// activeSuppressions drops expired suppressions and returns the rest
+func (s *Server) activeSuppressions() []*responses.Suppression {
+	now := s.clock()
+	active := s.suppressions[:0]
+	for _, suppression := range s.suppressions {
+		if suppression.ExpiresAt.After(now) {
+			active = append(active, suppression)
+		}
+	}
+	s.suppressions = active
+	return active
+}
+
+func (s *Server) getSuppressions(c *call) (int, interface{}) {
+	if !c.key.allows("suppressions:read") {
+		return forbidden()
+	}
+
+	query := c.r.URL.Query()
+	var fromTime, toTime time.Time
+	for name, target := range map[string]*time.Time{"from_time": &fromTime, "to_time": &toTime} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return errorf(http.StatusBadRequest, "invalid %s %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	suppressions := []responses.Suppression{}
+	for _, suppression := range s.activeSuppressions() {
+		if email := query.Get("email"); email != "" && !strings.EqualFold(email, suppression.Email) {
+			continue
+		}
+		if domain := query.Get("domain"); domain != "" && !strings.EqualFold(domain, suppression.Domain) {
+			continue
+		}
+		if !fromTime.IsZero() && suppression.CreatedAt.Before(fromTime) {
+			continue
+		}
+		if !toTime.IsZero() && !suppression.CreatedAt.Before(toTime) {
+			continue
+		}
+		suppressions = append(suppressions, *suppression)
+	}
+	return paginate(c, suppressions, func(suppression responses.Suppression) string { return suppression.ID.String() })
+}
+
+func (s *Server) createSuppression(c *call) (int, interface{}) {
+	if !c.key.allows("suppressions:write") {
+		return forbidden()
+	}
+	var req requests.CreateSuppressionRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if !strings.Contains(req.Email, "@") {
+		return errorf(http.StatusBadRequest, "invalid email %q", req.Email)
+	}
+	now := s.clock()
+	if !req.ExpiresAt.After(now) {
+		return errorf(http.StatusBadRequest, "expires_at must be in the future")
+	}
+
+	domain := ""
+	if req.Domain != nil {
+		domain = strings.ToLower(*req.Domain)
+	}
+	reason := ""
+	if req.Reason != nil {
+		reason = *req.Reason
+	}
+
+	// Suppressing an address again replaces the existing suppression
+	for _, existing := range s.activeSuppressions() {
+		if strings.EqualFold(existing.Email, req.Email) && existing.Domain == domain {
+			existing.ExpiresAt = req.ExpiresAt.UTC()
+			existing.Reason = reason
+			return http.StatusCreated, responses.CreateSuppressionResponse{Object: "list", Data: []responses.Suppression{*existing}}
+		}
+	}
+
+	suppression := &responses.Suppression{
+		Object:    "suppression",
+		ID:        uuid.New(),
+		CreatedAt: now,
+		Email:     strings.ToLower(req.Email),
+		ExpiresAt: req.ExpiresAt.UTC(),
+		Domain:    domain,
+		Reason:    reason,
+	}
+	s.suppressions = append(s.suppressions, suppression)
+	return http.StatusCreated, responses.CreateSuppressionResponse{Object: "list", Data: []responses.Suppression{*suppression}}
+}
+
+func (s *Server) deleteSuppression(c *call) (int, interface{}) {
+	if !c.key.allows("suppressions:delete") {
+		return forbidden()
+	}
+	query := c.r.URL.Query()
+	email := query.Get("email")
+	if email == "" {
+		return errorf(http.StatusBadRequest, "email is required")
+	}
+	domain := query.Get("domain")
+
+	deleted := false
+	remaining := s.suppressions[:0]
+	for _, suppression := range s.suppressions {
+		if strings.EqualFold(suppression.Email, email) && (domain == "" || strings.EqualFold(suppression.Domain, domain)) {
+			deleted = true
+			continue
+		}
+		remaining = append(remaining, suppression)
+	}
+	s.suppressions = remaining
+	if !deleted {
+		return errorf(http.StatusNotFound, "suppression not found")
+	}
+	return success("Suppression deleted successfully")
+}
+
+func (s *Server) deleteAllSuppressions(c *call) (int, interface{}) {
+	if !c.key.allows("suppressions:wipe") {
+		return forbidden()
+	}
+	domain := c.r.URL.Query().Get("domain")
+
+	remaining := s.suppressions[:0]
+	for _, suppression := range s.suppressions {
+		if domain != "" && !strings.EqualFold(suppression.Domain, domain) {
+			remaining = append(remaining, suppression)
+		}
+	}
+	s.suppressions = remaining
+	return success("Suppressions deleted successfully")
+}
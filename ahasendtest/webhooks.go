@@ -0,0 +1,217 @@
+package ahasendtest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// validateScope checks a global or scoped resource's scope and domains
+func validateScope(scope string, domains []string) (string, bool) {
+	switch scope {
+	case "global":
+		return "", true
+	case "scoped":
+		if len(domains) == 0 {
+			return "a scoped resource needs at least one domain", false
+		}
+		return "", true
+	default:
+		return "scope must be global or scoped", false
+	}
+}
+
+// scopeDomains returns the domains a key must hold a scope for to manage a
+// global or scoped resource. A global resource needs the scope for every
+// domain, which is represented by no domains.
+func scopeDomains(scope string, domains []string) []string {
+	if scope != "scoped" {
+		return nil
+	}
+	return domains
+}
+
+// webhookVisible reports whether a key holding scope may see a webhook
+func webhookVisible(key *apiKey, scope string, webhook *responses.Webhook) bool {
+	return key.allows(scope, scopeDomains(webhook.Scope, webhook.Domains)...)
+}
+
+func (s *Server) findWebhook(id string) *responses.Webhook {
+	for _, webhook := range s.webhooks {
+		if webhook.ID.String() == id {
+			return webhook
+		}
+	}
+	return nil
+}
+
+func (s *Server) getWebhooks(c *call) (int, interface{}) {
+	if !c.key.allowsAny("webhooks:read") {
+		return forbidden()
+	}
+
+	query := c.r.URL.Query()
+	filters := map[string]func(*responses.Webhook) bool{
+		"enabled":                func(w *responses.Webhook) bool { return w.Enabled },
+		"on_reception":           func(w *responses.Webhook) bool { return w.OnReception },
+		"on_delivered":           func(w *responses.Webhook) bool { return w.OnDelivered },
+		"on_transient_error":     func(w *responses.Webhook) bool { return w.OnTransientError },
+		"on_failed":              func(w *responses.Webhook) bool { return w.OnFailed },
+		"on_bounced":             func(w *responses.Webhook) bool { return w.OnBounced },
+		"on_suppressed":          func(w *responses.Webhook) bool { return w.OnSuppressed },
+		"on_opened":              func(w *responses.Webhook) bool { return w.OnOpened },
+		"on_clicked":             func(w *responses.Webhook) bool { return w.OnClicked },
+		"on_suppression_created": func(w *responses.Webhook) bool { return w.OnSuppressionCreated },
+		"on_dns_error":           func(w *responses.Webhook) bool { return w.OnDNSError },
+	}
+
+	webhooks := []responses.Webhook{}
+	for _, webhook := range s.webhooks {
+		if !webhookVisible(c.key, "webhooks:read", webhook) {
+			continue
+		}
+		matches := true
+		for name, field := range filters {
+			if value := query.Get(name); value != "" && (value == "true") != field(webhook) {
+				matches = false
+			}
+		}
+		if matches {
+			webhooks = append(webhooks, *webhook)
+		}
+	}
+	return paginate(c, webhooks, func(webhook responses.Webhook) string { return webhook.ID.String() })
+}
+
+func (s *Server) createWebhook(c *call) (int, interface{}) {
+	var req requests.CreateWebhookRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.URL) == "" {
+		return errorf(http.StatusBadRequest, "name and url are required")
+	}
+	domains := []string{}
+	if req.Domains != nil {
+		domains = *req.Domains
+	}
+	if message, ok := validateScope(req.Scope, domains); !ok {
+		return errorf(http.StatusBadRequest, "%s", message)
+	}
+	if !c.key.allows("webhooks:write", scopeDomains(req.Scope, domains)...) {
+		return forbidden()
+	}
+
+	now := s.clock()
+	webhook := &responses.Webhook{
+		Object:               "webhook",
+		ID:                   uuid.New(),
+		CreatedAt:            now,
+		UpdatedAt:            now,
+		Name:                 req.Name,
+		URL:                  req.URL,
+		Enabled:              req.Enabled == nil || *req.Enabled,
+		Secret:               "aha-whsec-" + randomString(32),
+		OnReception:          req.OnReception,
+		OnDelivered:          req.OnDelivered,
+		OnTransientError:     req.OnTransientError,
+		OnFailed:             req.OnFailed,
+		OnBounced:            req.OnBounced,
+		OnSuppressed:         req.OnSuppressed,
+		OnOpened:             req.OnOpened,
+		OnClicked:            req.OnClicked,
+		OnSuppressionCreated: req.OnSuppressionCreated,
+		OnDNSError:           req.OnDnsError,
+		Scope:                req.Scope,
+		Domains:              domains,
+	}
+	s.webhooks = append(s.webhooks, webhook)
+	return http.StatusCreated, *webhook
+}
+
+func (s *Server) getWebhook(c *call) (int, interface{}) {
+	webhook := s.findWebhook(c.params["webhook_id"])
+	if webhook == nil {
+		return errorf(http.StatusNotFound, "webhook not found")
+	}
+	if !webhookVisible(c.key, "webhooks:read", webhook) {
+		return forbidden()
+	}
+	return http.StatusOK, *webhook
+}
+
+func (s *Server) updateWebhook(c *call) (int, interface{}) {
+	webhook := s.findWebhook(c.params["webhook_id"])
+	if webhook == nil {
+		return errorf(http.StatusNotFound, "webhook not found")
+	}
+	if !webhookVisible(c.key, "webhooks:write", webhook) {
+		return forbidden()
+	}
+	var req requests.UpdateWebhookRequest
+	if err := c.decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+
+	scope, domains := webhook.Scope, webhook.Domains
+	if req.Scope != nil {
+		scope = *req.Scope
+	}
+	if req.Domains != nil {
+		domains = *req.Domains
+	}
+	if scope == "global" {
+		domains = []string{}
+	}
+	if message, ok := validateScope(scope, domains); !ok {
+		return errorf(http.StatusBadRequest, "%s", message)
+	}
+	if !c.key.allows("webhooks:write", scopeDomains(scope, domains)...) {
+		return forbidden()
+	}
+
+	setString := func(target *string, value *string) {
+		if value != nil {
+			*target = *value
+		}
+	}
+	setBool := func(target *bool, value *bool) {
+		if value != nil {
+			*target = *value
+		}
+	}
+	setString(&webhook.Name, req.Name)
+	setString(&webhook.URL, req.URL)
+	setBool(&webhook.Enabled, req.Enabled)
+	setBool(&webhook.OnReception, req.OnReception)
+	setBool(&webhook.OnDelivered, req.OnDelivered)
+	setBool(&webhook.OnTransientError, req.OnTransientError)
+	setBool(&webhook.OnFailed, req.OnFailed)
+	setBool(&webhook.OnBounced, req.OnBounced)
+	setBool(&webhook.OnSuppressed, req.OnSuppressed)
+	setBool(&webhook.OnOpened, req.OnOpened)
+	setBool(&webhook.OnClicked, req.OnClicked)
+	setBool(&webhook.OnSuppressionCreated, req.OnSuppressionCreated)
+	setBool(&webhook.OnDNSError, req.OnDnsError)
+	webhook.Scope = scope
+	webhook.Domains = domains
+	webhook.UpdatedAt = s.clock()
+	return http.StatusOK, *webhook
+}
+
+func (s *Server) deleteWebhook(c *call) (int, interface{}) {
+	for i, webhook := range s.webhooks {
+		if webhook.ID.String() != c.params["webhook_id"] {
+			continue
+		}
+		if !webhookVisible(c.key, "webhooks:delete", webhook) {
+			return forbidden()
+		}
+		s.webhooks = append(s.webhooks[:i], s.webhooks[i+1:]...)
+		return success("Webhook deleted successfully")
+	}
+	return errorf(http.StatusNotFound, "webhook not found")
+}
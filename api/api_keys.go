@@ -224,3 +224,17 @@ func (a *APIKeysAPIService) UpdateAPIKey(
 	resp, err := a.client.Execute(ctx, config)
 	return &result, resp, err
 }
+
+// GetAPIKeysPager returns a Pager that walks every page of GetAPIKeys,
+// starting from the cursor in pagination (optional).
+func (a *APIKeysAPIService) GetAPIKeysPager(
+	ctx context.Context,
+	accountId uuid.UUID,
+	pagination *common.PaginationParams,
+	opts ...RequestOption,
+) *Pager[responses.APIKey] {
+	fetch := func(ctx context.Context, page common.PaginationParams) (*responses.PaginatedAPIKeysResponse, *http.Response, error) {
+		return a.GetAPIKeys(ctx, accountId, &page, opts...)
+	}
+	return NewPager[responses.APIKey](ctx, fetch, paginationOrZero(pagination))
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -132,3 +133,39 @@ func TestMessagesAPICreateConversationMessageUsesToField(t *testing.T) {
 	require.Contains(t, body, "to")
 	assert.NotContains(t, body, "recipients")
 }
+
+func TestMessagesAPICreateMessageRunsPreflightCheck(t *testing.T) {
+	calls := 0
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"object":"list","data":[]}`))
+	})
+	defer cleanup()
+
+	request := requests.CreateMessageRequest{
+		From:        common.SenderAddress{Email: "sender@example.com"},
+		Recipients:  []common.Recipient{{Email: "recipient@example.com"}},
+		Subject:     "Hello",
+		TextContent: ahasend.String("Hi"),
+	}
+	var checked interface{}
+	_, _, err := client.MessagesAPI.CreateMessage(context.Background(), uuid.New(), request,
+		WithPreflightCheck(func(body interface{}) error {
+			checked = body
+			return errors.New("missing first_name")
+		}))
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, ErrorTypeValidation, apiErr.Type)
+	assert.Contains(t, apiErr.Message, "missing first_name")
+	assert.Equal(t, request, checked)
+	assert.Zero(t, calls, "a failed check must not reach the server")
+
+	_, _, err = client.MessagesAPI.CreateMessage(context.Background(), uuid.New(), request,
+		WithPreflightCheck(func(body interface{}) error { return nil }))
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
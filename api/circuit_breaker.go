@@ -0,0 +1,393 @@
+/*
+AhaSend Go SDK - Circuit Breaker
+
+Stops requests from piling up behind retries while the AhaSend API is failing.
+Each endpoint type has its own circuit, which opens when too many requests fail
+with network errors or 5xx responses and fails requests fast until a trial
+request succeeds again.
+*/
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // Requests are sent normally
+	CircuitOpen                         // Requests fail fast without being sent
+	CircuitHalfOpen                     // Trial requests test whether the API has recovered
+)
+
+// String returns the string representation of CircuitState
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures the circuit breaker. Zero values are
+// replaced with the defaults from DefaultCircuitBreakerConfig.
+type CircuitBreakerConfig struct {
+	// Enabled controls whether the circuit breaker is used at all
+	Enabled bool `json:"enabled"`
+	// FailureRatio is the share of requests in a window that must fail for the circuit to open
+	FailureRatio float64 `json:"failure_ratio"`
+	// MinRequests is the number of requests a window must see before the circuit can open
+	MinRequests int `json:"min_requests"`
+	// Window is how long requests are counted for before the counts start over
+	Window time.Duration `json:"window"`
+	// OpenTimeout is how long the circuit stays open before letting trial requests through
+	OpenTimeout time.Duration `json:"open_timeout"`
+	// HalfOpenRequests is the number of trial requests that must succeed to close the circuit again
+	HalfOpenRequests int `json:"half_open_requests"`
+	// Fallback handles requests rejected while the circuit is open (optional)
+	Fallback CircuitFallback `json:"-"`
+}
+
+// DefaultCircuitBreakerConfig returns sensible default circuit breaker configuration
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Enabled:          true,
+		FailureRatio:     0.5,
+		MinRequests:      10,
+		Window:           30 * time.Second,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+// withDefaults fills in unset fields from DefaultCircuitBreakerConfig
+func (cc CircuitBreakerConfig) withDefaults() CircuitBreakerConfig {
+	defaults := DefaultCircuitBreakerConfig()
+	if cc.FailureRatio <= 0 {
+		cc.FailureRatio = defaults.FailureRatio
+	}
+	if cc.MinRequests <= 0 {
+		cc.MinRequests = defaults.MinRequests
+	}
+	if cc.Window <= 0 {
+		cc.Window = defaults.Window
+	}
+	if cc.OpenTimeout <= 0 {
+		cc.OpenTimeout = defaults.OpenTimeout
+	}
+	if cc.HalfOpenRequests <= 0 {
+		cc.HalfOpenRequests = defaults.HalfOpenRequests
+	}
+	return cc
+}
+
+// FallbackRequest is a request the circuit breaker rejected, with everything
+// needed to send it later
+type FallbackRequest struct {
+	EndpointType EndpointType
+	Method       string
+	PathTemplate string
+	URL          *url.URL
+	Header       http.Header // Includes the Idempotency-Key, if any
+	Body         []byte      // JSON request body; nil when there is none
+}
+
+// CircuitFallback handles a request rejected while its circuit is open, for
+// example by queuing it to disk to send once the API recovers. The call still
+// fails with a CircuitOpenError, whose Handled field reports whether the
+// fallback returned nil.
+type CircuitFallback func(ctx context.Context, req FallbackRequest) error
+
+// CircuitOpenError is returned, without the request being sent, while the
+// circuit for the request's endpoint type is open
+type CircuitOpenError struct {
+	EndpointType EndpointType
+	// RetryAfter is how long until the circuit lets trial requests through
+	RetryAfter time.Duration
+	// Handled is true when the configured fallback accepted the request
+	Handled bool
+	// FallbackErr is the error returned by the fallback, if it failed
+	FallbackErr error
+}
+
+// Error implements the error interface
+func (e *CircuitOpenError) Error() string {
+	msg := fmt.Sprintf("circuit breaker open for %s endpoints, retry in %s", e.EndpointType, e.RetryAfter)
+	switch {
+	case e.Handled:
+		msg += " (request handled by fallback)"
+	case e.FallbackErr != nil:
+		msg += fmt.Sprintf(" (fallback failed: %v)", e.FallbackErr)
+	}
+	return msg
+}
+
+// Unwrap returns the fallback's error
+func (e *CircuitOpenError) Unwrap() error {
+	return e.FallbackErr
+}
+
+// IsRetryable returns true: the request can be sent again once RetryAfter
+// has passed. The SDK never retries it on its own.
+func (e *CircuitOpenError) IsRetryable() bool {
+	return true
+}
+
+// CircuitMonitor can be implemented by a RequestMonitor to be told when a
+// circuit changes state
+type CircuitMonitor interface {
+	OnCircuitStateChange(ctx context.Context, endpointType string, from, to CircuitState)
+}
+
+// CircuitBreaker keeps one circuit per endpoint type
+type CircuitBreaker struct {
+	general     *circuit
+	statistics  *circuit
+	sendMessage *circuit
+
+	// onStateChange is called, without locks held, after a circuit changes state
+	onStateChange func(ctx context.Context, endpointType EndpointType, from, to CircuitState)
+}
+
+// NewCircuitBreaker creates a circuit breaker with closed circuits
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	config = config.withDefaults()
+	return &CircuitBreaker{
+		general:     &circuit{config: config},
+		statistics:  &circuit{config: config},
+		sendMessage: &circuit{config: config},
+	}
+}
+
+// getCircuit returns the circuit for a specific endpoint type
+func (cb *CircuitBreaker) getCircuit(endpointType EndpointType) *circuit {
+	switch endpointType {
+	case StatisticsAPI:
+		return cb.statistics
+	case SendMessageAPI:
+		return cb.sendMessage
+	default:
+		return cb.general
+	}
+}
+
+// State returns the current state of the circuit for endpointType
+func (cb *CircuitBreaker) State(endpointType EndpointType) CircuitState {
+	c := cb.getCircuit(endpointType)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == CircuitOpen && !time.Now().Before(c.openUntil) {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// Reset closes the circuit for endpointType and clears its counts
+func (cb *CircuitBreaker) Reset(endpointType EndpointType) {
+	cb.transition(context.Background(), endpointType, func(c *circuit, now time.Time) {
+		c.setState(CircuitClosed, now)
+	})
+}
+
+// check reports whether the circuit for endpointType is open, and if so for
+// how much longer, without counting as a request
+func (cb *CircuitBreaker) check(endpointType EndpointType) (time.Duration, bool) {
+	c := cb.getCircuit(endpointType)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state != CircuitOpen {
+		return 0, false
+	}
+	wait := time.Until(c.openUntil)
+	return wait, wait > 0
+}
+
+// allow admits a request to endpointType, or returns a CircuitOpenError. An
+// admitted request must report its outcome through the returned function.
+func (cb *CircuitBreaker) allow(ctx context.Context, endpointType EndpointType) (func(*http.Response, error), error) {
+	var openErr error
+	var generation uint64
+	cb.transition(ctx, endpointType, func(c *circuit, now time.Time) {
+		if c.state == CircuitOpen {
+			if now.Before(c.openUntil) {
+				openErr = &CircuitOpenError{EndpointType: endpointType, RetryAfter: c.openUntil.Sub(now)}
+				return
+			}
+			c.setState(CircuitHalfOpen, now)
+		}
+		if c.state == CircuitHalfOpen {
+			if c.trials >= c.config.HalfOpenRequests {
+				openErr = &CircuitOpenError{EndpointType: endpointType}
+				return
+			}
+			c.trials++
+		}
+		generation = c.generation
+	})
+	if openErr != nil {
+		return nil, openErr
+	}
+
+	return func(resp *http.Response, err error) {
+		cb.record(ctx, endpointType, generation, resp, err)
+	}, nil
+}
+
+// record counts the outcome of a request admitted by allow. Outcomes of
+// requests admitted before the circuit last changed state are ignored.
+func (cb *CircuitBreaker) record(ctx context.Context, endpointType EndpointType, generation uint64, resp *http.Response, err error) {
+	failure := err != nil || (resp != nil && resp.StatusCode >= 500)
+	// A request the caller gave up on says nothing about the API
+	ignored := err != nil && ctx.Err() != nil
+
+	cb.transition(ctx, endpointType, func(c *circuit, now time.Time) {
+		if generation != c.generation {
+			return
+		}
+
+		switch c.state {
+		case CircuitHalfOpen:
+			switch {
+			case ignored:
+				c.trials--
+			case failure:
+				c.setState(CircuitOpen, now)
+			default:
+				c.successes++
+				if c.successes >= c.config.HalfOpenRequests {
+					c.setState(CircuitClosed, now)
+				}
+			}
+		case CircuitClosed:
+			if ignored {
+				return
+			}
+			if now.Sub(c.windowStart) >= c.config.Window {
+				c.windowStart = now
+				c.requests, c.failures = 0, 0
+			}
+			c.requests++
+			if failure {
+				c.failures++
+			}
+			if c.requests >= c.config.MinRequests &&
+				float64(c.failures)/float64(c.requests) >= c.config.FailureRatio {
+				c.setState(CircuitOpen, now)
+			}
+		}
+	})
+}
+
+// transition runs update on the circuit for endpointType under its lock, then
+// reports any change of state
+func (cb *CircuitBreaker) transition(ctx context.Context, endpointType EndpointType, update func(c *circuit, now time.Time)) {
+	c := cb.getCircuit(endpointType)
+	c.mu.Lock()
+	from := c.state
+	update(c, time.Now())
+	to := c.state
+	c.mu.Unlock()
+
+	if from != to && cb.onStateChange != nil {
+		cb.onStateChange(ctx, endpointType, from, to)
+	}
+}
+
+// circuit tracks the requests to one endpoint type
+type circuit struct {
+	mu     sync.Mutex
+	config CircuitBreakerConfig
+
+	state      CircuitState
+	generation uint64 // Incremented on every change of state
+
+	// Closed: requests and failures counted since windowStart
+	windowStart time.Time
+	requests    int
+	failures    int
+
+	// Open: when trial requests may start
+	openUntil time.Time
+
+	// Half-open: trial requests admitted and succeeded
+	trials    int
+	successes int
+}
+
+// setState moves the circuit to state and starts it afresh. The caller must
+// hold c.mu.
+func (c *circuit) setState(state CircuitState, now time.Time) {
+	c.state = state
+	c.generation++
+	c.windowStart = now
+	c.requests, c.failures = 0, 0
+	c.trials, c.successes = 0, 0
+	if state == CircuitOpen {
+		c.openUntil = now.Add(c.config.OpenTimeout)
+	}
+}
+
+// rejectedByCircuit hands a request rejected by the circuit breaker to the
+// configured fallback, if any, and returns the error for the call
+func (c *APIClient) rejectedByCircuit(ctx context.Context, req *http.Request, body []byte, config RequestConfig, openErr *CircuitOpenError) error {
+	fallback := c.cfg.CircuitBreakerConfig.Fallback
+	if fallback == nil {
+		return openErr
+	}
+
+	fallbackErr := fallback(ctx, FallbackRequest{
+		EndpointType: config.endpointType,
+		Method:       config.Method,
+		PathTemplate: config.PathTemplate,
+		URL:          req.URL,
+		Header:       req.Header.Clone(),
+		Body:         body,
+	})
+	rejected := *openErr
+	rejected.Handled = fallbackErr == nil
+	rejected.FallbackErr = fallbackErr
+	return &rejected
+}
+
+// newClientCircuitBreaker creates the circuit breaker for a client, reporting
+// state changes to its RequestMonitor
+func newClientCircuitBreaker(cfg *Configuration) *CircuitBreaker {
+	cb := NewCircuitBreaker(cfg.CircuitBreakerConfig)
+	cb.onStateChange = func(ctx context.Context, endpointType EndpointType, from, to CircuitState) {
+		if monitor, ok := cfg.RequestMonitor.(CircuitMonitor); ok {
+			monitor.OnCircuitStateChange(ctx, endpointType.String(), from, to)
+		}
+	}
+	return cb
+}
+
+// GetCircuitState returns the state of the circuit for a specific endpoint
+// type. It is always CircuitClosed when the circuit breaker is disabled.
+func (c *APIClient) GetCircuitState(endpointType EndpointType) CircuitState {
+	if c.circuitBreaker == nil {
+		return CircuitClosed
+	}
+	return c.circuitBreaker.State(endpointType)
+}
+
+// WithCircuitBreaker enables the circuit breaker with the given configuration
+func WithCircuitBreaker(config CircuitBreakerConfig) ClientOption {
+	return func(cfg *Configuration) {
+		config.Enabled = true
+		cfg.CircuitBreakerConfig = config
+	}
+}
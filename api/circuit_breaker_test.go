@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type circuitTransition struct {
+	endpointType string
+	from, to     CircuitState
+}
+
+// recordingCircuitMonitor is a RequestMonitor that records circuit state changes
+type recordingCircuitMonitor struct {
+	mu          sync.Mutex
+	transitions []circuitTransition
+}
+
+func (m *recordingCircuitMonitor) OnRequestStart(context.Context, string, string, map[string]string) {
+}
+
+func (m *recordingCircuitMonitor) OnRequestComplete(context.Context, string, string, int, time.Duration, error) {
+}
+
+func (m *recordingCircuitMonitor) OnRetry(context.Context, string, string, int, error) {}
+
+func (m *recordingCircuitMonitor) OnRateLimitWait(context.Context, string, time.Duration) {}
+
+func (m *recordingCircuitMonitor) OnCircuitStateChange(_ context.Context, endpointType string, from, to CircuitState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transitions = append(m.transitions, circuitTransition{endpointType, from, to})
+}
+
+func (m *recordingCircuitMonitor) recorded() []circuitTransition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]circuitTransition(nil), m.transitions...)
+}
+
+func failingResponse() *http.Response {
+	return &http.Response{StatusCode: http.StatusServiceUnavailable}
+}
+
+func okResponse() *http.Response {
+	return &http.Response{StatusCode: http.StatusOK}
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	ctx := context.Background()
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		Window:       time.Minute,
+		OpenTimeout:  50 * time.Millisecond,
+	})
+
+	send := func(resp *http.Response, err error) error {
+		done, openErr := cb.allow(ctx, SendMessageAPI)
+		if openErr != nil {
+			return openErr
+		}
+		done(resp, err)
+		return nil
+	}
+
+	// Below MinRequests the circuit stays closed however many fail
+	require.NoError(t, send(okResponse(), nil))
+	require.NoError(t, send(failingResponse(), nil))
+	require.NoError(t, send(nil, errors.New("connection refused")))
+	assert.Equal(t, CircuitClosed, cb.State(SendMessageAPI))
+
+	require.NoError(t, send(okResponse(), nil))
+	assert.Equal(t, CircuitOpen, cb.State(SendMessageAPI), "2 of 4 requests failed")
+	assert.Equal(t, CircuitClosed, cb.State(GeneralAPI), "circuits are per endpoint type")
+
+	err := send(okResponse(), nil)
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Equal(t, SendMessageAPI, openErr.EndpointType)
+	assert.Greater(t, openErr.RetryAfter, time.Duration(0))
+	assert.True(t, openErr.IsRetryable())
+
+	// After OpenTimeout one trial request is let through at a time
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, cb.State(SendMessageAPI))
+	trial, err := cb.allow(ctx, SendMessageAPI)
+	require.NoError(t, err)
+	_, err = cb.allow(ctx, SendMessageAPI)
+	require.ErrorAs(t, err, &openErr)
+
+	// A failed trial opens the circuit again
+	trial(failingResponse(), nil)
+	assert.Equal(t, CircuitOpen, cb.State(SendMessageAPI))
+
+	// A successful trial closes it
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, send(okResponse(), nil))
+	assert.Equal(t, CircuitClosed, cb.State(SendMessageAPI))
+}
+
+func TestCircuitBreakerIgnoresStaleAndCanceledOutcomes(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{MinRequests: 1, OpenTimeout: time.Minute})
+
+	// A request admitted before the circuit opened reports after it closed
+	stale, err := cb.allow(context.Background(), GeneralAPI)
+	require.NoError(t, err)
+	done, err := cb.allow(context.Background(), GeneralAPI)
+	require.NoError(t, err)
+	done(failingResponse(), nil)
+	require.Equal(t, CircuitOpen, cb.State(GeneralAPI))
+	cb.Reset(GeneralAPI)
+	stale(failingResponse(), nil)
+	assert.Equal(t, CircuitClosed, cb.State(GeneralAPI))
+
+	// A request the caller canceled is not a failure of the API
+	ctx, cancel := context.WithCancel(context.Background())
+	done, err = cb.allow(ctx, GeneralAPI)
+	require.NoError(t, err)
+	cancel()
+	done(nil, context.Canceled)
+	assert.Equal(t, CircuitClosed, cb.State(GeneralAPI))
+}
+
+func TestAPIClientCircuitBreaker(t *testing.T) {
+	var requestCount int32
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"message":"unavailable"}`))
+	})
+	defer cleanup()
+
+	monitor := &recordingCircuitMonitor{}
+	cfg := client.GetConfig()
+	cfg.RetryConfig.Enabled = false
+	cfg.RequestMonitor = monitor
+	cfg.CircuitBreakerConfig = CircuitBreakerConfig{Enabled: true, MinRequests: 2, OpenTimeout: time.Minute}
+	client = NewAPIClientWithConfig(cfg)
+
+	for i := 0; i < 2; i++ {
+		_, _, err := client.UtilityAPI.Ping(context.Background())
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	}
+	assert.Equal(t, CircuitOpen, client.GetCircuitState(GeneralAPI))
+
+	_, httpResp, err := client.UtilityAPI.Ping(context.Background())
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Nil(t, httpResp)
+	assert.False(t, openErr.Handled)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requestCount), "an open circuit must not send the request")
+
+	assert.Equal(t, []circuitTransition{{"general", CircuitClosed, CircuitOpen}}, monitor.recorded())
+}
+
+func TestAPIClientCircuitBreakerStopsRetries(t *testing.T) {
+	var requestCount int32
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer cleanup()
+
+	cfg := client.GetConfig()
+	cfg.RetryConfig = RetryConfig{Enabled: true, MaxRetries: 5, BaseDelay: 1, MaxDelay: 1, BackoffStrategy: BackoffConstant}
+	cfg.CircuitBreakerConfig = CircuitBreakerConfig{Enabled: true, MinRequests: 2, OpenTimeout: time.Minute}
+	client = NewAPIClientWithConfig(cfg)
+
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requestCount))
+}
+
+func TestAPIClientCircuitBreakerFallback(t *testing.T) {
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer cleanup()
+
+	var fallbackRequests []FallbackRequest
+	cfg := client.GetConfig()
+	cfg.RetryConfig.Enabled = false
+	WithCircuitBreaker(CircuitBreakerConfig{
+		MinRequests: 1,
+		OpenTimeout: time.Minute,
+		Fallback: func(ctx context.Context, req FallbackRequest) error {
+			fallbackRequests = append(fallbackRequests, req)
+			return nil
+		},
+	})(cfg)
+	client = NewAPIClientWithConfig(cfg)
+
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+	require.Error(t, err)
+	require.Empty(t, fallbackRequests, "the fallback is only used while the circuit is open")
+
+	accountID := uuid.New()
+	_, _, err = client.DomainsAPI.CreateDomain(context.Background(), accountID, requests.CreateDomainRequest{
+		Domain: "example.com",
+	}, WithIdempotencyKey("fallback-key"))
+
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.True(t, openErr.Handled)
+
+	require.Len(t, fallbackRequests, 1)
+	req := fallbackRequests[0]
+	assert.Equal(t, GeneralAPI, req.EndpointType)
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/v2/accounts/{account_id}/domains", req.PathTemplate)
+	assert.Equal(t, "/v2/accounts/"+accountID.String()+"/domains", req.URL.Path)
+	assert.Equal(t, "fallback-key", req.Header.Get("Idempotency-Key"))
+	assert.JSONEq(t, `{"domain":"example.com"}`, string(req.Body))
+}
+
+func TestAPIClientCircuitBreakerFallbackError(t *testing.T) {
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer cleanup()
+
+	queueFull := errors.New("queue full")
+	cfg := client.GetConfig()
+	cfg.RetryConfig.Enabled = false
+	WithCircuitBreaker(CircuitBreakerConfig{
+		MinRequests: 1,
+		OpenTimeout: time.Minute,
+		Fallback: func(ctx context.Context, req FallbackRequest) error {
+			return queueFull
+		},
+	})(cfg)
+	client = NewAPIClientWithConfig(cfg)
+
+	_, _, _ = client.UtilityAPI.Ping(context.Background())
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.False(t, openErr.Handled)
+	assert.ErrorIs(t, err, queueFull)
+}
+
+func TestCircuitBreakerDisabledByDefault(t *testing.T) {
+	client := NewAPIClient()
+	assert.Nil(t, client.circuitBreaker)
+	assert.Equal(t, CircuitClosed, client.GetCircuitState(SendMessageAPI))
+
+	client = NewAPIClient(WithCircuitBreaker(DefaultCircuitBreakerConfig()))
+	assert.NotNil(t, client.circuitBreaker)
+}
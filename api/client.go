@@ -93,8 +93,20 @@ type RequestConfig struct {
 	// Optional: Custom retry configuration for this request
 	CustomRetry *RetryConfig
 
+	// Optional: Caller-defined operation this request performs; see WithOperationID
+	OperationID string
+
+	// Optional: Check run against the request body before it is sent
+	PreflightCheck func(body interface{}) error
+
 	// Internal: Endpoint type for rate limiting classification
 	endpointType EndpointType
+
+	// Internal: Time spent waiting for the rate limiter, for request logging
+	rateLimitWait time.Duration
+
+	// Internal: Tracer span for this request, if a RequestTracer is configured
+	span RequestSpan
 }
 
 // RequestOption allows modifying RequestConfig using functional options pattern
@@ -127,6 +139,15 @@ func WithRetry(retry RetryConfig) RequestOption {
 	}
 }
 
+// WithPreflightCheck runs check against the request body after it passes
+// validation and before it is sent. An error aborts the request with a
+// validation APIError; see templates.PreflightCheck.
+func WithPreflightCheck(check func(body interface{}) error) RequestOption {
+	return func(rc *RequestConfig) {
+		rc.PreflightCheck = check
+	}
+}
+
 // WithHeaders adds additional headers to the request
 func WithHeaders(headers map[string]string) RequestOption {
 	return func(rc *RequestConfig) {
@@ -149,6 +170,22 @@ func WithIdempotencyKey(key string) RequestOption {
 	}
 }
 
+// WithOperationID names the business operation a request performs, such as
+// "order-123/receipt". With an IdempotencyStore configured, every request for
+// the same operation is sent with the same idempotency key, and once one has
+// succeeded later ones return its result without contacting the API.
+//
+// An operation belongs to the endpoint it was first sent to; using its ID for
+// another endpoint fails. Only successes are stored, but the API answers a
+// reused key with the response it gave the first time, so an operation that
+// failed with a 4xx keeps failing the same way until it is removed with
+// IdempotencyStore.Delete.
+func WithOperationID(operationID string) RequestOption {
+	return func(rc *RequestConfig) {
+		rc.OperationID = operationID
+	}
+}
+
 // WithRequestAPIKey sets an API key for this specific request (overrides client-level auth)
 func WithRequestAPIKey(key string) RequestOption {
 	return func(rc *RequestConfig) {
@@ -236,6 +273,7 @@ type APIClient struct {
 	cfg               *Configuration
 	common            service // Reuse a single struct instead of allocating one for each service on the heap.
 	rateLimiter       *RateLimiter
+	circuitBreaker    *CircuitBreaker
 	idempotencyHelper *IdempotencyHelper
 
 	// API Services
@@ -297,6 +335,14 @@ func NewAPIClient(opts ...ClientOption) *APIClient {
 	if cfg.CustomerRateLimits != nil {
 		c.rateLimiter.ConfigureFromCustomerConfig(*cfg.CustomerRateLimits)
 	}
+	if cfg.RateLimiterBackend != nil {
+		c.rateLimiter.SetBackend(cfg.RateLimiterBackend, cfg.rateLimiterNamespace())
+	}
+
+	// Initialize circuit breaker
+	if cfg.CircuitBreakerConfig.Enabled {
+		c.circuitBreaker = newClientCircuitBreaker(cfg)
+	}
 
 	// Initialize idempotency helper
 	c.idempotencyHelper = NewIdempotencyHelper(cfg.IdempotencyConfig)
@@ -398,7 +444,7 @@ func (c *APIClient) NewIdempotencyKeyBuilder(baseKey ...string) *IdempotencyKeyB
 // Core Execute Method and Supporting Functions
 
 // Execute is the centralized method for executing all API requests
-func (c *APIClient) Execute(ctx context.Context, config RequestConfig) (*http.Response, error) {
+func (c *APIClient) Execute(ctx context.Context, config RequestConfig) (resp *http.Response, err error) {
 	// Step 1: Validate and build the path
 	if err := validatePathParams(config.PathTemplate, config.PathParams); err != nil {
 		return nil, &APIError{
@@ -440,6 +486,7 @@ func (c *APIClient) Execute(ctx context.Context, config RequestConfig) (*http.Re
 
 	// Step 4: Create the request body
 	var bodyReader io.Reader
+	var requestBody []byte
 	if config.Body != nil {
 		if validator, ok := config.Body.(requestBodyValidator); ok {
 			if err := validator.Validate(); err != nil {
@@ -450,6 +497,15 @@ func (c *APIClient) Execute(ctx context.Context, config RequestConfig) (*http.Re
 			}
 		}
 
+		if config.PreflightCheck != nil {
+			if err := config.PreflightCheck(config.Body); err != nil {
+				return nil, &APIError{
+					Type:    ErrorTypeValidation,
+					Message: fmt.Sprintf("Pre-flight check failed: %v", err),
+				}
+			}
+		}
+
 		jsonBody, err := json.Marshal(config.Body)
 		if err != nil {
 			return nil, &APIError{
@@ -458,6 +514,7 @@ func (c *APIClient) Execute(ctx context.Context, config RequestConfig) (*http.Re
 			}
 		}
 		bodyReader = bytes.NewReader(jsonBody)
+		requestBody = jsonBody
 	}
 
 	// Step 5: Create the HTTP request
@@ -471,20 +528,66 @@ func (c *APIClient) Execute(ctx context.Context, config RequestConfig) (*http.Re
 		return nil, err
 	}
 
-	// Step 7: Apply rate limiting (unless skipped)
+	// Reuse the key of a named operation, and replay its result if it has
+	// already completed
+	if config.OperationID != "" {
+		record, err := c.beginOperation(ctx, req, config.OperationID)
+		if err != nil {
+			return nil, err
+		}
+		if record.Completed {
+			resp = record.replay(req)
+			return resp, decodeResult(config, record.Response)
+		}
+	}
+
+	// Fail fast while the circuit for this endpoint type is open
+	if c.circuitBreaker != nil {
+		if wait, open := c.circuitBreaker.check(config.endpointType); open {
+			openErr := &CircuitOpenError{EndpointType: config.endpointType, RetryAfter: wait}
+			return nil, c.rejectedByCircuit(ctx, req, requestBody, config, openErr)
+		}
+	}
+
+	// Step 7: Start tracing, now that the request is complete
+	if tracer := c.cfg.RequestTracer; tracer != nil {
+		ctx, config.span = tracer.StartRequest(ctx, RequestInfo{
+			Method:         config.Method,
+			PathTemplate:   config.PathTemplate,
+			EndpointType:   config.endpointType,
+			URL:            req.URL,
+			AccountID:      config.PathParams["account_id"],
+			IdempotencyKey: req.Header.Get("Idempotency-Key"),
+		})
+		req = req.WithContext(ctx)
+		span := config.span
+		defer func() { span.End(resp, err) }()
+	}
+
+	// Step 8: Apply rate limiting (unless skipped)
 	if !config.SkipRateLimit && c.rateLimiter != nil {
+		waitStart := time.Now()
 		if err := c.applyRateLimit(ctx, config.endpointType); err != nil {
 			return nil, err
 		}
+		config.rateLimitWait = time.Since(waitStart)
+		if config.span != nil {
+			config.span.RateLimited(config.rateLimitWait)
+		}
 	}
 
-	// Step 8: Execute with retry logic
-	resp, err := c.executeWithRetry(ctx, req, config)
+	// Step 9: Execute with retry logic
+	resp, err = c.executeWithRetry(ctx, req, config)
 	if err != nil {
+		// The circuit may have opened while retrying
+		var openErr *CircuitOpenError
+		if errors.As(err, &openErr) {
+			return nil, c.rejectedByCircuit(ctx, req, requestBody, config, openErr)
+		}
 		return nil, err
 	}
 
-	// Step 9: Read response body
+	// Step 10: Read response body
 	responseBody, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
@@ -494,23 +597,36 @@ func (c *APIClient) Execute(ctx context.Context, config RequestConfig) (*http.Re
 	// Replace body with new reader so it can be read again if needed
 	resp.Body = io.NopCloser(bytes.NewBuffer(responseBody))
 
-	// Step 10: Handle errors for non-2xx responses
+	// Step 11: Handle errors for non-2xx responses
 	if resp.StatusCode >= 300 {
 		return resp, c.handleErrorResponse(resp, responseBody, config.Method, path)
 	}
 
-	// Step 11: Decode successful response into result
+	// Step 12: Decode successful response into result
+	if err := decodeResult(config, responseBody); err != nil {
+		return resp, err
+	}
+
+	// Step 13: Remember the outcome of a named operation
+	if config.OperationID != "" {
+		c.completeOperation(ctx, config.OperationID, resp.StatusCode, responseBody)
+	}
+
+	return resp, nil
+}
+
+// decodeResult decodes a successful response body into config.Result
+func decodeResult(config RequestConfig, responseBody []byte) error {
 	if config.Result != nil && len(responseBody) > 0 {
 		if err := json.Unmarshal(responseBody, config.Result); err != nil {
-			return resp, &APIError{
+			return &APIError{
 				Type:    ErrorTypeUnknown,
 				Message: fmt.Sprintf("Failed to decode response: %v", err),
 				Raw:     responseBody,
 			}
 		}
 	}
-
-	return resp, nil
+	return nil
 }
 
 // applyHeaders applies headers to the request with authentication hierarchy
@@ -562,9 +678,18 @@ func (c *APIClient) applyHeaders(ctx context.Context, req *http.Request, config
 		req.Header.Set("Idempotency-Key", idempotencyKey.(string))
 	}
 
-	// Auto-generate idempotency key for POST requests if not already set and auto-generation is enabled
-	if config.Method == http.MethodPost {
-		if req.Header.Get("Idempotency-Key") == "" && c.cfg.IdempotencyConfig.AutoGenerate {
+	// Derive or auto-generate idempotency key for POST requests if not already set
+	if config.Method == http.MethodPost && req.Header.Get("Idempotency-Key") == "" {
+		if c.cfg.IdempotencyConfig.DeriveFromContent && config.Body != nil {
+			key, err := c.idempotencyHelper.DeriveKey(req.Method, req.URL.Path, config.Body)
+			if err != nil {
+				return &APIError{
+					Type:    ErrorTypeValidation,
+					Message: err.Error(),
+				}
+			}
+			req.Header.Set("Idempotency-Key", key)
+		} else if c.cfg.IdempotencyConfig.AutoGenerate {
 			req.Header.Set("Idempotency-Key", c.idempotencyHelper.GenerateKey())
 		}
 	}
@@ -622,7 +747,7 @@ func (c *APIClient) executeWithRetry(ctx context.Context, req *http.Request, con
 
 	// If retries are disabled, execute once
 	if !retryConfig.IsRetryEnabled() {
-		return c.cfg.HTTPClient.Do(req)
+		return c.sendAttempt(ctx, req, config, 1)
 	}
 
 	// CRITICAL FIX: Store original body bytes for retry attempts
@@ -639,8 +764,18 @@ func (c *APIClient) executeWithRetry(ctx context.Context, req *http.Request, con
 
 	var lastErr error
 	var lastResp *http.Response
+	var delay time.Duration
+	start := time.Now()
 
 	for attempt := 0; attempt <= retryConfig.MaxRetries; attempt++ {
+		// With adaptive rate limiting, retries wait on the rate limiter too,
+		// so they honour a pause another caller's 429 started
+		if attempt > 0 && c.cfg.AdaptiveRateLimit && !config.SkipRateLimit && c.rateLimiter != nil {
+			if err := c.applyRateLimit(ctx, config.endpointType); err != nil {
+				return lastResp, err
+			}
+		}
+
 		// Clone the request for each attempt
 		reqClone := req.Clone(ctx)
 		if bodyBytes != nil {
@@ -649,7 +784,7 @@ func (c *APIClient) executeWithRetry(ctx context.Context, req *http.Request, con
 		}
 
 		// Execute the request
-		resp, err := c.cfg.HTTPClient.Do(reqClone)
+		resp, err := c.sendAttempt(ctx, reqClone, config, attempt+1)
 
 		// Check if we should retry
 		if !c.shouldRetry(err, resp, retryConfig, attempt) {
@@ -659,29 +794,60 @@ func (c *APIClient) executeWithRetry(ctx context.Context, req *http.Request, con
 		lastErr = err
 		lastResp = resp
 
-		// Calculate delay before next attempt
-		if attempt < retryConfig.MaxRetries {
-			delay := retryConfig.GetDelay(attempt + 1)
+		// Calculate delay before next attempt, preferring the one the
+		// server asked for
+		if serverDelay, ok := retryConfig.retryAfterDelay(resp); ok {
+			delay = serverDelay
+		} else {
+			delay = retryConfig.nextDelay(attempt+1, delay)
+		}
 
-			select {
-			case <-ctx.Done():
-				return lastResp, ctx.Err()
-			case <-time.After(delay):
-				// Continue to next attempt
-			}
+		// Give up rather than retry past the call's time budget
+		if retryConfig.MaxElapsedTime > 0 && time.Since(start)+delay > retryConfig.MaxElapsedTime {
+			break
+		}
+
+		// This response is superseded by the retry
+		if resp != nil {
+			resp.Body.Close()
 		}
-	}
 
-	// All retries exhausted
-	if lastErr != nil {
-		return lastResp, lastErr
+		select {
+		case <-ctx.Done():
+			return lastResp, ctx.Err()
+		case <-time.After(delay):
+			// Continue to next attempt
+		}
 	}
 
-	return lastResp, &APIError{
-		Type:       ErrorTypeServer,
-		StatusCode: lastResp.StatusCode,
-		Message:    fmt.Sprintf("Request failed after %d retries", retryConfig.MaxRetries),
+	// The time budget ran out, so the last outcome stands and is handled as
+	// if it had not been retryable
+	return lastResp, lastErr
+}
+
+// sendAttempt sends one attempt of a request, reporting it to the configured
+// RequestTracer and RequestLogger
+func (c *APIClient) sendAttempt(ctx context.Context, req *http.Request, config RequestConfig, attempt int) (resp *http.Response, err error) {
+	if c.circuitBreaker != nil {
+		done, openErr := c.circuitBreaker.allow(ctx, config.endpointType)
+		if openErr != nil {
+			return nil, openErr
+		}
+		defer func() { done(resp, err) }()
+	}
+	if config.span != nil {
+		end := config.span.StartAttempt(req, attempt)
+		defer func() { end(resp, err) }()
+	}
+	if c.cfg.RequestLogger != nil {
+		resp, err = c.sendLoggedAttempt(ctx, req, config, attempt)
+	} else {
+		resp, err = c.cfg.HTTPClient.Do(req)
 	}
+	if c.cfg.AdaptiveRateLimit && c.rateLimiter != nil {
+		c.rateLimiter.UpdateFromResponse(config.endpointType, resp)
+	}
+	return resp, err
 }
 
 // shouldRetry determines whether a request should be retried based on error and response
@@ -691,6 +857,12 @@ func (c *APIClient) shouldRetry(err error, resp *http.Response, config RetryConf
 		return false
 	}
 
+	// An open circuit rejects every attempt until it lets trial requests through
+	var openErr *CircuitOpenError
+	if errors.As(err, &openErr) {
+		return false
+	}
+
 	// Network errors are always retryable
 	if err != nil {
 		return true
@@ -252,6 +252,9 @@ func validateRateLimitConfig(cfg *Configuration, result *ValidationResult) {
 	// Validate retry configuration
 	validateRetryConfig(cfg, result)
 
+	// Validate circuit breaker configuration
+	validateCircuitBreakerConfig(cfg, result)
+
 	// Validate rate limit configurations
 	validateRateLimitConfigStruct := func(config *RateLimitConfig, name string) {
 		if config != nil {
@@ -328,16 +331,39 @@ func validateRetryConfig(cfg *Configuration, result *ValidationResult) {
 
 	// Validate BackoffStrategy
 	validStrategies := map[BackoffStrategy]bool{
-		BackoffExponential: true,
-		BackoffLinear:      true,
-		BackoffConstant:    true,
+		BackoffExponential:        true,
+		BackoffLinear:             true,
+		BackoffConstant:           true,
+		BackoffFullJitter:         true,
+		BackoffEqualJitter:        true,
+		BackoffDecorrelatedJitter: true,
 	}
 	if !validStrategies[retryConfig.BackoffStrategy] {
 		result.Errors = append(result.Errors, ConfigurationValidationError{
 			Field:   "RetryConfig.BackoffStrategy",
 			Value:   retryConfig.BackoffStrategy,
-			Message: "must be 'exponential', 'linear', or 'constant'",
+			Message: "must be 'exponential', 'linear', 'constant', 'full_jitter', 'equal_jitter', or 'decorrelated_jitter'",
+		})
+	}
+
+	// Validate MaxRetryAfter
+	if retryConfig.MaxRetryAfter < 0 {
+		result.Errors = append(result.Errors, ConfigurationValidationError{
+			Field:   "RetryConfig.MaxRetryAfter",
+			Value:   retryConfig.MaxRetryAfter,
+			Message: "cannot be negative",
+		})
+	}
+
+	// Validate MaxElapsedTime
+	if retryConfig.MaxElapsedTime < 0 {
+		result.Errors = append(result.Errors, ConfigurationValidationError{
+			Field:   "RetryConfig.MaxElapsedTime",
+			Value:   retryConfig.MaxElapsedTime,
+			Message: "cannot be negative",
 		})
+	} else if retryConfig.MaxElapsedTime > 0 && retryConfig.MaxElapsedTime < retryConfig.BaseDelay {
+		result.Warnings = append(result.Warnings, "RetryConfig.MaxElapsedTime is less than BaseDelay, requests will not be retried")
 	}
 
 	// Warn about potentially problematic configurations
@@ -350,6 +376,50 @@ func validateRetryConfig(cfg *Configuration, result *ValidationResult) {
 	}
 }
 
+// validateCircuitBreakerConfig validates circuit breaker configuration
+func validateCircuitBreakerConfig(cfg *Configuration, result *ValidationResult) {
+	cbConfig := cfg.CircuitBreakerConfig
+	if !cbConfig.Enabled {
+		return
+	}
+
+	if cbConfig.FailureRatio < 0 || cbConfig.FailureRatio > 1 {
+		result.Errors = append(result.Errors, ConfigurationValidationError{
+			Field:   "CircuitBreakerConfig.FailureRatio",
+			Value:   cbConfig.FailureRatio,
+			Message: "must be between 0 and 1",
+		})
+	}
+	if cbConfig.MinRequests < 0 {
+		result.Errors = append(result.Errors, ConfigurationValidationError{
+			Field:   "CircuitBreakerConfig.MinRequests",
+			Value:   cbConfig.MinRequests,
+			Message: "cannot be negative",
+		})
+	}
+	if cbConfig.Window < 0 {
+		result.Errors = append(result.Errors, ConfigurationValidationError{
+			Field:   "CircuitBreakerConfig.Window",
+			Value:   cbConfig.Window,
+			Message: "cannot be negative",
+		})
+	}
+	if cbConfig.OpenTimeout < 0 {
+		result.Errors = append(result.Errors, ConfigurationValidationError{
+			Field:   "CircuitBreakerConfig.OpenTimeout",
+			Value:   cbConfig.OpenTimeout,
+			Message: "cannot be negative",
+		})
+	}
+	if cbConfig.HalfOpenRequests < 0 {
+		result.Errors = append(result.Errors, ConfigurationValidationError{
+			Field:   "CircuitBreakerConfig.HalfOpenRequests",
+			Value:   cbConfig.HalfOpenRequests,
+			Message: "cannot be negative",
+		})
+	}
+}
+
 // validateIdempotencyConfig validates idempotency configuration
 func validateIdempotencyConfig(cfg *Configuration, result *ValidationResult) {
 	// Validate idempotency key prefix
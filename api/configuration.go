@@ -74,6 +74,12 @@ const (
 	BackoffLinear BackoffStrategy = "linear"
 	// BackoffConstant uses constant delay between retries
 	BackoffConstant BackoffStrategy = "constant"
+	// BackoffFullJitter waits a random time between zero and the exponential delay
+	BackoffFullJitter BackoffStrategy = "full_jitter"
+	// BackoffEqualJitter waits half the exponential delay plus a random time up to the other half
+	BackoffEqualJitter BackoffStrategy = "equal_jitter"
+	// BackoffDecorrelatedJitter waits a random time between BaseDelay and three times the previous delay
+	BackoffDecorrelatedJitter BackoffStrategy = "decorrelated_jitter"
 )
 
 // RetryConfig provides comprehensive retry configuration options
@@ -92,6 +98,12 @@ type RetryConfig struct {
 	BaseDelay time.Duration `json:"base_delay"`
 	// MaxDelay is the maximum delay between retries (prevents exponential backoff from growing too large)
 	MaxDelay time.Duration `json:"max_delay"`
+	// MaxRetryAfter caps the delay taken from a Retry-After header, which
+	// replaces the backoff delay when the server sends one (0 means no cap)
+	MaxRetryAfter time.Duration `json:"max_retry_after"`
+	// MaxElapsedTime is the overall time budget for a call and its retries. No
+	// retry is started that could not begin within it (0 means no budget).
+	MaxElapsedTime time.Duration `json:"max_elapsed_time"`
 }
 
 // DefaultRetryConfig returns sensible default retry configuration
@@ -104,6 +116,8 @@ func DefaultRetryConfig() RetryConfig {
 		BackoffStrategy:       BackoffExponential,
 		BaseDelay:             1 * time.Second,
 		MaxDelay:              30 * time.Second,
+		MaxRetryAfter:         0, // Wait as long as the server asks
+		MaxElapsedTime:        0, // No overall time budget
 	}
 }
 
@@ -112,8 +126,18 @@ func (rc RetryConfig) IsRetryEnabled() bool {
 	return rc.Enabled && rc.MaxRetries > 0
 }
 
-// GetDelay calculates the delay for a specific retry attempt
+// GetDelay calculates the delay for a specific retry attempt.
+//
+// BackoffDecorrelatedJitter derives each delay from the previous one; as
+// GetDelay has no previous delay to go on, it assumes the one before attempt
+// was the unjittered exponential delay.
 func (rc RetryConfig) GetDelay(attempt int) time.Duration {
+	return rc.nextDelay(attempt, 0)
+}
+
+// nextDelay calculates the delay for a retry attempt given the delay before
+// the previous attempt, or 0 if it is not known
+func (rc RetryConfig) nextDelay(attempt int, previous time.Duration) time.Duration {
 	if attempt <= 0 {
 		return rc.BaseDelay
 	}
@@ -129,6 +153,23 @@ func (rc RetryConfig) GetDelay(attempt int) time.Duration {
 		delay = time.Duration(attempt) * rc.BaseDelay
 	case BackoffConstant:
 		delay = rc.BaseDelay
+	case BackoffFullJitter:
+		delay = randomDelay(rc.exponentialDelay(attempt))
+	case BackoffEqualJitter:
+		half := rc.exponentialDelay(attempt) / 2
+		delay = half + randomDelay(half)
+	case BackoffDecorrelatedJitter:
+		if previous <= 0 {
+			previous = rc.exponentialDelay(attempt - 1)
+		}
+		upper := 3 * previous
+		if upper > rc.MaxDelay {
+			upper = rc.MaxDelay
+		}
+		delay = rc.BaseDelay
+		if upper > delay {
+			delay += randomDelay(upper - delay)
+		}
 	default:
 		delay = rc.BaseDelay
 	}
@@ -141,6 +182,40 @@ func (rc RetryConfig) GetDelay(attempt int) time.Duration {
 	return delay
 }
 
+// exponentialDelay returns BaseDelay doubled for each attempt after the
+// first, capped at MaxDelay
+func (rc RetryConfig) exponentialDelay(attempt int) time.Duration {
+	delay := rc.BaseDelay
+	for i := 1; i < attempt && delay < rc.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > rc.MaxDelay {
+		delay = rc.MaxDelay
+	}
+	return delay
+}
+
+// retryAfterDelay returns the delay the server asked for before retrying
+// resp, capped at MaxRetryAfter if it is set
+func (rc RetryConfig) retryAfterDelay(resp *http.Response) (time.Duration, bool) {
+	delay, ok := serverRetryDelay(resp)
+	if !ok {
+		return 0, false
+	}
+	if rc.MaxRetryAfter > 0 && delay > rc.MaxRetryAfter {
+		delay = rc.MaxRetryAfter
+	}
+	return delay, true
+}
+
+// randomDelay returns a random duration between 0 and max inclusive
+func randomDelay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
 // RequestMonitor provides hooks for monitoring HTTP requests
 type RequestMonitor interface {
 	// OnRequestStart is called before a request is sent
@@ -171,12 +246,18 @@ type Configuration struct {
 	APIKey string `json:"apiKey,omitempty"`
 
 	// Rate limiting configuration
-	EnableRateLimit    bool                     `json:"enableRateLimit,omitempty"`
-	CustomerRateLimits *CustomerRateLimitConfig `json:"customerRateLimits,omitempty"`
+	EnableRateLimit      bool                     `json:"enableRateLimit,omitempty"`
+	CustomerRateLimits   *CustomerRateLimitConfig `json:"customerRateLimits,omitempty"`
+	AdaptiveRateLimit    bool                     `json:"adaptiveRateLimit,omitempty"`
+	RateLimiterBackend   RateLimiterBackend       `json:"-"`                              // Not serialized - runtime configuration only
+	RateLimiterNamespace string                   `json:"rateLimiterNamespace,omitempty"` // Bucket namespace on RateLimiterBackend, derived from APIKey when empty
 
 	// Retry configuration
 	RetryConfig RetryConfig `json:"retryConfig"`
 
+	// Circuit breaker configuration (disabled by default)
+	CircuitBreakerConfig CircuitBreakerConfig `json:"circuitBreakerConfig,omitempty"`
+
 	// Default rate limits (can be overridden per customer)
 	DefaultGeneralRateLimit     *RateLimitConfig `json:"defaultGeneralRateLimit,omitempty"`
 	DefaultStatisticsRateLimit  *RateLimitConfig `json:"defaultStatisticsRateLimit,omitempty"`
@@ -184,9 +265,17 @@ type Configuration struct {
 
 	// Idempotency configuration
 	IdempotencyConfig IdempotencyConfig `json:"idempotencyConfig,omitempty"`
+	IdempotencyStore  IdempotencyStore  `json:"-"` // Not serialized - runtime configuration only
 
 	// Monitoring configuration
 	RequestMonitor RequestMonitor `json:"-"` // Not serialized - runtime configuration only
+
+	// Logging configuration
+	RequestLogger RequestLogger `json:"-"` // Not serialized - runtime configuration only
+	LogBodies     bool          `json:"logBodies,omitempty"`
+
+	// Tracing configuration
+	RequestTracer RequestTracer `json:"-"` // Not serialized - runtime configuration only
 }
 
 // NewConfiguration returns a new Configuration object with default settings
@@ -269,6 +358,14 @@ func NewAPIClientWithConfig(cfg *Configuration) *APIClient {
 	if cfg.CustomerRateLimits != nil {
 		c.rateLimiter.ConfigureFromCustomerConfig(*cfg.CustomerRateLimits)
 	}
+	if cfg.RateLimiterBackend != nil {
+		c.rateLimiter.SetBackend(cfg.RateLimiterBackend, cfg.rateLimiterNamespace())
+	}
+
+	// Initialize circuit breaker
+	if cfg.CircuitBreakerConfig.Enabled {
+		c.circuitBreaker = newClientCircuitBreaker(cfg)
+	}
 
 	// Initialize idempotency helper
 	c.idempotencyHelper = NewIdempotencyHelper(cfg.IdempotencyConfig)
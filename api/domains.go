@@ -266,3 +266,18 @@ func (a *DomainsAPIService) GetDomains(
 	resp, err := a.client.Execute(ctx, config)
 	return &result, resp, err
 }
+
+// GetDomainsPager returns a Pager that walks every page of GetDomains,
+// starting from the cursor in pagination (optional).
+func (a *DomainsAPIService) GetDomainsPager(
+	ctx context.Context,
+	accountId uuid.UUID,
+	dnsValid *bool,
+	pagination *common.PaginationParams,
+	opts ...RequestOption,
+) *Pager[responses.Domain] {
+	fetch := func(ctx context.Context, page common.PaginationParams) (*responses.PaginatedDomainsResponse, *http.Response, error) {
+		return a.GetDomains(ctx, accountId, dnsValid, &page, opts...)
+	}
+	return NewPager[responses.Domain](ctx, fetch, paginationOrZero(pagination))
+}
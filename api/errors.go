@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ErrorType represents the category of error
@@ -118,6 +119,23 @@ func retryAfterSeconds(resp *http.Response, errType ErrorType) int {
 	return 0
 }
 
+// serverRetryDelay returns how long the server asked the client to wait
+// before retrying resp: the Retry-After of a 429 or 503, which may be an HTTP
+// date, or of an in-progress idempotency conflict.
+func serverRetryDelay(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	if seconds := retryAfterSeconds(resp, classifyErrorType(resp)); seconds > 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return 0, false
+}
+
 // idempotencyInProgressRetryAfter reports whether a 409 response describes an
 // idempotent request that is still in flight, and if so how many seconds to
 // wait before retrying.
@@ -6,6 +6,13 @@
 package api
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	"github.com/google/uuid"
 )
 
@@ -15,6 +22,13 @@ type IdempotencyConfig struct {
 	AutoGenerate bool
 	// KeyPrefix adds a prefix to all generated idempotency keys (optional)
 	KeyPrefix string
+	// DeriveFromContent derives the key for POST requests with a body from a
+	// hash of the body, the account ID and Namespace instead of generating a
+	// random one, so identical requests share a key
+	DeriveFromContent bool
+	// Namespace is mixed into content-derived keys, so that services sending
+	// the same content do not share keys (optional)
+	Namespace string
 }
 
 // DefaultIdempotencyConfig returns the default idempotency configuration
@@ -100,6 +114,13 @@ func (h *IdempotencyHelper) GenerateKey() string {
 	return GenerateIdempotencyKeyWithPrefix(h.config.KeyPrefix)
 }
 
+// DeriveKey returns the content-derived idempotency key for a request body
+// sent with method to path. Headers that change between otherwise identical
+// submissions, such as Date, are left out of the hash.
+func (h *IdempotencyHelper) DeriveKey(method, path string, body interface{}) (string, error) {
+	return DeriveIdempotencyKey(h.config.Namespace, method, path, body, h.config.KeyPrefix)
+}
+
 // EnsureKey returns the provided key or generates a new one if empty
 func (h *IdempotencyHelper) EnsureKey(key string) string {
 	if key != "" {
@@ -110,3 +131,66 @@ func (h *IdempotencyHelper) EnsureKey(key string) string {
 	}
 	return ""
 }
+
+// DeriveIdempotencyKey returns an idempotency key derived from a hash of
+// namespace, the request's method and path and the canonical JSON form of
+// body, with an optional prefix. path is the path the request is sent to,
+// with its parameters filled in, such as "/v2/accounts/<id>/messages", so
+// the same body sent to two endpoints, or for two accounts or sub-accounts,
+// gets two keys. The same inputs always give the same key.
+func DeriveIdempotencyKey(namespace, method, path string, body interface{}, prefix ...string) (string, error) {
+	canonical, err := canonicalRequestBody(body)
+	if err != nil {
+		return "", fmt.Errorf("deriving idempotency key: %w", err)
+	}
+
+	hash := sha256.New()
+	for _, part := range [][]byte{[]byte(namespace), []byte(method), []byte(path), canonical} {
+		// Length-prefix each part so that no two inputs hash the same bytes
+		fmt.Fprintf(hash, "%d:", len(part))
+		hash.Write(part)
+	}
+	key := hex.EncodeToString(hash.Sum(nil)[:16])
+
+	if len(prefix) > 0 && prefix[0] != "" {
+		return prefix[0] + "-" + key, nil
+	}
+	return key, nil
+}
+
+// volatileMessageHeaders are message headers left out of content-derived keys
+var volatileMessageHeaders = []string{"Date", "Message-Id"}
+
+// canonicalRequestBody encodes body as JSON with object keys sorted and
+// volatile message headers removed
+func canonicalRequestBody(body interface{}) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if fields, ok := value.(map[string]interface{}); ok {
+		if headers, ok := fields["headers"].(map[string]interface{}); ok {
+			for name := range headers {
+				for _, volatile := range volatileMessageHeaders {
+					if strings.EqualFold(name, volatile) {
+						delete(headers, name)
+					}
+				}
+			}
+			if len(headers) == 0 {
+				delete(fields, "headers")
+			}
+		}
+	}
+
+	// Maps are encoded with sorted keys
+	return json.Marshal(value)
+}
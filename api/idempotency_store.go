@@ -0,0 +1,324 @@
+// Persistent idempotency keys for the AhaSend Go SDK.
+//
+// This file maps caller-defined operation IDs to the idempotency keys they
+// were first sent with, so that a retried job reuses its key, and remembers
+// the result once an operation succeeds.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is what an IdempotencyStore keeps for one operation
+type IdempotencyRecord struct {
+	OperationID string    `json:"operation_id"`
+	Key         string    `json:"key"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Method and Path are the endpoint the operation was first sent to. A
+	// request for the operation to any other endpoint is refused.
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+
+	// Completed is set once a request for the operation succeeded.
+	// StatusCode and Response are the status and body the API returned.
+	Completed   bool            `json:"completed"`
+	StatusCode  int             `json:"status_code,omitempty"`
+	Response    json.RawMessage `json:"response,omitempty"`
+	CompletedAt time.Time       `json:"completed_at,omitempty"`
+}
+
+// replay returns a response carrying the recorded result, marked as replayed
+// the way the API marks results it replays for a known key
+func (r IdempotencyRecord) replay(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode),
+		StatusCode: r.StatusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type":        {"application/json"},
+			"Idempotency-Key":     {r.Key},
+			"Idempotent-Replayed": {"true"},
+		},
+		Body:          io.NopCloser(bytes.NewReader(r.Response)),
+		ContentLength: int64(len(r.Response)),
+		Request:       req,
+	}
+}
+
+// IdempotencyStore maps operation IDs to idempotency records. Implementations
+// must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Load returns the record for operationID; ok is false when there is none
+	Load(ctx context.Context, operationID string) (record IdempotencyRecord, ok bool, err error)
+	// LoadOrStore returns the record for record.OperationID if there is one,
+	// with loaded set to true. Otherwise it stores record and returns it.
+	LoadOrStore(ctx context.Context, record IdempotencyRecord) (actual IdempotencyRecord, loaded bool, err error)
+	// Store saves record, replacing any record for the same operation
+	Store(ctx context.Context, record IdempotencyRecord) error
+	// Delete forgets an operation, so that it is next sent with a new key.
+	// Deleting an unknown operation is not an error.
+	Delete(ctx context.Context, operationID string) error
+}
+
+// beginOperation looks up or records the idempotency key for operationID and
+// sets it on req. A key already set on req is used for a new operation, and an
+// operation recorded for another endpoint is refused, as its key and result
+// belong to that endpoint.
+func (c *APIClient) beginOperation(ctx context.Context, req *http.Request, operationID string) (IdempotencyRecord, error) {
+	store := c.cfg.IdempotencyStore
+	if store == nil {
+		return IdempotencyRecord{}, &APIError{
+			Type:    ErrorTypeValidation,
+			Message: "WithOperationID requires an IdempotencyStore; set one with WithIdempotencyStore",
+		}
+	}
+
+	key := req.Header.Get("Idempotency-Key")
+	if key == "" {
+		key = c.idempotencyHelper.GenerateKey()
+	}
+	record, _, err := store.LoadOrStore(ctx, IdempotencyRecord{
+		OperationID: operationID,
+		Key:         key,
+		CreatedAt:   time.Now(),
+		Method:      req.Method,
+		Path:        req.URL.Path,
+	})
+	if err != nil {
+		return IdempotencyRecord{}, &APIError{
+			Type:    ErrorTypeIdempotency,
+			Message: fmt.Sprintf("Failed to load idempotency key for operation %q: %v", operationID, err),
+		}
+	}
+	// Records written before Method and Path were kept match any endpoint
+	if (record.Method != "" && record.Method != req.Method) || (record.Path != "" && record.Path != req.URL.Path) {
+		return IdempotencyRecord{}, &APIError{
+			Type: ErrorTypeValidation,
+			Message: fmt.Sprintf("Operation %q was started with %s %s and cannot be reused for %s %s",
+				operationID, record.Method, record.Path, req.Method, req.URL.Path),
+		}
+	}
+	req.Header.Set("Idempotency-Key", record.Key)
+	return record, nil
+}
+
+// completeOperation records the successful result of an operation. Failing to
+// record it is not an error: the next request for the operation is sent with
+// the same key, and the API replays the result.
+func (c *APIClient) completeOperation(ctx context.Context, operationID string, statusCode int, body []byte) {
+	store := c.cfg.IdempotencyStore
+	if store == nil {
+		return
+	}
+	record, ok, err := store.Load(ctx, operationID)
+	if err != nil || !ok {
+		return
+	}
+	record.Completed = true
+	record.StatusCode = statusCode
+	record.Response = append(json.RawMessage(nil), body...)
+	record.CompletedAt = time.Now()
+	_ = store.Store(ctx, record)
+}
+
+// WithIdempotencyStore sets the store that WithOperationID keys are kept in
+func WithIdempotencyStore(store IdempotencyStore) ClientOption {
+	return func(cfg *Configuration) {
+		cfg.IdempotencyStore = store
+	}
+}
+
+// MemoryIdempotencyStore keeps idempotency records in memory. Records do not
+// survive a restart.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+// NewMemoryIdempotencyStore returns an empty MemoryIdempotencyStore
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]IdempotencyRecord)}
+}
+
+// Load implements IdempotencyStore
+func (m *MemoryIdempotencyStore) Load(_ context.Context, operationID string) (IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[operationID]
+	return record, ok, nil
+}
+
+// LoadOrStore implements IdempotencyStore
+func (m *MemoryIdempotencyStore) LoadOrStore(_ context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.records[record.OperationID]; ok {
+		return existing, true, nil
+	}
+	m.records[record.OperationID] = record
+	return record, false, nil
+}
+
+// Store implements IdempotencyStore
+func (m *MemoryIdempotencyStore) Store(_ context.Context, record IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[record.OperationID] = record
+	return nil
+}
+
+// Delete implements IdempotencyStore
+func (m *MemoryIdempotencyStore) Delete(_ context.Context, operationID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, operationID)
+	return nil
+}
+
+// FileIdempotencyStore keeps each idempotency record in a JSON file in one
+// directory. A record is created with a hard link, which fails if the file
+// already exists, so processes sharing the directory agree on each
+// operation's key. The directory must be on a local file system.
+type FileIdempotencyStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileIdempotencyStore returns a FileIdempotencyStore that keeps records in
+// dir, creating it if necessary
+func NewFileIdempotencyStore(dir string) (*FileIdempotencyStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating idempotency store directory: %w", err)
+	}
+	return &FileIdempotencyStore{dir: dir}, nil
+}
+
+// Load implements IdempotencyStore
+func (f *FileIdempotencyStore) Load(_ context.Context, operationID string) (IdempotencyRecord, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record, err := f.read(f.path(operationID))
+	if os.IsNotExist(err) {
+		return IdempotencyRecord{}, false, nil
+	}
+	return record, err == nil, err
+}
+
+// LoadOrStore implements IdempotencyStore
+func (f *FileIdempotencyStore) LoadOrStore(_ context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := f.path(record.OperationID)
+	if existing, err := f.read(name); err == nil {
+		return existing, true, nil
+	} else if !os.IsNotExist(err) {
+		return IdempotencyRecord{}, false, err
+	}
+
+	err := f.write(record, func(tmp string) error { return os.Link(tmp, name) })
+	if os.IsExist(err) {
+		// Another process created it first
+		existing, err := f.read(name)
+		return existing, err == nil, err
+	}
+	if err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+	return record, false, nil
+}
+
+// Store implements IdempotencyStore
+func (f *FileIdempotencyStore) Store(_ context.Context, record IdempotencyRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := f.path(record.OperationID)
+	return f.write(record, func(tmp string) error { return os.Rename(tmp, name) })
+}
+
+// Delete implements IdempotencyStore
+func (f *FileIdempotencyStore) Delete(_ context.Context, operationID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.Remove(f.path(operationID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("deleting idempotency record: %w", err)
+	}
+	return nil
+}
+
+// path returns the file a record is kept in. Operation IDs are hashed so that
+// any ID makes a safe file name.
+func (f *FileIdempotencyStore) path(operationID string) string {
+	sum := sha256.Sum256([]byte(operationID))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// read decodes the record in the named file. A missing file is reported with
+// an error satisfying os.IsNotExist.
+func (f *FileIdempotencyStore) read(name string) (IdempotencyRecord, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return IdempotencyRecord{}, err
+		}
+		return IdempotencyRecord{}, fmt.Errorf("reading idempotency record: %w", err)
+	}
+	var record IdempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return IdempotencyRecord{}, fmt.Errorf("decoding idempotency record: %w", err)
+	}
+	return record, nil
+}
+
+// write writes record to a synced temporary file and moves it into place
+// with install, which receives the temporary file's name
+func (f *FileIdempotencyStore) write(record IdempotencyRecord, install func(tmp string) error) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding idempotency record: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(f.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("writing idempotency record: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing idempotency record: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing idempotency record: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing idempotency record: %w", err)
+	}
+	if err := install(tmp.Name()); err != nil {
+		if os.IsExist(err) {
+			return err
+		}
+		return fmt.Errorf("writing idempotency record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) IdempotencyStore{
+		"memory": func(t *testing.T) IdempotencyStore { return NewMemoryIdempotencyStore() },
+		"file": func(t *testing.T) IdempotencyStore {
+			store, err := NewFileIdempotencyStore(t.TempDir())
+			require.NoError(t, err)
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			ctx := context.Background()
+
+			_, ok, err := store.Load(ctx, "order-123/receipt")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			first := IdempotencyRecord{OperationID: "order-123/receipt", Key: "key-1", CreatedAt: time.Now()}
+			actual, loaded, err := store.LoadOrStore(ctx, first)
+			require.NoError(t, err)
+			assert.False(t, loaded)
+			assert.Equal(t, "key-1", actual.Key)
+
+			actual, loaded, err = store.LoadOrStore(ctx, IdempotencyRecord{OperationID: "order-123/receipt", Key: "key-2"})
+			require.NoError(t, err)
+			assert.True(t, loaded)
+			assert.Equal(t, "key-1", actual.Key, "the first key sticks")
+
+			actual.Completed = true
+			actual.StatusCode = http.StatusOK
+			actual.Response = []byte(`{"object":"list"}`)
+			require.NoError(t, store.Store(ctx, actual))
+
+			record, ok, err := store.Load(ctx, "order-123/receipt")
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.True(t, record.Completed)
+			assert.JSONEq(t, `{"object":"list"}`, string(record.Response))
+
+			require.NoError(t, store.Delete(ctx, "order-123/receipt"))
+			require.NoError(t, store.Delete(ctx, "order-123/receipt"))
+			_, ok, err = store.Load(ctx, "order-123/receipt")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestFileIdempotencyStoreConcurrentLoadOrStore(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// Separate stores stand in for separate processes
+	keys := make([]string, 8)
+	var wg sync.WaitGroup
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store, err := NewFileIdempotencyStore(dir)
+			require.NoError(t, err)
+			record, _, err := store.LoadOrStore(ctx, IdempotencyRecord{OperationID: "op", Key: uuid.NewString()})
+			require.NoError(t, err)
+			keys[i] = record.Key
+		}(i)
+	}
+	wg.Wait()
+
+	for _, key := range keys {
+		assert.Equal(t, keys[0], key)
+	}
+}
+
+func receiptMessage() requests.CreateMessageRequest {
+	text := "Your receipt"
+	return requests.CreateMessageRequest{
+		From:        common.SenderAddress{Email: "shop@example.com"},
+		Recipients:  []common.Recipient{{Email: "customer@example.com"}},
+		Subject:     "Receipt",
+		TextContent: &text,
+	}
+}
+
+func TestOperationIDReusesKeyAndReplaysResult(t *testing.T) {
+	var requestCount int32
+	var keys []string
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&requestCount, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"message":"try again"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"message","id":"msg-1","recipient":{"email":"customer@example.com"},"status":"queued"}]}`))
+	})
+	defer cleanup()
+
+	cfg := client.GetConfig()
+	cfg.RetryConfig.Enabled = false
+	WithIdempotencyStore(NewMemoryIdempotencyStore())(cfg)
+	accountID := uuid.New()
+	ctx := context.Background()
+
+	// The first attempt fails; the job is retried later
+	_, _, err := client.MessagesAPI.CreateMessage(ctx, accountID, receiptMessage(), WithOperationID("order-123/receipt"))
+	require.Error(t, err)
+
+	resp, httpResp, err := client.MessagesAPI.CreateMessage(ctx, accountID, receiptMessage(), WithOperationID("order-123/receipt"))
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, "msg-1", *resp.Data[0].ID)
+	assert.Empty(t, httpResp.Header.Get("Idempotent-Replayed"))
+
+	require.Len(t, keys, 2)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1], "retries of an operation must reuse its key")
+
+	// Once completed, the operation is answered locally
+	resp, httpResp, err = client.MessagesAPI.CreateMessage(ctx, accountID, receiptMessage(), WithOperationID("order-123/receipt"))
+	require.NoError(t, err)
+	assert.Equal(t, "msg-1", *resp.Data[0].ID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requestCount))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, "true", httpResp.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, keys[0], httpResp.Request.Header.Get("Idempotency-Key"))
+
+	// A different operation gets its own key
+	_, _, err = client.MessagesAPI.CreateMessage(ctx, accountID, receiptMessage(), WithOperationID("order-124/receipt"))
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	assert.NotEqual(t, keys[0], keys[2])
+}
+
+func TestOperationIDUsesExplicitKeyForNewOperation(t *testing.T) {
+	var keys []string
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer cleanup()
+
+	cfg := client.GetConfig()
+	cfg.RetryConfig.Enabled = false
+	cfg.IdempotencyStore = NewMemoryIdempotencyStore()
+
+	ctx := context.Background()
+	accountID := uuid.New()
+	_, _, _ = client.MessagesAPI.CreateMessage(ctx, accountID, receiptMessage(),
+		WithOperationID("op"), WithIdempotencyKey("my-key"))
+	_, _, _ = client.MessagesAPI.CreateMessage(ctx, accountID, receiptMessage(),
+		WithOperationID("op"), WithIdempotencyKey("another-key"))
+
+	assert.Equal(t, []string{"my-key", "my-key"}, keys)
+}
+
+func TestOperationIDRequiresStore(t *testing.T) {
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no request should be sent")
+	})
+	defer cleanup()
+
+	_, _, err := client.MessagesAPI.CreateMessage(context.Background(), uuid.New(), receiptMessage(), WithOperationID("op"))
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, ErrorTypeValidation, apiErr.Type)
+}
+
+func TestOperationIDIsTiedToItsEndpoint(t *testing.T) {
+	var requestCount int32
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"message","id":"msg-1","recipient":{"email":"customer@example.com"},"status":"queued"}]}`))
+	})
+	defer cleanup()
+
+	cfg := client.GetConfig()
+	store := NewMemoryIdempotencyStore()
+	cfg.IdempotencyStore = store
+	accountID := uuid.New()
+	ctx := context.Background()
+
+	_, _, err := client.MessagesAPI.CreateMessage(ctx, accountID, receiptMessage(), WithOperationID("order-123/receipt"))
+	require.NoError(t, err)
+	record, ok, err := store.Load(ctx, "order-123/receipt")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, http.MethodPost, record.Method)
+	assert.Equal(t, "/v2/accounts/"+accountID.String()+"/messages", record.Path)
+
+	// Reusing the ID elsewhere must not replay the message response
+	_, _, err = client.MessagesAPI.CancelMessage(ctx, accountID, "msg-1", WithOperationID("order-123/receipt"))
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, ErrorTypeValidation, apiErr.Type)
+	assert.Contains(t, apiErr.Message, "cannot be reused")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requestCount))
+}
@@ -1,10 +1,17 @@
 package api
 
 import (
+	"context"
+	"net/http"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIdempotencyConfig(t *testing.T) {
@@ -165,3 +172,125 @@ func TestIdempotencyConfigInConfiguration(t *testing.T) {
 	assert.False(t, clientConfig.AutoGenerate, "Should use custom auto-generate setting")
 	assert.Equal(t, "custom", clientConfig.KeyPrefix, "Should use custom prefix")
 }
+
+func TestDeriveIdempotencyKey(t *testing.T) {
+	const messagesPath = "/v2/accounts/account-1/messages"
+	message := receiptMessage()
+	key, err := DeriveIdempotencyKey("billing", http.MethodPost, messagesPath, message)
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	again, err := DeriveIdempotencyKey("billing", http.MethodPost, messagesPath, receiptMessage())
+	require.NoError(t, err)
+	assert.Equal(t, key, again, "identical requests share a key")
+
+	prefixed, err := DeriveIdempotencyKey("billing", http.MethodPost, messagesPath, message, "svc")
+	require.NoError(t, err)
+	assert.Equal(t, "svc-"+key, prefixed)
+
+	// Volatile headers do not change the key
+	volatile := receiptMessage()
+	volatile.Headers = map[string]string{"Date": time.Now().Format(time.RFC1123Z), "message-id": "<1@example.com>"}
+	derived, err := DeriveIdempotencyKey("billing", http.MethodPost, messagesPath, volatile)
+	require.NoError(t, err)
+	assert.Equal(t, key, derived)
+
+	// Anything else does
+	changed := receiptMessage()
+	changed.Subject = "Updated receipt"
+	changed.Headers = map[string]string{"X-Order": "123"}
+	for name, args := range map[string][]interface{}{
+		"namespace": {"marketing", http.MethodPost, messagesPath, message},
+		"method":    {"billing", http.MethodPut, messagesPath, message},
+		"account":   {"billing", http.MethodPost, "/v2/accounts/account-2/messages", message},
+		"content":   {"billing", http.MethodPost, messagesPath, changed},
+	} {
+		other, err := DeriveIdempotencyKey(args[0].(string), args[1].(string), args[2].(string), args[3])
+		require.NoError(t, err)
+		assert.NotEqual(t, key, other, name)
+	}
+
+	// The same message scheduled for different times is a different send
+	morning := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	evening := time.Date(2024, 5, 6, 17, 0, 0, 0, time.UTC)
+	keys := make(map[string]bool)
+	for _, at := range []*time.Time{nil, &morning, &evening} {
+		scheduled := receiptMessage()
+		if at != nil {
+			scheduled.Schedule = &common.MessageSchedule{FirstAttempt: at}
+		}
+		other, err := DeriveIdempotencyKey("billing", http.MethodPost, messagesPath, scheduled)
+		require.NoError(t, err)
+		keys[other] = true
+	}
+	assert.Len(t, keys, 3, "the schedule is part of the key")
+
+	// Map key order does not matter
+	a, err := DeriveIdempotencyKey("", "", "", map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": 1.5, "d": "x"}})
+	require.NoError(t, err)
+	b, err := DeriveIdempotencyKey("", "", "", map[string]interface{}{"b": map[string]interface{}{"d": "x", "c": 1.5}, "a": 1})
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	_, err = DeriveIdempotencyKey("", "", "", make(chan int))
+	assert.Error(t, err)
+}
+
+func TestContentDerivedIdempotencyKeys(t *testing.T) {
+	var keys []string
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[]}`))
+	})
+	defer cleanup()
+
+	client.SetIdempotencyConfig(IdempotencyConfig{AutoGenerate: true, DeriveFromContent: true, Namespace: "billing"})
+	accountID := uuid.New()
+	ctx := context.Background()
+
+	changed := receiptMessage()
+	changed.Subject = "Another receipt"
+	for _, message := range []requests.CreateMessageRequest{receiptMessage(), receiptMessage(), changed} {
+		_, _, err := client.MessagesAPI.CreateMessage(ctx, accountID, message)
+		require.NoError(t, err)
+	}
+	_, _, err := client.MessagesAPI.CreateMessage(ctx, accountID, receiptMessage(), WithIdempotencyKey("explicit"))
+	require.NoError(t, err)
+
+	expected, err := DeriveIdempotencyKey("billing", http.MethodPost, "/v2/accounts/"+accountID.String()+"/messages", receiptMessage())
+	require.NoError(t, err)
+	require.Len(t, keys, 4)
+	assert.Equal(t, expected, keys[0])
+	assert.Equal(t, keys[0], keys[1], "a double submission reuses the key")
+	assert.NotEqual(t, keys[0], keys[2])
+	assert.Equal(t, "explicit", keys[3], "an explicit key wins")
+}
+
+func TestContentDerivedIdempotencyKeysDifferPerEndpoint(t *testing.T) {
+	var keys []string
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	defer cleanup()
+
+	client.SetIdempotencyConfig(IdempotencyConfig{AutoGenerate: true, DeriveFromContent: true})
+	accountID := uuid.New()
+	ctx := context.Background()
+
+	// The same key details for two sub-accounts are two different keys
+	request := requests.CreateAPIKeyRequest{Label: "ci", Scopes: []string{"messages:send:all"}}
+	for _, subAccountID := range []uuid.UUID{uuid.New(), uuid.New()} {
+		_, _, err := client.SubAccountsAPI.CreateSubAccountAPIKey(ctx, accountID, subAccountID, request)
+		require.NoError(t, err)
+	}
+	_, _, err := client.APIKeysAPI.CreateAPIKey(ctx, accountID, request)
+	require.NoError(t, err)
+
+	require.Len(t, keys, 3)
+	assert.NotEqual(t, keys[0], keys[1], "sub-accounts do not share keys")
+	assert.NotEqual(t, keys[0], keys[2], "endpoints do not share keys")
+	assert.NotEqual(t, keys[1], keys[2])
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RequestLogger receives one record for every attempt APIClient.Execute makes
+// to send a request, including each retry. On Go 1.21 and later, WithLogger
+// installs a RequestLogger that writes to a log/slog Logger.
+type RequestLogger interface {
+	LogRequestAttempt(ctx context.Context, attempt RequestAttempt)
+}
+
+// RequestAttempt describes a single attempt to send a request.
+//
+// Headers and bodies are redacted before they reach a RequestLogger: the
+// Authorization header, API key secrets, webhook secrets, SMTP passwords and
+// message content never appear in a RequestAttempt.
+type RequestAttempt struct {
+	Method       string
+	PathTemplate string
+	EndpointType EndpointType
+
+	// Attempt is 1 for the first attempt and increases with each retry
+	Attempt int
+
+	// StatusCode is zero when no response was received
+	StatusCode int
+	Duration   time.Duration
+
+	// RequestID is the X-Request-Id response header, if any
+	RequestID string
+
+	// RateLimitWait is the time spent waiting on the client-side rate limiter
+	// before the first attempt; it is zero for retries
+	RateLimitWait time.Duration
+
+	// Err is the transport error, if the attempt failed without a response
+	Err error
+
+	// RequestHeader, RequestBody and ResponseBody are only set when body
+	// logging is enabled with WithBodyLogging
+	RequestHeader http.Header
+	RequestBody   []byte
+	ResponseBody  []byte
+}
+
+// WithRequestLogger sets a logger that records every request attempt
+func WithRequestLogger(logger RequestLogger) ClientOption {
+	return func(cfg *Configuration) {
+		cfg.RequestLogger = logger
+	}
+}
+
+// WithBodyLogging includes redacted request headers and request and response
+// bodies in request log records. It is meant for debugging: bodies can be
+// large, and redaction only covers the fields the SDK knows to be sensitive.
+func WithBodyLogging(enabled bool) ClientOption {
+	return func(cfg *Configuration) {
+		cfg.LogBodies = enabled
+	}
+}
+
+const (
+	// redacted replaces sensitive values in logged headers and bodies
+	redacted = "[REDACTED]"
+
+	// maxLoggedBodyBytes caps the size of a logged body
+	maxLoggedBodyBytes = 16 << 10
+)
+
+var (
+	// redactedHeaders are never logged verbatim
+	redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+	// redactedFields are JSON object keys whose values are replaced when a body
+	// is logged: credentials, and message content that may contain personal
+	// data, including the subject and custom headers, which often carry
+	// tokens
+	redactedFields = map[string]bool{
+		"secret_key":     true,
+		"secret":         true,
+		"password":       true,
+		"subject":        true,
+		"headers":        true,
+		"text_content":   true,
+		"html_content":   true,
+		"amp_content":    true,
+		"content":        true,
+		"content_parsed": true,
+		"substitutions":  true,
+	}
+
+	// secretPattern matches API key secrets wherever they appear, such as in
+	// error messages or non-JSON bodies
+	secretPattern = regexp.MustCompile(`aha-sk-[A-Za-z0-9_\-]+`)
+)
+
+// redactHeader returns a copy of header that is safe to log
+func redactHeader(header http.Header) http.Header {
+	safe := header.Clone()
+	for _, name := range redactedHeaders {
+		if safe.Get(name) != "" {
+			safe.Set(name, redacted)
+		}
+	}
+	return safe
+}
+
+// redactBody returns a copy of a request or response body that is safe to log.
+// JSON bodies have sensitive fields replaced; anything else only has API key
+// secrets removed. Bodies longer than maxLoggedBodyBytes are truncated.
+func redactBody(body []byte) []byte {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err == nil {
+		if encoded, err := json.Marshal(redactValue(value)); err == nil {
+			body = encoded
+		}
+	}
+
+	body = secretPattern.ReplaceAll(body, []byte(redacted))
+	if len(body) > maxLoggedBodyBytes {
+		truncated := make([]byte, maxLoggedBodyBytes, maxLoggedBodyBytes+32)
+		copy(truncated, body)
+		body = append(truncated, fmt.Sprintf("... (%d bytes)", len(body))...)
+	}
+	return body
+}
+
+// redactValue walks a decoded JSON value replacing sensitive fields
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			switch {
+			case redactedFields[strings.ToLower(key)]:
+				v[key] = redacted
+			case strings.EqualFold(key, "data"):
+				// Attachment data is a base64 string; list responses use
+				// "data" for the array of results, which is walked instead
+				if _, ok := field.(string); ok {
+					v[key] = redacted
+				} else {
+					v[key] = redactValue(field)
+				}
+			default:
+				v[key] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return value
+}
+
+// sendLoggedAttempt sends one attempt of a request and reports it to the
+// configured RequestLogger
+func (c *APIClient) sendLoggedAttempt(ctx context.Context, req *http.Request, config RequestConfig, attempt int) (*http.Response, error) {
+	record := RequestAttempt{
+		Method:       config.Method,
+		PathTemplate: config.PathTemplate,
+		EndpointType: config.endpointType,
+		Attempt:      attempt,
+	}
+	if attempt == 1 {
+		record.RateLimitWait = config.rateLimitWait
+	}
+	if c.cfg.LogBodies {
+		record.RequestHeader = redactHeader(req.Header)
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				requestBody, _ := io.ReadAll(body)
+				body.Close()
+				record.RequestBody = redactBody(requestBody)
+			}
+		}
+	}
+
+	start := time.Now()
+	resp, err := c.cfg.HTTPClient.Do(req)
+	record.Duration = time.Since(start)
+	record.Err = err
+
+	if resp != nil {
+		record.StatusCode = resp.StatusCode
+		record.RequestID = resp.Header.Get("X-Request-Id")
+		if c.cfg.LogBodies && resp.Body != nil {
+			responseBody, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+			if readErr != nil {
+				// Surface the failure where Execute reads the body
+				resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(responseBody), errorReader{readErr}))
+			}
+			record.ResponseBody = redactBody(responseBody)
+		}
+	}
+
+	c.cfg.RequestLogger.LogRequestAttempt(ctx, record)
+	return resp, err
+}
+
+// errorReader is an io.Reader that always fails with err
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,81 @@
+//go:build go1.21
+
+package api
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogRequestLogger is a RequestLogger that writes one record per request
+// attempt to a log/slog Logger.
+//
+// Successful attempts are logged at LevelInfo, 4xx responses at LevelWarn, and
+// 5xx responses and transport errors at LevelError.
+type SlogRequestLogger struct {
+	Logger *slog.Logger
+}
+
+// NewSlogRequestLogger returns a RequestLogger that writes to logger, or to
+// slog.Default() when logger is nil
+func NewSlogRequestLogger(logger *slog.Logger) *SlogRequestLogger {
+	return &SlogRequestLogger{Logger: logger}
+}
+
+// WithLogger logs every request attempt to logger. Combine it with
+// WithBodyLogging to include redacted headers and bodies.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return WithRequestLogger(NewSlogRequestLogger(logger))
+}
+
+// LogRequestAttempt implements RequestLogger
+func (l *SlogRequestLogger) LogRequestAttempt(ctx context.Context, attempt RequestAttempt) {
+	logger := l.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case attempt.Err != nil || attempt.StatusCode >= 500:
+		level = slog.LevelError
+	case attempt.StatusCode >= 400:
+		level = slog.LevelWarn
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", attempt.Method),
+		slog.String("path", attempt.PathTemplate),
+		slog.String("endpoint_type", attempt.EndpointType.String()),
+		slog.Int("attempt", attempt.Attempt),
+		slog.Int("status", attempt.StatusCode),
+		slog.Duration("duration", attempt.Duration),
+	}
+	if attempt.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", attempt.RequestID))
+	}
+	if attempt.RateLimitWait > 0 {
+		attrs = append(attrs, slog.Duration("rate_limit_wait", attempt.RateLimitWait))
+	}
+	if attempt.Err != nil {
+		attrs = append(attrs, slog.String("error", secretPattern.ReplaceAllString(attempt.Err.Error(), redacted)))
+	}
+	if attempt.RequestHeader != nil {
+		headers := make([]slog.Attr, 0, len(attempt.RequestHeader))
+		for name := range attempt.RequestHeader {
+			headers = append(headers, slog.String(name, attempt.RequestHeader.Get(name)))
+		}
+		attrs = append(attrs, slog.Attr{Key: "request_headers", Value: slog.GroupValue(headers...)})
+	}
+	if attempt.RequestBody != nil {
+		attrs = append(attrs, slog.String("request_body", string(attempt.RequestBody)))
+	}
+	if attempt.ResponseBody != nil {
+		attrs = append(attrs, slog.String("response_body", string(attempt.ResponseBody)))
+	}
+
+	logger.LogAttrs(ctx, level, "ahasend request", attrs...)
+}
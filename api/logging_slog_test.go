@@ -0,0 +1,63 @@
+//go:build go1.21
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogRequestLogger(t *testing.T) {
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-456")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message":"invalid API key aha-sk-leaked"}`))
+	})
+	defer cleanup()
+
+	var buf bytes.Buffer
+	cfg := client.GetConfig()
+	cfg.APIKey = "aha-sk-leaked"
+	cfg.RetryConfig.Enabled = false
+	WithLogger(slog.New(slog.NewJSONHandler(&buf, nil)))(cfg)
+	WithBodyLogging(true)(cfg)
+
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+	require.Error(t, err)
+
+	assert.NotContains(t, buf.String(), "aha-sk-leaked")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "ahasend request", record["msg"])
+	assert.Equal(t, "GET", record["method"])
+	assert.Equal(t, "/v2/ping", record["path"])
+	assert.Equal(t, "general", record["endpoint_type"])
+	assert.Equal(t, float64(1), record["attempt"])
+	assert.Equal(t, float64(http.StatusUnauthorized), record["status"])
+	assert.Equal(t, "req-456", record["request_id"])
+	assert.Contains(t, record, "duration")
+	headers, ok := record["request_headers"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, redacted, headers["Authorization"])
+}
+
+func TestSlogRequestLoggerRespectsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogRequestLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	logger.LogRequestAttempt(context.Background(), RequestAttempt{Method: http.MethodGet, PathTemplate: "/v2/ping", Attempt: 1, StatusCode: http.StatusOK})
+	assert.Empty(t, buf.String())
+
+	logger.LogRequestAttempt(context.Background(), RequestAttempt{Method: http.MethodGet, PathTemplate: "/v2/ping", Attempt: 1, StatusCode: http.StatusBadGateway})
+	assert.Contains(t, buf.String(), "level=ERROR")
+}
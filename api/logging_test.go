@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingRequestLogger struct {
+	mu       sync.Mutex
+	attempts []RequestAttempt
+}
+
+func (l *recordingRequestLogger) LogRequestAttempt(_ context.Context, attempt RequestAttempt) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.attempts = append(l.attempts, attempt)
+}
+
+func TestRequestLoggerRecordsEachAttempt(t *testing.T) {
+	calls := 0
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-Request-Id", "req-123")
+		w.Header().Set("Content-Type", "application/json")
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"message":"try again"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":"pong"}`))
+	})
+	defer cleanup()
+
+	logger := &recordingRequestLogger{}
+	cfg := client.GetConfig()
+	WithRequestLogger(logger)(cfg)
+	cfg.RetryConfig = RetryConfig{Enabled: true, MaxRetries: 2, BaseDelay: 1, MaxDelay: 1, BackoffStrategy: BackoffConstant}
+
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, logger.attempts, 2)
+	first, second := logger.attempts[0], logger.attempts[1]
+	assert.Equal(t, http.MethodGet, first.Method)
+	assert.Equal(t, "/v2/ping", first.PathTemplate)
+	assert.Equal(t, GeneralAPI, first.EndpointType)
+	assert.Equal(t, 1, first.Attempt)
+	assert.Equal(t, http.StatusServiceUnavailable, first.StatusCode)
+	assert.Equal(t, "req-123", first.RequestID)
+	assert.Equal(t, 2, second.Attempt)
+	assert.Equal(t, http.StatusOK, second.StatusCode)
+	assert.Zero(t, second.RateLimitWait)
+	assert.Nil(t, first.RequestHeader, "headers are only logged with body logging enabled")
+	assert.Nil(t, second.ResponseBody, "bodies are only logged with body logging enabled")
+}
+
+func TestRequestLoggerRedactsBodies(t *testing.T) {
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"message","id":"m1","recipient":{"email":"a@example.com"},"status":"queued"}]}`))
+	})
+	defer cleanup()
+
+	logger := &recordingRequestLogger{}
+	cfg := client.GetConfig()
+	cfg.APIKey = "aha-sk-supersecret"
+	WithRequestLogger(logger)(cfg)
+	WithBodyLogging(true)(cfg)
+
+	text := "Your password reset code is 123456"
+	response, _, err := client.MessagesAPI.CreateMessage(context.Background(), uuid.New(), requests.CreateMessageRequest{
+		From:          common.SenderAddress{Email: "sender@example.com"},
+		Recipients:    []common.Recipient{{Email: "a@example.com"}},
+		Subject:       "Reset",
+		TextContent:   &text,
+		Attachments:   []common.Attachment{{Data: "c2VjcmV0", ContentType: "text/plain", FileName: "code.txt"}},
+		Substitutions: map[string]interface{}{"code": "123456"},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Data, 1, "logging must leave the response body readable")
+
+	require.Len(t, logger.attempts, 1)
+	attempt := logger.attempts[0]
+	assert.Equal(t, SendMessageAPI, attempt.EndpointType)
+	assert.Equal(t, redacted, attempt.RequestHeader.Get("Authorization"))
+	assert.NotContains(t, string(attempt.RequestBody), "123456")
+	assert.NotContains(t, string(attempt.RequestBody), "c2VjcmV0")
+	assert.Contains(t, string(attempt.RequestBody), "code.txt")
+	assert.Contains(t, string(attempt.ResponseBody), `"id":"m1"`)
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		hidden   []string
+		retained []string
+	}{
+		{
+			name:     "API key secret",
+			body:     `{"object":"api_key","label":"ci","secret_key":"aha-sk-abc123"}`,
+			hidden:   []string{"aha-sk-abc123"},
+			retained: []string{`"label":"ci"`},
+		},
+		{
+			name:     "SMTP password",
+			body:     `{"object":"smtp_credential","username":"aha-smtp-x","password":"hunter2"}`,
+			hidden:   []string{"hunter2"},
+			retained: []string{"aha-smtp-x"},
+		},
+		{
+			name:     "webhook secret in a list",
+			body:     `{"object":"list","data":[{"name":"hook","secret":"aha-whsec-xyz"}]}`,
+			hidden:   []string{"aha-whsec-xyz"},
+			retained: []string{`"name":"hook"`},
+		},
+		{
+			name:   "message content",
+			body:   `{"html_content":"<p>hi</p>","amp_content":"amp","content":"raw"}`,
+			hidden: []string{"<p>hi</p>", `"amp"`, `"raw"`},
+		},
+		{
+			name:   "non-JSON body",
+			body:   `invalid key aha-sk-abc123`,
+			hidden: []string{"aha-sk-abc123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(redactBody([]byte(tt.body)))
+			for _, s := range tt.hidden {
+				assert.NotContains(t, got, s)
+			}
+			for _, s := range tt.retained {
+				assert.Contains(t, got, s)
+			}
+			if json.Valid([]byte(tt.body)) {
+				assert.True(t, json.Valid([]byte(got)), "redacted JSON should stay valid")
+			}
+		})
+	}
+
+	// A message request keeps its addresses but not its content
+	text := "Your code is 123456"
+	message, err := json.Marshal(requests.CreateMessageRequest{
+		From:        common.SenderAddress{Email: "shop@example.com"},
+		Recipients:  []common.Recipient{{Email: "customer@example.com"}},
+		Subject:     "Password reset for Jane Doe",
+		TextContent: &text,
+		Headers:     map[string]string{"X-Reset-Token": "tok_secret"},
+	})
+	require.NoError(t, err)
+	got := string(redactBody(message))
+	for _, hidden := range []string{"Jane Doe", "123456", "X-Reset-Token", "tok_secret"} {
+		assert.NotContains(t, got, hidden)
+	}
+	assert.Contains(t, got, "customer@example.com")
+	assert.Contains(t, got, `"subject":"`+redacted+`"`)
+
+	large := make([]byte, maxLoggedBodyBytes*2)
+	for i := range large {
+		large[i] = 'x'
+	}
+	assert.Less(t, len(redactBody(large)), maxLoggedBodyBytes+64)
+}
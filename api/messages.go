@@ -179,10 +179,24 @@ func (a *MessagesAPIService) GetMessages(
 ) (*responses.PaginatedMessagesResponse, *http.Response, error) {
 	var result responses.PaginatedMessagesResponse
 
+	if err := params.Validate(); err != nil {
+		return &result, nil, &APIError{
+			Type:    ErrorTypeValidation,
+			Message: fmt.Sprintf("Invalid query parameters: %v", err),
+		}
+	}
+
 	// Build query parameters
 	queryParams := url.Values{}
-	if params.Status != nil {
-		queryParams.Set("status", *params.Status)
+	status := common.JoinMessageStatuses(params.Statuses)
+	if params.Status != nil && *params.Status != "" {
+		if status != "" {
+			status += ","
+		}
+		status += *params.Status
+	}
+	if status != "" {
+		queryParams.Set("status", status)
 	}
 	if len(params.Tags) > 0 {
 		queryParams.Set("tags", strings.Join(params.Tags, ","))
@@ -272,3 +286,19 @@ func (a *MessagesAPIService) GetMessageByAPIID(
 	resp, err := a.client.Execute(ctx, config)
 	return &result, resp, err
 }
+
+// GetMessagesPager returns a Pager that walks every page of GetMessages for
+// the given filters, starting from params' pagination cursor.
+func (a *MessagesAPIService) GetMessagesPager(
+	ctx context.Context,
+	accountId uuid.UUID,
+	params requests.GetMessagesParams,
+	opts ...RequestOption,
+) *Pager[responses.Message] {
+	fetch := func(ctx context.Context, page common.PaginationParams) (*responses.PaginatedMessagesResponse, *http.Response, error) {
+		pageParams := params
+		pageParams.PaginationParams = page
+		return a.GetMessages(ctx, accountId, pageParams, opts...)
+	}
+	return NewPager[responses.Message](ctx, fetch, params.PaginationParams)
+}
@@ -0,0 +1,368 @@
+// Batch sending for the AhaSend Go SDK.
+//
+// This file splits arbitrarily large recipient lists into CreateMessage calls
+// that fit the API's per-request recipient limit, and sends them concurrently
+// with stable per-chunk idempotency keys.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// MaxRecipientsPerMessage is the maximum number of recipients the API accepts
+// in a single CreateMessage request
+const MaxRecipientsPerMessage = 100
+
+const (
+	// defaultBatchConcurrency is how many chunks are in flight at once when
+	// BatchSendOptions.Concurrency is not set
+	defaultBatchConcurrency = 4
+	// maxBatchConflictRetries bounds how often a chunk waits out an in-flight
+	// request holding the same idempotency key
+	maxBatchConflictRetries = 3
+	// minBatchConflictDelay is the shortest wait before retrying a chunk whose
+	// conflict response did not say how long to wait
+	minBatchConflictDelay = 100 * time.Millisecond
+)
+
+// BatchSendOptions configures CreateMessageBatch
+type BatchSendOptions struct {
+	// ChunkSize is the number of recipients per request (default and maximum:
+	// MaxRecipientsPerMessage)
+	ChunkSize int
+	// Concurrency is the number of chunks sent at once (default: 4). Every
+	// request still waits for the send-message rate limit bucket.
+	Concurrency int
+	// IdempotencyKey is the base key the per-chunk keys are derived from. Set
+	// it to a value that is stable for the batch - an order or campaign ID -
+	// so that re-running the batch after a crash replays the chunks that
+	// already succeeded instead of sending them again. When empty, a key is
+	// generated and reported in BatchSendResult.IdempotencyKey.
+	IdempotencyKey string
+}
+
+// BatchChunkResult is the outcome of sending one chunk of a batch
+type BatchChunkResult struct {
+	// Index is the zero-based position of the chunk in the batch
+	Index int
+	// IdempotencyKey is the key the chunk was sent with
+	IdempotencyKey string
+	// Recipients are the recipients of the chunk
+	Recipients []common.Recipient
+	// Response is the API response, nil if the request failed
+	Response *responses.CreateMessageResponse
+	// HTTPResponse is the raw HTTP response of the last attempt, if any
+	HTTPResponse *http.Response
+	// Err is the request error, nil if the API accepted the chunk
+	Err error
+}
+
+// BatchRecipientFailure describes a recipient that was not sent to
+type BatchRecipientFailure struct {
+	Recipient common.Recipient
+	// ChunkIndex is the chunk the recipient was part of
+	ChunkIndex int
+	// Err is the request error when the whole chunk failed, or the error the
+	// API reported for this recipient
+	Err error
+}
+
+// BatchSendResult aggregates the outcome of a batch send
+type BatchSendResult struct {
+	// IdempotencyKey is the base key the chunk keys were derived from
+	IdempotencyKey string
+	// Data merges the per-recipient results of every accepted chunk, in
+	// chunk order
+	Data []responses.CreateSingleMessageResponse
+	// Failures lists every recipient that was not sent to, whether because its
+	// chunk failed or because the API rejected it individually
+	Failures []BatchRecipientFailure
+	// Chunks holds the per-chunk outcomes, in chunk order
+	Chunks []BatchChunkResult
+}
+
+// Succeeded reports whether every recipient was accepted
+func (r *BatchSendResult) Succeeded() bool {
+	return len(r.Failures) == 0
+}
+
+// Err returns an error summarising the failures, or nil if there were none
+func (r *BatchSendResult) Err() error {
+	if len(r.Failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("batch send failed for %d recipient(s): %w", len(r.Failures), r.Failures[0].Err)
+}
+
+// batchChunk is a unit of work handed to the batch workers
+type batchChunk struct {
+	index      int
+	recipients []common.Recipient
+}
+
+/*
+CreateMessageBatch Create Message Batch
+
+Sends request to every recipient in recipients, splitting them into chunks of
+at most MaxRecipientsPerMessage and issuing one CreateMessage call per chunk.
+The Recipients field of request is ignored.
+
+Each chunk is sent with the idempotency key `<base>-chunk-<index>`, derived with
+IdempotencyKeyBuilder.WithSuffix. As long as the recipients arrive in the same
+order with the same chunk size, re-running a batch with the same base key
+replays chunks the API already accepted rather than sending them twice.
+
+The returned error is reserved for problems that prevent the batch from running
+at all, such as a cancelled context before any work started; failed chunks and
+rejected recipients are reported in BatchSendResult.Failures.
+
+	@param ctx context.Context - for authentication, logging, cancellation, deadlines, tracing, etc.
+	@param accountId Account ID
+	@param request CreateMessageRequest - The message to send; Recipients is ignored
+	@param recipients []common.Recipient - Every recipient of the batch
+	@param options BatchSendOptions - chunking, concurrency and idempotency settings
+	@param opts ...RequestOption - optional request options applied to every chunk
+	@return BatchSendResult, error
+*/
+func (a *MessagesAPIService) CreateMessageBatch(
+	ctx context.Context,
+	accountId uuid.UUID,
+	request requests.CreateMessageRequest,
+	recipients []common.Recipient,
+	options BatchSendOptions,
+	opts ...RequestOption,
+) (*BatchSendResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	chunkSize := options.chunkSize()
+	chunks := make(chan batchChunk)
+
+	go func() {
+		defer close(chunks)
+		for index, start := 0, 0; start < len(recipients); index, start = index+1, start+chunkSize {
+			end := start + chunkSize
+			if end > len(recipients) {
+				end = len(recipients)
+			}
+			// Chunks are handed out even after ctx is cancelled: the workers
+			// fail them fast, which records their recipients as failures.
+			chunks <- batchChunk{index: index, recipients: recipients[start:end]}
+		}
+	}()
+
+	return a.sendBatch(ctx, accountId, request, chunks, options, opts)
+}
+
+// CreateMessageBatchFromChannel is CreateMessageBatch for recipients that are
+// produced incrementally. Chunks are sent as soon as they fill up, and the
+// batch completes once recipients is closed and every chunk was sent. The
+// caller must close recipients, or cancel ctx to abandon the batch.
+func (a *MessagesAPIService) CreateMessageBatchFromChannel(
+	ctx context.Context,
+	accountId uuid.UUID,
+	request requests.CreateMessageRequest,
+	recipients <-chan common.Recipient,
+	options BatchSendOptions,
+	opts ...RequestOption,
+) (*BatchSendResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	chunkSize := options.chunkSize()
+	chunks := make(chan batchChunk)
+
+	go func() {
+		defer close(chunks)
+		index := 0
+		pending := make([]common.Recipient, 0, chunkSize)
+		flush := func() {
+			if len(pending) == 0 {
+				return
+			}
+			chunks <- batchChunk{index: index, recipients: pending}
+			index++
+			pending = make([]common.Recipient, 0, chunkSize)
+		}
+
+		for {
+			select {
+			case recipient, ok := <-recipients:
+				if !ok {
+					flush()
+					return
+				}
+				pending = append(pending, recipient)
+				if len(pending) == chunkSize {
+					flush()
+				}
+			case <-ctx.Done():
+				// Recipients already read are reported as failed rather than
+				// silently dropped; the rest were never seen by the batch.
+				flush()
+				return
+			}
+		}
+	}()
+
+	return a.sendBatch(ctx, accountId, request, chunks, options, opts)
+}
+
+// sendBatch drains chunks with a bounded pool of workers and aggregates the
+// results.
+func (a *MessagesAPIService) sendBatch(
+	ctx context.Context,
+	accountId uuid.UUID,
+	request requests.CreateMessageRequest,
+	chunks <-chan batchChunk,
+	options BatchSendOptions,
+	opts []RequestOption,
+) (*BatchSendResult, error) {
+	baseKey := options.IdempotencyKey
+	if baseKey == "" {
+		baseKey = a.client.GenerateIdempotencyKey()
+	}
+	keys := NewIdempotencyKeyBuilder(baseKey)
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	var (
+		mu      sync.Mutex
+		results []BatchChunkResult
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				result := a.sendBatchChunk(ctx, accountId, request, chunk, keys.WithSuffix(fmt.Sprintf("chunk-%d", chunk.index)), opts)
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+
+	batch := &BatchSendResult{
+		IdempotencyKey: baseKey,
+		Chunks:         results,
+	}
+	for _, chunk := range results {
+		if chunk.Err != nil {
+			for _, recipient := range chunk.Recipients {
+				batch.Failures = append(batch.Failures, BatchRecipientFailure{
+					Recipient:  recipient,
+					ChunkIndex: chunk.Index,
+					Err:        chunk.Err,
+				})
+			}
+			continue
+		}
+		for _, single := range chunk.Response.Data {
+			batch.Data = append(batch.Data, single)
+			if single.Error != nil || single.Status == "error" {
+				message := "message was not sent"
+				if single.Error != nil {
+					message = *single.Error
+				}
+				batch.Failures = append(batch.Failures, BatchRecipientFailure{
+					Recipient:  single.Recipient,
+					ChunkIndex: chunk.Index,
+					Err:        errors.New(message),
+				})
+			}
+		}
+	}
+
+	return batch, nil
+}
+
+// sendBatchChunk sends one chunk, waiting out an earlier request that still
+// holds the chunk's idempotency key - the situation a batch re-run right after
+// a crash runs into.
+func (a *MessagesAPIService) sendBatchChunk(
+	ctx context.Context,
+	accountId uuid.UUID,
+	request requests.CreateMessageRequest,
+	chunk batchChunk,
+	key string,
+	opts []RequestOption,
+) BatchChunkResult {
+	result := BatchChunkResult{
+		Index:          chunk.index,
+		IdempotencyKey: key,
+		Recipients:     chunk.recipients,
+	}
+
+	request.Recipients = chunk.recipients
+	chunkOpts := append(append([]RequestOption{}, opts...), WithIdempotencyKey(key))
+
+	for attempt := 0; ; attempt++ {
+		resp, httpResp, err := a.CreateMessage(ctx, accountId, request, chunkOpts...)
+		result.HTTPResponse = httpResp
+
+		var apiErr *APIError
+		if err != nil && attempt < maxBatchConflictRetries &&
+			errors.As(err, &apiErr) && apiErr.Type == ErrorTypeIdempotencyConflict {
+			select {
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+				return result
+			case <-time.After(a.conflictRetryDelay(apiErr, attempt)):
+				continue
+			}
+		}
+
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Response = resp
+		return result
+	}
+}
+
+// conflictRetryDelay returns how long to wait before retrying a chunk after
+// apiErr: its Retry-After when it has one, and otherwise the client's backoff
+// delay for the attempt, so that a conflict without Retry-After does not
+// retry in a tight loop.
+func (a *MessagesAPIService) conflictRetryDelay(apiErr *APIError, attempt int) time.Duration {
+	if apiErr.RetryAfter > 0 {
+		return time.Duration(apiErr.RetryAfter) * time.Second
+	}
+	delay := a.client.cfg.RetryConfig.GetDelay(attempt + 1)
+	if delay < minBatchConflictDelay {
+		delay = minBatchConflictDelay
+	}
+	return delay
+}
+
+// chunkSize returns the effective number of recipients per request
+func (o BatchSendOptions) chunkSize() int {
+	if o.ChunkSize <= 0 || o.ChunkSize > MaxRecipientsPerMessage {
+		return MaxRecipientsPerMessage
+	}
+	return o.ChunkSize
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchTestRecipients(n int) []common.Recipient {
+	recipients := make([]common.Recipient, n)
+	for i := range recipients {
+		recipients[i] = common.Recipient{Email: fmt.Sprintf("user%d@example.com", i)}
+	}
+	return recipients
+}
+
+func batchTestRequest() requests.CreateMessageRequest {
+	return requests.CreateMessageRequest{
+		From:        common.SenderAddress{Email: "sender@example.com"},
+		Subject:     "Batch",
+		TextContent: ahasend.String("Hello"),
+	}
+}
+
+// echoBatchServer accepts every recipient except those listed in reject, and
+// records the idempotency key and recipient count of every request.
+func echoBatchServer(t *testing.T, reject map[string]bool, keys *sync.Map) http.HandlerFunc {
+	t.Helper()
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Runs on the server's goroutine, where require cannot stop the test
+		var request requests.CreateMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		keys.Store(r.Header.Get("Idempotency-Key"), len(request.Recipients))
+
+		response := responses.CreateMessageResponse{Object: "list"}
+		for _, recipient := range request.Recipients {
+			single := responses.CreateSingleMessageResponse{Object: "message", Recipient: recipient, Status: "queued"}
+			if reject[recipient.Email] {
+				single.Status = "error"
+				single.Error = ahasend.String("recipient is suppressed")
+			} else {
+				single.ID = ahasend.String("<" + uuid.NewString() + "@example.com>")
+			}
+			response.Data = append(response.Data, single)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		assert.NoError(t, json.NewEncoder(w).Encode(response))
+	}
+}
+
+func TestCreateMessageBatchSplitsIntoChunksWithStableKeys(t *testing.T) {
+	var keys sync.Map
+	client, cleanup := newContractTestClient(t, echoBatchServer(t, nil, &keys))
+	defer cleanup()
+
+	result, err := client.MessagesAPI.CreateMessageBatch(context.Background(), uuid.New(), batchTestRequest(),
+		batchTestRecipients(250), BatchSendOptions{IdempotencyKey: "campaign-42", Concurrency: 3})
+
+	require.NoError(t, err)
+	assert.True(t, result.Succeeded())
+	assert.NoError(t, result.Err())
+	assert.Equal(t, "campaign-42", result.IdempotencyKey)
+	require.Len(t, result.Chunks, 3)
+	require.Len(t, result.Data, 250)
+	assert.Equal(t, "user0@example.com", result.Data[0].Recipient.Email, "results are merged in chunk order")
+	assert.Equal(t, "user249@example.com", result.Data[249].Recipient.Email)
+
+	for index, want := range []int{100, 100, 50} {
+		count, ok := keys.Load(fmt.Sprintf("campaign-42-chunk-%d", index))
+		require.True(t, ok, "chunk %d should be sent with a derived key", index)
+		assert.Equal(t, want, count)
+		assert.Equal(t, fmt.Sprintf("campaign-42-chunk-%d", index), result.Chunks[index].IdempotencyKey)
+	}
+}
+
+func TestCreateMessageBatchReportsRejectedRecipients(t *testing.T) {
+	var keys sync.Map
+	client, cleanup := newContractTestClient(t, echoBatchServer(t, map[string]bool{"user3@example.com": true}, &keys))
+	defer cleanup()
+
+	result, err := client.MessagesAPI.CreateMessageBatch(context.Background(), uuid.New(), batchTestRequest(),
+		batchTestRecipients(5), BatchSendOptions{ChunkSize: 2})
+
+	require.NoError(t, err)
+	assert.False(t, result.Succeeded())
+	require.Len(t, result.Failures, 1)
+	assert.Equal(t, "user3@example.com", result.Failures[0].Recipient.Email)
+	assert.Equal(t, 1, result.Failures[0].ChunkIndex)
+	assert.EqualError(t, result.Failures[0].Err, "recipient is suppressed")
+	assert.NotEmpty(t, result.IdempotencyKey, "a base key is generated when none is given")
+}
+
+func TestCreateMessageBatchReportsFailedChunks(t *testing.T) {
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"invalid sender"}`))
+	})
+	defer cleanup()
+
+	result, err := client.MessagesAPI.CreateMessageBatch(context.Background(), uuid.New(), batchTestRequest(),
+		batchTestRecipients(3), BatchSendOptions{ChunkSize: 2})
+
+	require.NoError(t, err)
+	require.Len(t, result.Failures, 3)
+	var apiErr *APIError
+	require.ErrorAs(t, result.Failures[0].Err, &apiErr)
+	assert.Equal(t, ErrorTypeValidation, apiErr.Type)
+	assert.Empty(t, result.Data)
+}
+
+func TestCreateMessageBatchFromChannel(t *testing.T) {
+	var keys sync.Map
+	client, cleanup := newContractTestClient(t, echoBatchServer(t, nil, &keys))
+	defer cleanup()
+
+	recipients := make(chan common.Recipient)
+	go func() {
+		defer close(recipients)
+		for _, recipient := range batchTestRecipients(7) {
+			recipients <- recipient
+		}
+	}()
+
+	result, err := client.MessagesAPI.CreateMessageBatchFromChannel(context.Background(), uuid.New(), batchTestRequest(),
+		recipients, BatchSendOptions{ChunkSize: 3, IdempotencyKey: "stream"})
+
+	require.NoError(t, err)
+	assert.True(t, result.Succeeded())
+	assert.Len(t, result.Data, 7)
+	require.Len(t, result.Chunks, 3)
+	count, ok := keys.Load("stream-chunk-2")
+	require.True(t, ok)
+	assert.Equal(t, 1, count)
+}
+
+func TestCreateMessageBatchRejectsCancelledContext(t *testing.T) {
+	client := NewAPIClient(WithAPIKey("test-key"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.MessagesAPI.CreateMessageBatch(ctx, uuid.New(), batchTestRequest(), batchTestRecipients(3), BatchSendOptions{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCreateMessageBatchConflictDelay(t *testing.T) {
+	client := NewAPIClient(WithAPIKey("test-key"), WithRetryConfig(RetryConfig{
+		Enabled:         true,
+		MaxRetries:      3,
+		BackoffStrategy: BackoffConstant,
+		BaseDelay:       2 * time.Second,
+		MaxDelay:        2 * time.Second,
+	}))
+
+	assert.Equal(t, 5*time.Second, client.MessagesAPI.conflictRetryDelay(&APIError{RetryAfter: 5}, 0))
+	assert.Equal(t, 2*time.Second, client.MessagesAPI.conflictRetryDelay(&APIError{}, 0),
+		"a conflict without Retry-After waits the client's backoff delay")
+
+	client = NewAPIClient(WithAPIKey("test-key"), WithRetryConfig(RetryConfig{Enabled: false}))
+	assert.Equal(t, minBatchConflictDelay, client.MessagesAPI.conflictRetryDelay(&APIError{}, 0))
+}
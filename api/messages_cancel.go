@@ -0,0 +1,253 @@
+// Bulk cancellation for the AhaSend Go SDK.
+//
+// This file finds scheduled messages with GetMessages and cancels them
+// concurrently, telling apart the messages that were cancelled from those
+// that were sent before their cancellation reached the API.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// defaultCancelConcurrency is how many messages are cancelled at once when
+// BulkCancelOptions.Concurrency is not set
+const defaultCancelConcurrency = 4
+
+// BulkCancelOptions configures CancelMessages
+type BulkCancelOptions struct {
+	// Concurrency is the number of messages cancelled at once (default: 4).
+	// Every request still waits for the client's rate limiter.
+	Concurrency int
+	// DryRun only lists the scheduled messages that match the filter, in
+	// BulkCancelResult.Matched, without cancelling any of them
+	DryRun bool
+	// Retry overrides the client's retry configuration for the cancel
+	// requests. Retrying a cancellation is safe: a message an earlier attempt
+	// already cancelled is reported as cancelled.
+	Retry *RetryConfig
+}
+
+// BulkCancelSkip describes a matched message that could not be cancelled
+// because it had left the scheduled status
+type BulkCancelSkip struct {
+	// Message is the message as it was listed
+	Message responses.Message
+	// Status is the status the message had when its cancellation failed
+	Status common.MessageStatus
+}
+
+// BulkCancelFailure describes a matched message whose cancellation failed
+type BulkCancelFailure struct {
+	// Message is the message as it was listed
+	Message responses.Message
+	// Err is the error of the last cancel attempt
+	Err error
+}
+
+// BulkCancelResult aggregates the outcome of CancelMessages. Every list is in
+// the order the messages were listed.
+type BulkCancelResult struct {
+	// DryRun is set when nothing was cancelled because of
+	// BulkCancelOptions.DryRun
+	DryRun bool
+	// Matched lists every scheduled message the filter found
+	Matched []responses.Message
+	// Cancelled lists the IDs of the messages that are now cancelled
+	Cancelled []uuid.UUID
+	// AlreadySent lists the messages that were sent, or otherwise left the
+	// scheduled status, before they could be cancelled
+	AlreadySent []BulkCancelSkip
+	// Failures lists the messages whose cancellation failed for any other
+	// reason, such as a network error; they may still be scheduled
+	Failures []BulkCancelFailure
+}
+
+// Succeeded reports whether every matched message was cancelled
+func (r *BulkCancelResult) Succeeded() bool {
+	return len(r.Cancelled) == len(r.Matched)
+}
+
+// Err returns an error summarising the failures, or nil if there were none.
+// Messages that were already sent are not failures.
+func (r *BulkCancelResult) Err() error {
+	if len(r.Failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("bulk cancel failed for %d message(s): %w", len(r.Failures), r.Failures[0].Err)
+}
+
+// cancelOutcome is the outcome of cancelling the matched message at index
+type cancelOutcome struct {
+	index  int
+	status common.MessageStatus
+	err    error
+}
+
+/*
+CancelMessages Cancel Messages
+
+Finds the scheduled messages matching params and cancels them, for example
+every message of an aborted campaign:
+
+	result, err := client.MessagesAPI.CancelMessages(ctx, accountID, requests.GetMessagesParams{
+		Tags: []string{"campaign-42"},
+	}, api.BulkCancelOptions{DryRun: true})
+
+The filter is applied with GetMessages; its Statuses and Status are ignored.
+The status filter of GetMessages does not accept "Scheduled", so every message
+matching the other filters, whatever its status, is listed and only the
+scheduled ones are kept. To keep that listing from walking the whole account,
+params must set at least one of Tags, Sender, Recipient, Subject,
+MessageIDHeader or FromTime; a filter with none of them is rejected with an
+ErrorTypeValidation error. Every matching message is listed before the first
+one is cancelled. A message that is sent while the cancellation runs is
+reported in BulkCancelResult.AlreadySent, and any other message that could not
+be cancelled in BulkCancelResult.Failures.
+
+The returned error is reserved for problems that prevent the cancellation from
+running at all, such as a failed listing; nothing is cancelled in that case.
+
+	@param ctx context.Context - for authentication, logging, cancellation, deadlines, tracing, etc.
+	@param accountId Account ID
+	@param params GetMessagesParams - filters selecting the messages to cancel
+	@param options BulkCancelOptions - concurrency, dry run and retry settings
+	@param opts ...RequestOption - optional request options applied to every request
+	@return BulkCancelResult, error
+*/
+func (a *MessagesAPIService) CancelMessages(
+	ctx context.Context,
+	accountId uuid.UUID,
+	params requests.GetMessagesParams,
+	options BulkCancelOptions,
+	opts ...RequestOption,
+) (*BulkCancelResult, error) {
+	if !narrowsListing(params) {
+		return nil, &APIError{
+			Type:    ErrorTypeValidation,
+			Message: "CancelMessages needs a filter: set Tags, Sender, Recipient, Subject, MessageIDHeader or FromTime",
+		}
+	}
+	params.Statuses = nil
+	params.Status = nil
+
+	result := &BulkCancelResult{DryRun: options.DryRun}
+	err := a.GetMessagesPager(ctx, accountId, params, opts...).ForEach(func(msg responses.Message) error {
+		if msg.Status.Is(common.MessageStatusScheduled) {
+			result.Matched = append(result.Matched, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled messages: %w", err)
+	}
+	if options.DryRun || len(result.Matched) == 0 {
+		return result, nil
+	}
+
+	cancelOpts := append([]RequestOption{}, opts...)
+	if options.Retry != nil {
+		cancelOpts = append(cancelOpts, WithRetry(*options.Retry))
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultCancelConcurrency
+	}
+
+	var (
+		mu       sync.Mutex
+		outcomes []cancelOutcome
+		wg       sync.WaitGroup
+	)
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		// Messages are handed out even after ctx is cancelled: the workers
+		// fail them fast, which records them as failures.
+		for index := range result.Matched {
+			indexes <- index
+		}
+	}()
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				outcome := a.cancelScheduledMessage(ctx, accountId, result.Matched[index], cancelOpts)
+				outcome.index = index
+				mu.Lock()
+				outcomes = append(outcomes, outcome)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(outcomes, func(i, j int) bool { return outcomes[i].index < outcomes[j].index })
+
+	for _, outcome := range outcomes {
+		msg := result.Matched[outcome.index]
+		switch {
+		case outcome.err != nil:
+			result.Failures = append(result.Failures, BulkCancelFailure{Message: msg, Err: outcome.err})
+		case outcome.status.Is(common.MessageStatusCancelled):
+			result.Cancelled = append(result.Cancelled, msg.ID)
+		default:
+			result.AlreadySent = append(result.AlreadySent, BulkCancelSkip{Message: msg, Status: outcome.status})
+		}
+	}
+
+	return result, nil
+}
+
+// narrowsListing reports whether params limits the listing to part of the
+// account, rather than every message it ever sent
+func narrowsListing(params requests.GetMessagesParams) bool {
+	set := func(s *string) bool { return s != nil && *s != "" }
+	return len(params.Tags) > 0 || set(params.Sender) || set(params.Recipient) ||
+		set(params.Subject) || set(params.MessageIDHeader) || params.FromTime != nil
+}
+
+// cancelScheduledMessage cancels msg. When the API refuses, the message is
+// fetched again to learn whether it is no longer scheduled - because it was
+// sent, or because an earlier attempt whose response was lost cancelled it -
+// or whether the refusal is a failure.
+func (a *MessagesAPIService) cancelScheduledMessage(
+	ctx context.Context,
+	accountId uuid.UUID,
+	msg responses.Message,
+	opts []RequestOption,
+) cancelOutcome {
+	_, _, err := a.CancelMessage(ctx, accountId, msg.ID.String(), opts...)
+	if err == nil {
+		return cancelOutcome{status: common.MessageStatusCancelled}
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return cancelOutcome{err: err}
+	}
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity:
+	default:
+		return cancelOutcome{err: err}
+	}
+
+	current, _, getErr := a.GetMessageByAPIID(ctx, accountId, msg.ID.String(), opts...)
+	if getErr != nil || current.Status.Is(common.MessageStatusScheduled) {
+		return cancelOutcome{err: err}
+	}
+	return cancelOutcome{status: current.Status}
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scheduledMessageServer serves the messages endpoints for messages, which
+// keep their status between requests. Like the API, it does not take
+// "Scheduled" as a status filter. Cancelling a message in sendOnCancel
+// finds it already delivered, and cancelling one in failOnCancel fails with
+// a server error.
+type scheduledMessageServer struct {
+	t            *testing.T
+	mu           sync.Mutex
+	messages     []*responses.Message
+	sendOnCancel map[uuid.UUID]bool
+	failOnCancel map[uuid.UUID]bool
+	cancels      int
+	queries      []string
+}
+
+func newScheduledMessageServer(t *testing.T, n int) *scheduledMessageServer {
+	s := &scheduledMessageServer{t: t, sendOnCancel: map[uuid.UUID]bool{}, failOnCancel: map[uuid.UUID]bool{}}
+	for i := 0; i < n; i++ {
+		s.messages = append(s.messages, &responses.Message{
+			Object:    "message",
+			ID:        uuid.New(),
+			Recipient: fmt.Sprintf("user%d@example.com", i),
+			Status:    common.MessageStatusScheduled,
+		})
+	}
+	return s
+}
+
+func (s *scheduledMessageServer) find(id string) *responses.Message {
+	for _, msg := range s.messages {
+		if msg.ID.String() == id {
+			return msg
+		}
+	}
+	return nil
+}
+
+func (s *scheduledMessageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reply := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		require.NoError(s.t, json.NewEncoder(w).Encode(body))
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 4:
+		s.queries = append(s.queries, r.URL.RawQuery)
+		if r.URL.Query().Has("status") {
+			reply(http.StatusBadRequest, map[string]string{"message": "invalid status filter"})
+			return
+		}
+		page := responses.PaginatedMessagesResponse{Object: "list"}
+		for _, msg := range s.messages {
+			page.Data = append(page.Data, *msg)
+		}
+		reply(http.StatusOK, page)
+	case r.Method == http.MethodGet && len(parts) == 5:
+		reply(http.StatusOK, s.find(parts[4]))
+	case r.Method == http.MethodDelete && len(parts) == 6:
+		s.cancels++
+		msg := s.find(parts[4])
+		if s.failOnCancel[msg.ID] {
+			reply(http.StatusInternalServerError, map[string]string{"message": "internal error"})
+			return
+		}
+		if s.sendOnCancel[msg.ID] {
+			msg.Status = common.MessageStatusDelivered
+		}
+		if !msg.Status.Is(common.MessageStatusScheduled) {
+			reply(http.StatusBadRequest, map[string]string{"message": "only scheduled messages can be cancelled"})
+			return
+		}
+		msg.Status = common.MessageStatusCancelled
+		reply(http.StatusOK, map[string]string{"message": "Message cancelled successfully"})
+	default:
+		reply(http.StatusNotFound, map[string]string{"message": "not found"})
+	}
+}
+
+// campaign is the filter of the tests that do not look at the listing query
+var campaign = requests.GetMessagesParams{Tags: []string{"campaign-42"}}
+
+func TestCancelMessagesCancelsEveryScheduledMessage(t *testing.T) {
+	server := newScheduledMessageServer(t, 25)
+	server.messages[3].Status = common.MessageStatusDelivered
+	client, cleanup := newContractTestClient(t, server.ServeHTTP)
+	defer cleanup()
+
+	result, err := client.MessagesAPI.CancelMessages(context.Background(), uuid.New(), requests.GetMessagesParams{
+		Tags:     []string{"campaign-42"},
+		Statuses: []common.MessageStatus{common.MessageStatusDelivered},
+	}, BulkCancelOptions{Concurrency: 5})
+
+	require.NoError(t, err)
+	assert.True(t, result.Succeeded())
+	assert.NoError(t, result.Err())
+	assert.Len(t, result.Matched, 24)
+	require.Len(t, result.Cancelled, 24)
+	assert.Equal(t, server.messages[0].ID, result.Cancelled[0], "results are in listing order")
+	assert.Equal(t, server.messages[24].ID, result.Cancelled[23])
+	assert.Equal(t, 24, server.cancels)
+	require.Len(t, server.queries, 1)
+	query, err := url.ParseQuery(server.queries[0])
+	require.NoError(t, err)
+	assert.NotContains(t, query, "status", "scheduled messages are picked out of the listing")
+	assert.Equal(t, "campaign-42", query.Get("tags"))
+}
+
+func TestCancelMessagesDryRunCancelsNothing(t *testing.T) {
+	server := newScheduledMessageServer(t, 3)
+	client, cleanup := newContractTestClient(t, server.ServeHTTP)
+	defer cleanup()
+
+	result, err := client.MessagesAPI.CancelMessages(context.Background(), uuid.New(), campaign, BulkCancelOptions{DryRun: true})
+
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Len(t, result.Matched, 3)
+	assert.Empty(t, result.Cancelled)
+	assert.False(t, result.Succeeded())
+	assert.Zero(t, server.cancels)
+	for _, msg := range server.messages {
+		assert.Equal(t, common.MessageStatusScheduled, msg.Status)
+	}
+}
+
+func TestCancelMessagesReportsAlreadySentAndFailures(t *testing.T) {
+	server := newScheduledMessageServer(t, 4)
+	server.sendOnCancel[server.messages[1].ID] = true
+	server.failOnCancel[server.messages[2].ID] = true
+	client, cleanup := newContractTestClient(t, server.ServeHTTP)
+	defer cleanup()
+
+	result, err := client.MessagesAPI.CancelMessages(context.Background(), uuid.New(), campaign,
+		BulkCancelOptions{Retry: &RetryConfig{Enabled: false}})
+
+	require.NoError(t, err)
+	assert.False(t, result.Succeeded())
+	assert.Equal(t, []uuid.UUID{server.messages[0].ID, server.messages[3].ID}, result.Cancelled)
+
+	require.Len(t, result.AlreadySent, 1)
+	assert.Equal(t, server.messages[1].ID, result.AlreadySent[0].Message.ID)
+	assert.Equal(t, common.MessageStatusDelivered, result.AlreadySent[0].Status)
+
+	require.Len(t, result.Failures, 1)
+	assert.Equal(t, server.messages[2].ID, result.Failures[0].Message.ID)
+	var apiErr *APIError
+	require.ErrorAs(t, result.Err(), &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+}
+
+func TestCancelMessagesTreatsLostCancellationAsCancelled(t *testing.T) {
+	server := newScheduledMessageServer(t, 1)
+	client, cleanup := newContractTestClient(t, server.ServeHTTP)
+	defer cleanup()
+
+	// The message is listed as scheduled, but an earlier attempt cancelled it
+	// before this one reached the API
+	listed := *server.messages[0]
+	server.messages[0].Status = common.MessageStatusCancelled
+	outcome := client.MessagesAPI.cancelScheduledMessage(context.Background(), uuid.New(), listed, nil)
+
+	assert.NoError(t, outcome.err)
+	assert.Equal(t, common.MessageStatusCancelled, outcome.status)
+}
+
+func TestCancelMessagesReturnsListingErrors(t *testing.T) {
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"missing scope messages:read:all"}`))
+	})
+	defer cleanup()
+
+	result, err := client.MessagesAPI.CancelMessages(context.Background(), uuid.New(), campaign, BulkCancelOptions{})
+
+	assert.Nil(t, result)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+}
+
+func TestCancelMessagesMatchesStatusesIgnoringCase(t *testing.T) {
+	server := newScheduledMessageServer(t, 3)
+	server.messages[0].Status = "scheduled"
+	server.messages[1].Status = "SCHEDULED"
+	client, cleanup := newContractTestClient(t, server.ServeHTTP)
+	defer cleanup()
+
+	result, err := client.MessagesAPI.CancelMessages(context.Background(), uuid.New(), campaign, BulkCancelOptions{})
+
+	require.NoError(t, err)
+	assert.Len(t, result.Matched, 3)
+	assert.True(t, result.Succeeded())
+}
+
+func TestCancelMessagesRejectsUnnarrowedFilter(t *testing.T) {
+	server := newScheduledMessageServer(t, 3)
+	client, cleanup := newContractTestClient(t, server.ServeHTTP)
+	defer cleanup()
+
+	empty, limit := "", int32(10)
+	for name, params := range map[string]requests.GetMessagesParams{
+		"empty":           {},
+		"status only":     {Statuses: []common.MessageStatus{common.MessageStatusScheduled}},
+		"empty sender":    {Sender: &empty},
+		"to time only":    {ToTime: &time.Time{}},
+		"pagination only": {PaginationParams: common.PaginationParams{Limit: &limit}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := client.MessagesAPI.CancelMessages(context.Background(), uuid.New(), params, BulkCancelOptions{})
+
+			assert.Nil(t, result)
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, ErrorTypeValidation, apiErr.Type)
+		})
+	}
+	assert.Empty(t, server.queries, "nothing is listed")
+}
@@ -84,3 +84,53 @@ func TestMessagesAPIGetMessagesOmitsEmptyTagsQueryParam(t *testing.T) {
 	require.NotNil(t, lastRequest)
 	assert.Empty(t, lastRequest.URL.Query().Get("tags"))
 }
+
+func TestMessagesAPIGetMessagesSerializesTypedStatuses(t *testing.T) {
+	var queries []url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"object":"list","data":[],"pagination":{"has_more":false}}`))
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cfg := NewConfiguration()
+	cfg.Host = serverURL.Host
+	cfg.Scheme = serverURL.Scheme
+	cfg.APIKey = "test-key"
+
+	client := NewAPIClientWithConfig(cfg)
+	accountID := uuid.New()
+
+	for _, params := range []requests.GetMessagesParams{
+		{Statuses: []common.MessageStatus{common.MessageStatusBounced, common.MessageStatusFailed}},
+		{Statuses: []common.MessageStatus{common.MessageStatusBounced}, Status: ahasend.String("Sandbox Bounced")},
+		{},
+	} {
+		_, _, err := client.MessagesAPI.GetMessages(context.Background(), accountID, params)
+		require.NoError(t, err)
+	}
+
+	require.Len(t, queries, 3)
+	assert.Equal(t, "Bounced,Failed", queries[0].Get("status"))
+	assert.Equal(t, "Bounced,Sandbox Bounced", queries[1].Get("status"))
+	assert.NotContains(t, queries[2], "status")
+
+	// A response-only status is rejected rather than dropped, which would
+	// widen the filter to every message
+	for _, params := range []requests.GetMessagesParams{
+		{Statuses: []common.MessageStatus{common.MessageStatusScheduled}},
+		{Statuses: []common.MessageStatus{common.MessageStatusBounced}, Status: ahasend.String("cancelled")},
+	} {
+		_, _, err := client.MessagesAPI.GetMessages(context.Background(), accountID, params)
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, ErrorTypeValidation, apiErr.Type)
+	}
+	assert.Len(t, queries, 3, "nothing is sent")
+}
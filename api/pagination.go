@@ -0,0 +1,273 @@
+// Auto-pagination for the AhaSend Go SDK.
+//
+// This file provides a generic pager that walks cursor-paginated list
+// endpoints lazily, one page at a time, on top of common.PaginatedResponse.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+)
+
+// ErrPaginationCursorLoop is returned by a Pager when the API hands back the
+// cursor it was just given, which would otherwise make the walk spin forever.
+var ErrPaginationCursorLoop = errors.New("pagination cursor did not advance")
+
+// PageDirection selects which cursor a Pager follows between pages
+type PageDirection int
+
+const (
+	// PageForward follows Pagination.NextCursor using the `after` parameter
+	PageForward PageDirection = iota
+	// PageBackward follows Pagination.PreviousCursor using the `before` parameter
+	// until the API stops returning one
+	PageBackward
+)
+
+// String returns the string representation of PageDirection
+func (d PageDirection) String() string {
+	switch d {
+	case PageForward:
+		return "forward"
+	case PageBackward:
+		return "backward"
+	default:
+		return "unknown"
+	}
+}
+
+// PageFetcher fetches a single page of a list endpoint for the given
+// pagination parameters. The per-service pager constructors wrap the list
+// methods in one of these; it is exported so callers can page through
+// endpoints the SDK does not wrap yet.
+type PageFetcher[T any] func(ctx context.Context, page common.PaginationParams) (*common.PaginatedResponse[T], *http.Response, error)
+
+// Pager walks every page of a cursor-paginated list endpoint lazily.
+//
+// Pages are only fetched when the items of the previous page have been
+// consumed, and every fetch goes through APIClient.Execute, so the client's
+// rate limiter and retry configuration apply to each page. Use it either as
+// an iterator:
+//
+//	pager := client.MessagesAPI.GetMessagesPager(ctx, accountID, params)
+//	for pager.Next() {
+//		msg := pager.Item()
+//	}
+//	if err := pager.Err(); err != nil { ... }
+//
+// or with the callback form, ForEach. A Pager is not safe for concurrent use.
+type Pager[T any] struct {
+	ctx       context.Context
+	fetch     PageFetcher[T]
+	params    common.PaginationParams
+	direction PageDirection
+	maxItems  int
+
+	page     []T
+	index    int
+	item     T
+	yielded  int
+	cursor   *string
+	started  bool
+	done     bool
+	err      error
+	lastPage *common.PaginatedResponse[T]
+	lastResp *http.Response
+}
+
+// NewPager creates a pager over fetch starting from params.
+//
+// The direction is inferred from params: a Before cursor without an After
+// cursor starts a backward walk, everything else walks forward. The
+// deprecated Cursor field is treated as an After cursor.
+func NewPager[T any](ctx context.Context, fetch PageFetcher[T], params common.PaginationParams) *Pager[T] {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	p := &Pager[T]{
+		ctx:       ctx,
+		fetch:     fetch,
+		params:    params,
+		direction: PageForward,
+	}
+
+	switch {
+	case params.After != nil:
+		p.cursor = params.After
+	case params.Before != nil:
+		p.cursor = params.Before
+		p.direction = PageBackward
+	case params.Cursor != nil:
+		p.cursor = params.Cursor
+	}
+
+	return p
+}
+
+// WithDirection sets the traversal direction. It must be called before the
+// first call to Next or ForEach.
+func (p *Pager[T]) WithDirection(direction PageDirection) *Pager[T] {
+	p.direction = direction
+	return p
+}
+
+// WithMaxItems caps the total number of items the pager yields across all
+// pages. Zero or a negative value means no cap. It must be called before the
+// first call to Next or ForEach.
+func (p *Pager[T]) WithMaxItems(maxItems int) *Pager[T] {
+	p.maxItems = maxItems
+	return p
+}
+
+// Next advances to the next item, fetching the next page when the current
+// one is exhausted. It returns false when there are no more items, the item
+// cap was reached, the context was cancelled, or a request failed; Err
+// distinguishes the last three from normal completion.
+func (p *Pager[T]) Next() bool {
+	if p.done {
+		return false
+	}
+
+	if p.maxItems > 0 && p.yielded >= p.maxItems {
+		p.done = true
+		return false
+	}
+
+	for p.index >= len(p.page) {
+		if p.started && p.cursor == nil {
+			p.done = true
+			return false
+		}
+		if !p.fetchPage() {
+			p.done = true
+			return false
+		}
+	}
+
+	p.item = p.page[p.index]
+	p.index++
+	p.yielded++
+	return true
+}
+
+// Item returns the current item. It is only valid after Next returned true.
+func (p *Pager[T]) Item() T {
+	return p.item
+}
+
+// Err returns the first error encountered while paging, or nil if paging
+// finished normally.
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+// Page returns the most recently fetched page, or nil before the first fetch
+func (p *Pager[T]) Page() *common.PaginatedResponse[T] {
+	return p.lastPage
+}
+
+// Response returns the HTTP response of the most recent page fetch
+func (p *Pager[T]) Response() *http.Response {
+	return p.lastResp
+}
+
+// ForEach calls fn for every remaining item. It stops at the first error
+// returned by fn or by a page fetch and returns it.
+func (p *Pager[T]) ForEach(fn func(item T) error) error {
+	for p.Next() {
+		if err := fn(p.Item()); err != nil {
+			p.done = true
+			return err
+		}
+	}
+	return p.Err()
+}
+
+// All collects every remaining item into a slice. Combine it with
+// WithMaxItems when the endpoint may return more than fits in memory.
+func (p *Pager[T]) All() ([]T, error) {
+	var items []T
+	err := p.ForEach(func(item T) error {
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+// fetchPage retrieves the page at the current cursor and reports whether
+// paging can continue.
+func (p *Pager[T]) fetchPage() bool {
+	if err := p.ctx.Err(); err != nil {
+		p.err = err
+		return false
+	}
+
+	page := p.params
+	page.Cursor = nil
+	page.After = nil
+	page.Before = nil
+	if p.cursor != nil {
+		if p.direction == PageBackward {
+			page.Before = p.cursor
+		} else {
+			page.After = p.cursor
+		}
+	}
+
+	// Don't ask for more than the cap leaves room for
+	if p.maxItems > 0 {
+		remaining := int32(p.maxItems - p.yielded)
+		if page.Limit == nil || *page.Limit > remaining {
+			page.Limit = &remaining
+		}
+	}
+
+	result, resp, err := p.fetch(p.ctx, page)
+	p.lastResp = resp
+	if err != nil {
+		p.err = err
+		return false
+	}
+	if result == nil {
+		p.err = fmt.Errorf("pagination: fetcher returned no page")
+		return false
+	}
+
+	// HasMore describes the items after the page, so it only gates forward
+	// walks; a backward page omits PreviousCursor once the start is reached.
+	var next *string
+	if p.direction == PageBackward {
+		next = result.Pagination.PreviousCursor
+	} else if result.Pagination.HasMore {
+		next = result.Pagination.NextCursor
+	}
+	if next != nil && *next == "" {
+		next = nil
+	}
+	if next != nil && p.cursor != nil && *next == *p.cursor {
+		p.err = fmt.Errorf("%w: %s", ErrPaginationCursorLoop, *next)
+		return false
+	}
+
+	p.started = true
+	p.lastPage = result
+	p.page = result.Data
+	p.index = 0
+	p.cursor = next
+	return true
+}
+
+// paginationOrZero dereferences the optional pagination parameters taken by
+// the list methods that accept a pointer.
+func paginationOrZero(pagination *common.PaginationParams) common.PaginationParams {
+	if pagination == nil {
+		return common.PaginationParams{}
+	}
+	return *pagination
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticPages serves pages of ints keyed by the cursor they are requested with
+func staticPages(t *testing.T, pages map[string]common.PaginatedResponse[int], calls *[]common.PaginationParams) PageFetcher[int] {
+	t.Helper()
+	return func(ctx context.Context, page common.PaginationParams) (*common.PaginatedResponse[int], *http.Response, error) {
+		*calls = append(*calls, page)
+		key := ""
+		if page.After != nil {
+			key = "after:" + *page.After
+		}
+		if page.Before != nil {
+			key = "before:" + *page.Before
+		}
+		result, ok := pages[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("unexpected page %q", key)
+		}
+		return &result, nil, nil
+	}
+}
+
+func TestPagerWalksForwardAcrossPages(t *testing.T) {
+	var calls []common.PaginationParams
+	pager := NewPager[int](context.Background(), staticPages(t, map[string]common.PaginatedResponse[int]{
+		"":         {Data: []int{1, 2}, Pagination: common.PaginationInfo{HasMore: true, NextCursor: ahasend.String("c1")}},
+		"after:c1": {Data: []int{3}, Pagination: common.PaginationInfo{HasMore: true, NextCursor: ahasend.String("c2")}},
+		"after:c2": {Data: []int{4, 5}, Pagination: common.PaginationInfo{HasMore: false}},
+	}, &calls), common.PaginationParams{})
+
+	var got []int
+	for pager.Next() {
+		got = append(got, pager.Item())
+	}
+
+	require.NoError(t, pager.Err())
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, got)
+	assert.Len(t, calls, 3)
+	assert.False(t, pager.Next(), "an exhausted pager stays exhausted")
+}
+
+func TestPagerFetchesLazily(t *testing.T) {
+	var calls []common.PaginationParams
+	pager := NewPager[int](context.Background(), staticPages(t, map[string]common.PaginatedResponse[int]{
+		"":         {Data: []int{1, 2}, Pagination: common.PaginationInfo{HasMore: true, NextCursor: ahasend.String("c1")}},
+		"after:c1": {Data: []int{3}},
+	}, &calls), common.PaginationParams{})
+
+	assert.Empty(t, calls, "nothing is fetched before Next")
+	require.True(t, pager.Next())
+	require.True(t, pager.Next())
+	assert.Len(t, calls, 1, "the second page is only fetched once the first is consumed")
+	require.True(t, pager.Next())
+	assert.Len(t, calls, 2)
+}
+
+func TestPagerWalksBackward(t *testing.T) {
+	var calls []common.PaginationParams
+	pager := NewPager[int](context.Background(), staticPages(t, map[string]common.PaginatedResponse[int]{
+		"before:c3": {Data: []int{5, 6}, Pagination: common.PaginationInfo{HasMore: true, PreviousCursor: ahasend.String("c2")}},
+		"before:c2": {Data: []int{3, 4}, Pagination: common.PaginationInfo{HasMore: true}},
+	}, &calls), common.PaginationParams{Before: ahasend.String("c3")})
+
+	got, err := pager.All()
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 6, 3, 4}, got)
+	for _, call := range calls {
+		assert.Nil(t, call.After)
+		assert.NotNil(t, call.Before)
+	}
+}
+
+func TestPagerMaxItemsCapsTotalAndPageSize(t *testing.T) {
+	var calls []common.PaginationParams
+	pager := NewPager[int](context.Background(), staticPages(t, map[string]common.PaginatedResponse[int]{
+		"":         {Data: []int{1, 2}, Pagination: common.PaginationInfo{HasMore: true, NextCursor: ahasend.String("c1")}},
+		"after:c1": {Data: []int{3, 4}, Pagination: common.PaginationInfo{HasMore: true, NextCursor: ahasend.String("c2")}},
+	}, &calls), common.PaginationParams{Limit: ahasend.Int32(2)}).WithMaxItems(3)
+
+	got, err := pager.All()
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, got)
+	require.Len(t, calls, 2)
+	assert.Equal(t, int32(2), *calls[0].Limit)
+	assert.Equal(t, int32(1), *calls[1].Limit, "the last page only asks for what the cap leaves room for")
+}
+
+func TestPagerStopsOnContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls []common.PaginationParams
+	pager := NewPager[int](ctx, staticPages(t, map[string]common.PaginatedResponse[int]{
+		"":         {Data: []int{1}, Pagination: common.PaginationInfo{HasMore: true, NextCursor: ahasend.String("c1")}},
+		"after:c1": {Data: []int{2}},
+	}, &calls), common.PaginationParams{})
+
+	require.True(t, pager.Next())
+	cancel()
+
+	assert.False(t, pager.Next())
+	assert.ErrorIs(t, pager.Err(), context.Canceled)
+	assert.Len(t, calls, 1)
+}
+
+func TestPagerDetectsCursorLoop(t *testing.T) {
+	var calls []common.PaginationParams
+	pager := NewPager[int](context.Background(), staticPages(t, map[string]common.PaginatedResponse[int]{
+		"after:c1": {Data: []int{1}, Pagination: common.PaginationInfo{HasMore: true, NextCursor: ahasend.String("c1")}},
+	}, &calls), common.PaginationParams{After: ahasend.String("c1")})
+
+	_, err := pager.All()
+
+	assert.ErrorIs(t, err, ErrPaginationCursorLoop)
+}
+
+func TestPagerForEachStopsOnCallbackError(t *testing.T) {
+	var calls []common.PaginationParams
+	pager := NewPager[int](context.Background(), staticPages(t, map[string]common.PaginatedResponse[int]{
+		"":         {Data: []int{1, 2}, Pagination: common.PaginationInfo{HasMore: true, NextCursor: ahasend.String("c1")}},
+		"after:c1": {Data: []int{3}},
+	}, &calls), common.PaginationParams{})
+
+	stop := errors.New("stop")
+	var seen []int
+	err := pager.ForEach(func(item int) error {
+		seen = append(seen, item)
+		if item == 2 {
+			return stop
+		}
+		return nil
+	})
+
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, []int{1, 2}, seen)
+	assert.Len(t, calls, 1)
+}
+
+func TestMessagesAPIGetMessagesPagerFollowsCursors(t *testing.T) {
+	var requestCount int32
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		count := atomic.AddInt32(&requestCount, 1)
+		query := r.URL.Query()
+		assert.Equal(t, "Delivered", query.Get("status"), "filters are kept on every page")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if count == 1 {
+			assert.Empty(t, query.Get("after"))
+			_, _ = fmt.Fprintf(w, `{"object":"list","data":[{"id":"%s"}],"pagination":{"has_more":true,"next_cursor":"page-2"}}`, uuid.New())
+			return
+		}
+		assert.Equal(t, "page-2", query.Get("after"))
+		_, _ = fmt.Fprintf(w, `{"object":"list","data":[{"id":"%s"}],"pagination":{"has_more":false}}`, uuid.New())
+	})
+	defer cleanup()
+
+	pager := client.MessagesAPI.GetMessagesPager(context.Background(), uuid.New(), requests.GetMessagesParams{
+		Status: ahasend.String("Delivered"),
+	})
+	messages, err := pager.All()
+
+	require.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requestCount))
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRateLimitWindow is the window assumed for a server limit that does
+// not state one. AhaSend limits are expressed per second.
+const defaultRateLimitWindow = time.Second
+
+// defaultRateLimitPause is how long callers are held back after a 429 that
+// says neither when to retry nor when the window resets
+const defaultRateLimitPause = time.Second
+
+// serverRateLimit is what a response says about the server's rate limit
+type serverRateLimit struct {
+	limit      int // -1 when not reported
+	remaining  int // -1 when not reported
+	window     time.Duration
+	reset      time.Duration // 0 when not reported
+	retryAfter time.Duration // 0 when not reported
+}
+
+// parseServerRateLimit reads the rate-limit headers of a response. It accepts
+// both the RateLimit-* fields of the IETF draft and the X-RateLimit-* fields
+// in common use, a RateLimit-Policy window ("100;w=60"), and Retry-After as
+// either delay seconds or an HTTP date. The second result is false when the
+// response carries none of them.
+func parseServerRateLimit(header http.Header, now time.Time) (serverRateLimit, bool) {
+	limits := serverRateLimit{limit: -1, remaining: -1, window: defaultRateLimitWindow}
+	found := false
+
+	if n, ok := headerInt(header, "RateLimit-Limit", "X-RateLimit-Limit"); ok && n > 0 {
+		limits.limit = n
+		found = true
+	}
+	if n, ok := headerInt(header, "RateLimit-Remaining", "X-RateLimit-Remaining"); ok && n >= 0 {
+		limits.remaining = n
+		found = true
+	}
+	if n, ok := headerInt(header, "RateLimit-Reset", "X-RateLimit-Reset"); ok && n > 0 {
+		// Large values are Unix timestamps rather than delays
+		if n > 1_000_000_000 {
+			limits.reset = time.Unix(int64(n), 0).Sub(now)
+		} else {
+			limits.reset = time.Duration(n) * time.Second
+		}
+		if limits.reset < 0 {
+			limits.reset = 0
+		}
+		found = true
+	}
+	if window, ok := policyWindow(header.Get("RateLimit-Policy")); ok {
+		limits.window = window
+	}
+	if delay, ok := parseRetryAfter(header.Get("Retry-After"), now); ok {
+		limits.retryAfter = delay
+		found = true
+	}
+	return limits, found
+}
+
+// headerInt returns the first of names that holds an integer
+func headerInt(header http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		value := strings.TrimSpace(header.Get(name))
+		if value == "" {
+			continue
+		}
+		// Some servers list one value per policy; the first is the one in force
+		if i := strings.IndexAny(value, ",;"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// policyWindow returns the window of the first policy in a RateLimit-Policy
+// header, such as 60 seconds for "100;w=60"
+func policyWindow(policy string) (time.Duration, bool) {
+	if i := strings.Index(policy, ","); i >= 0 {
+		policy = policy[:i]
+	}
+	for _, param := range strings.Split(policy, ";")[1:] {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || name != "w" {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses a Retry-After value, which is either a number of
+// seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		delay := at.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// UpdateFromResponse adapts the bucket for endpointType to what resp says
+// about the server's rate limit.
+//
+// A reported limit replaces the configured rate and burst: a limit of L per
+// window W allows L/W requests per second with bursts of L. The tokens
+// available never exceed what the server reports as remaining. When nothing
+// remains, or on a 429, every caller waiting on the bucket is held back until
+// the server's Retry-After or window reset has passed, not just the caller
+// that received the response.
+func (rl *RateLimiter) UpdateFromResponse(endpointType EndpointType, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	bucket := rl.GetBucket(endpointType)
+	if bucket == nil {
+		return
+	}
+
+	now := time.Now()
+	limits, found := parseServerRateLimit(resp.Header, now)
+	if !found && resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	if pause := bucket.learn(limits, resp.StatusCode == http.StatusTooManyRequests, now); pause > 0 {
+		bucket.pauseBackend(pause)
+	}
+}
+
+// learn applies limits reported by the server and returns how long the bucket
+// was paused for, if at all
+func (tb *TokenBucket) learn(limits serverRateLimit, tooManyRequests bool, now time.Time) time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill(now)
+
+	if limits.limit > 0 {
+		tb.learned = true
+		tb.serverLimit = limits.limit
+		tb.tokensPerSecond = float64(limits.limit) / limits.window.Seconds()
+		tb.maxTokens = limits.limit
+		tb.tokens = math.Min(tb.tokens, float64(tb.maxTokens))
+	}
+	if limits.remaining >= 0 {
+		tb.serverRemaining = limits.remaining
+		tb.tokens = math.Min(tb.tokens, float64(limits.remaining))
+	}
+	if limits.reset > 0 {
+		tb.serverReset = now.Add(limits.reset)
+	}
+
+	var pause time.Duration
+	switch {
+	case tooManyRequests && limits.retryAfter > 0:
+		pause = limits.retryAfter
+	case tooManyRequests && limits.reset > 0:
+		pause = limits.reset
+	case tooManyRequests:
+		pause = defaultRateLimitPause
+	case limits.remaining == 0 && limits.reset > 0:
+		pause = limits.reset
+	}
+	if pause > 0 {
+		tb.pause(now.Add(pause))
+	}
+	return pause
+}
+
+// pause holds back every caller until the given time. The caller must hold
+// tb.mu.
+func (tb *TokenBucket) pause(until time.Time) {
+	if until.After(tb.pausedUntil) {
+		tb.pausedUntil = until
+	}
+	tb.tokens = 0
+	if tb.pausedUntil.After(tb.lastRefill) {
+		tb.lastRefill = tb.pausedUntil
+	}
+}
+
+// WithAdaptiveRateLimit makes the client adjust its rate limits to the
+// rate-limit headers and 429 responses it receives from the server
+func WithAdaptiveRateLimit(enabled bool) ClientOption {
+	return func(cfg *Configuration) {
+		cfg.AdaptiveRateLimit = enabled
+	}
+}
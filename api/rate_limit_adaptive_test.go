@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServerRateLimit(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected serverRateLimit
+		found    bool
+	}{
+		{
+			name:     "no headers",
+			header:   http.Header{},
+			expected: serverRateLimit{limit: -1, remaining: -1, window: time.Second},
+		},
+		{
+			name: "X-RateLimit fields",
+			header: http.Header{
+				"X-Ratelimit-Limit":     {"50"},
+				"X-Ratelimit-Remaining": {"7"},
+				"X-Ratelimit-Reset":     {"2"},
+			},
+			expected: serverRateLimit{limit: 50, remaining: 7, window: time.Second, reset: 2 * time.Second},
+			found:    true,
+		},
+		{
+			name: "IETF fields with policy window",
+			header: http.Header{
+				"Ratelimit-Limit":     {"600"},
+				"Ratelimit-Remaining": {"0"},
+				"Ratelimit-Reset":     {"30"},
+				"Ratelimit-Policy":    {"600;w=60, 10000;w=3600"},
+			},
+			expected: serverRateLimit{limit: 600, remaining: 0, window: time.Minute, reset: 30 * time.Second},
+			found:    true,
+		},
+		{
+			name:     "reset as Unix timestamp",
+			header:   http.Header{"X-Ratelimit-Reset": {"1777636805"}},
+			expected: serverRateLimit{limit: -1, remaining: -1, window: time.Second, reset: 5 * time.Second},
+			found:    true,
+		},
+		{
+			name:     "Retry-After seconds",
+			header:   http.Header{"Retry-After": {"3"}},
+			expected: serverRateLimit{limit: -1, remaining: -1, window: time.Second, retryAfter: 3 * time.Second},
+			found:    true,
+		},
+		{
+			name:     "Retry-After date",
+			header:   http.Header{"Retry-After": {now.Add(4 * time.Second).Format(http.TimeFormat)}},
+			expected: serverRateLimit{limit: -1, remaining: -1, window: time.Second, retryAfter: 4 * time.Second},
+			found:    true,
+		},
+		{
+			name:     "garbage",
+			header:   http.Header{"X-Ratelimit-Limit": {"lots"}, "Retry-After": {"soon"}},
+			expected: serverRateLimit{limit: -1, remaining: -1, window: time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits, found := parseServerRateLimit(tt.header, now)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, limits)
+		})
+	}
+}
+
+func TestTokenBucketLearnsServerLimits(t *testing.T) {
+	rl := NewRateLimiter()
+
+	rl.UpdateFromResponse(GeneralAPI, &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Ratelimit-Limit":     {"20"},
+			"Ratelimit-Remaining": {"3"},
+			"Ratelimit-Reset":     {"1"},
+		},
+	})
+
+	status := rl.GetStatus(GeneralAPI)
+	assert.True(t, status.Learned)
+	assert.Equal(t, 20, status.RequestsPerSecond)
+	assert.Equal(t, 20, status.BurstCapacity)
+	assert.Equal(t, 20, status.ServerLimit)
+	assert.Equal(t, 3, status.ServerRemaining)
+	assert.LessOrEqual(t, status.TokensAvailable, 3, "tokens never exceed what the server has left")
+	assert.False(t, status.ServerReset.IsZero())
+	assert.True(t, status.PausedUntil.IsZero())
+
+	other := rl.GetStatus(SendMessageAPI)
+	assert.False(t, other.Learned, "each endpoint type learns separately")
+	assert.Equal(t, -1, other.ServerLimit)
+
+	// An explicit configuration replaces what was learned
+	rl.SetRateLimit(GeneralAPI, 5, 5)
+	status = rl.GetStatus(GeneralAPI)
+	assert.False(t, status.Learned)
+	assert.Equal(t, 5, status.RequestsPerSecond)
+}
+
+func TestTokenBucketPauseHoldsBackAllCallers(t *testing.T) {
+	bucket := NewTokenBucket(RateLimitConfig{RequestsPerSecond: 1000, BurstCapacity: 1000, Enabled: true})
+
+	pause := 100 * time.Millisecond
+	bucket.learn(serverRateLimit{limit: -1, remaining: -1, window: time.Second, retryAfter: pause}, true, time.Now())
+	assert.False(t, bucket.GetStatus(GeneralAPI).PausedUntil.IsZero())
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	waited := make([]time.Duration, 5)
+	for i := range waited {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			require.NoError(t, bucket.WaitForTokenWithContext(context.Background()))
+			waited[i] = time.Since(start)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, d := range waited {
+		assert.GreaterOrEqual(t, d, pause-5*time.Millisecond)
+	}
+}
+
+func TestTokenBucketPauseRespectsContext(t *testing.T) {
+	bucket := NewTokenBucket(RateLimitConfig{RequestsPerSecond: 10, BurstCapacity: 10, Enabled: true})
+	bucket.learn(serverRateLimit{limit: -1, remaining: -1, window: time.Second, retryAfter: time.Hour}, true, time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bucket.WaitForTokenWithContext(ctx), context.DeadlineExceeded)
+}
+
+func TestAPIClientAdaptiveRateLimit(t *testing.T) {
+	requests := 0
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Limit", "30")
+		if requests == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "29")
+			_, _ = w.Write([]byte(`{"message":"pong"}`))
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message":"slow down"}`))
+	})
+	defer cleanup()
+
+	cfg := client.GetConfig()
+	cfg.EnableRateLimit = true
+	cfg.RetryConfig = RetryConfig{Enabled: false}
+	WithAdaptiveRateLimit(true)(cfg)
+	client = NewAPIClientWithConfig(cfg)
+
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+	require.NoError(t, err)
+	status := client.GetRateLimitStatus(GeneralAPI)
+	assert.True(t, status.Learned)
+	assert.Equal(t, 30, status.RequestsPerSecond)
+	assert.Equal(t, 29, status.ServerRemaining)
+
+	_, _, err = client.UtilityAPI.Ping(context.Background())
+	require.Error(t, err)
+	status = client.GetRateLimitStatus(GeneralAPI)
+	assert.WithinDuration(t, time.Now().Add(time.Second), status.PausedUntil, 200*time.Millisecond)
+
+	// The next caller waits out the pause instead of hitting the server
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, err = client.UtilityAPI.Ping(ctx)
+	require.Error(t, err)
+	assert.Equal(t, 2, requests)
+}
+
+func TestAPIClientIgnoresRateLimitHeadersByDefault(t *testing.T) {
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Limit", "30")
+		_, _ = w.Write([]byte(`{"message":"pong"}`))
+	})
+	defer cleanup()
+
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+	require.NoError(t, err)
+	assert.False(t, client.GetRateLimitStatus(GeneralAPI).Learned)
+}
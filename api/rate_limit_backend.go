@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// RateLimiterBackend stores token bucket state outside the process, so that
+// several processes sending for the same account share one rate limit instead
+// of each assuming it has the whole quota to itself.
+//
+// A RateLimiter with a backend keeps classifying requests by EndpointType and
+// keeps the semantics of WaitForTokenWithContext; only the bucket state moves.
+// If the backend fails, the RateLimiter falls back to its in-process bucket
+// rather than blocking requests, and keeps using it for
+// BackendRetryInterval before it tries the backend again. The ratelimit
+// package provides Redis and file-lock implementations.
+type RateLimiterBackend interface {
+	// Take tries to take one token from the bucket named key, which refills at
+	// rate tokens per second up to burst tokens. It returns zero if a token was
+	// taken, or how long to wait before trying again.
+	Take(ctx context.Context, key string, rate float64, burst int) (time.Duration, error)
+
+	// Pause empties the bucket named key and stops it handing out tokens for
+	// d, holding back every process that shares it. It is called when the
+	// server answers with a 429.
+	Pause(ctx context.Context, key string, d time.Duration) error
+}
+
+// BackendRetryInterval is how long a bucket uses its in-process state after
+// its backend failed, before it tries the backend again. It keeps an
+// unreachable backend from adding its timeout to every request.
+const BackendRetryInterval = 10 * time.Second
+
+// SetBackend moves the state of every bucket to backend, or back into the
+// process if backend is nil. Bucket keys are "<namespace>:<EndpointType
+// name>", such as "key-1a2b3c4d5e6f7a8b:send_message". Every account or API
+// key sharing backend needs its own namespace, since each has its own rate
+// limits; an empty namespace is "default".
+func (rl *RateLimiter) SetBackend(backend RateLimiterBackend, namespace string) {
+	if namespace == "" {
+		namespace = "default"
+	}
+	for _, endpointType := range []EndpointType{GeneralAPI, StatisticsAPI, SendMessageAPI} {
+		bucket := rl.GetBucket(endpointType)
+		bucket.mu.Lock()
+		bucket.backend = backend
+		bucket.key = namespace + ":" + endpointType.String()
+		bucket.backendDownUntil = time.Time{}
+		bucket.backendErr = nil
+		bucket.mu.Unlock()
+	}
+}
+
+// backendFailed records a failed backend call and switches tb to its
+// in-process state for BackendRetryInterval. The caller must hold tb.mu.
+func (tb *TokenBucket) backendFailed(now time.Time, err error) {
+	tb.backendErr = err
+	tb.backendDownUntil = now.Add(BackendRetryInterval)
+}
+
+// backendAvailable reports whether the backend should be tried at now. The
+// caller must hold tb.mu.
+func (tb *TokenBucket) backendAvailable(now time.Time) bool {
+	return tb.backend != nil && !now.Before(tb.backendDownUntil)
+}
+
+// pauseBackend forwards a pause to the shared backend, if there is one
+func (tb *TokenBucket) pauseBackend(d time.Duration) {
+	tb.mu.Lock()
+	if !tb.backendAvailable(time.Now()) || d <= 0 {
+		tb.mu.Unlock()
+		return
+	}
+	backend, key := tb.backend, tb.key
+	tb.mu.Unlock()
+
+	// Best effort: the local bucket is already paused
+	if err := backend.Pause(context.Background(), key, d); err != nil {
+		tb.mu.Lock()
+		tb.backendFailed(time.Now(), err)
+		tb.mu.Unlock()
+	}
+}
+
+// WithRateLimiterBackend shares rate limiter state through backend
+func WithRateLimiterBackend(backend RateLimiterBackend) ClientOption {
+	return func(cfg *Configuration) {
+		cfg.RateLimiterBackend = backend
+	}
+}
+
+// WithRateLimiterNamespace sets the namespace of the buckets shared through a
+// RateLimiterBackend. By default the namespace is derived from the API key,
+// so clients with different keys never share buckets; set it when requests
+// authenticate with per-request keys, or to share buckets between keys that
+// share a rate limit.
+func WithRateLimiterNamespace(namespace string) ClientOption {
+	return func(cfg *Configuration) {
+		cfg.RateLimiterNamespace = namespace
+	}
+}
+
+// rateLimiterNamespace returns the namespace of the client's shared buckets:
+// RateLimiterNamespace, or a hash of the API key that does not reveal it
+func (c *Configuration) rateLimiterNamespace() string {
+	if c.RateLimiterNamespace != "" {
+		return c.RateLimiterNamespace
+	}
+	sum := sha256.Sum256([]byte(c.APIKey))
+	return "key-" + hex.EncodeToString(sum[:8])
+}
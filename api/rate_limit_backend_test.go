@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingBackend struct {
+	mu     sync.Mutex
+	err    error
+	wait   time.Duration
+	takes  []string
+	pauses map[string]time.Duration
+}
+
+func (b *recordingBackend) Take(_ context.Context, key string, rate float64, burst int) (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.takes = append(b.takes, key)
+	return b.wait, b.err
+}
+
+func (b *recordingBackend) Pause(_ context.Context, key string, d time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pauses == nil {
+		b.pauses = map[string]time.Duration{}
+	}
+	b.pauses[key] = d
+	return b.err
+}
+
+func TestRateLimiterUsesBackend(t *testing.T) {
+	backend := &recordingBackend{}
+	rl := NewRateLimiter()
+	rl.SetBackend(backend, "account-1")
+
+	require.NoError(t, rl.WaitForTokenWithContext(context.Background(), http.MethodPost, "/v2/accounts/1/messages"))
+	require.NoError(t, rl.WaitForTokenWithContext(context.Background(), http.MethodGet, "/v2/accounts/1/statistics/bounce"))
+	assert.Equal(t, []string{"account-1:send_message", "account-1:statistics"}, backend.takes)
+
+	// A backend that asks for a wait is honoured until the context gives up
+	backend.wait = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, rl.GetBucket(GeneralAPI).WaitForTokenWithContext(ctx), context.DeadlineExceeded)
+}
+
+func TestRateLimiterFallsBackWhenBackendFails(t *testing.T) {
+	backend := &recordingBackend{err: errors.New("connection refused")}
+	rl := NewRateLimiter()
+	rl.SetRateLimit(GeneralAPI, 1, 1)
+	rl.SetBackend(backend, "")
+
+	bucket := rl.GetBucket(GeneralAPI)
+	require.NoError(t, bucket.WaitForTokenWithContext(context.Background()))
+	assert.Len(t, backend.takes, 1)
+
+	// The in-process bucket still limits while the backend is down
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bucket.WaitForTokenWithContext(ctx), context.DeadlineExceeded)
+	assert.Len(t, backend.takes, 1, "the failed backend is not tried again right away")
+
+	status := bucket.GetStatus(GeneralAPI)
+	assert.Equal(t, "connection refused", status.BackendError)
+	assert.WithinDuration(t, time.Now().Add(BackendRetryInterval), status.BackendDownUntil, time.Second)
+}
+
+func TestRateLimiterRetriesBackendAfterInterval(t *testing.T) {
+	backend := &recordingBackend{err: errors.New("connection refused")}
+	rl := NewRateLimiter()
+	rl.SetBackend(backend, "")
+	bucket := rl.GetBucket(SendMessageAPI)
+
+	require.NoError(t, bucket.WaitForTokenWithContext(context.Background()))
+	rl.UpdateFromResponse(SendMessageAPI, &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": {"1"}},
+	})
+	assert.Empty(t, backend.pauses, "pauses skip the failed backend too")
+
+	// Once the interval has passed the backend is tried again, and a success
+	// clears the failure
+	bucket.mu.Lock()
+	bucket.backendDownUntil = time.Now().Add(-time.Millisecond)
+	bucket.pausedUntil = time.Time{}
+	bucket.mu.Unlock()
+	backend.mu.Lock()
+	backend.err = nil
+	backend.mu.Unlock()
+
+	require.NoError(t, bucket.WaitForTokenWithContext(context.Background()))
+	assert.Equal(t, []string{"default:send_message", "default:send_message"}, backend.takes)
+	status := bucket.GetStatus(SendMessageAPI)
+	assert.Empty(t, status.BackendError)
+	assert.True(t, status.BackendDownUntil.IsZero())
+}
+
+func TestRateLimiterForwardsPausesToBackend(t *testing.T) {
+	backend := &recordingBackend{}
+	rl := NewRateLimiter()
+	rl.SetBackend(backend, "account-1")
+
+	rl.UpdateFromResponse(SendMessageAPI, &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": {"3"}},
+	})
+	assert.Equal(t, map[string]time.Duration{"account-1:send_message": 3 * time.Second}, backend.pauses)
+}
+
+func TestClientConfiguresRateLimiterBackend(t *testing.T) {
+	backend := &recordingBackend{}
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"message":"pong"}`))
+	})
+	defer cleanup()
+
+	cfg := client.GetConfig()
+	WithRateLimiterBackend(backend)(cfg)
+	client = NewAPIClientWithConfig(cfg)
+
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+	require.NoError(t, err)
+	require.Len(t, backend.takes, 1)
+	assert.Equal(t, cfg.rateLimiterNamespace()+":general", backend.takes[0])
+
+	// Clients with different API keys on one backend never share a bucket
+	other := *cfg
+	other.APIKey = "aha-sk-another-key"
+	assert.NotEqual(t, cfg.rateLimiterNamespace(), other.rateLimiterNamespace())
+	assert.NotContains(t, cfg.rateLimiterNamespace(), cfg.APIKey)
+
+	WithRateLimiterNamespace("account-1")(&other)
+	assert.Equal(t, "account-1", other.rateLimiterNamespace())
+}
@@ -60,6 +60,23 @@ type RateLimitStatus struct {
 	BurstCapacity     int          `json:"burst_capacity"`
 	TokensAvailable   int          `json:"tokens_available"`
 	NextRefillTime    time.Time    `json:"next_refill_time"`
+
+	// Learned reports whether RequestsPerSecond and BurstCapacity were learned
+	// from the server's rate-limit headers rather than configured
+	Learned bool `json:"learned"`
+	// ServerLimit and ServerRemaining are the request allowance and what is
+	// left of it, as last reported by the server; -1 when unknown
+	ServerLimit     int `json:"server_limit"`
+	ServerRemaining int `json:"server_remaining"`
+	// ServerReset is when the server's current window resets, if known
+	ServerReset time.Time `json:"server_reset,omitempty"`
+	// PausedUntil is set while every caller is held back after a 429
+	PausedUntil time.Time `json:"paused_until,omitempty"`
+	// BackendError is the last error of the shared RateLimiterBackend, empty
+	// once a call succeeds again, and BackendDownUntil is set while the
+	// in-process bucket stands in for the backend after that error
+	BackendError     string    `json:"backend_error,omitempty"`
+	BackendDownUntil time.Time `json:"backend_down_until,omitempty"`
 }
 
 // TokenBucket implements a token bucket rate limiter
@@ -70,6 +87,19 @@ type TokenBucket struct {
 	lastRefill      time.Time
 	config          RateLimitConfig
 	mu              sync.Mutex
+
+	// State learned from server responses, see RateLimiter.UpdateFromResponse
+	learned         bool
+	serverLimit     int
+	serverRemaining int
+	serverReset     time.Time
+	pausedUntil     time.Time
+
+	// Shared bucket state, see RateLimiter.SetBackend
+	backend          RateLimiterBackend
+	key              string
+	backendDownUntil time.Time // In-process state is used until then
+	backendErr       error     // The last backend failure
 }
 
 // NewTokenBucket creates a new token bucket with the specified configuration
@@ -80,6 +110,8 @@ func NewTokenBucket(config RateLimitConfig) *TokenBucket {
 		tokensPerSecond: float64(config.RequestsPerSecond),
 		lastRefill:      time.Now(),
 		config:          config,
+		serverLimit:     -1,
+		serverRemaining: -1,
 	}
 }
 
@@ -90,63 +122,90 @@ func (tb *TokenBucket) WaitForToken() error {
 
 // WaitForTokenWithContext blocks until a token is available, respecting the rate limit and context cancellation
 func (tb *TokenBucket) WaitForTokenWithContext(ctx context.Context) error {
+	for {
+		waitTime, ok := tb.take(ctx)
+		if ok {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Wait for either the timeout or context cancellation
+		timer := time.NewTimer(waitTime)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			// Try again to acquire a token
+		}
+	}
+}
+
+// take tries to take a token. If none is available it reports how long to
+// wait before trying again.
+func (tb *TokenBucket) take(ctx context.Context) (time.Duration, bool) {
 	tb.mu.Lock()
+	defer tb.mu.Unlock()
 
 	if !tb.config.Enabled {
-		tb.mu.Unlock()
-		return nil
+		return 0, true
 	}
 
-	// Refill tokens based on elapsed time
 	now := time.Now()
-	elapsed := now.Sub(tb.lastRefill)
-	tokensToAdd := elapsed.Seconds() * tb.tokensPerSecond
+	tb.refill(now)
 
-	tb.tokens = math.Min(tb.tokens+tokensToAdd, float64(tb.maxTokens))
-	tb.lastRefill = now
+	// Backing off after a 429: nobody gets a token until the pause ends
+	if now.Before(tb.pausedUntil) {
+		return tb.pausedUntil.Sub(now), false
+	}
 
-	// If we have tokens available, consume one and return
-	if tb.tokens >= 1.0 {
-		tb.tokens -= 1.0
+	if tb.backendAvailable(now) {
+		backend, key, rate, burst := tb.backend, tb.key, tb.tokensPerSecond, tb.maxTokens
 		tb.mu.Unlock()
-		return nil
+		waitTime, err := backend.Take(ctx, key, rate, burst)
+		tb.mu.Lock()
+		if err == nil {
+			tb.backendErr = nil
+			return waitTime, waitTime <= 0
+		}
+		// The shared store is unavailable: fall back to this process's bucket
+		// rather than stop sending altogether, unless the caller gave up
+		if ctx.Err() == nil {
+			now = time.Now()
+			tb.backendFailed(now, err)
+		}
+		tb.refill(time.Now())
 	}
 
-	// Calculate wait time for the next token
-	waitTime := time.Duration((1.0-tb.tokens)/tb.tokensPerSecond*float64(time.Second)) + time.Millisecond
-	tb.mu.Unlock()
-
-	// Wait for either the timeout or context cancellation
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(waitTime):
-		// Continue to acquire token
+	if tb.tokens >= 1.0 {
+		tb.tokens -= 1.0
+		return 0, true
+	}
+	if tb.tokensPerSecond <= 0 {
+		return time.Second, false
 	}
+	// Calculate wait time for the next token
+	return time.Duration((1.0-tb.tokens)/tb.tokensPerSecond*float64(time.Second)) + time.Millisecond, false
+}
 
-	// Re-acquire lock and consume the token
-	tb.mu.Lock()
-	defer tb.mu.Unlock()
+// refill adds the tokens accumulated since the last refill. The caller must
+// hold tb.mu.
+func (tb *TokenBucket) refill(now time.Time) {
+	if now.Before(tb.lastRefill) {
+		// Refilling resumes when a pause ends
+		return
+	}
+	elapsed := now.Sub(tb.lastRefill)
+	tokensToAdd := elapsed.Seconds() * tb.tokensPerSecond
 
-	// Refill again after waiting (in case more time passed than expected)
-	now = time.Now()
-	elapsed = now.Sub(tb.lastRefill)
-	tokensToAdd = elapsed.Seconds() * tb.tokensPerSecond
 	tb.tokens = math.Min(tb.tokens+tokensToAdd, float64(tb.maxTokens))
 	tb.lastRefill = now
-
-	// Consume a token
-	if tb.tokens >= 1.0 {
-		tb.tokens -= 1.0
-	} else {
-		// This shouldn't happen but handle gracefully
-		tb.tokens = 0
-	}
-
-	return nil
 }
 
-// UpdateConfig updates the rate limit configuration
+// UpdateConfig updates the rate limit configuration. Limits learned from the
+// server are discarded until the next response that reports them.
 func (tb *TokenBucket) UpdateConfig(config RateLimitConfig) {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
@@ -154,6 +213,7 @@ func (tb *TokenBucket) UpdateConfig(config RateLimitConfig) {
 	tb.config = config
 	tb.tokensPerSecond = float64(config.RequestsPerSecond)
 	tb.maxTokens = config.BurstCapacity
+	tb.learned = false
 
 	// Adjust current tokens if new max is lower
 	if tb.tokens > float64(tb.maxTokens) {
@@ -168,25 +228,49 @@ func (tb *TokenBucket) GetStatus(endpointType EndpointType) RateLimitStatus {
 
 	// Calculate next refill time
 	now := time.Now()
-	elapsed := now.Sub(tb.lastRefill)
-	tokensToAdd := elapsed.Seconds() * tb.tokensPerSecond
-	currentTokens := math.Min(tb.tokens+tokensToAdd, float64(tb.maxTokens))
+	currentTokens := tb.tokens
+	if !now.Before(tb.lastRefill) {
+		tokensToAdd := now.Sub(tb.lastRefill).Seconds() * tb.tokensPerSecond
+		currentTokens = math.Min(tb.tokens+tokensToAdd, float64(tb.maxTokens))
+	}
 
 	var nextRefill time.Time
-	if currentTokens < float64(tb.maxTokens) {
+	if currentTokens < float64(tb.maxTokens) && tb.tokensPerSecond > 0 {
 		tokensNeeded := float64(tb.maxTokens) - currentTokens
 		secondsToWait := tokensNeeded / tb.tokensPerSecond
-		nextRefill = now.Add(time.Duration(secondsToWait * float64(time.Second)))
+		refillFrom := now
+		if tb.lastRefill.After(now) {
+			refillFrom = tb.lastRefill
+		}
+		nextRefill = refillFrom.Add(time.Duration(secondsToWait * float64(time.Second)))
 	}
 
-	return RateLimitStatus{
+	status := RateLimitStatus{
 		EndpointType:      endpointType,
 		Enabled:           tb.config.Enabled,
 		RequestsPerSecond: tb.config.RequestsPerSecond,
 		BurstCapacity:     tb.config.BurstCapacity,
 		TokensAvailable:   int(math.Floor(currentTokens)),
 		NextRefillTime:    nextRefill,
+		Learned:           tb.learned,
+		ServerLimit:       tb.serverLimit,
+		ServerRemaining:   tb.serverRemaining,
+		ServerReset:       tb.serverReset,
+	}
+	if tb.learned {
+		status.RequestsPerSecond = int(math.Max(1, math.Round(tb.tokensPerSecond)))
+		status.BurstCapacity = tb.maxTokens
+	}
+	if now.Before(tb.pausedUntil) {
+		status.PausedUntil = tb.pausedUntil
+	}
+	if tb.backendErr != nil {
+		status.BackendError = tb.backendErr.Error()
+	}
+	if tb.backend != nil && now.Before(tb.backendDownUntil) {
+		status.BackendDownUntil = tb.backendDownUntil
 	}
+	return status
 }
 
 // RateLimiter manages rate limiting for different endpoint types
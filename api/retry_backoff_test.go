@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryWaitsForRetryAfter(t *testing.T) {
+	var requests int32
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"message":"pong"}`))
+	})
+	defer cleanup()
+
+	cfg := client.GetConfig()
+	cfg.RetryConfig = RetryConfig{
+		Enabled:         true,
+		MaxRetries:      2,
+		BackoffStrategy: BackoffConstant,
+		BaseDelay:       time.Millisecond,
+		MaxDelay:        time.Millisecond,
+		MaxRetryAfter:   5 * time.Second,
+	}
+
+	start := time.Now()
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "the retry should wait for Retry-After, not BaseDelay")
+}
+
+func TestRetryCapsRetryAfter(t *testing.T) {
+	var requests int32
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"message":"pong"}`))
+	})
+	defer cleanup()
+
+	cfg := client.GetConfig()
+	cfg.RetryConfig = RetryConfig{
+		Enabled:         true,
+		MaxRetries:      2,
+		BackoffStrategy: BackoffConstant,
+		BaseDelay:       time.Millisecond,
+		MaxDelay:        time.Second,
+		MaxRetryAfter:   10 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, _, err := client.UtilityAPI.Ping(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryStopsAtMaxElapsedTime(t *testing.T) {
+	var requests int32
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"message":"unavailable"}`))
+	})
+	defer cleanup()
+
+	cfg := client.GetConfig()
+	cfg.RetryConfig = RetryConfig{
+		Enabled:         true,
+		MaxRetries:      10,
+		BackoffStrategy: BackoffConstant,
+		BaseDelay:       50 * time.Millisecond,
+		MaxDelay:        50 * time.Millisecond,
+		MaxElapsedTime:  120 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, httpResp, err := client.UtilityAPI.Ping(context.Background())
+	require.Error(t, err)
+
+	// Attempts start at about 0, 50 and 100ms; the next would start past the
+	// budget, so the call ends well before MaxRetries would have let it
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&requests), int32(2))
+	assert.LessOrEqual(t, atomic.LoadInt32(&requests), int32(3))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// The last response is reported as it would have been without retries
+	apiErr, ok := err.(*APIError)
+	require.True(t, ok, "expected an *APIError, got %T", err)
+	assert.Equal(t, ErrorTypeServer, apiErr.Type)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, "unavailable", apiErr.Message)
+	require.NotNil(t, httpResp)
+}
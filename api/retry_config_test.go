@@ -1,6 +1,7 @@
 package api
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
@@ -18,6 +19,8 @@ func TestRetryConfig(t *testing.T) {
 		assert.Equal(t, BackoffExponential, config.BackoffStrategy)
 		assert.Equal(t, time.Second, config.BaseDelay)
 		assert.Equal(t, 30*time.Second, config.MaxDelay)
+		assert.Zero(t, config.MaxRetryAfter, "Retry-After is honoured in full unless capped")
+		assert.Zero(t, config.MaxElapsedTime, "calls have no time budget unless one is set")
 	})
 
 	t.Run("IsRetryEnabled", func(t *testing.T) {
@@ -91,6 +94,107 @@ func TestRetryConfig(t *testing.T) {
 			assert.Equal(t, time.Second, delay2)
 			assert.Equal(t, time.Second, delay3)
 		})
+
+		t.Run("Full jitter", func(t *testing.T) {
+			config := RetryConfig{
+				BackoffStrategy: BackoffFullJitter,
+				BaseDelay:       time.Second,
+				MaxDelay:        10 * time.Second,
+			}
+
+			for i := 0; i < 100; i++ {
+				assert.LessOrEqual(t, config.GetDelay(1), time.Second)
+				assert.LessOrEqual(t, config.GetDelay(3), 4*time.Second)
+				assert.LessOrEqual(t, config.GetDelay(10), 10*time.Second)
+				assert.GreaterOrEqual(t, config.GetDelay(3), time.Duration(0))
+			}
+		})
+
+		t.Run("Equal jitter", func(t *testing.T) {
+			config := RetryConfig{
+				BackoffStrategy: BackoffEqualJitter,
+				BaseDelay:       time.Second,
+				MaxDelay:        10 * time.Second,
+			}
+
+			for i := 0; i < 100; i++ {
+				delay := config.GetDelay(3)
+				assert.GreaterOrEqual(t, delay, 2*time.Second)
+				assert.LessOrEqual(t, delay, 4*time.Second)
+
+				delay = config.GetDelay(10)
+				assert.GreaterOrEqual(t, delay, 5*time.Second)
+				assert.LessOrEqual(t, delay, 10*time.Second)
+			}
+		})
+
+		t.Run("Decorrelated jitter", func(t *testing.T) {
+			config := RetryConfig{
+				BackoffStrategy: BackoffDecorrelatedJitter,
+				BaseDelay:       time.Second,
+				MaxDelay:        10 * time.Second,
+			}
+
+			for i := 0; i < 100; i++ {
+				delay := config.nextDelay(2, 2*time.Second)
+				assert.GreaterOrEqual(t, delay, time.Second)
+				assert.LessOrEqual(t, delay, 6*time.Second)
+
+				delay = config.nextDelay(5, 8*time.Second)
+				assert.GreaterOrEqual(t, delay, time.Second)
+				assert.LessOrEqual(t, delay, 10*time.Second)
+
+				delay = config.GetDelay(1)
+				assert.GreaterOrEqual(t, delay, time.Second)
+				assert.LessOrEqual(t, delay, 3*time.Second)
+			}
+		})
+
+		t.Run("Jitter spreads delays", func(t *testing.T) {
+			config := RetryConfig{
+				BackoffStrategy: BackoffFullJitter,
+				BaseDelay:       time.Second,
+				MaxDelay:        10 * time.Second,
+			}
+
+			delays := make(map[time.Duration]bool)
+			for i := 0; i < 20; i++ {
+				delays[config.GetDelay(3)] = true
+			}
+			assert.Greater(t, len(delays), 1, "workers retrying together should not wait the same time")
+		})
+	})
+
+	t.Run("retryAfterDelay", func(t *testing.T) {
+		config := RetryConfig{MaxDelay: 30 * time.Second, MaxRetryAfter: time.Minute}
+
+		resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"5"}}}
+		delay, ok := config.retryAfterDelay(resp)
+		assert.True(t, ok)
+		assert.Equal(t, 5*time.Second, delay)
+
+		resp.Header.Set("Retry-After", "600")
+		delay, ok = config.retryAfterDelay(resp)
+		assert.True(t, ok)
+		assert.Equal(t, time.Minute, delay, "capped at MaxRetryAfter")
+
+		config.MaxRetryAfter = 0
+		delay, _ = config.retryAfterDelay(resp)
+		assert.Equal(t, 10*time.Minute, delay, "not capped when MaxRetryAfter is not set")
+
+		resp = &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{
+			"Retry-After": {time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)},
+		}}
+		delay, ok = config.retryAfterDelay(resp)
+		assert.True(t, ok)
+		assert.InDelta(t, float64(10*time.Second), float64(delay), float64(2*time.Second))
+
+		resp = &http.Response{StatusCode: http.StatusInternalServerError, Header: http.Header{"Retry-After": {"5"}}}
+		_, ok = config.retryAfterDelay(resp)
+		assert.False(t, ok, "Retry-After only applies to 429 and 503")
+
+		_, ok = config.retryAfterDelay(nil)
+		assert.False(t, ok)
 	})
 }
 
@@ -238,3 +238,19 @@ func (a *RoutesAPIService) UpdateRoute(
 	resp, err := a.client.Execute(ctx, config)
 	return &result, resp, err
 }
+
+// GetRoutesPager returns a Pager that walks every page of GetRoutesWithParams
+// for the given filters, starting from params' pagination cursor.
+func (a *RoutesAPIService) GetRoutesPager(
+	ctx context.Context,
+	accountId uuid.UUID,
+	params requests.GetRoutesParams,
+	opts ...RequestOption,
+) *Pager[responses.Route] {
+	fetch := func(ctx context.Context, page common.PaginationParams) (*responses.PaginatedRoutesResponse, *http.Response, error) {
+		pageParams := params
+		pageParams.PaginationParams = page
+		return a.GetRoutesWithParams(ctx, accountId, pageParams, opts...)
+	}
+	return NewPager[responses.Route](ctx, fetch, params.PaginationParams)
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+)
+
+// methodScopes maps each SDK method, as "Service.Method", to the scope its
+// endpoint requires. Methods that need no scope map to nil.
+var methodScopes = map[string][]common.Scope{
+	"APIKeysAPIService.CreateAPIKey":    {common.ScopeAPIKeysWrite},
+	"APIKeysAPIService.DeleteAPIKey":    {common.ScopeAPIKeysDelete},
+	"APIKeysAPIService.GetAPIKey":       {common.ScopeAPIKeysRead},
+	"APIKeysAPIService.GetAPIKeys":      {common.ScopeAPIKeysRead},
+	"APIKeysAPIService.GetAPIKeysPager": {common.ScopeAPIKeysRead},
+	"APIKeysAPIService.UpdateAPIKey":    {common.ScopeAPIKeysWrite},
+
+	"AccountsAPIService.AddAccountMember":    {common.ScopeAccountsMembersAdd},
+	"AccountsAPIService.GetAccount":          {common.ScopeAccountsRead},
+	"AccountsAPIService.GetAccountMembers":   {common.ScopeAccountsMembersRead},
+	"AccountsAPIService.RemoveAccountMember": {common.ScopeAccountsMembersRemove},
+	"AccountsAPIService.UpdateAccount":       {common.ScopeAccountsWrite},
+
+	"DomainsAPIService.CheckDomainDNS":  {common.ScopeDomainsWrite},
+	"DomainsAPIService.CreateDomain":    {common.ScopeDomainsWrite},
+	"DomainsAPIService.DeleteDomain":    {common.ScopeDomainsDelete},
+	"DomainsAPIService.GetDomain":       {common.ScopeDomainsRead},
+	"DomainsAPIService.GetDomains":      {common.ScopeDomainsRead},
+	"DomainsAPIService.GetDomainsPager": {common.ScopeDomainsRead},
+	"DomainsAPIService.UpdateDomain":    {common.ScopeDomainsWrite},
+
+	"MessagesAPIService.CancelMessage":                 {common.ScopeMessagesCancel},
+	"MessagesAPIService.CancelMessages":                {common.ScopeMessagesRead, common.ScopeMessagesCancel},
+	"MessagesAPIService.CreateConversationMessage":     {common.ScopeMessagesSend},
+	"MessagesAPIService.CreateMessage":                 {common.ScopeMessagesSend},
+	"MessagesAPIService.CreateMessageBatch":            {common.ScopeMessagesSend},
+	"MessagesAPIService.CreateMessageBatchFromChannel": {common.ScopeMessagesSend},
+	"MessagesAPIService.GetMessage":                    {common.ScopeMessagesRead},
+	"MessagesAPIService.GetMessageByAPIID":             {common.ScopeMessagesRead},
+	"MessagesAPIService.GetMessages":                   {common.ScopeMessagesRead},
+	"MessagesAPIService.GetMessagesPager":              {common.ScopeMessagesRead},
+
+	"RoutesAPIService.CreateRoute":         {common.ScopeRoutesWrite},
+	"RoutesAPIService.DeleteRoute":         {common.ScopeRoutesDelete},
+	"RoutesAPIService.GetRoute":            {common.ScopeRoutesRead},
+	"RoutesAPIService.GetRoutes":           {common.ScopeRoutesRead},
+	"RoutesAPIService.GetRoutesPager":      {common.ScopeRoutesRead},
+	"RoutesAPIService.GetRoutesWithParams": {common.ScopeRoutesRead},
+	"RoutesAPIService.UpdateRoute":         {common.ScopeRoutesWrite},
+
+	"SMTPCredentialsAPIService.CreateSMTPCredential":    {common.ScopeSMTPCredentialsWrite},
+	"SMTPCredentialsAPIService.DeleteSMTPCredential":    {common.ScopeSMTPCredentialsDelete},
+	"SMTPCredentialsAPIService.GetSMTPCredential":       {common.ScopeSMTPCredentialsRead},
+	"SMTPCredentialsAPIService.GetSMTPCredentials":      {common.ScopeSMTPCredentialsRead},
+	"SMTPCredentialsAPIService.GetSMTPCredentialsPager": {common.ScopeSMTPCredentialsRead},
+
+	"StatisticsAPIService.GetBounceStatistics":         {common.ScopeStatisticsTransactionalRead},
+	"StatisticsAPIService.GetDeliverabilityStatistics": {common.ScopeStatisticsTransactionalRead},
+	"StatisticsAPIService.GetDeliveryTimeStatistics":   {common.ScopeStatisticsTransactionalRead},
+
+	"SubAccountsAPIService.CreateSubAccount":           {common.ScopeSubAccountsWrite},
+	"SubAccountsAPIService.CreateSubAccountAPIKey":     {common.ScopeSubAccountAPIKeysWrite},
+	"SubAccountsAPIService.DeleteSubAccount":           {common.ScopeSubAccountsDelete},
+	"SubAccountsAPIService.DeleteSubAccountAPIKey":     {common.ScopeSubAccountAPIKeysDelete},
+	"SubAccountsAPIService.GetSubAccount":              {common.ScopeSubAccountsRead},
+	"SubAccountsAPIService.GetSubAccountAPIKey":        {common.ScopeSubAccountAPIKeysRead},
+	"SubAccountsAPIService.GetSubAccountsUsage":        {common.ScopeSubAccountsUsage},
+	"SubAccountsAPIService.ListSubAccountAPIKeys":      {common.ScopeSubAccountAPIKeysRead},
+	"SubAccountsAPIService.ListSubAccountAPIKeysPager": {common.ScopeSubAccountAPIKeysRead},
+	"SubAccountsAPIService.ListSubAccounts":            {common.ScopeSubAccountsRead},
+	"SubAccountsAPIService.ListSubAccountsPager":       {common.ScopeSubAccountsRead},
+	"SubAccountsAPIService.SuspendSubAccount":          {common.ScopeSubAccountsSuspend},
+	"SubAccountsAPIService.UnsuspendSubAccount":        {common.ScopeSubAccountsSuspend},
+	"SubAccountsAPIService.UpdateSubAccount":           {common.ScopeSubAccountsWrite},
+	"SubAccountsAPIService.UpdateSubAccountAPIKey":     {common.ScopeSubAccountAPIKeysWrite},
+
+	"SuppressionsAPIService.CreateSuppression":     {common.ScopeSuppressionsWrite},
+	"SuppressionsAPIService.DeleteAllSuppressions": {common.ScopeSuppressionsWipe},
+	"SuppressionsAPIService.DeleteSuppression":     {common.ScopeSuppressionsDelete},
+	"SuppressionsAPIService.GetSuppressions":       {common.ScopeSuppressionsRead},
+	"SuppressionsAPIService.GetSuppressionsPager":  {common.ScopeSuppressionsRead},
+
+	"UtilityAPIService.Ping": nil,
+
+	"WebhooksAPIService.CreateWebhook":    {common.ScopeWebhooksWrite},
+	"WebhooksAPIService.DeleteWebhook":    {common.ScopeWebhooksDelete},
+	"WebhooksAPIService.GetWebhook":       {common.ScopeWebhooksRead},
+	"WebhooksAPIService.GetWebhooks":      {common.ScopeWebhooksRead},
+	"WebhooksAPIService.GetWebhooksPager": {common.ScopeWebhooksRead},
+	"WebhooksAPIService.UpdateWebhook":    {common.ScopeWebhooksWrite},
+}
+
+// RequiredScopes returns the least-privilege set of scopes an API key needs to
+// call the given SDK methods. Pass the methods themselves, so that the
+// compiler checks their names:
+//
+//	scopes, err := api.RequiredScopes(
+//		client.MessagesAPI.CreateMessage,
+//		client.MessagesAPI.GetMessages,
+//	)
+//
+// Method names such as "MessagesAPI.CreateMessage" are accepted as well.
+// Scopes that can be restricted to a domain are returned for every domain;
+// use common.RestrictScopes to restrict them, and common.FormatScopes to turn
+// them into the strings of a CreateAPIKeyRequest.
+func RequiredScopes(methods ...interface{}) ([]common.Scope, error) {
+	var scopes []common.Scope
+	for _, method := range methods {
+		name, err := methodName(method)
+		if err != nil {
+			return nil, err
+		}
+		required, ok := methodScopes[name]
+		if !ok {
+			return nil, fmt.Errorf("no scopes known for method %s", name)
+		}
+		scopes = append(scopes, required...)
+	}
+	return common.SortScopes(scopes), nil
+}
+
+// methodName returns the "Service.Method" name of an SDK method value or
+// name
+func methodName(method interface{}) (string, error) {
+	if name, ok := method.(string); ok {
+		service, action, found := strings.Cut(name, ".")
+		if !found {
+			return "", fmt.Errorf("invalid method name %q: use Service.Method", name)
+		}
+		if !strings.HasSuffix(service, "Service") {
+			service += "Service"
+		}
+		return service + "." + action, nil
+	}
+
+	value := reflect.ValueOf(method)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return "", fmt.Errorf("%T is not an SDK method", method)
+	}
+	fn := runtime.FuncForPC(value.Pointer())
+	if fn == nil {
+		return "", fmt.Errorf("%T is not an SDK method", method)
+	}
+
+	// Method values are named like "<pkg>/api.(*MessagesAPIService).CreateMessage-fm"
+	full := strings.TrimSuffix(fn.Name(), "-fm")
+	full = full[strings.LastIndex(full, "/")+1:]
+	full = strings.TrimPrefix(full, "api.")
+	full = strings.NewReplacer("(*", "", ")", "").Replace(full)
+	return full, nil
+}
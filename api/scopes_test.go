@@ -0,0 +1,63 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredScopes(t *testing.T) {
+	client := NewAPIClient()
+
+	scopes, err := RequiredScopes(
+		client.MessagesAPI.CreateMessage,
+		client.MessagesAPI.CreateMessageBatch,
+		client.MessagesAPI.GetMessages,
+		client.DomainsAPI.GetDomains,
+		client.UtilityAPI.Ping,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"domains:read", "messages:read:all", "messages:send:all"}, common.FormatScopes(scopes...))
+
+	scopes, err = RequiredScopes("MessagesAPI.CancelMessage", "SuppressionsAPIService.DeleteAllSuppressions")
+	require.NoError(t, err)
+	assert.Equal(t, []common.Scope{common.ScopeMessagesCancel, common.ScopeSuppressionsWipe}, scopes)
+
+	restricted := common.RestrictScopes(scopes, "example.com")
+	assert.Equal(t, []string{"messages:cancel:{example.com}", "suppressions:wipe"}, common.FormatScopes(restricted...))
+
+	_, err = RequiredScopes("MessagesAPI.SendMessage")
+	assert.Error(t, err)
+	_, err = RequiredScopes(func() {})
+	assert.Error(t, err)
+	_, err = RequiredScopes(42)
+	assert.Error(t, err)
+}
+
+func TestRequiredScopesCoversEveryMethod(t *testing.T) {
+	client := reflect.ValueOf(NewAPIClient()).Elem()
+	methods := 0
+	for i := 0; i < client.NumField(); i++ {
+		field := client.Type().Field(i)
+		if !field.IsExported() || !strings.HasSuffix(field.Type.String(), "APIService") {
+			continue
+		}
+		for j := 0; j < field.Type.NumMethod(); j++ {
+			name := field.Type.Elem().Name() + "." + field.Type.Method(j).Name
+			_, ok := methodScopes[name]
+			assert.True(t, ok, "no scopes for %s", name)
+			methods++
+		}
+	}
+	assert.Equal(t, len(methodScopes), methods)
+
+	for name, scopes := range methodScopes {
+		for _, scope := range scopes {
+			assert.NoError(t, scope.Validate(), name)
+		}
+	}
+}
@@ -185,3 +185,17 @@ func (a *SMTPCredentialsAPIService) GetSMTPCredentials(
 	resp, err := a.client.Execute(ctx, config)
 	return &result, resp, err
 }
+
+// GetSMTPCredentialsPager returns a Pager that walks every page of
+// GetSMTPCredentials, starting from the cursor in pagination (optional).
+func (a *SMTPCredentialsAPIService) GetSMTPCredentialsPager(
+	ctx context.Context,
+	accountId uuid.UUID,
+	pagination *common.PaginationParams,
+	opts ...RequestOption,
+) *Pager[responses.SMTPCredential] {
+	fetch := func(ctx context.Context, page common.PaginationParams) (*responses.PaginatedSMTPCredentialsResponse, *http.Response, error) {
+		return a.GetSMTPCredentials(ctx, accountId, &page, opts...)
+	}
+	return NewPager[responses.SMTPCredential](ctx, fetch, paginationOrZero(pagination))
+}
@@ -571,3 +571,32 @@ func (a *SubAccountsAPIService) DeleteSubAccountAPIKey(
 	resp, err := a.client.Execute(ctx, config)
 	return &result, resp, err
 }
+
+// ListSubAccountsPager returns a Pager that walks every page of
+// ListSubAccounts, starting from the cursor in pagination (optional).
+func (a *SubAccountsAPIService) ListSubAccountsPager(
+	ctx context.Context,
+	accountId uuid.UUID,
+	pagination *common.PaginationParams,
+	opts ...RequestOption,
+) *Pager[responses.SubAccount] {
+	fetch := func(ctx context.Context, page common.PaginationParams) (*responses.PaginatedSubAccountsResponse, *http.Response, error) {
+		return a.ListSubAccounts(ctx, accountId, &page, opts...)
+	}
+	return NewPager[responses.SubAccount](ctx, fetch, paginationOrZero(pagination))
+}
+
+// ListSubAccountAPIKeysPager returns a Pager that walks every page of
+// ListSubAccountAPIKeys, starting from the cursor in pagination (optional).
+func (a *SubAccountsAPIService) ListSubAccountAPIKeysPager(
+	ctx context.Context,
+	accountId uuid.UUID,
+	subAccountId uuid.UUID,
+	pagination *common.PaginationParams,
+	opts ...RequestOption,
+) *Pager[responses.APIKey] {
+	fetch := func(ctx context.Context, page common.PaginationParams) (*responses.PaginatedAPIKeysResponse, *http.Response, error) {
+		return a.ListSubAccountAPIKeys(ctx, accountId, subAccountId, &page, opts...)
+	}
+	return NewPager[responses.APIKey](ctx, fetch, paginationOrZero(pagination))
+}
@@ -233,3 +233,19 @@ func (a *SuppressionsAPIService) GetSuppressions(
 	resp, err := a.client.Execute(ctx, config)
 	return &result, resp, err
 }
+
+// GetSuppressionsPager returns a Pager that walks every page of
+// GetSuppressions for the given filters, starting from params' pagination cursor.
+func (a *SuppressionsAPIService) GetSuppressionsPager(
+	ctx context.Context,
+	accountId uuid.UUID,
+	params requests.GetSuppressionsParams,
+	opts ...RequestOption,
+) *Pager[responses.Suppression] {
+	fetch := func(ctx context.Context, page common.PaginationParams) (*responses.PaginatedSuppressionsResponse, *http.Response, error) {
+		pageParams := params
+		pageParams.PaginationParams = page
+		return a.GetSuppressions(ctx, accountId, pageParams, opts...)
+	}
+	return NewPager[responses.Suppression](ctx, fetch, params.PaginationParams)
+}
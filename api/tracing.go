@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RequestTracer follows each call to APIClient.Execute from start to finish,
+// with a hook around every attempt. It is the extension point tracing
+// integrations build on; the github.com/AhaSend/ahasend-go/ahasendotel module
+// provides an OpenTelemetry implementation.
+type RequestTracer interface {
+	// StartRequest is called once per Execute call, after the request is
+	// built and before client-side rate limiting. The returned context
+	// replaces ctx for the rest of the call.
+	StartRequest(ctx context.Context, info RequestInfo) (context.Context, RequestSpan)
+}
+
+// RequestSpan receives the events of one Execute call
+type RequestSpan interface {
+	// RateLimited reports the time spent waiting on the client-side rate
+	// limiter. It is not called when rate limiting is skipped or disabled.
+	RateLimited(wait time.Duration)
+
+	// StartAttempt is called before each attempt is sent, with attempt
+	// starting at 1. Headers it sets on req are sent with the attempt, which
+	// is how trace context is propagated. The returned function is called
+	// with the attempt's outcome.
+	StartAttempt(req *http.Request, attempt int) func(resp *http.Response, err error)
+
+	// End is called once with the final outcome of the Execute call
+	End(resp *http.Response, err error)
+}
+
+// RequestInfo describes a request about to be sent
+type RequestInfo struct {
+	Method       string
+	PathTemplate string
+	EndpointType EndpointType
+
+	// URL is the request URL, including any query parameters
+	URL *url.URL
+
+	// AccountID is the account_id path parameter, if the endpoint has one
+	AccountID string
+
+	// IdempotencyKey is the Idempotency-Key header, if one is sent
+	IdempotencyKey string
+}
+
+// WithRequestTracer sets a tracer that observes every request
+func WithRequestTracer(tracer RequestTracer) ClientOption {
+	return func(cfg *Configuration) {
+		cfg.RequestTracer = tracer
+	}
+}
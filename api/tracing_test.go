@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tracerContextKey struct{}
+
+type recordingTracer struct {
+	mu     sync.Mutex
+	infos  []RequestInfo
+	events []string
+}
+
+func (tr *recordingTracer) StartRequest(ctx context.Context, info RequestInfo) (context.Context, RequestSpan) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.infos = append(tr.infos, info)
+	tr.events = append(tr.events, "start")
+	return context.WithValue(ctx, tracerContextKey{}, "traced"), tr
+}
+
+func (tr *recordingTracer) RateLimited(wait time.Duration) {
+	tr.record("rate limited")
+}
+
+func (tr *recordingTracer) StartAttempt(req *http.Request, attempt int) func(*http.Response, error) {
+	req.Header.Set("Traceparent", fmt.Sprintf("attempt-%d", attempt))
+	tr.record(fmt.Sprintf("attempt %d %v", attempt, req.Context().Value(tracerContextKey{})))
+	return func(resp *http.Response, err error) {
+		tr.record(fmt.Sprintf("attempt %d status %d", attempt, resp.StatusCode))
+	}
+}
+
+func (tr *recordingTracer) End(resp *http.Response, err error) {
+	tr.record(fmt.Sprintf("end %d %v", resp.StatusCode, err != nil))
+}
+
+func (tr *recordingTracer) record(event string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.events = append(tr.events, event)
+}
+
+func TestRequestTracerObservesRequestAndAttempts(t *testing.T) {
+	var traceparents []string
+	client, cleanup := newContractTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+		w.Header().Set("Content-Type", "application/json")
+		if len(traceparents) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"message":"upstream"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"not found"}`))
+	})
+	defer cleanup()
+
+	tracer := &recordingTracer{}
+	cfg := client.GetConfig()
+	WithRequestTracer(tracer)(cfg)
+	cfg.EnableRateLimit = true
+	cfg.RetryConfig = RetryConfig{Enabled: true, MaxRetries: 1, BaseDelay: 1, MaxDelay: 1, BackoffStrategy: BackoffConstant}
+	client = NewAPIClientWithConfig(cfg)
+
+	accountID := uuid.New()
+	_, _, err := client.DomainsAPI.GetDomain(context.Background(), accountID, "example.com")
+	require.Error(t, err)
+
+	require.Len(t, tracer.infos, 1)
+	info := tracer.infos[0]
+	assert.Equal(t, http.MethodGet, info.Method)
+	assert.Equal(t, "/v2/accounts/{account_id}/domains/{domain}", info.PathTemplate)
+	assert.Equal(t, accountID.String(), info.AccountID)
+	assert.Equal(t, GeneralAPI, info.EndpointType)
+	assert.Equal(t, "/v2/accounts/"+accountID.String()+"/domains/example.com", info.URL.Path)
+
+	assert.Equal(t, []string{"attempt-1", "attempt-2"}, traceparents)
+	assert.Equal(t, []string{
+		"start",
+		"rate limited",
+		"attempt 1 traced",
+		"attempt 1 status 502",
+		"attempt 2 traced",
+		"attempt 2 status 404",
+		"end 404 true",
+	}, tracer.events)
+}
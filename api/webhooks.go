@@ -277,3 +277,19 @@ func (a *WebhooksAPIService) UpdateWebhook(
 	resp, err := a.client.Execute(ctx, config)
 	return &result, resp, err
 }
+
+// GetWebhooksPager returns a Pager that walks every page of GetWebhooks for
+// the given filters, starting from params' pagination cursor.
+func (a *WebhooksAPIService) GetWebhooksPager(
+	ctx context.Context,
+	accountId uuid.UUID,
+	params GetWebhooksParams,
+	opts ...RequestOption,
+) *Pager[responses.Webhook] {
+	fetch := func(ctx context.Context, page common.PaginationParams) (*responses.PaginatedWebhooksResponse, *http.Response, error) {
+		pageParams := params
+		pageParams.PaginationParams = page
+		return a.GetWebhooks(ctx, accountId, pageParams, opts...)
+	}
+	return NewPager[responses.Webhook](ctx, fetch, params.PaginationParams)
+}
@@ -0,0 +1,124 @@
+package builder
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/google/uuid"
+)
+
+const (
+	// DispositionAttachment marks a part as a downloadable attachment
+	DispositionAttachment = "attachment"
+	// DispositionInline marks a part as rendered inside the message body
+	DispositionInline = "inline"
+
+	// contentIDDomain is the right-hand side of generated Content-IDs
+	contentIDDomain = "inline.ahasend"
+)
+
+// NewAttachment builds an attachment from raw data. The content type is taken
+// from the file name's extension, falling back to sniffing the data, and the
+// data is base64-encoded.
+func NewAttachment(fileName string, data []byte) common.Attachment {
+	return common.Attachment{
+		Base64:             true,
+		Data:               base64.StdEncoding.EncodeToString(data),
+		ContentType:        DetectContentType(fileName, data),
+		ContentDisposition: DispositionAttachment,
+		FileName:           fileName,
+	}
+}
+
+// NewInlineAttachment builds an inline attachment from raw data and returns
+// it together with the `cid:` reference to use in HTML, e.g. as an img src.
+// contentID is the bare Content-ID without angle brackets; when empty one is
+// generated.
+func NewInlineAttachment(fileName string, data []byte, contentID string) (common.Attachment, string) {
+	if contentID == "" {
+		contentID = GenerateContentID()
+	}
+	contentID = strings.Trim(contentID, "<>")
+
+	attachment := NewAttachment(fileName, data)
+	attachment.ContentDisposition = DispositionInline
+	// The API only renders a part inline when its Content-ID is wrapped in
+	// angle brackets, like the MIME header it becomes.
+	wrapped := "<" + contentID + ">"
+	attachment.ContentID = &wrapped
+
+	return attachment, "cid:" + contentID
+}
+
+// ReadAttachment reads r to the end and builds an attachment from it
+func ReadAttachment(fileName string, r io.Reader) (common.Attachment, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return common.Attachment{}, fmt.Errorf("failed to read attachment %s: %w", fileName, err)
+	}
+	return NewAttachment(fileName, data), nil
+}
+
+// LoadAttachment reads a file from disk and builds an attachment named after
+// the file's base name
+func LoadAttachment(filePath string) (common.Attachment, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return common.Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return NewAttachment(filepath.Base(filePath), data), nil
+}
+
+// LoadAttachmentFS reads a file from fsys and builds an attachment named after
+// the file's base name
+func LoadAttachmentFS(fsys fs.FS, name string) (common.Attachment, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return common.Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return NewAttachment(path.Base(name), data), nil
+}
+
+// commonContentTypes covers attachment extensions that Go's built-in MIME
+// table lacks, so detection does not depend on the host's mime.types file
+var commonContentTypes = map[string]string{
+	".csv":  "text/csv; charset=utf-8",
+	".txt":  "text/plain; charset=utf-8",
+	".ics":  "text/calendar; charset=utf-8",
+	".zip":  "application/zip",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// DetectContentType returns the MIME type of an attachment. The file name's
+// extension wins when it is registered, since sniffing cannot tell apart
+// formats such as CSV and plain text; otherwise the data is sniffed.
+func DetectContentType(fileName string, data []byte) string {
+	if ext := filepath.Ext(fileName); ext != "" {
+		if contentType := mime.TypeByExtension(ext); contentType != "" {
+			return contentType
+		}
+		if contentType, ok := commonContentTypes[strings.ToLower(ext)]; ok {
+			return contentType
+		}
+	}
+	return http.DetectContentType(data)
+}
+
+// GenerateContentID returns a new unique Content-ID, without angle brackets
+func GenerateContentID() string {
+	return uuid.New().String() + "@" + contentIDDomain
+}
@@ -0,0 +1,7 @@
+// Package builder provides a fluent builder for message requests.
+//
+// It takes care of the pointer fields, attachment encoding and inline image
+// Content-IDs that building a requests.CreateMessageRequest or
+// requests.CreateConversationMessageRequest by hand requires, and validates
+// the request - including its encoded size - before it leaves the process.
+package builder
@@ -0,0 +1,460 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+)
+
+const (
+	// DefaultMaxPayloadSize is the default limit on the JSON-encoded size of a
+	// built request, matching the 25MB the API accepts for a message
+	// including its attachments
+	DefaultMaxPayloadSize = 25 * 1024 * 1024
+
+	// maxConversationRecipients is the API's limit on to, cc and bcc combined
+	maxConversationRecipients = 50
+)
+
+var (
+	// ErrPayloadTooLarge is returned by the Build methods when the encoded
+	// request exceeds the builder's maximum payload size
+	ErrPayloadTooLarge = errors.New("message payload too large")
+	// ErrUnknownInline is returned when CID is asked for an inline name that
+	// was never attached
+	ErrUnknownInline = errors.New("unknown inline attachment")
+)
+
+// MessageBuilder builds message requests fluently.
+//
+// Setters never fail; problems such as an unreadable attachment are recorded
+// and returned by the Build methods, so a whole message can be described in
+// one chain:
+//
+//	b := builder.New().
+//		From("sender@example.com", "Example").
+//		To("user@example.com").
+//		Subject("Your receipt").
+//		InlineFile("logo", "assets/logo.png")
+//	b.HTML(`<img src="` + b.CID("logo") + `"> Thanks for your order`)
+//	request, err := b.BuildMessage()
+//
+// A MessageBuilder is not safe for concurrent use.
+type MessageBuilder struct {
+	from          common.SenderAddress
+	to            []common.Recipient
+	cc            []common.SenderAddress
+	bcc           []common.SenderAddress
+	replyTo       *common.SenderAddress
+	subject       string
+	text          *string
+	html          *string
+	amp           *string
+	attachments   []common.Attachment
+	inline        map[string]string
+	headers       map[string]string
+	substitutions map[string]interface{}
+	tags          []string
+	sandbox       *bool
+	sandboxResult *string
+	tracking      *common.Tracking
+	retention     *common.Retention
+	schedule      *common.MessageSchedule
+
+	maxPayloadSize int
+	err            error
+}
+
+// New creates an empty message builder
+func New() *MessageBuilder {
+	return &MessageBuilder{
+		inline:         map[string]string{},
+		maxPayloadSize: DefaultMaxPayloadSize,
+	}
+}
+
+// From sets the sender address and optional display name
+func (b *MessageBuilder) From(email string, name ...string) *MessageBuilder {
+	b.from = common.SenderAddress{Email: email, Name: optionalName(name)}
+	return b
+}
+
+// To adds a recipient with an optional display name
+func (b *MessageBuilder) To(email string, name ...string) *MessageBuilder {
+	b.to = append(b.to, common.Recipient{Email: email, Name: optionalName(name)})
+	return b
+}
+
+// ToRecipient adds a fully specified recipient, including per-recipient
+// substitutions. Substitutions are only sent by BuildMessage.
+func (b *MessageBuilder) ToRecipient(recipient common.Recipient) *MessageBuilder {
+	b.to = append(b.to, recipient)
+	return b
+}
+
+// CC adds a carbon-copy recipient. CC recipients are only supported by
+// BuildConversation.
+func (b *MessageBuilder) CC(email string, name ...string) *MessageBuilder {
+	b.cc = append(b.cc, common.SenderAddress{Email: email, Name: optionalName(name)})
+	return b
+}
+
+// BCC adds a blind carbon-copy recipient. BCC recipients are only supported
+// by BuildConversation.
+func (b *MessageBuilder) BCC(email string, name ...string) *MessageBuilder {
+	b.bcc = append(b.bcc, common.SenderAddress{Email: email, Name: optionalName(name)})
+	return b
+}
+
+// ReplyTo sets the Reply-To address
+func (b *MessageBuilder) ReplyTo(email string, name ...string) *MessageBuilder {
+	b.replyTo = &common.SenderAddress{Email: email, Name: optionalName(name)}
+	return b
+}
+
+// Subject sets the subject line
+func (b *MessageBuilder) Subject(subject string) *MessageBuilder {
+	b.subject = subject
+	return b
+}
+
+// Text sets the plain text body
+func (b *MessageBuilder) Text(content string) *MessageBuilder {
+	b.text = &content
+	return b
+}
+
+// HTML sets the HTML body
+func (b *MessageBuilder) HTML(content string) *MessageBuilder {
+	b.html = &content
+	return b
+}
+
+// AMP sets the AMP for Email body
+func (b *MessageBuilder) AMP(content string) *MessageBuilder {
+	b.amp = &content
+	return b
+}
+
+// Header sets a custom header
+func (b *MessageBuilder) Header(name, value string) *MessageBuilder {
+	if b.headers == nil {
+		b.headers = make(map[string]string)
+	}
+	b.headers[name] = value
+	return b
+}
+
+// Tag adds one or more tags
+func (b *MessageBuilder) Tag(tags ...string) *MessageBuilder {
+	b.tags = append(b.tags, tags...)
+	return b
+}
+
+// Substitution sets a global substitution value. Substitutions are only sent
+// by BuildMessage.
+func (b *MessageBuilder) Substitution(name string, value interface{}) *MessageBuilder {
+	if b.substitutions == nil {
+		b.substitutions = make(map[string]interface{})
+	}
+	b.substitutions[name] = value
+	return b
+}
+
+// Sandbox enables sandbox mode with an optional simulated result
+func (b *MessageBuilder) Sandbox(result ...string) *MessageBuilder {
+	enabled := true
+	b.sandbox = &enabled
+	if len(result) > 0 && result[0] != "" {
+		b.sandboxResult = &result[0]
+	}
+	return b
+}
+
+// Tracking sets open and click tracking
+func (b *MessageBuilder) Tracking(open, click bool) *MessageBuilder {
+	b.tracking = &common.Tracking{Open: &open, Click: &click}
+	return b
+}
+
+// Retention sets how many days metadata and data are retained
+func (b *MessageBuilder) Retention(metadataDays, dataDays int32) *MessageBuilder {
+	b.retention = &common.Retention{Metadata: &metadataDays, Data: &dataDays}
+	return b
+}
+
+// ScheduleAt schedules the first delivery attempt
+func (b *MessageBuilder) ScheduleAt(firstAttempt time.Time) *MessageBuilder {
+	if b.schedule == nil {
+		b.schedule = &common.MessageSchedule{}
+	}
+	b.schedule.FirstAttempt = &firstAttempt
+	return b
+}
+
+// ExpiresAt drops the message if it has not been delivered by expires
+func (b *MessageBuilder) ExpiresAt(expires time.Time) *MessageBuilder {
+	if b.schedule == nil {
+		b.schedule = &common.MessageSchedule{}
+	}
+	b.schedule.Expires = &expires
+	return b
+}
+
+// MaxPayloadSize sets the limit on the encoded request size checked by the
+// Build methods. Zero or a negative value disables the check.
+func (b *MessageBuilder) MaxPayloadSize(bytes int) *MessageBuilder {
+	b.maxPayloadSize = bytes
+	return b
+}
+
+// Attachment adds a prepared attachment
+func (b *MessageBuilder) Attachment(attachment common.Attachment) *MessageBuilder {
+	b.attachments = append(b.attachments, attachment)
+	return b
+}
+
+// AttachBytes adds an attachment from raw data
+func (b *MessageBuilder) AttachBytes(fileName string, data []byte) *MessageBuilder {
+	return b.Attachment(NewAttachment(fileName, data))
+}
+
+// AttachReader adds an attachment read from r
+func (b *MessageBuilder) AttachReader(fileName string, r io.Reader) *MessageBuilder {
+	attachment, err := ReadAttachment(fileName, r)
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+	return b.Attachment(attachment)
+}
+
+// AttachFile adds an attachment read from a file on disk
+func (b *MessageBuilder) AttachFile(filePath string) *MessageBuilder {
+	attachment, err := LoadAttachment(filePath)
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+	return b.Attachment(attachment)
+}
+
+// AttachFS adds an attachment read from fsys
+func (b *MessageBuilder) AttachFS(fsys fs.FS, name string) *MessageBuilder {
+	attachment, err := LoadAttachmentFS(fsys, name)
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+	return b.Attachment(attachment)
+}
+
+// InlineBytes adds an inline image from raw data under name. Use CID(name)
+// to reference it from the HTML body.
+func (b *MessageBuilder) InlineBytes(name, fileName string, data []byte) *MessageBuilder {
+	attachment, ref := NewInlineAttachment(fileName, data, "")
+	b.inline[name] = ref
+	return b.Attachment(attachment)
+}
+
+// InlineReader adds an inline image read from r under name
+func (b *MessageBuilder) InlineReader(name, fileName string, r io.Reader) *MessageBuilder {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		b.fail(fmt.Errorf("failed to read inline attachment %s: %w", name, err))
+		return b
+	}
+	return b.InlineBytes(name, fileName, data)
+}
+
+// InlineFile adds an inline image read from a file on disk under name
+func (b *MessageBuilder) InlineFile(name, filePath string) *MessageBuilder {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		b.fail(fmt.Errorf("failed to read inline attachment %s: %w", name, err))
+		return b
+	}
+	return b.InlineBytes(name, filepath.Base(filePath), data)
+}
+
+// InlineFS adds an inline image read from fsys under name
+func (b *MessageBuilder) InlineFS(name string, fsys fs.FS, fileName string) *MessageBuilder {
+	data, err := fs.ReadFile(fsys, fileName)
+	if err != nil {
+		b.fail(fmt.Errorf("failed to read inline attachment %s: %w", name, err))
+		return b
+	}
+	return b.InlineBytes(name, path.Base(fileName), data)
+}
+
+// CID returns the `cid:` reference of the inline attachment added under name,
+// for use as an img src in the HTML body. Asking for a name that was never
+// added records an error that the Build methods return.
+func (b *MessageBuilder) CID(name string) string {
+	ref, ok := b.inline[name]
+	if !ok {
+		b.fail(fmt.Errorf("%w: %s", ErrUnknownInline, name))
+		return ""
+	}
+	return ref
+}
+
+// BuildMessage validates the builder and returns a CreateMessageRequest.
+// CC and BCC recipients are not supported by that endpoint and are reported
+// as an error; use BuildConversation for them.
+func (b *MessageBuilder) BuildMessage() (requests.CreateMessageRequest, error) {
+	if err := b.validate(); err != nil {
+		return requests.CreateMessageRequest{}, err
+	}
+	if len(b.cc) > 0 || len(b.bcc) > 0 {
+		return requests.CreateMessageRequest{}, fmt.Errorf("cc and bcc recipients require a conversation message")
+	}
+
+	request := requests.CreateMessageRequest{
+		From:          b.from,
+		Recipients:    append([]common.Recipient(nil), b.to...),
+		Subject:       b.subject,
+		ReplyTo:       b.replyTo,
+		TextContent:   b.text,
+		HtmlContent:   b.html,
+		AmpContent:    b.amp,
+		Attachments:   append([]common.Attachment(nil), b.attachments...),
+		Headers:       copyHeaders(b.headers),
+		Substitutions: b.substitutions,
+		Tags:          append([]string(nil), b.tags...),
+		Sandbox:       b.sandbox,
+		SandboxResult: b.sandboxResult,
+		Tracking:      b.tracking,
+		Retention:     b.retention,
+		Schedule:      b.schedule,
+	}
+
+	if err := b.checkPayloadSize(request); err != nil {
+		return requests.CreateMessageRequest{}, err
+	}
+	return request, nil
+}
+
+// BuildConversation validates the builder and returns a
+// CreateConversationMessageRequest. Substitutions are not supported by that
+// endpoint and are reported as an error.
+func (b *MessageBuilder) BuildConversation() (requests.CreateConversationMessageRequest, error) {
+	if err := b.validate(); err != nil {
+		return requests.CreateConversationMessageRequest{}, err
+	}
+	if len(b.substitutions) > 0 {
+		return requests.CreateConversationMessageRequest{}, fmt.Errorf("substitutions are not supported by conversation messages")
+	}
+	if total := len(b.to) + len(b.cc) + len(b.bcc); total > maxConversationRecipients {
+		return requests.CreateConversationMessageRequest{}, fmt.Errorf("to, cc and bcc must not exceed %d recipients combined, got %d", maxConversationRecipients, total)
+	}
+
+	to := make([]common.SenderAddress, len(b.to))
+	for i, recipient := range b.to {
+		if len(recipient.Substitutions) > 0 {
+			return requests.CreateConversationMessageRequest{}, fmt.Errorf("substitutions are not supported by conversation messages")
+		}
+		to[i] = common.SenderAddress{Email: recipient.Email, Name: recipient.Name}
+	}
+
+	request := requests.CreateConversationMessageRequest{
+		From:          b.from,
+		To:            to,
+		CC:            append([]common.SenderAddress(nil), b.cc...),
+		BCC:           append([]common.SenderAddress(nil), b.bcc...),
+		Subject:       b.subject,
+		ReplyTo:       b.replyTo,
+		TextContent:   b.text,
+		HtmlContent:   b.html,
+		AmpContent:    b.amp,
+		Attachments:   append([]common.Attachment(nil), b.attachments...),
+		Headers:       copyHeaders(b.headers),
+		Tags:          append([]string(nil), b.tags...),
+		Sandbox:       b.sandbox,
+		SandboxResult: b.sandboxResult,
+		Tracking:      b.tracking,
+		Retention:     b.retention,
+		Schedule:      b.schedule,
+	}
+
+	if err := b.checkPayloadSize(request); err != nil {
+		return requests.CreateConversationMessageRequest{}, err
+	}
+	return request, nil
+}
+
+// validate checks the constraints shared by both request types
+func (b *MessageBuilder) validate() error {
+	if b.err != nil {
+		return b.err
+	}
+	if b.from.Email == "" {
+		return fmt.Errorf("from address is required")
+	}
+	if len(b.to) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	if b.text == nil && b.html == nil {
+		return fmt.Errorf("either text or html content is required")
+	}
+	if b.replyTo != nil {
+		for name := range b.headers {
+			if strings.EqualFold(name, "reply-to") {
+				return fmt.Errorf("reply-to must not be set both as an address and as a header")
+			}
+		}
+	}
+	return nil
+}
+
+// checkPayloadSize encodes request the way the client will and compares the
+// result against the configured limit
+func (b *MessageBuilder) checkPayloadSize(request interface{}) error {
+	if b.maxPayloadSize <= 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	if len(encoded) > b.maxPayloadSize {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrPayloadTooLarge, len(encoded), b.maxPayloadSize)
+	}
+	return nil
+}
+
+// fail records the first error a setter ran into; later ones are often
+// consequences of it
+func (b *MessageBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func optionalName(name []string) *string {
+	if len(name) == 0 || name[0] == "" {
+		return nil
+	}
+	return &name[0]
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(headers))
+	for name, value := range headers {
+		copied[name] = value
+	}
+	return copied
+}
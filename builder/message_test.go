@@ -0,0 +1,168 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough of a PNG file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestBuildMessage(t *testing.T) {
+	request, err := New().
+		From("sender@example.com", "Sender").
+		To("one@example.com", "One").
+		ToRecipient(common.Recipient{Email: "two@example.com", Substitutions: map[string]interface{}{"code": "42"}}).
+		ReplyTo("support@example.com").
+		Subject("Hello").
+		Text("Hi").
+		HTML("<p>Hi</p>").
+		Header("X-Campaign", "launch").
+		Tag("welcome", "onboarding").
+		Substitution("company", "Example").
+		Tracking(true, false).
+		BuildMessage()
+
+	require.NoError(t, err)
+	assert.Equal(t, "sender@example.com", request.From.Email)
+	require.NotNil(t, request.From.Name)
+	assert.Equal(t, "Sender", *request.From.Name)
+	require.Len(t, request.Recipients, 2)
+	assert.Equal(t, "42", request.Recipients[1].Substitutions["code"])
+	assert.Equal(t, "support@example.com", request.ReplyTo.Email)
+	assert.Equal(t, "Hi", *request.TextContent)
+	assert.Equal(t, "<p>Hi</p>", *request.HtmlContent)
+	assert.Equal(t, "launch", request.Headers["X-Campaign"])
+	assert.Equal(t, []string{"welcome", "onboarding"}, request.Tags)
+	assert.Equal(t, "Example", request.Substitutions["company"])
+	assert.True(t, *request.Tracking.Open)
+	assert.False(t, *request.Tracking.Click)
+}
+
+func TestBuildConversation(t *testing.T) {
+	request, err := New().
+		From("sender@example.com").
+		To("one@example.com").
+		CC("two@example.com", "Two").
+		BCC("audit@example.com").
+		Subject("Thread").
+		Text("Hi").
+		BuildConversation()
+
+	require.NoError(t, err)
+	require.Len(t, request.To, 1)
+	assert.Equal(t, "one@example.com", request.To[0].Email)
+	require.Len(t, request.CC, 1)
+	assert.Equal(t, "Two", *request.CC[0].Name)
+	require.Len(t, request.BCC, 1)
+}
+
+func TestBuildRejectsIncompleteMessages(t *testing.T) {
+	_, err := New().To("one@example.com").Text("Hi").BuildMessage()
+	assert.ErrorContains(t, err, "from address")
+
+	_, err = New().From("sender@example.com").Text("Hi").BuildMessage()
+	assert.ErrorContains(t, err, "recipient")
+
+	_, err = New().From("sender@example.com").To("one@example.com").BuildMessage()
+	assert.ErrorContains(t, err, "text or html")
+
+	_, err = New().From("sender@example.com").To("one@example.com").CC("two@example.com").Text("Hi").BuildMessage()
+	assert.ErrorContains(t, err, "conversation")
+
+	_, err = New().From("sender@example.com").To("one@example.com").Substitution("a", 1).Text("Hi").BuildConversation()
+	assert.ErrorContains(t, err, "substitutions")
+
+	_, err = New().From("sender@example.com").To("one@example.com").ReplyTo("r@example.com").Header("Reply-To", "x@example.com").Text("Hi").BuildMessage()
+	assert.ErrorContains(t, err, "reply-to")
+}
+
+func TestAttachmentsAreEncodedAndTyped(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "report.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("a,b\n1,2\n"), 0o600))
+
+	fsys := fstest.MapFS{"docs/readme.txt": {Data: []byte("hello")}}
+
+	request, err := New().
+		From("sender@example.com").
+		To("one@example.com").
+		Text("See attached").
+		AttachFile(csvPath).
+		AttachFS(fsys, "docs/readme.txt").
+		AttachReader("blob", bytes.NewReader(pngHeader)).
+		BuildMessage()
+
+	require.NoError(t, err)
+	require.Len(t, request.Attachments, 3)
+
+	csv := request.Attachments[0]
+	assert.Equal(t, "report.csv", csv.FileName)
+	assert.True(t, strings.HasPrefix(csv.ContentType, "text/csv"), csv.ContentType)
+	assert.True(t, csv.Base64)
+	assert.Equal(t, DispositionAttachment, csv.ContentDisposition)
+	decoded, err := base64.StdEncoding.DecodeString(csv.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n1,2\n", string(decoded))
+
+	assert.Equal(t, "readme.txt", request.Attachments[1].FileName)
+	assert.Equal(t, "image/png", request.Attachments[2].ContentType, "content type is sniffed without an extension")
+}
+
+func TestInlineAttachmentsGetContentIDs(t *testing.T) {
+	b := New().
+		From("sender@example.com").
+		To("one@example.com").
+		InlineBytes("logo", "logo.png", pngHeader)
+	ref := b.CID("logo")
+	request, err := b.HTML(`<img src="` + ref + `">`).BuildMessage()
+
+	require.NoError(t, err)
+	require.Len(t, request.Attachments, 1)
+	inline := request.Attachments[0]
+	assert.Equal(t, DispositionInline, inline.ContentDisposition)
+	require.NotNil(t, inline.ContentID)
+	assert.True(t, strings.HasPrefix(*inline.ContentID, "<") && strings.HasSuffix(*inline.ContentID, ">"),
+		"the API requires Content-IDs wrapped in angle brackets")
+	assert.Equal(t, "cid:"+strings.Trim(*inline.ContentID, "<>"), ref)
+	assert.Contains(t, *request.HtmlContent, ref)
+}
+
+func TestBuildReportsAttachmentErrors(t *testing.T) {
+	_, err := New().
+		From("sender@example.com").
+		To("one@example.com").
+		Text("Hi").
+		AttachFile(filepath.Join(t.TempDir(), "missing.pdf")).
+		BuildMessage()
+	assert.ErrorContains(t, err, "failed to read attachment")
+
+	b := New().From("sender@example.com").To("one@example.com")
+	b.HTML(`<img src="` + b.CID("nope") + `">`)
+	_, err = b.BuildMessage()
+	assert.ErrorIs(t, err, ErrUnknownInline)
+}
+
+func TestBuildEnforcesPayloadSize(t *testing.T) {
+	b := New().
+		From("sender@example.com").
+		To("one@example.com").
+		Text("Hi").
+		AttachBytes("big.bin", make([]byte, 4096)).
+		MaxPayloadSize(1024)
+
+	_, err := b.BuildMessage()
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+
+	_, err = b.MaxPayloadSize(0).BuildMessage()
+	assert.NoError(t, err)
+}
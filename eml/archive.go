@@ -0,0 +1,57 @@
+package eml
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// Writer receives the messages Archive exports. MboxWriter and DirWriter
+// implement it.
+type Writer interface {
+	Write(msg *responses.Message) error
+}
+
+// ArchiveResult summarizes an Archive run
+type ArchiveResult struct {
+	// Messages is the number of messages written
+	Messages int
+	// WithoutContent lists the messages that were written from their summary
+	// alone, with an empty body, because AhaSend no longer retains their
+	// content
+	WithoutContent []uuid.UUID
+}
+
+// Archive writes every message matching params to w, in the order GetMessages
+// lists them. Listings omit message content, so each message is fetched with
+// GetMessageByAPIID before it is written:
+//
+//	f, err := os.Create("receipts-2026-09.mbox")
+//	...
+//	params := requests.GetMessagesParams{Tags: []string{"receipt"}, FromTime: &from, ToTime: &to}
+//	result, err := eml.Archive(ctx, client, accountID, params, eml.NewMboxWriter(f))
+//
+// On error, the result counts the messages written before it.
+func Archive(ctx context.Context, client *api.APIClient, accountID uuid.UUID, params requests.GetMessagesParams, w Writer, opts ...api.RequestOption) (ArchiveResult, error) {
+	var result ArchiveResult
+	err := client.MessagesAPI.GetMessagesPager(ctx, accountID, params, opts...).ForEach(func(summary responses.Message) error {
+		msg, _, err := client.MessagesAPI.GetMessageByAPIID(ctx, accountID, summary.ID.String(), opts...)
+		if err != nil {
+			return fmt.Errorf("failed to fetch message %s: %w", summary.ID, err)
+		}
+		if err := w.Write(msg); err != nil {
+			return fmt.Errorf("failed to write message %s: %w", summary.ID, err)
+		}
+
+		result.Messages++
+		if (msg.Content == nil || *msg.Content == "") && msg.ContentParsed == nil {
+			result.WithoutContent = append(result.WithoutContent, msg.ID)
+		}
+		return nil
+	})
+	return result, err
+}
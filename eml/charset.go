@@ -0,0 +1,57 @@
+package eml
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from
+// ISO-8859-1, to their runes. Unassigned bytes map to the replacement rune.
+var windows1252 = [32]rune{
+	'€', utf8.RuneError, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', utf8.RuneError, 'Ž', utf8.RuneError,
+	utf8.RuneError, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', utf8.RuneError, 'ž', 'Ÿ',
+}
+
+// decodeCharset converts text in charset to UTF-8. The standard library has
+// no charset tables, so only UTF-8, US-ASCII, ISO-8859-1 and Windows-1252 are
+// supported; text in other charsets is returned as it is, with an error.
+func decodeCharset(charset string, data []byte) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return string(data), nil
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1":
+		return decodeSingleByte(data, false), nil
+	case "windows-1252", "cp1252":
+		return decodeSingleByte(data, true), nil
+	}
+	return string(data), fmt.Errorf("unsupported charset %q", charset)
+}
+
+func decodeSingleByte(data []byte, windows bool) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		if windows && c >= 0x80 && c <= 0x9F {
+			b.WriteRune(windows1252[c-0x80])
+			continue
+		}
+		b.WriteRune(rune(c))
+	}
+	return b.String()
+}
+
+// charsetReader lets mime.WordDecoder decode encoded words in the charsets
+// decodeCharset supports
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	text, err := decodeCharset(charset, data)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(text), nil
+}
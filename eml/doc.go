@@ -0,0 +1,45 @@
+// Package eml converts between RFC 5322 messages, as found in .eml files,
+// and the SDK's message types.
+//
+// Parse reads a raw MIME message - multipart/alternative, multipart/related
+// and multipart/mixed bodies, RFC 2047 encoded headers, quoted-printable and
+// base64 parts - and collects what a message request can carry: the text,
+// HTML and AMP bodies, attachments, inline parts with their Content-IDs, the
+// Reply-To address and custom headers:
+//
+//	f, err := os.Open("legacy/welcome.eml")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//
+//	msg, err := eml.Parse(f)
+//	if err != nil {
+//		return err
+//	}
+//	request, dropped := msg.MessageRequest()
+//	for _, drop := range dropped {
+//		log.Printf("not imported: %s", drop)
+//	}
+//
+// Anything that cannot be carried over, such as trace headers, Date and
+// Message-ID, which AhaSend sets itself, or a second text/plain body, is
+// reported rather than silently lost. MessageRequest also reports Cc and Bcc
+// recipients, which only ConversationRequest supports.
+//
+// In the other direction, Build turns a responses.Message retrieved with
+// GetMessage back into a message a mail client can open: the raw Content
+// when the API returned it, or a message rebuilt from ContentParsed. WriteEML
+// writes a single .eml file, and MboxWriter and DirWriter collect many
+// messages into an mbox stream or a directory of .eml files. Archive pairs
+// them with GetMessages to export every message matching a filter:
+//
+//	f, err := os.Create("receipts.mbox")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//
+//	params := requests.GetMessagesParams{Tags: []string{"receipt"}}
+//	result, err := eml.Archive(ctx, client, accountID, params, eml.NewMboxWriter(f))
+package eml
@@ -0,0 +1,360 @@
+package eml
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/responses"
+)
+
+// leadingHeaders are written first, in this order, when a message is rebuilt
+var leadingHeaders = []string{"Date", "From", "Sender", "To", "Cc", "Reply-To", "Subject", "Message-Id"}
+
+// listAddressHeaders hold address lists, which are encoded address by
+// address so that they stay parseable
+var listAddressHeaders = map[string]bool{
+	"From":     true,
+	"Sender":   true,
+	"To":       true,
+	"Cc":       true,
+	"Bcc":      true,
+	"Reply-To": true,
+}
+
+// Build returns msg as an RFC 5322 message with CRLF line endings. The raw
+// Content is used as it is when the API returned it. Otherwise the message is
+// rebuilt from ContentParsed, and when that is missing too, from the summary
+// fields alone, with an empty body.
+func Build(msg *responses.Message) ([]byte, error) {
+	if msg.Content != nil && *msg.Content != "" {
+		return toCRLF([]byte(*msg.Content)), nil
+	}
+
+	parsed := msg.ContentParsed
+	if parsed == nil {
+		parsed = &responses.ContentParsed{}
+	}
+
+	var buf bytes.Buffer
+	writeHeaders(&buf, messageHeaders(msg, parsed))
+	if err := writeBody(&buf, parsed); err != nil {
+		return nil, fmt.Errorf("failed to build message %s: %w", msg.ID, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteEML writes msg to w as an .eml file
+func WriteEML(w io.Writer, msg *responses.Message) error {
+	data, err := Build(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// messageHeaders returns the top-level headers of a rebuilt message: those of
+// ContentParsed without the ones describing the MIME structure, which are
+// written with the body, completed from the summary fields
+func messageHeaders(msg *responses.Message, parsed *responses.ContentParsed) map[string][]string {
+	headers := make(map[string][]string, len(parsed.Headers)+5)
+	for name, values := range parsed.Headers {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if structuralHeaders[name] || len(values) == 0 {
+			continue
+		}
+		headers[name] = append(headers[name], values...)
+	}
+
+	fill := func(name, value string) {
+		if _, ok := headers[name]; !ok && value != "" {
+			headers[name] = []string{value}
+		}
+	}
+	date := msg.CreatedAt
+	if msg.SentAt != nil {
+		date = *msg.SentAt
+	}
+	if !date.IsZero() {
+		fill("Date", date.Format(time.RFC1123Z))
+	}
+	fill("From", msg.Sender)
+	fill("To", msg.Recipient)
+	fill("Subject", msg.Subject)
+	fill("Message-Id", msg.MessageID)
+	headers["Mime-Version"] = []string{"1.0"}
+	return headers
+}
+
+func writeHeaders(buf *bytes.Buffer, headers map[string][]string) {
+	var rest []string
+	for name := range headers {
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+
+	written := make(map[string]bool, len(headers))
+	for _, name := range append(append([]string(nil), leadingHeaders...), rest...) {
+		if written[name] {
+			continue
+		}
+		written[name] = true
+		for _, value := range headers[name] {
+			fmt.Fprintf(buf, "%s: %s\r\n", name, encodeHeader(name, value))
+		}
+	}
+}
+
+// encodeHeader encodes non-ASCII header values as RFC 2047 encoded words
+func encodeHeader(name, value string) string {
+	value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+	if isASCII(value) {
+		return value
+	}
+	if listAddressHeaders[name] {
+		if addresses, err := mail.ParseAddressList(value); err == nil {
+			formatted := make([]string, len(addresses))
+			for i, address := range addresses {
+				formatted[i] = address.String()
+			}
+			return strings.Join(formatted, ", ")
+		}
+	}
+	return mime.QEncoding.Encode("utf-8", value)
+}
+
+// writeBody writes the MIME headers and body of a rebuilt message: the text
+// parts as multipart/alternative, wrapped in multipart/related when there
+// are inline attachments and in multipart/mixed when there are others
+func writeBody(buf *bytes.Buffer, parsed *responses.ContentParsed) error {
+	var inline, attached []responses.ContentAttachment
+	for _, attachment := range parsed.Attachments {
+		if attachment.ContentID != "" {
+			inline = append(inline, attachment)
+		} else {
+			attached = append(attached, attachment)
+		}
+	}
+
+	body := textEntity(parsed.Parts)
+	if len(inline) > 0 {
+		body = multipartEntity("multipart/related", body, attachmentEntities(inline)...)
+	}
+	if len(attached) > 0 {
+		body = multipartEntity("multipart/mixed", body, attachmentEntities(attached)...)
+	}
+	return body(buf)
+}
+
+// entity writes the headers of a MIME entity, a blank line and its body
+type entity func(w io.Writer) error
+
+func textEntity(parts []responses.ContentPart) entity {
+	switch len(parts) {
+	case 0:
+		return partEntity(responses.ContentPart{ContentType: "text/plain"})
+	case 1:
+		return partEntity(parts[0])
+	}
+	alternatives := make([]entity, len(parts))
+	for i, part := range parts {
+		alternatives[i] = partEntity(part)
+	}
+	return multipartEntity("multipart/alternative", alternatives[0], alternatives[1:]...)
+}
+
+func partEntity(part responses.ContentPart) entity {
+	return func(w io.Writer) error {
+		contentType := part.ContentType
+		if mediaType, params, err := mime.ParseMediaType(contentType); err == nil {
+			if params["charset"] == "" {
+				params["charset"] = "utf-8"
+			}
+			contentType = mime.FormatMediaType(mediaType, params)
+		}
+		fmt.Fprintf(w, "Content-Type: %s\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", contentType)
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := io.WriteString(qp, part.Content); err != nil {
+			return err
+		}
+		return qp.Close()
+	}
+}
+
+func attachmentEntities(attachments []responses.ContentAttachment) []entity {
+	entities := make([]entity, len(attachments))
+	for i, attachment := range attachments {
+		entities[i] = attachmentEntity(attachment)
+	}
+	return entities
+}
+
+func attachmentEntity(attachment responses.ContentAttachment) entity {
+	return func(w io.Writer) error {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		disposition := dispositionAttachment
+		if attachment.ContentID != "" {
+			disposition = dispositionInline
+		}
+		if attachment.Filename != "" {
+			disposition = mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename})
+		}
+
+		fmt.Fprintf(w, "Content-Type: %s\r\nContent-Disposition: %s\r\nContent-Transfer-Encoding: base64\r\n", contentType, disposition)
+		if attachment.ContentID != "" {
+			fmt.Fprintf(w, "Content-Id: <%s>\r\n", strings.Trim(attachment.ContentID, "<>"))
+		}
+		if _, err := io.WriteString(w, "\r\n"); err != nil {
+			return err
+		}
+
+		// The API returns attachment content base64-encoded; content that
+		// does not decode is taken to be the data itself
+		data, err := base64.StdEncoding.DecodeString(attachment.Content)
+		if err != nil {
+			data = []byte(attachment.Content)
+		}
+		encoded := base64.StdEncoding.EncodeToString(data)
+		for len(encoded) > 76 {
+			if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+				return err
+			}
+			encoded = encoded[76:]
+		}
+		_, err = io.WriteString(w, encoded)
+		return err
+	}
+}
+
+func multipartEntity(mediaType string, first entity, rest ...entity) entity {
+	return func(w io.Writer) error {
+		// A throwaway multipart.Writer generates a random boundary
+		boundary := multipart.NewWriter(io.Discard).Boundary()
+		contentType := mime.FormatMediaType(mediaType, map[string]string{"boundary": boundary})
+		if _, err := fmt.Fprintf(w, "Content-Type: %s\r\n\r\n", contentType); err != nil {
+			return err
+		}
+		for i, part := range append([]entity{first}, rest...) {
+			delimiter := "--" + boundary + "\r\n"
+			if i > 0 {
+				// The CRLF before a boundary belongs to the boundary, not
+				// to the part it ends
+				delimiter = "\r\n" + delimiter
+			}
+			if _, err := io.WriteString(w, delimiter); err != nil {
+				return err
+			}
+			if err := part(w); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "\r\n--%s--\r\n", boundary)
+		return err
+	}
+}
+
+// MboxWriter writes messages to an mbox stream in the mboxrd format: each
+// message starts with a "From " line, lines starting with "From " (after any
+// number of ">") get another ">", and line endings are LF
+type MboxWriter struct {
+	w *bufio.Writer
+}
+
+// NewMboxWriter returns an MboxWriter that writes to w
+func NewMboxWriter(w io.Writer) *MboxWriter {
+	return &MboxWriter{w: bufio.NewWriter(w)}
+}
+
+// Write appends msg to the mbox
+func (m *MboxWriter) Write(msg *responses.Message) error {
+	data, err := Build(msg)
+	if err != nil {
+		return err
+	}
+
+	sender := msg.Sender
+	if sender == "" {
+		sender = "MAILER-DAEMON"
+	}
+	date := msg.CreatedAt
+	if msg.SentAt != nil {
+		date = *msg.SentAt
+	}
+	fmt.Fprintf(m.w, "From %s %s\n", sender, date.UTC().Format(time.ANSIC))
+
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) {
+			m.w.WriteByte('>')
+		}
+		m.w.Write(line)
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		m.w.WriteByte('\n')
+	}
+	m.w.WriteByte('\n')
+	return m.w.Flush()
+}
+
+// DirWriter writes each message to its own .eml file in a directory, named
+// after the message's API ID
+type DirWriter struct {
+	dir string
+}
+
+// NewDirWriter returns a DirWriter for dir, creating it if needed
+func NewDirWriter(dir string) (*DirWriter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	return &DirWriter{dir: dir}, nil
+}
+
+// Write writes msg to <dir>/<id>.eml, replacing any earlier export of it
+func (d *DirWriter) Write(msg *responses.Message) error {
+	data, err := Build(msg)
+	if err != nil {
+		return err
+	}
+	path := d.Path(msg)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Path returns the path Write writes msg to
+func (d *DirWriter) Path(msg *responses.Message) string {
+	return filepath.Join(d.dir, msg.ID.String()+".eml")
+}
+
+func toCRLF(data []byte) []byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
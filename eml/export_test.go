@@ -0,0 +1,152 @@
+package eml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/ahasendtest"
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func storedMessage() *responses.Message {
+	sentAt := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+	return &responses.Message{
+		ID:        uuid.New(),
+		MessageID: "<stored-1@example.com>",
+		SentAt:    &sentAt,
+		Subject:   "Your order ✓",
+		Sender:    "shop@example.com",
+		Recipient: "customer@example.com",
+		ContentParsed: &responses.ContentParsed{
+			Parts: []responses.ContentPart{
+				{ContentType: "text/plain", Content: "Thanks for your order ✓\nFrom the shop"},
+				{ContentType: "text/html", Content: `<p>Thanks</p><img src="cid:logo@example.com">`},
+			},
+			Attachments: []responses.ContentAttachment{
+				{Filename: "logo.png", ContentType: "image/png", Content: base64.StdEncoding.EncodeToString([]byte("\x89PNG")), ContentID: "logo@example.com"},
+				{Filename: "résumé.pdf", ContentType: "application/pdf", Content: base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))},
+			},
+			Headers: map[string][]string{
+				"From":         {"Jürgen Shop <shop@example.com>"},
+				"Subject":      {"Your order ✓"},
+				"Content-Type": {"multipart/mixed; boundary=old"},
+				"X-Campaign":   {"autumn"},
+			},
+		},
+	}
+}
+
+func TestBuildRoundTrips(t *testing.T) {
+	data, err := Build(storedMessage())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "boundary=old")
+
+	msg, err := Parse(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "shop@example.com", msg.From.Email)
+	assert.Equal(t, "Jürgen Shop", *msg.From.Name)
+	require.Len(t, msg.To, 1)
+	assert.Equal(t, "customer@example.com", msg.To[0].Email)
+	assert.Equal(t, "Your order ✓", msg.Subject)
+	assert.Equal(t, "Thanks for your order ✓\r\nFrom the shop", *msg.TextContent)
+	assert.Equal(t, `<p>Thanks</p><img src="cid:logo@example.com">`, *msg.HtmlContent)
+	assert.Equal(t, map[string]string{"X-Campaign": "autumn"}, msg.Headers)
+
+	require.Len(t, msg.Attachments, 2)
+	assert.Equal(t, "logo.png", msg.Attachments[0].FileName)
+	assert.Equal(t, "<logo@example.com>", *msg.Attachments[0].ContentID)
+	assert.Equal(t, "résumé.pdf", msg.Attachments[1].FileName)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")), msg.Attachments[1].Data)
+
+	var dropped []string
+	for _, drop := range msg.Dropped {
+		dropped = append(dropped, drop.What)
+	}
+	assert.Equal(t, []string{"header Date", "header Message-Id"}, dropped)
+}
+
+func TestBuildUsesRawContent(t *testing.T) {
+	raw := "From: shop@example.com\nSubject: Raw\n\nHello\n"
+	data, err := Build(&responses.Message{Content: &raw, ContentParsed: storedMessage().ContentParsed})
+	require.NoError(t, err)
+	assert.Equal(t, "From: shop@example.com\r\nSubject: Raw\r\n\r\nHello\r\n", string(data))
+}
+
+func TestBuildFromSummary(t *testing.T) {
+	stored := storedMessage()
+	stored.ContentParsed = nil
+
+	data, err := Build(stored)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "Date: Tue, 01 Sep 2026 10:00:00 +0000\r\nFrom: shop@example.com\r\nTo: customer@example.com\r\nSubject: =?utf-8?q?Your_order_=E2=9C=93?=\r\nMessage-Id: <stored-1@example.com>\r\n"), string(data))
+
+	msg, err := Parse(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "", *msg.TextContent)
+	assert.Empty(t, msg.Attachments)
+}
+
+func TestMboxWriter(t *testing.T) {
+	first := "From: a@example.com\nSubject: One\n\nFrom the start\n>From quoted\nFromage\n"
+	second := "From: b@example.com\r\nSubject: Two\r\n\r\nBye"
+
+	var buf bytes.Buffer
+	mbox := NewMboxWriter(&buf)
+	require.NoError(t, mbox.Write(&responses.Message{Sender: "a@example.com", Content: &first, CreatedAt: time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)}))
+	require.NoError(t, mbox.Write(&responses.Message{Content: &second}))
+
+	assert.Equal(t, "From a@example.com Tue Sep  1 10:00:00 2026\n"+
+		"From: a@example.com\nSubject: One\n\n>From the start\n>>From quoted\nFromage\n\n"+
+		"From MAILER-DAEMON Mon Jan  1 00:00:00 0001\n"+
+		"From: b@example.com\nSubject: Two\n\nBye\n\n", buf.String())
+}
+
+func TestArchive(t *testing.T) {
+	server := ahasendtest.NewServer()
+	defer server.Close()
+	client := server.Client(api.WithRetryConfig(api.RetryConfig{Enabled: false}))
+	accountID := server.AccountID()
+
+	for _, tag := range []string{"receipt", "newsletter", "receipt"} {
+		_, _, err := client.MessagesAPI.CreateMessage(context.Background(), accountID, requests.CreateMessageRequest{
+			From:        common.SenderAddress{Email: "shop@example.com"},
+			Recipients:  []common.Recipient{{Email: "customer@example.com"}},
+			Subject:     "Your " + tag,
+			TextContent: ahasend.String("Hello"),
+			Tags:        []string{tag},
+		})
+		require.NoError(t, err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	writer, err := NewDirWriter(dir)
+	require.NoError(t, err)
+	result, err := Archive(context.Background(), client, accountID, requests.GetMessagesParams{Tags: []string{"receipt"}}, writer)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Messages)
+	assert.Empty(t, result.WithoutContent)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	f, err := os.Open(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	defer f.Close()
+	msg, err := Parse(f)
+	require.NoError(t, err)
+	assert.Equal(t, "Your receipt", msg.Subject)
+	assert.Equal(t, "Hello", *msg.TextContent)
+}
@@ -0,0 +1,439 @@
+package eml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+)
+
+const (
+	dispositionAttachment = "attachment"
+	dispositionInline     = "inline"
+)
+
+// Message is a MIME message reduced to what a message request can carry
+type Message struct {
+	From        common.SenderAddress
+	To          []common.SenderAddress
+	CC          []common.SenderAddress
+	BCC         []common.SenderAddress
+	ReplyTo     *common.SenderAddress
+	Subject     string
+	TextContent *string
+	HtmlContent *string
+	AmpContent  *string
+	Attachments []common.Attachment
+	// Headers holds the custom headers, with their names in canonical form
+	Headers map[string]string
+	// Dropped lists the headers and parts that could not be carried over
+	Dropped []Drop
+}
+
+// Drop is a header or part of a message that could not be carried over into
+// a request
+type Drop struct {
+	// What names the header or part, such as "header Received" or
+	// "part 1.2 (text/plain)"
+	What   string
+	Reason string
+}
+
+// String returns the drop as "what: reason"
+func (d Drop) String() string {
+	return d.What + ": " + d.Reason
+}
+
+// addressHeaders are read into the address fields of a Message
+var addressHeaders = map[string]bool{
+	"From":     true,
+	"To":       true,
+	"Cc":       true,
+	"Bcc":      true,
+	"Reply-To": true,
+}
+
+// structuralHeaders describe the MIME structure and are consumed by Parse
+var structuralHeaders = map[string]bool{
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Content-Disposition":       true,
+	"Content-Id":                true,
+}
+
+// droppedHeaders cannot be sent through the API, with the reason why
+var droppedHeaders = map[string]string{
+	"Date":                       "set by AhaSend when the message is sent",
+	"Message-Id":                 "set by AhaSend when the message is sent",
+	"Sender":                     "messages are sent from the From address",
+	"Return-Path":                "added in transit",
+	"Received":                   "added in transit",
+	"Received-Spf":               "added in transit",
+	"Delivered-To":               "added in transit",
+	"X-Original-To":              "added in transit",
+	"X-Received":                 "added in transit",
+	"Authentication-Results":     "added in transit",
+	"Arc-Seal":                   "added in transit",
+	"Arc-Message-Signature":      "added in transit",
+	"Arc-Authentication-Results": "added in transit",
+	"Dkim-Signature":             "AhaSend signs the message itself",
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+var addressParser = &mail.AddressParser{WordDecoder: wordDecoder}
+
+// Parse reads an RFC 5322 message from r. It only fails when r cannot be read
+// or its header is malformed; problems with single headers or parts are
+// reported in Message.Dropped.
+func Parse(r io.Reader) (*Message, error) {
+	raw, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	body, err := io.ReadAll(raw.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+
+	p := &parser{msg: &Message{}}
+	p.readHeaders(raw.Header)
+	p.readEntity(textproto.MIMEHeader(raw.Header), body, "")
+	return p.msg, nil
+}
+
+// parser collects a Message from the entities of a MIME message
+type parser struct {
+	msg         *Message
+	attachments int
+}
+
+func (p *parser) drop(what, reason string, args ...interface{}) {
+	p.msg.Dropped = append(p.msg.Dropped, Drop{What: what, Reason: fmt.Sprintf(reason, args...)})
+}
+
+func (p *parser) readHeaders(header mail.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := header[name]
+		what := "header " + name
+		switch {
+		case addressHeaders[name]:
+			p.readAddresses(name, strings.Join(values, ", "))
+		case name == "Subject":
+			p.msg.Subject = decodeHeader(values[0])
+			if len(values) > 1 {
+				p.drop(what, "repeated header; only the first value is kept")
+			}
+		case structuralHeaders[name]:
+		case droppedHeaders[name] != "":
+			p.drop(what, "%s", droppedHeaders[name])
+		default:
+			if p.msg.Headers == nil {
+				p.msg.Headers = make(map[string]string)
+			}
+			p.msg.Headers[name] = decodeHeader(values[0])
+			if len(values) > 1 {
+				p.drop(what, "repeated header; only the first value is kept")
+			}
+		}
+	}
+}
+
+func (p *parser) readAddresses(name, value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+	what := "header " + name
+	list, err := addressParser.ParseList(value)
+	if err != nil {
+		p.drop(what, "invalid address list: %v", err)
+		return
+	}
+
+	addresses := make([]common.SenderAddress, len(list))
+	for i, address := range list {
+		addresses[i] = senderAddress(address)
+	}
+	switch name {
+	case "From":
+		p.msg.From = addresses[0]
+		if len(addresses) > 1 {
+			p.drop(what, "only the first of %d addresses is kept", len(addresses))
+		}
+	case "Reply-To":
+		p.msg.ReplyTo = &addresses[0]
+		if len(addresses) > 1 {
+			p.drop(what, "only the first of %d addresses is kept", len(addresses))
+		}
+	case "To":
+		p.msg.To = addresses
+	case "Cc":
+		p.msg.CC = addresses
+	case "Bcc":
+		p.msg.BCC = addresses
+	}
+}
+
+// readEntity reads the body of an entity, recursing into multipart bodies.
+// path numbers the part like IMAP does: "" for the message itself, "1.2" for
+// the second part of its first part.
+func (p *parser) readEntity(header textproto.MIMEHeader, body []byte, path string) {
+	mediaType, params := contentType(header)
+	what := "message body"
+	if path != "" {
+		what = "part " + path
+	}
+	what += " (" + mediaType + ")"
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		p.readMultipart(body, params["boundary"], path, what)
+		return
+	}
+
+	data, err := decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+	if err != nil {
+		p.drop(what, "%v", err)
+		return
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	fileName := dispositionParams["filename"]
+	if fileName == "" {
+		fileName = params["name"]
+	}
+	fileName = decodeHeader(fileName)
+
+	if field := p.bodyField(mediaType); field != nil && disposition != dispositionAttachment && fileName == "" {
+		if *field != nil {
+			p.drop(what, "the message already has a %s body", mediaType)
+			return
+		}
+		text, err := decodeCharset(params["charset"], data)
+		if err != nil {
+			p.drop(what, "%v; the content is kept undecoded", err)
+		}
+		*field = &text
+		return
+	}
+
+	p.addAttachment(header, mediaType, params, disposition, fileName, data)
+}
+
+func (p *parser) readMultipart(body []byte, boundary, path, what string) {
+	if boundary == "" {
+		p.drop(what, "multipart body without a boundary")
+		return
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for i := 1; ; i++ {
+		part, err := reader.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			p.drop(what, "malformed multipart body: %v", err)
+			return
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			p.drop(what, "malformed multipart body: %v", err)
+			return
+		}
+
+		child := strconv.Itoa(i)
+		if path != "" {
+			child = path + "." + child
+		}
+		p.readEntity(part.Header, data, child)
+	}
+}
+
+// bodyField returns the Message field a part of mediaType is a body for, or
+// nil if it is not a body type
+func (p *parser) bodyField(mediaType string) **string {
+	switch mediaType {
+	case "text/plain":
+		return &p.msg.TextContent
+	case "text/html":
+		return &p.msg.HtmlContent
+	case "text/x-amp-html":
+		return &p.msg.AmpContent
+	}
+	return nil
+}
+
+func (p *parser) addAttachment(header textproto.MIMEHeader, mediaType string, params map[string]string, disposition, fileName string, data []byte) {
+	p.attachments++
+	if fileName == "" {
+		fileName = "attachment-" + strconv.Itoa(p.attachments) + extension(mediaType)
+	}
+
+	contentType := mediaType
+	if charset := params["charset"]; charset != "" {
+		contentType = mime.FormatMediaType(mediaType, map[string]string{"charset": charset})
+	}
+
+	attachment := common.Attachment{
+		Base64:             true,
+		Data:               base64.StdEncoding.EncodeToString(data),
+		ContentType:        contentType,
+		ContentDisposition: dispositionAttachment,
+		FileName:           fileName,
+	}
+	// Only parts with a Content-ID can be referenced from the HTML body, so
+	// inline parts without one are sent as regular attachments
+	if contentID := strings.Trim(strings.TrimSpace(header.Get("Content-Id")), "<>"); contentID != "" && disposition != dispositionAttachment {
+		wrapped := "<" + contentID + ">"
+		attachment.ContentDisposition = dispositionInline
+		attachment.ContentID = &wrapped
+	}
+	p.msg.Attachments = append(p.msg.Attachments, attachment)
+}
+
+// contentType returns the media type and parameters of an entity. RFC 2045
+// makes text/plain the default, and unparseable types are treated as opaque
+// data.
+func contentType(header textproto.MIMEHeader) (string, map[string]string) {
+	value := header.Get("Content-Type")
+	if value == "" {
+		return "text/plain", map[string]string{}
+	}
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil && !errors.Is(err, mime.ErrInvalidMediaParameter) {
+		return "application/octet-stream", map[string]string{}
+	}
+	return strings.ToLower(mediaType), params
+}
+
+func decodeTransferEncoding(encoding string, body []byte) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "7bit", "8bit", "binary":
+		return body, nil
+	case "quoted-printable":
+		data, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted-printable content: %w", err)
+		}
+		return data, nil
+	case "base64":
+		compact := strings.Join(strings.Fields(string(body)), "")
+		data, err := base64.StdEncoding.DecodeString(compact)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(compact, "="))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 content: %w", err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unsupported Content-Transfer-Encoding %q", encoding)
+}
+
+// decodeHeader decodes RFC 2047 encoded words, keeping the value as it is
+// when they are malformed
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// extension returns a file extension for attachments of mediaType that have
+// no file name
+func extension(mediaType string) string {
+	if mediaType == "message/rfc822" {
+		return ".eml"
+	}
+	if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ".bin"
+}
+
+func senderAddress(address *mail.Address) common.SenderAddress {
+	sender := common.SenderAddress{Email: address.Address}
+	if address.Name != "" {
+		name := address.Name
+		sender.Name = &name
+	}
+	return sender
+}
+
+// MessageRequest returns m as a CreateMessageRequest, with the To addresses
+// as recipients. The returned drops are m.Dropped plus any Cc and Bcc
+// recipients, which CreateMessageRequest cannot carry.
+func (m *Message) MessageRequest() (requests.CreateMessageRequest, []Drop) {
+	request := requests.CreateMessageRequest{
+		From:        m.From,
+		Subject:     m.Subject,
+		ReplyTo:     m.ReplyTo,
+		TextContent: m.TextContent,
+		HtmlContent: m.HtmlContent,
+		AmpContent:  m.AmpContent,
+		Attachments: m.Attachments,
+		Headers:     copyHeaders(m.Headers),
+	}
+	for _, to := range m.To {
+		request.Recipients = append(request.Recipients, common.Recipient{Email: to.Email, Name: to.Name})
+	}
+
+	dropped := append([]Drop(nil), m.Dropped...)
+	if len(m.CC) > 0 {
+		dropped = append(dropped, Drop{What: "header Cc", Reason: "CreateMessageRequest has no Cc recipients; use ConversationRequest"})
+	}
+	if len(m.BCC) > 0 {
+		dropped = append(dropped, Drop{What: "header Bcc", Reason: "CreateMessageRequest has no Bcc recipients; use ConversationRequest"})
+	}
+	return request, dropped
+}
+
+// ConversationRequest returns m as a CreateConversationMessageRequest, which
+// keeps the To, Cc and Bcc recipients apart. The returned drops are
+// m.Dropped.
+func (m *Message) ConversationRequest() (requests.CreateConversationMessageRequest, []Drop) {
+	return requests.CreateConversationMessageRequest{
+		From:        m.From,
+		To:          m.To,
+		CC:          m.CC,
+		BCC:         m.BCC,
+		Subject:     m.Subject,
+		ReplyTo:     m.ReplyTo,
+		TextContent: m.TextContent,
+		HtmlContent: m.HtmlContent,
+		AmpContent:  m.AmpContent,
+		Attachments: m.Attachments,
+		Headers:     copyHeaders(m.Headers),
+	}, append([]Drop(nil), m.Dropped...)
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(headers))
+	for name, value := range headers {
+		copied[name] = value
+	}
+	return copied
+}
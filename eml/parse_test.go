@@ -0,0 +1,189 @@
+package eml
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crlf turns the line endings of a literal message into CRLF
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.TrimLeft(s, "\n"), "\n", "\r\n")
+}
+
+var legacyMessage = crlf(`
+Received: from mail.example.com by mx.example.net; Tue, 1 Sep 2026 10:00:00 +0000
+DKIM-Signature: v=1; a=rsa-sha256; d=example.com; b=abc
+Date: Tue, 1 Sep 2026 10:00:00 +0000
+Message-ID: <legacy-1@example.com>
+MIME-Version: 1.0
+From: =?UTF-8?Q?J=C3=BCrgen_Shop?= <shop@example.com>
+To: "Customer" <customer@example.com>, other@example.com
+Cc: cc@example.com
+Reply-To: support@example.com
+Subject: =?UTF-8?B?WW91ciBvcmRlciDinJM=?=
+X-Campaign: =?ISO-8859-1?Q?caf=E9?=
+List-Unsubscribe: <https://example.com/unsubscribe>
+Content-Type: multipart/mixed; boundary="mixed"
+
+--mixed
+Content-Type: multipart/related; boundary="related"
+
+--related
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Thanks for your order =E2=9C=93 =
+see you soon
+--alt
+Content-Type: text/html; charset=iso-8859-1
+Content-Transfer-Encoding: base64
+
+PHA+Q2Fm6Txicj48aW1nIHNyYz0iY2lkOmxvZ29AZXhhbXBsZS5jb20iPjwvcD4=
+--alt--
+--related
+Content-Type: image/png
+Content-Transfer-Encoding: base64
+Content-ID: <logo@example.com>
+
+iVBORw0KGgo=
+--related--
+--mixed
+Content-Type: text/plain; charset=utf-8
+
+A second text part
+--mixed
+Content-Type: application/pdf
+Content-Disposition: attachment; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf
+Content-Transfer-Encoding: base64
+
+JVBERi0xLjQK
+--mixed
+Content-Type: text/csv; name="orders.csv"
+
+id,total
+--mixed
+Content-Type: application/octet-stream
+Content-Transfer-Encoding: x-uuencode
+
+begin 644 data
+--mixed--
+`)
+
+func TestParse(t *testing.T) {
+	msg, err := Parse(strings.NewReader(legacyMessage))
+	require.NoError(t, err)
+
+	assert.Equal(t, "shop@example.com", msg.From.Email)
+	require.NotNil(t, msg.From.Name)
+	assert.Equal(t, "Jürgen Shop", *msg.From.Name)
+	require.Len(t, msg.To, 2)
+	assert.Equal(t, "Customer", *msg.To[0].Name)
+	assert.Equal(t, "other@example.com", msg.To[1].Email)
+	assert.Nil(t, msg.To[1].Name)
+	require.Len(t, msg.CC, 1)
+	require.NotNil(t, msg.ReplyTo)
+	assert.Equal(t, "support@example.com", msg.ReplyTo.Email)
+	assert.Equal(t, "Your order ✓", msg.Subject)
+
+	require.NotNil(t, msg.TextContent)
+	assert.Equal(t, "Thanks for your order ✓ see you soon", *msg.TextContent)
+	require.NotNil(t, msg.HtmlContent)
+	assert.Equal(t, `<p>Café<br><img src="cid:logo@example.com"></p>`, *msg.HtmlContent)
+	assert.Nil(t, msg.AmpContent)
+
+	assert.Equal(t, map[string]string{
+		"X-Campaign":       "café",
+		"List-Unsubscribe": "<https://example.com/unsubscribe>",
+	}, msg.Headers)
+
+	require.Len(t, msg.Attachments, 3)
+	logo := msg.Attachments[0]
+	assert.Equal(t, "inline", logo.ContentDisposition)
+	require.NotNil(t, logo.ContentID)
+	assert.Equal(t, "<logo@example.com>", *logo.ContentID)
+	assert.Equal(t, "image/png", logo.ContentType)
+	assert.Equal(t, "attachment-1.png", logo.FileName)
+	assert.True(t, logo.Base64)
+	data, err := base64.StdEncoding.DecodeString(logo.Data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\x89PNG\r\n\x1a\n"), data)
+
+	pdf := msg.Attachments[1]
+	assert.Equal(t, "résumé.pdf", pdf.FileName)
+	assert.Equal(t, "attachment", pdf.ContentDisposition)
+	assert.Nil(t, pdf.ContentID)
+	data, err = base64.StdEncoding.DecodeString(pdf.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4\n", string(data))
+
+	csv := msg.Attachments[2]
+	assert.Equal(t, "orders.csv", csv.FileName)
+	assert.Equal(t, "text/csv", csv.ContentType)
+
+	var dropped []string
+	for _, drop := range msg.Dropped {
+		dropped = append(dropped, drop.What)
+	}
+	assert.Equal(t, []string{
+		"header Date",
+		"header Dkim-Signature",
+		"header Message-Id",
+		"header Received",
+		"part 2 (text/plain)",
+		"part 5 (application/octet-stream)",
+	}, dropped)
+}
+
+func TestParseSinglePart(t *testing.T) {
+	msg, err := Parse(strings.NewReader(crlf(`
+From: shop@example.com
+To: customer@example.com
+Subject: Plain
+Content-Type: text/html; charset=koi8-r
+
+<p>Hello</p>
+`)))
+	require.NoError(t, err)
+	assert.Nil(t, msg.TextContent)
+	require.NotNil(t, msg.HtmlContent)
+	assert.Equal(t, "<p>Hello</p>\r\n", *msg.HtmlContent)
+	require.Len(t, msg.Dropped, 1)
+	assert.Equal(t, `message body (text/html): unsupported charset "koi8-r"; the content is kept undecoded`, msg.Dropped[0].String())
+}
+
+func TestParseMalformed(t *testing.T) {
+	_, err := Parse(strings.NewReader("not a message"))
+	assert.Error(t, err)
+}
+
+func TestMessageRequests(t *testing.T) {
+	msg, err := Parse(strings.NewReader(legacyMessage))
+	require.NoError(t, err)
+
+	request, dropped := msg.MessageRequest()
+	require.Len(t, request.Recipients, 2)
+	assert.Equal(t, "customer@example.com", request.Recipients[0].Email)
+	assert.Equal(t, "Your order ✓", request.Subject)
+	assert.Equal(t, msg.TextContent, request.TextContent)
+	assert.Len(t, request.Attachments, 3)
+	assert.Equal(t, "café", request.Headers["X-Campaign"])
+	require.Len(t, dropped, len(msg.Dropped)+1)
+	assert.Equal(t, "header Cc", dropped[len(dropped)-1].What)
+
+	conversation, dropped := msg.ConversationRequest()
+	assert.Len(t, conversation.To, 2)
+	assert.Len(t, conversation.CC, 1)
+	assert.Equal(t, "support@example.com", conversation.ReplyTo.Email)
+	assert.Equal(t, msg.Dropped, dropped)
+
+	// The requests do not share the headers map with the message
+	request.Headers["X-Campaign"] = "changed"
+	assert.Equal(t, "café", msg.Headers["X-Campaign"])
+}
@@ -152,7 +152,7 @@ func getBounceStats(ctx context.Context, client *api.APIClient, accountID uuid.U
 
 	if httpResp.StatusCode >= 200 && httpResp.StatusCode < 300 && response.Data != nil && len(response.Data) > 0 {
 		var totalBounces int
-		bouncesByClassification := make(map[string]int)
+		bouncesByClassification := make(map[common.BounceClassification]int)
 
 		for _, stat := range response.Data {
 			for _, bounce := range stat.Bounces {
@@ -352,9 +352,9 @@ func calculatePercentage(value, total int) float64 {
 	return float64(value) / float64(total) * 100
 }
 
-func getStringValue(ptr *string) string {
+func getStringValue[T ~string](ptr *T) string {
 	if ptr != nil {
-		return *ptr
+		return string(*ptr)
 	}
 	return "N/A"
 }
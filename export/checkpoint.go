@@ -0,0 +1,131 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+)
+
+// checkpoint records the progress of an export
+type checkpoint struct {
+	// Filter identifies the export: its filters, format and columns
+	Filter string `json:"filter"`
+	// From and To are the time range of the export
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Window is the window length in nanoseconds
+	Window time.Duration `json:"window"`
+	// Completed holds the start of every window written so far
+	Completed []time.Time `json:"completed"`
+	// Offset is the number of bytes written to the output so far
+	Offset int64 `json:"offset"`
+}
+
+func (c *checkpoint) completed(window Window) bool {
+	for _, start := range c.Completed {
+		if start.Equal(window.From) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterKey identifies an export by everything that affects its output
+// except the time range, which the checkpoint stores on its own
+func (e *Exporter) filterKey(params requests.GetMessagesParams) (string, error) {
+	params.FromTime = nil
+	params.ToTime = nil
+	data, err := json.Marshal(struct {
+		Params  requests.GetMessagesParams
+		Format  Format
+		Columns []string
+	}{params, e.opts.Format, e.opts.Columns})
+	if err != nil {
+		return "", fmt.Errorf("export: encoding filter: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// loadCheckpoint returns the checkpoint of the export of params, or a new one
+// when there is none. A checkpoint for a different export is an error.
+func (e *Exporter) loadCheckpoint(params requests.GetMessagesParams) (*checkpoint, error) {
+	if params.FromTime == nil {
+		return nil, ErrNoTimeRange
+	}
+	filter, err := e.filterKey(params)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh := &checkpoint{Filter: filter, From: *params.FromTime, Window: e.opts.Window}
+	if params.ToTime != nil {
+		fresh.To = *params.ToTime
+	} else {
+		fresh.To = time.Now().UTC()
+	}
+	if e.opts.CheckpointFile == "" {
+		return fresh, nil
+	}
+
+	data, err := os.ReadFile(e.opts.CheckpointFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return fresh, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("export: reading checkpoint: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("export: reading checkpoint: %w", err)
+	}
+
+	// Without a ToTime the range ends when the export first started
+	sameTo := params.ToTime == nil || cp.To.Equal(*params.ToTime)
+	if cp.Filter != filter || !cp.From.Equal(fresh.From) || !sameTo || cp.Window != fresh.Window {
+		return nil, fmt.Errorf("export: checkpoint %s belongs to a different export", e.opts.CheckpointFile)
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint records cp with the output written up to offset. The file
+// is replaced atomically, so a crash leaves the previous checkpoint.
+func (e *Exporter) saveCheckpoint(cp *checkpoint, offset int64) error {
+	cp.Offset = offset
+	if e.opts.CheckpointFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("export: encoding checkpoint: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(e.opts.CheckpointFile), ".tmp-checkpoint-*")
+	if err != nil {
+		return fmt.Errorf("export: writing checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("export: writing checkpoint: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("export: syncing checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("export: writing checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), e.opts.CheckpointFile); err != nil {
+		return fmt.Errorf("export: writing checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"syscall"
+
+	"github.com/AhaSend/ahasend-go/models/responses"
+)
+
+// DefaultColumns are the columns of a CSV export that does not select any
+var DefaultColumns = []string{
+	"id",
+	"message_id",
+	"created_at",
+	"sent_at",
+	"delivered_at",
+	"sender",
+	"recipient",
+	"subject",
+	"status",
+	"direction",
+	"tags",
+	"num_attempts",
+	"bounce_classification",
+	"open_count",
+	"click_count",
+}
+
+// knownColumns are the JSON names of the fields of responses.Message
+var knownColumns = func() map[string]bool {
+	columns := make(map[string]bool)
+	t := reflect.TypeOf(responses.Message{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			columns[name] = true
+		}
+	}
+	return columns
+}()
+
+// newColumns checks the selected columns. A JSONL export without columns
+// writes whole messages, which nil stands for.
+func newColumns(format Format, columns []string) ([]string, error) {
+	switch format {
+	case FormatJSONL, FormatCSV:
+	default:
+		return nil, fmt.Errorf("export: unknown format %q", format)
+	}
+
+	seen := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if !knownColumns[column] {
+			return nil, fmt.Errorf("export: unknown column %q", column)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("export: column %q selected twice", column)
+		}
+		seen[column] = true
+	}
+	return columns, nil
+}
+
+// output counts the bytes written to the export's writer
+type output struct {
+	w       io.Writer
+	written int64
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	n, err := o.w.Write(p)
+	o.written += int64(n)
+	return n, err
+}
+
+// sync commits the output to stable storage when the writer supports it, so
+// that a checkpoint never records more than was stored. Pipes and devices
+// cannot be synced and are skipped.
+func (o *output) sync() error {
+	if s, ok := o.w.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil && !notRegularFile(err) {
+			return fmt.Errorf("export: syncing output: %w", err)
+		}
+	}
+	return nil
+}
+
+// truncater is implemented by *os.File
+type truncater interface {
+	Truncate(size int64) error
+	Seek(offset int64, whence int) (int64, error)
+}
+
+// prepareOutput cuts w back to offset when resuming from a checkpoint,
+// dropping anything written after it. A fresh export (offset 0) never touches
+// w, and writers that cannot seek, such as pipes, are appended to.
+func prepareOutput(w io.Writer, offset int64) (*output, error) {
+	t, ok := w.(truncater)
+	if !ok || offset == 0 {
+		return &output{w: w, written: offset}, nil
+	}
+	if err := t.Truncate(offset); err != nil {
+		if notRegularFile(err) {
+			return &output{w: w, written: offset}, nil
+		}
+		return nil, fmt.Errorf("export: truncating output: %w", err)
+	}
+	if _, err := t.Seek(offset, io.SeekStart); err != nil {
+		if notRegularFile(err) {
+			return &output{w: w, written: offset}, nil
+		}
+		return nil, fmt.Errorf("export: seeking output: %w", err)
+	}
+	return &output{w: w, written: offset}, nil
+}
+
+// notRegularFile reports whether err is what a pipe or character device, such
+// as stdout, returns from Truncate, Seek or Sync
+func notRegularFile(err error) bool {
+	return errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ESPIPE)
+}
+
+// rowWriter writes messages as rows of the selected columns
+type rowWriter struct {
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	columns []string
+}
+
+func newRowWriter(out *output, format Format, columns []string) *rowWriter {
+	buf := bufio.NewWriter(out)
+	r := &rowWriter{buf: buf, columns: columns}
+	if format == FormatCSV {
+		r.csv = csv.NewWriter(buf)
+	}
+	return r
+}
+
+// header writes the CSV header row
+func (r *rowWriter) header() error {
+	if r.csv == nil {
+		return nil
+	}
+	if err := r.csv.Write(r.columns); err != nil {
+		return fmt.Errorf("export: writing header: %w", err)
+	}
+	return r.flush()
+}
+
+func (r *rowWriter) write(msg responses.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("export: encoding message %s: %w", msg.ID, err)
+	}
+
+	if r.csv == nil && r.columns == nil {
+		data = append(data, '\n')
+		if _, err := r.buf.Write(data); err != nil {
+			return fmt.Errorf("export: writing message %s: %w", msg.ID, err)
+		}
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("export: encoding message %s: %w", msg.ID, err)
+	}
+
+	if r.csv != nil {
+		record := make([]string, len(r.columns))
+		for i, column := range r.columns {
+			record[i] = cell(fields[column])
+		}
+		if err := r.csv.Write(record); err != nil {
+			return fmt.Errorf("export: writing message %s: %w", msg.ID, err)
+		}
+		return nil
+	}
+
+	// Write the selected fields in the selected order
+	var line bytes.Buffer
+	line.WriteByte('{')
+	for i, column := range r.columns {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		value := fields[column]
+		if value == nil {
+			value = json.RawMessage("null")
+		}
+		fmt.Fprintf(&line, "%q:%s", column, value)
+	}
+	line.WriteString("}\n")
+	if _, err := r.buf.Write(line.Bytes()); err != nil {
+		return fmt.Errorf("export: writing message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+func (r *rowWriter) flush() error {
+	if r.csv != nil {
+		r.csv.Flush()
+		if err := r.csv.Error(); err != nil {
+			return fmt.Errorf("export: writing output: %w", err)
+		}
+	}
+	if err := r.buf.Flush(); err != nil {
+		return fmt.Errorf("export: writing output: %w", err)
+	}
+	return nil
+}
+
+// cell formats a JSON value for CSV: strings without quotes, lists of
+// strings joined with ";", missing and null values as empty cells and
+// anything else as JSON
+func cell(value json.RawMessage) string {
+	if len(value) == 0 || string(value) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	var list []string
+	if json.Unmarshal(value, &list) == nil {
+		return strings.Join(list, ";")
+	}
+	return string(value)
+}
@@ -0,0 +1,303 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// Format is the output format of an export
+type Format string
+
+// Output formats
+const (
+	// FormatJSONL writes one JSON object per line
+	FormatJSONL Format = "jsonl"
+	// FormatCSV writes a header row followed by one row per message
+	FormatCSV Format = "csv"
+)
+
+// ErrNoTimeRange is returned by Export when the filter has no FromTime
+var ErrNoTimeRange = errors.New("export: FromTime is required")
+
+// Options configures an Exporter
+type Options struct {
+	// Format defaults to FormatJSONL
+	Format Format
+
+	// Columns selects the message fields to write, by their JSON names, such
+	// as "id", "created_at" or "status". CSV exports default to
+	// DefaultColumns; JSONL exports write whole messages by default.
+	Columns []string
+
+	// Window is the length of the time windows the range is split into.
+	// Defaults to 24 hours.
+	Window time.Duration
+
+	// Concurrency is how many windows are fetched at once. Defaults to 4.
+	// Requests still go through the client's rate limiter.
+	Concurrency int
+
+	// CheckpointFile records the windows already written, so that an
+	// interrupted export can resume (optional)
+	CheckpointFile string
+
+	// RequestOptions are applied to every GetMessages call
+	RequestOptions []api.RequestOption
+}
+
+// Result summarizes an export
+type Result struct {
+	// Windows is the number of windows the range was split into
+	Windows int
+	// Resumed is the number of windows skipped because the checkpoint
+	// recorded them as written by an earlier run
+	Resumed int
+	// Rows is the number of rows written by this run, not counting the CSV
+	// header
+	Rows int
+	// Duplicates is the number of messages that were listed more than once
+	// and written once
+	Duplicates int
+}
+
+// Window is a half-open time range [From, To) of message creation times
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// contains reports whether t falls within w
+func (w Window) contains(t time.Time) bool {
+	return !t.Before(w.From) && t.Before(w.To)
+}
+
+// Exporter exports the messages of an account. It is safe for concurrent
+// use, but concurrent exports must not share a checkpoint file.
+type Exporter struct {
+	client    *api.APIClient
+	accountID uuid.UUID
+	opts      Options
+}
+
+// New returns an Exporter for the messages of accountID
+func New(client *api.APIClient, accountID uuid.UUID, opts Options) *Exporter {
+	if opts.Format == "" {
+		opts.Format = FormatJSONL
+	}
+	if opts.Format == FormatCSV && len(opts.Columns) == 0 {
+		opts.Columns = DefaultColumns
+	}
+	if opts.Window <= 0 {
+		opts.Window = 24 * time.Hour
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	return &Exporter{client: client, accountID: accountID, opts: opts}
+}
+
+// Windows returns the windows params' time range is split into. A missing
+// ToTime means now.
+func (e *Exporter) Windows(params requests.GetMessagesParams) ([]Window, error) {
+	if params.FromTime == nil {
+		return nil, ErrNoTimeRange
+	}
+	to := time.Now().UTC()
+	if params.ToTime != nil {
+		to = *params.ToTime
+	}
+	if !params.FromTime.Before(to) {
+		return nil, fmt.Errorf("export: FromTime %s is not before ToTime %s", params.FromTime.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	return splitWindows(*params.FromTime, to, e.opts.Window), nil
+}
+
+func splitWindows(from, to time.Time, size time.Duration) []Window {
+	var windows []Window
+	for start := from; start.Before(to); start = start.Add(size) {
+		end := start.Add(size)
+		if end.After(to) {
+			end = to
+		}
+		windows = append(windows, Window{From: start, To: end})
+	}
+	return windows
+}
+
+// windowResult is a fetched window, or the error fetching it
+type windowResult struct {
+	window   Window
+	messages []responses.Message
+	err      error
+}
+
+// Export writes the messages matching params to w. params must have a
+// FromTime; a missing ToTime means now, and is fixed in the checkpoint so
+// that a resumed export covers the same range.
+//
+// When resuming from a checkpoint and w is an *os.File, or anything else that
+// can be truncated and seeked, it is truncated to what the checkpoint records
+// as written before the export starts, which drops rows of a window that was
+// interrupted while it was being written. A fresh export never truncates or
+// seeks w, and pipes and other writers are appended to.
+func (e *Exporter) Export(ctx context.Context, params requests.GetMessagesParams, w io.Writer) (Result, error) {
+	var result Result
+
+	cols, err := newColumns(e.opts.Format, e.opts.Columns)
+	if err != nil {
+		return result, err
+	}
+
+	cp, err := e.loadCheckpoint(params)
+	if err != nil {
+		return result, err
+	}
+	params.ToTime = &cp.To
+
+	windows, err := e.Windows(params)
+	if err != nil {
+		return result, err
+	}
+	result.Windows = len(windows)
+
+	out, err := prepareOutput(w, cp.Offset)
+	if err != nil {
+		return result, err
+	}
+	rows := newRowWriter(out, e.opts.Format, cols)
+	if cp.Offset == 0 {
+		if err := rows.header(); err != nil {
+			return result, err
+		}
+		if err := e.saveCheckpoint(cp, out.written); err != nil {
+			return result, err
+		}
+	}
+
+	var pending []Window
+	for _, window := range windows {
+		if cp.completed(window) {
+			result.Resumed++
+			continue
+		}
+		pending = append(pending, window)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := e.fetchWindows(ctx, params, pending)
+
+	seen := make(map[uuid.UUID]bool)
+	for res := range results {
+		if err != nil {
+			continue // Drain the workers
+		}
+		if res.err != nil {
+			err = res.err
+			cancel()
+			continue
+		}
+
+		for _, msg := range res.messages {
+			if seen[msg.ID] {
+				result.Duplicates++
+				continue
+			}
+			seen[msg.ID] = true
+			if err = rows.write(msg); err != nil {
+				break
+			}
+			result.Rows++
+		}
+		if err == nil {
+			err = rows.flush()
+		}
+		if err == nil {
+			err = out.sync()
+		}
+		if err == nil {
+			cp.Completed = append(cp.Completed, res.window.From)
+			err = e.saveCheckpoint(cp, out.written)
+		}
+		if err != nil {
+			cancel()
+		}
+	}
+	if err == nil {
+		// The workers also stop when the caller's context ends
+		err = ctx.Err()
+	}
+	return result, err
+}
+
+// fetchWindows fetches windows with e.opts.Concurrency workers and returns
+// their results, in the order they complete. The channel is closed once
+// every window is fetched or ctx is cancelled.
+func (e *Exporter) fetchWindows(ctx context.Context, params requests.GetMessagesParams, windows []Window) <-chan windowResult {
+	jobs := make(chan Window)
+	results := make(chan windowResult)
+
+	go func() {
+		defer close(jobs)
+		for _, window := range windows {
+			select {
+			case jobs <- window:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for window := range jobs {
+				messages, err := e.fetchWindow(ctx, params, window)
+				select {
+				case results <- windowResult{window: window, messages: messages, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// fetchWindow lists the messages created within window, oldest first
+func (e *Exporter) fetchWindow(ctx context.Context, params requests.GetMessagesParams, window Window) ([]responses.Message, error) {
+	params.FromTime = &window.From
+	params.ToTime = &window.To
+
+	var messages []responses.Message
+	err := e.client.MessagesAPI.GetMessagesPager(ctx, e.accountID, params, e.opts.RequestOptions...).ForEach(func(msg responses.Message) error {
+		// Whether the API's bounds are inclusive does not matter: each
+		// message belongs to the one window that contains its creation time
+		if window.contains(msg.CreatedAt) {
+			messages = append(messages, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("export: listing messages from %s to %s: %w", window.From.Format(time.RFC3339), window.To.Format(time.RFC3339), err)
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+	return messages, nil
+}
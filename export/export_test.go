@@ -0,0 +1,250 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/ahasendtest"
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestServer returns a server holding ten messages, created an hour apart
+// from start and tagged "even" or "odd" by their hour
+func newTestServer(t *testing.T) (*ahasendtest.Server, *api.APIClient) {
+	t.Helper()
+
+	var mu sync.Mutex
+	now := start
+	server := ahasendtest.NewServer(ahasendtest.WithClock(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}))
+	t.Cleanup(server.Close)
+	client := server.Client(api.WithRetryConfig(api.RetryConfig{Enabled: false}))
+
+	for hour := 0; hour < 10; hour++ {
+		mu.Lock()
+		now = start.Add(time.Duration(hour) * time.Hour)
+		mu.Unlock()
+		tag := "even"
+		if hour%2 == 1 {
+			tag = "odd"
+		}
+		_, _, err := client.MessagesAPI.CreateMessage(context.Background(), server.AccountID(), requests.CreateMessageRequest{
+			From:        common.SenderAddress{Email: "shop@example.com"},
+			Recipients:  []common.Recipient{{Email: fmt.Sprintf("customer%d@example.com", hour)}},
+			Subject:     fmt.Sprintf("Message %d", hour),
+			TextContent: ahasend.String("Hello"),
+			Tags:        []string{tag, "all"},
+		})
+		require.NoError(t, err)
+	}
+	return server, client
+}
+
+func timeRange() requests.GetMessagesParams {
+	from, to := start, start.Add(10*time.Hour)
+	return requests.GetMessagesParams{FromTime: &from, ToTime: &to}
+}
+
+func TestExportJSONL(t *testing.T) {
+	server, client := newTestServer(t)
+
+	var buf bytes.Buffer
+	exporter := New(client, server.AccountID(), Options{Window: 3 * time.Hour})
+	result, err := exporter.Export(context.Background(), timeRange(), &buf)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Windows: 4, Rows: 10}, result)
+
+	subjects := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var msg responses.Message
+		require.NoError(t, json.Unmarshal([]byte(line), &msg))
+		subjects[msg.Subject] = true
+	}
+	assert.Len(t, subjects, 10)
+}
+
+func TestExportCSVColumnsAndFilters(t *testing.T) {
+	server, client := newTestServer(t)
+
+	params := timeRange()
+	params.Tags = []string{"odd"}
+	var buf bytes.Buffer
+	exporter := New(client, server.AccountID(), Options{
+		Format:      FormatCSV,
+		Columns:     []string{"subject", "tags", "bounce_classification"},
+		Window:      4 * time.Hour,
+		Concurrency: 1,
+	})
+	result, err := exporter.Export(context.Background(), params, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Rows)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"subject", "tags", "bounce_classification"},
+		{"Message 1", "odd;all", ""},
+		{"Message 3", "odd;all", ""},
+		{"Message 5", "odd;all", ""},
+		{"Message 7", "odd;all", ""},
+		{"Message 9", "odd;all", ""},
+	}, records)
+}
+
+func TestExportJSONLColumns(t *testing.T) {
+	server, client := newTestServer(t)
+
+	var buf bytes.Buffer
+	exporter := New(client, server.AccountID(), Options{Columns: []string{"recipient", "sent_at"}, Window: 24 * time.Hour})
+	_, err := exporter.Export(context.Background(), timeRange(), &buf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(buf.String(), `{"recipient":"customer0@example.com","sent_at":"2026-09-01T00:00:00Z"}`+"\n"), buf.String())
+}
+
+func TestExportResumesFromCheckpoint(t *testing.T) {
+	server, client := newTestServer(t)
+	dir := t.TempDir()
+	opts := Options{Window: 3 * time.Hour, Concurrency: 1, CheckpointFile: filepath.Join(dir, "export.checkpoint")}
+
+	f, err := os.OpenFile(filepath.Join(dir, "export.jsonl"), os.O_CREATE|os.O_RDWR, 0o644)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = New(client, server.AccountID(), opts).Export(context.Background(), timeRange(), f)
+	require.NoError(t, err)
+	complete, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+
+	// Pretend the export died while writing the last window, which holds the
+	// last message
+	data, err := os.ReadFile(opts.CheckpointFile)
+	require.NoError(t, err)
+	var cp checkpoint
+	require.NoError(t, json.Unmarshal(data, &cp))
+	require.Len(t, cp.Completed, 4)
+	lines := strings.SplitAfter(string(complete), "\n")
+	cp.Completed = cp.Completed[:3]
+	cp.Offset -= int64(len(lines[len(lines)-2]))
+	data, err = json.Marshal(cp)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(opts.CheckpointFile, data, 0o644))
+	_, err = f.Seek(cp.Offset, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"id":"half a row`)
+	require.NoError(t, err)
+
+	result, err := New(client, server.AccountID(), opts).Export(context.Background(), timeRange(), f)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Windows: 4, Resumed: 3, Rows: 1}, result)
+	resumed, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, string(complete), string(resumed))
+
+	// A checkpoint cannot be reused for another export
+	params := timeRange()
+	params.Tags = []string{"odd"}
+	_, err = New(client, server.AccountID(), opts).Export(context.Background(), params, f)
+	assert.ErrorContains(t, err, "different export")
+}
+
+func TestExportWithoutCheckpointLeavesOutputAlone(t *testing.T) {
+	server, client := newTestServer(t)
+	exporter := New(client, server.AccountID(), Options{Window: 3 * time.Hour})
+
+	// A file opened for append keeps what it already holds
+	f, err := os.OpenFile(filepath.Join(t.TempDir(), "export.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString("earlier export\n")
+	require.NoError(t, err)
+	result, err := exporter.Export(context.Background(), timeRange(), f)
+	require.NoError(t, err)
+	assert.Equal(t, 10, result.Rows)
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 11)
+	assert.Equal(t, "earlier export", lines[0])
+
+	// A pipe cannot be truncated or seeked
+	r, pw, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	read := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		read <- data
+	}()
+	result, err = exporter.Export(context.Background(), timeRange(), pw)
+	pw.Close()
+	require.NoError(t, err)
+	assert.Equal(t, 10, result.Rows)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(<-read)), "\n"), 10)
+}
+
+func TestExportStopsOnError(t *testing.T) {
+	server, client := newTestServer(t)
+	server.FailNext(1, http.StatusInternalServerError)
+
+	var buf bytes.Buffer
+	_, err := New(client, server.AccountID(), Options{Window: time.Hour}).Export(context.Background(), timeRange(), &buf)
+	var apiErr *api.APIError
+	assert.ErrorAs(t, err, &apiErr)
+}
+
+func TestExportDeduplicates(t *testing.T) {
+	id := uuid.New()
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		message := responses.Message{ID: id, CreatedAt: start.Add(time.Minute), Subject: "Listed twice"}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(responses.PaginatedMessagesResponse{
+			Object: "list",
+			Data:   []responses.Message{message, message},
+		})
+	}))
+	defer httpServer.Close()
+	serverURL, err := url.Parse(httpServer.URL)
+	require.NoError(t, err)
+	client := api.NewAPIClient(api.WithAPIKey("aha-sk-test"), func(cfg *api.Configuration) {
+		cfg.Host = serverURL.Host
+		cfg.Scheme = serverURL.Scheme
+	})
+
+	var buf bytes.Buffer
+	result, err := New(client, uuid.New(), Options{}).Export(context.Background(), timeRange(), &buf)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Windows: 1, Rows: 1, Duplicates: 1}, result)
+}
+
+func TestExportValidatesOptions(t *testing.T) {
+	client := api.NewAPIClient()
+	_, err := New(client, uuid.New(), Options{Columns: []string{"colour"}}).Export(context.Background(), timeRange(), &bytes.Buffer{})
+	assert.ErrorContains(t, err, "unknown column")
+
+	_, err = New(client, uuid.New(), Options{}).Export(context.Background(), requests.GetMessagesParams{}, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrNoTimeRange)
+}
@@ -0,0 +1,167 @@
+package common
+
+import "strings"
+
+// MessageStatus is the status of a message, as reported by the messages
+// endpoints and used to filter them. Values the SDK does not know yet are
+// decoded as they are; IsKnown reports whether a value is one of the
+// constants below.
+type MessageStatus string
+
+// Message statuses, as listed by the status filter of GetMessages
+// (openapi.yaml, getMessages)
+const (
+	MessageStatusReceived   MessageStatus = "Received"
+	MessageStatusDeferred   MessageStatus = "Deferred"
+	MessageStatusDelivered  MessageStatus = "Delivered"
+	MessageStatusBounced    MessageStatus = "Bounced"
+	MessageStatusFailed     MessageStatus = "Failed"
+	MessageStatusSuppressed MessageStatus = "Suppressed"
+
+	// Statuses of sandbox messages, which are never sent
+	MessageStatusSandboxDelivered  MessageStatus = "Sandbox Delivered"
+	MessageStatusSandboxDeferred   MessageStatus = "Sandbox Deferred"
+	MessageStatusSandboxFailed     MessageStatus = "Sandbox Failed"
+	MessageStatusSandboxBounced    MessageStatus = "Sandbox Bounced"
+	MessageStatusSandboxSuppressed MessageStatus = "Sandbox Suppressed"
+)
+
+// Response-only message statuses. The status filter of GetMessages does not
+// accept them: the spec only has "scheduled" in the status enum of the
+// CreateMessage response, and cancelling a message (cancelMessage) is
+// described as cancelling a scheduled message. A message is reported with
+// them before its first delivery attempt and after its cancellation.
+// requests.GetMessagesParams.Validate rejects them as filters.
+const (
+	MessageStatusScheduled MessageStatus = "Scheduled"
+	MessageStatusCancelled MessageStatus = "Cancelled"
+)
+
+// messageStatuses maps the lower-case form of each known status to it
+var messageStatuses = map[string]MessageStatus{
+	"received":           MessageStatusReceived,
+	"scheduled":          MessageStatusScheduled,
+	"deferred":           MessageStatusDeferred,
+	"delivered":          MessageStatusDelivered,
+	"bounced":            MessageStatusBounced,
+	"failed":             MessageStatusFailed,
+	"suppressed":         MessageStatusSuppressed,
+	"cancelled":          MessageStatusCancelled,
+	"sandbox delivered":  MessageStatusSandboxDelivered,
+	"sandbox deferred":   MessageStatusSandboxDeferred,
+	"sandbox failed":     MessageStatusSandboxFailed,
+	"sandbox bounced":    MessageStatusSandboxBounced,
+	"sandbox suppressed": MessageStatusSandboxSuppressed,
+}
+
+// canonical returns the known status s stands for, ignoring case, or s itself
+func (s MessageStatus) canonical() MessageStatus {
+	if known, ok := messageStatuses[strings.ToLower(string(s))]; ok {
+		return known
+	}
+	return s
+}
+
+// String returns the status as the API spells it
+func (s MessageStatus) String() string {
+	return string(s)
+}
+
+// IsKnown reports whether s is one of the MessageStatus constants, ignoring
+// case
+func (s MessageStatus) IsKnown() bool {
+	_, ok := messageStatuses[strings.ToLower(string(s))]
+	return ok
+}
+
+// Is reports whether s and other are the same status, ignoring case
+func (s MessageStatus) Is(other MessageStatus) bool {
+	return s.canonical() == other.canonical()
+}
+
+// IsFilterable reports whether s can be sent in the status filter of
+// GetMessages: it is not one of the response-only statuses
+func (s MessageStatus) IsFilterable() bool {
+	switch s.canonical() {
+	case MessageStatusScheduled, MessageStatusCancelled:
+		return false
+	}
+	return true
+}
+
+// IsSandbox reports whether s is the status of a sandbox message
+func (s MessageStatus) IsSandbox() bool {
+	return strings.HasPrefix(string(s.canonical()), "Sandbox ")
+}
+
+// IsTerminal reports whether a message with status s is done: delivered,
+// bounced, failed, suppressed or cancelled. Received, scheduled and deferred
+// messages, and unknown statuses, are not.
+func (s MessageStatus) IsTerminal() bool {
+	switch s.canonical() {
+	case MessageStatusDelivered, MessageStatusCancelled, MessageStatusSandboxDelivered:
+		return true
+	}
+	return s.IsFailure()
+}
+
+// IsFailure reports whether s means the message was not delivered: bounced,
+// failed or suppressed
+func (s MessageStatus) IsFailure() bool {
+	switch s.canonical() {
+	case MessageStatusBounced, MessageStatusFailed, MessageStatusSuppressed,
+		MessageStatusSandboxBounced, MessageStatusSandboxFailed, MessageStatusSandboxSuppressed:
+		return true
+	}
+	return false
+}
+
+// IsDelivered reports whether s means the message was delivered
+func (s MessageStatus) IsDelivered() bool {
+	switch s.canonical() {
+	case MessageStatusDelivered, MessageStatusSandboxDelivered:
+		return true
+	}
+	return false
+}
+
+// JoinMessageStatuses returns statuses as the comma-separated list the status
+// filter of GetMessages takes. It does not check them;
+// requests.GetMessagesParams.Validate rejects statuses that are not
+// filterable.
+func JoinMessageStatuses(statuses []MessageStatus) string {
+	values := make([]string, len(statuses))
+	for i, status := range statuses {
+		values[i] = string(status)
+	}
+	return strings.Join(values, ",")
+}
+
+// MessageDirection is whether a message was sent or received
+type MessageDirection string
+
+// Message directions
+const (
+	MessageDirectionInbound  MessageDirection = "inbound"
+	MessageDirectionOutbound MessageDirection = "outbound"
+)
+
+// String returns the direction as the API spells it
+func (d MessageDirection) String() string {
+	return string(d)
+}
+
+// IsKnown reports whether d is one of the MessageDirection constants
+func (d MessageDirection) IsKnown() bool {
+	return d == MessageDirectionInbound || d == MessageDirectionOutbound
+}
+
+// BounceClassification is the reason AhaSend gives for a bounce. The API does
+// not document a fixed set of classifications, so values are kept as they
+// are.
+type BounceClassification string
+
+// String returns the classification as the API spells it
+func (c BounceClassification) String() string {
+	return string(c)
+}
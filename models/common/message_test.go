@@ -0,0 +1,78 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageStatusPredicates(t *testing.T) {
+	tests := []struct {
+		status                                  MessageStatus
+		known, terminal, failure, delivered, sb bool
+	}{
+		{MessageStatusReceived, true, false, false, false, false},
+		{MessageStatusScheduled, true, false, false, false, false},
+		{MessageStatusDeferred, true, false, false, false, false},
+		{MessageStatusDelivered, true, true, false, true, false},
+		{MessageStatusBounced, true, true, true, false, false},
+		{MessageStatusFailed, true, true, true, false, false},
+		{MessageStatusSuppressed, true, true, true, false, false},
+		{MessageStatusCancelled, true, true, false, false, false},
+		{MessageStatusSandboxDelivered, true, true, false, true, true},
+		{MessageStatusSandboxDeferred, true, false, false, false, true},
+		{MessageStatusSandboxBounced, true, true, true, false, true},
+		{"delivered", true, true, false, true, false},
+		{"Quarantined", false, false, false, false, false},
+		{"", false, false, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			assert.Equal(t, tt.known, tt.status.IsKnown(), "IsKnown")
+			assert.Equal(t, tt.terminal, tt.status.IsTerminal(), "IsTerminal")
+			assert.Equal(t, tt.failure, tt.status.IsFailure(), "IsFailure")
+			assert.Equal(t, tt.delivered, tt.status.IsDelivered(), "IsDelivered")
+			assert.Equal(t, tt.sb, tt.status.IsSandbox(), "IsSandbox")
+		})
+	}
+}
+
+func TestMessageStatusIs(t *testing.T) {
+	assert.True(t, MessageStatusScheduled.Is(MessageStatusScheduled))
+	assert.True(t, MessageStatus("scheduled").Is(MessageStatusScheduled))
+	assert.True(t, MessageStatusScheduled.Is("SCHEDULED"))
+	assert.False(t, MessageStatusScheduled.Is(MessageStatusCancelled))
+	assert.True(t, MessageStatus("Quarantined").Is("Quarantined"))
+	assert.False(t, MessageStatus("Quarantined").Is("quarantined"), "unknown statuses are compared as they are")
+}
+
+func TestMessageEnumsDecodeUnknownValues(t *testing.T) {
+	var decoded struct {
+		Status         MessageStatus         `json:"status"`
+		Direction      MessageDirection      `json:"direction"`
+		Classification *BounceClassification `json:"classification"`
+	}
+	data := `{"status":"Quarantined","direction":"forwarded","classification":"mailbox_full"}`
+	require.NoError(t, json.Unmarshal([]byte(data), &decoded))
+
+	assert.Equal(t, MessageStatus("Quarantined"), decoded.Status)
+	assert.False(t, decoded.Status.IsKnown())
+	assert.Equal(t, MessageDirection("forwarded"), decoded.Direction)
+	assert.False(t, decoded.Direction.IsKnown())
+	assert.True(t, MessageDirectionOutbound.IsKnown())
+	require.NotNil(t, decoded.Classification)
+	assert.Equal(t, "mailbox_full", decoded.Classification.String())
+}
+
+func TestJoinMessageStatuses(t *testing.T) {
+	assert.Equal(t, "", JoinMessageStatuses(nil))
+	assert.Equal(t, "Delivered,Sandbox Delivered",
+		JoinMessageStatuses([]MessageStatus{MessageStatusDelivered, MessageStatusSandboxDelivered}))
+
+	// Response-only statuses cannot be filtered on
+	assert.False(t, MessageStatusScheduled.IsFilterable())
+	assert.False(t, MessageStatus("cancelled").IsFilterable())
+	assert.True(t, MessageStatusSandboxBounced.IsFilterable())
+}
@@ -0,0 +1,315 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Scope is a permission granted to an API key, such as "domains:read" or
+// "messages:send:{example.com}". Resource may itself contain a colon, as in
+// "accounts:members".
+//
+// Some scopes can be restricted to a single domain (see ScopeInfo.PerDomain).
+// For those, Domain holds the domain, and an empty Domain grants the scope for
+// every domain, which the API spells with an ":all" suffix. Domain is always
+// empty for the other scopes.
+type Scope struct {
+	Resource string
+	Action   string
+	Domain   string
+}
+
+// ScopeInfo describes a scope the API knows
+type ScopeInfo struct {
+	Resource    string
+	Action      string
+	PerDomain   bool
+	Description string
+}
+
+// Scope returns the scope described by i, granted for every domain
+func (i ScopeInfo) Scope() Scope {
+	return Scope{Resource: i.Resource, Action: i.Action}
+}
+
+// Name returns the scope name without a domain, such as "messages:send"
+func (i ScopeInfo) Name() string {
+	return i.Resource + ":" + i.Action
+}
+
+// Known scopes, granted for every domain where the scope takes one. Use
+// ForDomain to restrict them to a single domain.
+var (
+	ScopeMessagesSend   = Scope{Resource: "messages", Action: "send"}
+	ScopeMessagesCancel = Scope{Resource: "messages", Action: "cancel"}
+	ScopeMessagesRead   = Scope{Resource: "messages", Action: "read"}
+
+	ScopeDomainsRead   = Scope{Resource: "domains", Action: "read"}
+	ScopeDomainsWrite  = Scope{Resource: "domains", Action: "write"}
+	ScopeDomainsDelete = Scope{Resource: "domains", Action: "delete"}
+
+	ScopeAccountsRead          = Scope{Resource: "accounts", Action: "read"}
+	ScopeAccountsWrite         = Scope{Resource: "accounts", Action: "write"}
+	ScopeAccountsBilling       = Scope{Resource: "accounts", Action: "billing"}
+	ScopeAccountsMembersRead   = Scope{Resource: "accounts:members", Action: "read"}
+	ScopeAccountsMembersAdd    = Scope{Resource: "accounts:members", Action: "add"}
+	ScopeAccountsMembersUpdate = Scope{Resource: "accounts:members", Action: "update"}
+	ScopeAccountsMembersRemove = Scope{Resource: "accounts:members", Action: "remove"}
+
+	ScopeWebhooksRead   = Scope{Resource: "webhooks", Action: "read"}
+	ScopeWebhooksWrite  = Scope{Resource: "webhooks", Action: "write"}
+	ScopeWebhooksDelete = Scope{Resource: "webhooks", Action: "delete"}
+
+	ScopeRoutesRead   = Scope{Resource: "routes", Action: "read"}
+	ScopeRoutesWrite  = Scope{Resource: "routes", Action: "write"}
+	ScopeRoutesDelete = Scope{Resource: "routes", Action: "delete"}
+
+	ScopeSuppressionsRead   = Scope{Resource: "suppressions", Action: "read"}
+	ScopeSuppressionsWrite  = Scope{Resource: "suppressions", Action: "write"}
+	ScopeSuppressionsDelete = Scope{Resource: "suppressions", Action: "delete"}
+	ScopeSuppressionsWipe   = Scope{Resource: "suppressions", Action: "wipe"}
+
+	ScopeSMTPCredentialsRead   = Scope{Resource: "smtp-credentials", Action: "read"}
+	ScopeSMTPCredentialsWrite  = Scope{Resource: "smtp-credentials", Action: "write"}
+	ScopeSMTPCredentialsDelete = Scope{Resource: "smtp-credentials", Action: "delete"}
+
+	ScopeStatisticsTransactionalRead = Scope{Resource: "statistics-transactional", Action: "read"}
+
+	ScopeAPIKeysRead   = Scope{Resource: "api-keys", Action: "read"}
+	ScopeAPIKeysWrite  = Scope{Resource: "api-keys", Action: "write"}
+	ScopeAPIKeysDelete = Scope{Resource: "api-keys", Action: "delete"}
+
+	ScopeSubAccountsRead    = Scope{Resource: "sub-accounts", Action: "read"}
+	ScopeSubAccountsWrite   = Scope{Resource: "sub-accounts", Action: "write"}
+	ScopeSubAccountsDelete  = Scope{Resource: "sub-accounts", Action: "delete"}
+	ScopeSubAccountsSuspend = Scope{Resource: "sub-accounts", Action: "suspend"}
+	ScopeSubAccountsUsage   = Scope{Resource: "sub-accounts", Action: "usage"}
+
+	ScopeSubAccountAPIKeysRead   = Scope{Resource: "sub-account-api-keys", Action: "read"}
+	ScopeSubAccountAPIKeysWrite  = Scope{Resource: "sub-account-api-keys", Action: "write"}
+	ScopeSubAccountAPIKeysDelete = Scope{Resource: "sub-account-api-keys", Action: "delete"}
+)
+
+// knownScopes is the scope catalogue of the OpenAPI specification
+var knownScopes = []ScopeInfo{
+	{"messages", "send", true, "Send messages"},
+	{"messages", "cancel", true, "Cancel messages"},
+	{"messages", "read", true, "Read messages"},
+	{"domains", "read", false, "Read all domains"},
+	{"domains", "write", false, "Create and update domains"},
+	{"domains", "delete", true, "Delete domains"},
+	{"accounts", "read", false, "Read account information"},
+	{"accounts", "write", false, "Update account settings"},
+	{"accounts", "billing", false, "Access billing information"},
+	{"accounts:members", "read", false, "Read account members"},
+	{"accounts:members", "add", false, "Add account members"},
+	{"accounts:members", "update", false, "Update account members"},
+	{"accounts:members", "remove", false, "Remove account members"},
+	{"webhooks", "read", true, "Read webhooks"},
+	{"webhooks", "write", true, "Create and update webhooks"},
+	{"webhooks", "delete", true, "Delete webhooks"},
+	{"routes", "read", true, "Read routes"},
+	{"routes", "write", true, "Create and update routes"},
+	{"routes", "delete", true, "Delete routes"},
+	{"suppressions", "read", false, "Read suppressions"},
+	{"suppressions", "write", false, "Create suppressions"},
+	{"suppressions", "delete", false, "Delete suppressions"},
+	{"suppressions", "wipe", false, "Delete all suppressions"},
+	{"smtp-credentials", "read", true, "Read SMTP credentials"},
+	{"smtp-credentials", "write", true, "Create SMTP credentials"},
+	{"smtp-credentials", "delete", true, "Delete SMTP credentials"},
+	{"statistics-transactional", "read", true, "Read transactional statistics"},
+	{"api-keys", "read", false, "Read API keys"},
+	{"api-keys", "write", false, "Create and update API keys"},
+	{"api-keys", "delete", false, "Delete API keys"},
+	{"sub-accounts", "read", false, "List and read sub accounts"},
+	{"sub-accounts", "write", false, "Create and update sub accounts"},
+	{"sub-accounts", "delete", false, "Delete sub accounts"},
+	{"sub-accounts", "suspend", false, "Suspend and unsuspend sub accounts"},
+	{"sub-accounts", "usage", false, "Read per-sub-account usage and allocated cost"},
+	{"sub-account-api-keys", "read", false, "List and read API keys owned by sub accounts"},
+	{"sub-account-api-keys", "write", false, "Create and update API keys owned by sub accounts"},
+	{"sub-account-api-keys", "delete", false, "Delete API keys owned by sub accounts"},
+}
+
+// KnownScopes returns every scope the API knows
+func KnownScopes() []ScopeInfo {
+	return append([]ScopeInfo(nil), knownScopes...)
+}
+
+// LookupScope returns what the catalogue knows about the scope named name,
+// such as "messages:send", without a domain suffix
+func LookupScope(name string) (ScopeInfo, bool) {
+	for _, info := range knownScopes {
+		if info.Name() == name {
+			return info, true
+		}
+	}
+	return ScopeInfo{}, false
+}
+
+// ParseScope parses a scope as the API spells it: "domains:read",
+// "messages:send:all" or "messages:send:{example.com}". Unknown scopes, and
+// known scopes with a missing or unexpected domain suffix, are errors.
+func ParseScope(s string) (Scope, error) {
+	if info, ok := LookupScope(s); ok {
+		if info.PerDomain {
+			return Scope{}, fmt.Errorf("scope %q needs a domain: use %q or %q", s, s+":all", s+":{example.com}")
+		}
+		return info.Scope(), nil
+	}
+
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return Scope{}, fmt.Errorf("unknown scope %q", s)
+	}
+	name, suffix := s[:i], s[i+1:]
+	if j := strings.Index(s, ":{"); j >= 0 {
+		name, suffix = s[:j], s[j+1:]
+	}
+	info, ok := LookupScope(name)
+	if !ok {
+		return Scope{}, fmt.Errorf("unknown scope %q", s)
+	}
+	if !info.PerDomain {
+		return Scope{}, fmt.Errorf("scope %q does not take a domain: use %q", s, name)
+	}
+
+	scope := info.Scope()
+	if suffix == "all" {
+		return scope, nil
+	}
+	if len(suffix) > 2 && strings.HasPrefix(suffix, "{") && strings.HasSuffix(suffix, "}") {
+		return scope.ForDomain(suffix[1 : len(suffix)-1]), nil
+	}
+	return Scope{}, fmt.Errorf("invalid domain in scope %q: use %q or %q", s, name+":all", name+":{example.com}")
+}
+
+// ParseScopes parses each scope with ParseScope
+func ParseScopes(scopes []string) ([]Scope, error) {
+	parsed := make([]Scope, len(scopes))
+	for i, s := range scopes {
+		scope, err := ParseScope(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = scope
+	}
+	return parsed, nil
+}
+
+// Name returns the scope name without a domain, such as "messages:send"
+func (s Scope) Name() string {
+	return s.Resource + ":" + s.Action
+}
+
+// Info returns what the catalogue knows about s
+func (s Scope) Info() (ScopeInfo, bool) {
+	return LookupScope(s.Name())
+}
+
+// PerDomain reports whether s can be restricted to a single domain
+func (s Scope) PerDomain() bool {
+	info, ok := s.Info()
+	return ok && info.PerDomain
+}
+
+// ForDomain returns s restricted to domain. An empty domain grants s for
+// every domain.
+func (s Scope) ForDomain(domain string) Scope {
+	s.Domain = strings.ToLower(domain)
+	return s
+}
+
+// String returns s as the API spells it
+func (s Scope) String() string {
+	switch {
+	case s.Domain != "":
+		return s.Name() + ":{" + s.Domain + "}"
+	case s.PerDomain():
+		return s.Name() + ":all"
+	default:
+		return s.Name()
+	}
+}
+
+// Validate reports whether s is a scope the API accepts
+func (s Scope) Validate() error {
+	info, ok := s.Info()
+	if !ok {
+		return fmt.Errorf("unknown scope %q", s.Name())
+	}
+	if s.Domain != "" && !info.PerDomain {
+		return fmt.Errorf("scope %q does not take a domain", s.Name())
+	}
+	if strings.ContainsAny(s.Domain, "{}:") {
+		return fmt.Errorf("invalid domain %q in scope %q", s.Domain, s.Name())
+	}
+	return nil
+}
+
+// Covers reports whether holding s is enough for a call that needs other. A
+// scope granted for every domain covers the same scope restricted to any
+// domain.
+func (s Scope) Covers(other Scope) bool {
+	if s.Resource != other.Resource || s.Action != other.Action {
+		return false
+	}
+	return s.Domain == "" || strings.EqualFold(s.Domain, other.Domain)
+}
+
+// FormatScopes returns scopes as the strings CreateAPIKeyRequest and
+// UpdateAPIKeyRequest take
+func FormatScopes(scopes ...Scope) []string {
+	formatted := make([]string, len(scopes))
+	for i, scope := range scopes {
+		formatted[i] = scope.String()
+	}
+	return formatted
+}
+
+// RestrictScopes returns scopes with each scope that can be restricted to a
+// domain replaced by one scope per domain. Other scopes are kept as they are.
+// With no domains, scopes is returned unchanged.
+func RestrictScopes(scopes []Scope, domains ...string) []Scope {
+	if len(domains) == 0 {
+		return scopes
+	}
+	var restricted []Scope
+	for _, scope := range scopes {
+		if !scope.PerDomain() {
+			restricted = append(restricted, scope)
+			continue
+		}
+		for _, domain := range domains {
+			restricted = append(restricted, scope.ForDomain(domain))
+		}
+	}
+	return SortScopes(restricted)
+}
+
+// SortScopes sorts scopes by their string form and removes duplicates,
+// including scopes restricted to a domain that another scope already covers
+// for every domain
+func SortScopes(scopes []Scope) []Scope {
+	seen := make(map[string]bool, len(scopes))
+	all := make(map[string]bool)
+	for _, scope := range scopes {
+		if scope.Domain == "" {
+			all[scope.Name()] = true
+		}
+	}
+
+	var sorted []Scope
+	for _, scope := range scopes {
+		key := scope.String()
+		if seen[key] || (scope.Domain != "" && all[scope.Name()]) {
+			continue
+		}
+		seen[key] = true
+		sorted = append(sorted, scope)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].String() < sorted[j].String() })
+	return sorted
+}
@@ -0,0 +1,94 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Scope
+	}{
+		{"domains:read", ScopeDomainsRead},
+		{"accounts:members:remove", ScopeAccountsMembersRemove},
+		{"messages:send:all", ScopeMessagesSend},
+		{"messages:send:{example.com}", ScopeMessagesSend.ForDomain("example.com")},
+		{"domains:delete:{Example.com}", ScopeDomainsDelete.ForDomain("example.com")},
+		{"statistics-transactional:read:all", ScopeStatisticsTransactionalRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			scope, err := ParseScope(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, scope)
+			assert.NoError(t, scope.Validate())
+		})
+	}
+
+	for _, in := range []string{
+		"",
+		"messages",
+		"messages:send",
+		"messages:sned:all",
+		"messages:send:example.com",
+		"messages:send:{}",
+		"domains:read:all",
+		"domains:read:{example.com}",
+		"accounts:members",
+	} {
+		t.Run("invalid "+in, func(t *testing.T) {
+			_, err := ParseScope(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestScopeString(t *testing.T) {
+	assert.Equal(t, "domains:read", ScopeDomainsRead.String())
+	assert.Equal(t, "messages:send:all", ScopeMessagesSend.String())
+	assert.Equal(t, "routes:read:{example.com}", ScopeRoutesRead.ForDomain("example.com").String())
+	assert.Equal(t, []string{"messages:read:all", "api-keys:read"}, FormatScopes(ScopeMessagesRead, ScopeAPIKeysRead))
+
+	// Every scope in the catalogue round-trips
+	for _, info := range KnownScopes() {
+		scope, err := ParseScope(info.Scope().String())
+		require.NoError(t, err)
+		assert.Equal(t, info.Scope(), scope)
+		if info.PerDomain {
+			scope, err = ParseScope(info.Scope().ForDomain("example.com").String())
+			require.NoError(t, err)
+			assert.Equal(t, "example.com", scope.Domain)
+		}
+	}
+}
+
+func TestScopeValidate(t *testing.T) {
+	assert.NoError(t, ScopeWebhooksDelete.ForDomain("example.com").Validate())
+	assert.Error(t, Scope{Resource: "webhooks", Action: "remove"}.Validate())
+	assert.Error(t, ScopeDomainsRead.ForDomain("example.com").Validate())
+	assert.Error(t, ScopeMessagesSend.ForDomain("{example.com}").Validate())
+}
+
+func TestScopeCovers(t *testing.T) {
+	assert.True(t, ScopeMessagesSend.Covers(ScopeMessagesSend.ForDomain("example.com")))
+	assert.True(t, ScopeMessagesSend.ForDomain("example.com").Covers(ScopeMessagesSend.ForDomain("EXAMPLE.com")))
+	assert.False(t, ScopeMessagesSend.ForDomain("example.com").Covers(ScopeMessagesSend))
+	assert.False(t, ScopeMessagesSend.ForDomain("example.com").Covers(ScopeMessagesSend.ForDomain("example.org")))
+	assert.False(t, ScopeMessagesSend.Covers(ScopeMessagesRead))
+}
+
+func TestRestrictAndSortScopes(t *testing.T) {
+	scopes := RestrictScopes([]Scope{ScopeMessagesSend, ScopeDomainsRead, ScopeMessagesSend}, "b.example.com", "a.example.com")
+	assert.Equal(t, []string{
+		"domains:read",
+		"messages:send:{a.example.com}",
+		"messages:send:{b.example.com}",
+	}, FormatScopes(scopes...))
+
+	// A scope for every domain makes the same scope for one domain redundant
+	sorted := SortScopes([]Scope{ScopeRoutesRead.ForDomain("example.com"), ScopeRoutesRead})
+	assert.Equal(t, []Scope{ScopeRoutesRead}, sorted)
+}
@@ -1,8 +1,12 @@
 package requests
 
+import "github.com/AhaSend/ahasend-go/models/common"
+
 // CreateAPIKeyRequest represents a request to create a new API key.
 type CreateAPIKeyRequest struct {
-	Label  string   `json:"label"`
+	Label string `json:"label"`
+	// Scopes are the scopes granted to the key, as the API spells them. Build
+	// them from common.Scope values with common.FormatScopes to avoid typos.
 	Scopes []string `json:"scopes"`
 	// IPAllowList optionally restricts the source IPs allowed to authenticate
 	// with this key. Each entry is a CIDR block (e.g. "203.0.113.0/24") or a
@@ -11,6 +15,19 @@ type CreateAPIKeyRequest struct {
 	IPAllowList []string `json:"ip_allow_list,omitempty"`
 }
 
+// Validate checks CreateAPIKeyRequest client-side constraints.
+func (r CreateAPIKeyRequest) Validate() error {
+	if err := validateRequiredString("label", r.Label, maxAPIKeyLabelLength); err != nil {
+		return err
+	}
+
+	if err := validateOptionalNonEmptyStringSlice("scopes", &r.Scopes); err != nil {
+		return err
+	}
+
+	return validateScopes(r.Scopes)
+}
+
 // UpdateAPIKeyRequest represents a request to update an existing API key.
 type UpdateAPIKeyRequest struct {
 	Label  *string   `json:"label,omitempty"`
@@ -33,5 +50,23 @@ func (r UpdateAPIKeyRequest) Validate() error {
 		return err
 	}
 
-	return validateOptionalNonEmptyStringSlice("scopes", r.Scopes)
+	if err := validateOptionalNonEmptyStringSlice("scopes", r.Scopes); err != nil {
+		return err
+	}
+
+	if r.Scopes == nil {
+		return nil
+	}
+
+	return validateScopes(*r.Scopes)
+}
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if _, err := common.ParseScope(scope); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
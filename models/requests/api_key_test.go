@@ -48,6 +48,16 @@ func TestUpdateAPIKeyRequest_Validate(t *testing.T) {
 			request: UpdateAPIKeyRequest{Scopes: &[]string{}},
 			wantErr: true,
 		},
+		{
+			name:    "unknown scope",
+			request: UpdateAPIKeyRequest{Scopes: &[]string{"messages:sned:all"}},
+			wantErr: true,
+		},
+		{
+			name:    "domain scope without domain",
+			request: UpdateAPIKeyRequest{Scopes: &[]string{"messages:send"}},
+			wantErr: true,
+		},
 		{
 			name:    "valid ip_allow_list only",
 			request: UpdateAPIKeyRequest{IPAllowList: &[]string{"203.0.113.0/24"}},
@@ -71,6 +81,51 @@ func TestUpdateAPIKeyRequest_Validate(t *testing.T) {
 	}
 }
 
+func TestCreateAPIKeyRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request CreateAPIKeyRequest
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			request: CreateAPIKeyRequest{Label: "key", Scopes: []string{"messages:send:{example.com}", "domains:read"}},
+		},
+		{
+			name:    "blank label",
+			request: CreateAPIKeyRequest{Label: " ", Scopes: []string{"domains:read"}},
+			wantErr: true,
+		},
+		{
+			name:    "no scopes",
+			request: CreateAPIKeyRequest{Label: "key"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown scope",
+			request: CreateAPIKeyRequest{Label: "key", Scopes: []string{"domain:read"}},
+			wantErr: true,
+		},
+		{
+			name:    "domain on a scope that does not take one",
+			request: CreateAPIKeyRequest{Label: "key", Scopes: []string{"domains:read:all"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
 func TestCreateAPIKeyRequest_IPAllowListMarshaling(t *testing.T) {
 	t.Run("omits ip_allow_list when empty", func(t *testing.T) {
 		data, err := json.Marshal(CreateAPIKeyRequest{Label: "key", Scopes: []string{"messages:read:all"}})
@@ -1,6 +1,8 @@
 package requests
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/AhaSend/ahasend-go/models/common"
@@ -48,6 +50,12 @@ type CreateConversationMessageRequest struct {
 }
 
 type GetMessagesParams struct {
+	// Statuses filters messages by status
+	Statuses []common.MessageStatus
+	// Status filters messages by a comma-separated list of statuses.
+	//
+	// Deprecated: Use Statuses, which the compiler checks. Status is sent
+	// together with Statuses when both are set.
 	Status          *string
 	Tags            []string
 	Sender          *string
@@ -58,3 +66,24 @@ type GetMessagesParams struct {
 	ToTime          *time.Time
 	common.PaginationParams
 }
+
+// Validate checks GetMessagesParams client-side constraints: every status
+// filtered on must be one the API accepts, as filtering on a response-only
+// status such as Scheduled would otherwise match nothing, or be dropped and
+// match everything.
+func (p GetMessagesParams) Validate() error {
+	statuses := append([]common.MessageStatus(nil), p.Statuses...)
+	if p.Status != nil {
+		for _, status := range strings.Split(*p.Status, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				statuses = append(statuses, common.MessageStatus(status))
+			}
+		}
+	}
+	for _, status := range statuses {
+		if !status.IsFilterable() {
+			return fmt.Errorf("status %q cannot be filtered on", status)
+		}
+	}
+	return nil
+}
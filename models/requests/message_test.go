@@ -0,0 +1,48 @@
+package requests
+
+import (
+	"testing"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMessagesParams_Validate(t *testing.T) {
+	status := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		params  GetMessagesParams
+		wantErr bool
+	}{
+		{
+			name: "no filter",
+		},
+		{
+			name:   "filterable statuses",
+			params: GetMessagesParams{Statuses: []common.MessageStatus{common.MessageStatusBounced, common.MessageStatusSandboxFailed}, Status: status("Deferred, Failed")},
+		},
+		{
+			name:    "scheduled only",
+			params:  GetMessagesParams{Statuses: []common.MessageStatus{common.MessageStatusScheduled}},
+			wantErr: true,
+		},
+		{
+			name:    "cancelled in the deprecated status list",
+			params:  GetMessagesParams{Status: status("Bounced,cancelled")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package responses
 import (
 	"time"
 
+	"github.com/AhaSend/ahasend-go/models/common"
 	"github.com/google/uuid"
 )
 
@@ -34,3 +35,8 @@ type APIKeyScope struct {
 	Scope     string     `json:"scope"`
 	DomainID  *uuid.UUID `json:"domain_id,omitempty"`
 }
+
+// Parsed returns the scope as a common.Scope
+func (s APIKeyScope) Parsed() (common.Scope, error) {
+	return common.ParseScope(s.Scope)
+}
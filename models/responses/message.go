@@ -27,38 +27,39 @@ type ContentAttachment struct {
 
 // Message represents an AhaSend message
 type Message struct {
-	Object               string          `json:"object"`
-	ID                   uuid.UUID       `json:"id"`
-	MessageID            string          `json:"message_id"`
-	CreatedAt            time.Time       `json:"created_at"`
-	UpdatedAt            time.Time       `json:"updated_at"`
-	SentAt               *time.Time      `json:"sent_at,omitempty"`
-	DeliveredAt          *time.Time      `json:"delivered_at,omitempty"`
-	RetainUntil          time.Time       `json:"retain_until"`
-	Subject              string          `json:"subject"`
-	Content              *string         `json:"content,omitempty"`
-	ContentParsed        *ContentParsed  `json:"content_parsed,omitempty"`
-	Tags                 []string        `json:"tags"`
-	Sender               string          `json:"sender"`
-	Recipient            string          `json:"recipient"`
-	Direction            string          `json:"direction"`
-	Status               string          `json:"status"`
-	NumAttempts          int32           `json:"num_attempts"`
-	DeliveryAttempts     []DeliveryEvent `json:"delivery_attempts"`
-	IsBounceNotification bool            `json:"is_bounce_notification"`
-	BounceClassification *string         `json:"bounce_classification,omitempty"`
-	ClickCount           int32           `json:"click_count"`
-	OpenCount            int32           `json:"open_count"`
-	ReferenceMessageID   *int64          `json:"reference_message_id,omitempty"`
-	DomainID             uuid.UUID       `json:"domain_id"`
-	AccountID            uuid.UUID       `json:"account_id"`
+	Object               string                       `json:"object"`
+	ID                   uuid.UUID                    `json:"id"`
+	MessageID            string                       `json:"message_id"`
+	CreatedAt            time.Time                    `json:"created_at"`
+	UpdatedAt            time.Time                    `json:"updated_at"`
+	SentAt               *time.Time                   `json:"sent_at,omitempty"`
+	DeliveredAt          *time.Time                   `json:"delivered_at,omitempty"`
+	RetainUntil          time.Time                    `json:"retain_until"`
+	Subject              string                       `json:"subject"`
+	Content              *string                      `json:"content,omitempty"`
+	ContentParsed        *ContentParsed               `json:"content_parsed,omitempty"`
+	Tags                 []string                     `json:"tags"`
+	Sender               string                       `json:"sender"`
+	Recipient            string                       `json:"recipient"`
+	Direction            common.MessageDirection      `json:"direction"`
+	Status               common.MessageStatus         `json:"status"`
+	NumAttempts          int32                        `json:"num_attempts"`
+	DeliveryAttempts     []DeliveryEvent              `json:"delivery_attempts"`
+	IsBounceNotification bool                         `json:"is_bounce_notification"`
+	BounceClassification *common.BounceClassification `json:"bounce_classification,omitempty"`
+	ClickCount           int32                        `json:"click_count"`
+	OpenCount            int32                        `json:"open_count"`
+	ReferenceMessageID   *int64                       `json:"reference_message_id,omitempty"`
+	DomainID             uuid.UUID                    `json:"domain_id"`
+	AccountID            uuid.UUID                    `json:"account_id"`
 }
 
 // DeliveryEvent represents a single delivery attempt for a message
 type DeliveryEvent struct {
-	Time   time.Time `json:"time"`
-	Log    string    `json:"log"`
-	Status string    `json:"status"`
+	Time time.Time `json:"time"`
+	Log  string    `json:"log"`
+	// Status is the status the message had after the attempt
+	Status common.MessageStatus `json:"status"`
 }
 
 // MessageSchedule represents scheduling information for a message
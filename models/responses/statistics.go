@@ -2,6 +2,8 @@ package responses
 
 import (
 	"time"
+
+	"github.com/AhaSend/ahasend-go/models/common"
 )
 
 // DeliverabilityStatistics represents AhaSend email deliverability statistics for a time bucket
@@ -29,8 +31,8 @@ type DeliverabilityStatisticsResponse struct {
 
 // Bounce represents a bounce classification with count data
 type Bounce struct {
-	Classification string `json:"classification"`
-	Count          int    `json:"count"`
+	Classification common.BounceClassification `json:"classification"`
+	Count          int                         `json:"count"`
 }
 
 // BounceStatistics represents AhaSend bounce statistics for a time bucket
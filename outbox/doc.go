@@ -0,0 +1,32 @@
+// Package outbox delivers CreateMessage requests at least once, even when the
+// process that decided to send them dies before AhaSend has answered.
+//
+// Enqueue persists a request, together with the idempotency key it will be
+// sent with, before returning. An Outbox then drains its store in the
+// background through MessagesAPIService.CreateMessage, retrying failures with
+// backoff, and records the message IDs AhaSend returns:
+//
+//	store, err := outbox.NewFileStore("/var/lib/myapp/outbox")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	box := outbox.New(client, store, outbox.Options{})
+//	go box.Run(ctx)
+//
+//	entry, err := box.Enqueue(ctx, accountID, request)
+//	...
+//	entry, err = box.Get(ctx, entry.ID)
+//	fmt.Println(entry.Status, entry.MessageIDs())
+//
+// Entries survive restarts: a new Outbox over the same store picks up where
+// the last one stopped. If the process dies after a request reached AhaSend
+// but before its result was stored, the request is sent again with the same
+// idempotency key, and the API replays the stored result instead of sending
+// the message twice (see IDEMPOTENCY.md). Delivery is only exactly-once while
+// the API still remembers the key, so an outbox should not be left undrained
+// for long.
+//
+// Stores implement Store. FileStore keeps one file per entry in a directory
+// and MemoryStore keeps entries in memory, for tests and for processes that
+// only need the retries. A store should be drained by one Outbox at a time.
+package outbox
@@ -0,0 +1,158 @@
+package outbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore keeps each entry in a JSON file in one directory. Files are
+// written to a temporary file, synced and renamed into place, so an entry is
+// either stored completely or not at all, even if the process or machine
+// crashes while it is written.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore returns a FileStore that keeps entries in dir, creating it if
+// necessary. Temporary files left behind by a crash are removed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("outbox: creating store directory: %w", err)
+	}
+	leftovers, err := filepath.Glob(filepath.Join(dir, ".tmp-*"))
+	if err != nil {
+		return nil, fmt.Errorf("outbox: reading store directory: %w", err)
+	}
+	for _, name := range leftovers {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("outbox: removing temporary file: %w", err)
+		}
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Put implements Store
+func (f *FileStore) Put(_ context.Context, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("outbox: encoding entry: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tmp, err := os.CreateTemp(f.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("outbox: writing entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("outbox: writing entry: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("outbox: syncing entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("outbox: writing entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path(entry.ID)); err != nil {
+		return fmt.Errorf("outbox: writing entry: %w", err)
+	}
+	f.syncDir()
+	return nil
+}
+
+// Get implements Store
+func (f *FileStore) Get(_ context.Context, id string) (Entry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, err := f.read(f.path(id))
+	if os.IsNotExist(err) {
+		return Entry{}, ErrNotFound
+	}
+	return entry, err
+}
+
+// List implements Store
+func (f *FileStore) List(_ context.Context, status Status) ([]Entry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("outbox: reading store directory: %w", err)
+	}
+	var entries []Entry
+	for _, name := range names {
+		entry, err := f.read(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry.Status == status {
+			entries = append(entries, entry)
+		}
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// Delete implements Store
+func (f *FileStore) Delete(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.Remove(f.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("outbox: deleting entry: %w", err)
+	}
+	f.syncDir()
+	return nil
+}
+
+// path returns the file an entry is kept in. IDs are hashed so that any
+// idempotency key makes a safe file name.
+func (f *FileStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// read decodes the entry in the named file. A missing file is reported with
+// an error satisfying os.IsNotExist.
+func (f *FileStore) read(name string) (Entry, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Entry{}, err
+		}
+		return Entry{}, fmt.Errorf("outbox: reading entry: %w", err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, fmt.Errorf("outbox: decoding %s: %w", filepath.Base(name), err)
+	}
+	return entry, nil
+}
+
+// syncDir makes renames and removals in the directory durable. It is best
+// effort: some platforms cannot sync a directory.
+func (f *FileStore) syncDir() {
+	dir, err := os.Open(f.dir)
+	if err != nil {
+		return
+	}
+	defer dir.Close()
+	_ = dir.Sync()
+}
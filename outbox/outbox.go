@@ -0,0 +1,291 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/google/uuid"
+)
+
+// Options configures an Outbox
+type Options struct {
+	// Interval is how often Run looks for entries that are due. Defaults to 5
+	// seconds. Entries enqueued through the Outbox are sent without waiting.
+	Interval time.Duration
+
+	// Concurrency is how many entries are sent at once. Defaults to 1.
+	Concurrency int
+
+	// MaxAttempts is how many times an entry is sent before it is marked
+	// failed. Defaults to 10.
+	MaxAttempts int
+
+	// BaseDelay and MaxDelay bound the backoff between attempts, which grows
+	// exponentially with jitter. They default to 1 second and 5 minutes. A
+	// longer Retry-After from the API takes precedence.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// RequestOptions are applied to every CreateMessage call, after the
+	// entry's idempotency key
+	RequestOptions []api.RequestOption
+
+	// OnDone is called after an entry is sent or marked failed (optional)
+	OnDone func(Entry)
+
+	// OnError is called with store errors Run runs into; Run carries on
+	// regardless (optional)
+	OnError func(error)
+}
+
+// Outbox persists CreateMessage requests and sends them until they succeed.
+// It is safe for concurrent use.
+type Outbox struct {
+	client *api.APIClient
+	store  Store
+	opts   Options
+	wake   chan struct{}
+
+	enqueueMu sync.Mutex // Serializes Enqueue's check for an existing entry
+	flushMu   sync.Mutex // Ensures an entry is only sent by one Flush at a time
+}
+
+// New returns an Outbox that sends the entries in store through client
+func New(client *api.APIClient, store Store, opts Options) *Outbox {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = time.Second
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 5 * time.Minute
+	}
+	return &Outbox{
+		client: client,
+		store:  store,
+		opts:   opts,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Enqueue stores a request to be sent with a newly generated idempotency key,
+// which becomes the entry's ID. The request is durable once Enqueue returns.
+func (o *Outbox) Enqueue(ctx context.Context, accountID uuid.UUID, request requests.CreateMessageRequest) (Entry, error) {
+	return o.EnqueueWithKey(ctx, o.client.GenerateIdempotencyKey(), accountID, request)
+}
+
+// EnqueueWithKey stores a request to be sent with the given idempotency key.
+// If the outbox already holds an entry with that key, it is returned
+// unchanged, so a job that enqueues again after a crash does not send twice.
+func (o *Outbox) EnqueueWithKey(ctx context.Context, key string, accountID uuid.UUID, request requests.CreateMessageRequest) (Entry, error) {
+	if key == "" {
+		return Entry{}, errors.New("outbox: idempotency key is required")
+	}
+
+	o.enqueueMu.Lock()
+	defer o.enqueueMu.Unlock()
+
+	if existing, err := o.store.Get(ctx, key); err == nil {
+		return existing, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return Entry{}, err
+	}
+
+	now := time.Now()
+	entry := Entry{
+		ID:            key,
+		AccountID:     accountID,
+		Request:       request,
+		Status:        StatusPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		NextAttemptAt: now,
+	}
+	if err := o.store.Put(ctx, entry); err != nil {
+		return Entry{}, err
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return entry, nil
+}
+
+// Get returns the entry with the given ID, or ErrNotFound
+func (o *Outbox) Get(ctx context.Context, id string) (Entry, error) {
+	return o.store.Get(ctx, id)
+}
+
+// Run sends entries as they fall due until ctx is done, and returns ctx's
+// error
+func (o *Outbox) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := o.Flush(ctx); err != nil && ctx.Err() == nil && o.opts.OnError != nil {
+			o.opts.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush sends every pending entry that is due, and returns once each has been
+// sent, rescheduled or marked failed. It returns the first store error.
+func (o *Outbox) Flush(ctx context.Context) error {
+	o.flushMu.Lock()
+	defer o.flushMu.Unlock()
+
+	pending, err := o.store.List(ctx, StatusPending)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	due := make(chan Entry)
+	go func() {
+		defer close(due)
+		for _, entry := range pending {
+			if entry.NextAttemptAt.After(now) {
+				continue
+			}
+			select {
+			case due <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	for i := 0; i < o.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range due {
+				if err := o.send(ctx, entry); err != nil {
+					errOnce.Do(func() { firstErr = err })
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// Prune deletes sent and failed entries last updated before the given time,
+// and returns how many were deleted
+func (o *Outbox) Prune(ctx context.Context, before time.Time) (int, error) {
+	deleted := 0
+	for _, status := range []Status{StatusSent, StatusFailed} {
+		entries, err := o.store.List(ctx, status)
+		if err != nil {
+			return deleted, err
+		}
+		for _, entry := range entries {
+			if !entry.UpdatedAt.Before(before) {
+				continue
+			}
+			if err := o.store.Delete(ctx, entry.ID); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// send makes one attempt at an entry and stores the outcome
+func (o *Outbox) send(ctx context.Context, entry Entry) error {
+	opts := append([]api.RequestOption{api.WithIdempotencyKey(entry.ID)}, o.opts.RequestOptions...)
+	resp, _, err := o.client.MessagesAPI.CreateMessage(ctx, entry.AccountID, entry.Request, opts...)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down: the attempt does not count, and the entry is sent
+		// again with the same key next time
+		return nil
+	}
+
+	now := time.Now()
+	entry.Attempts++
+	entry.UpdatedAt = now
+	switch {
+	case err == nil:
+		entry.Status = StatusSent
+		entry.Response = resp
+		entry.LastError = ""
+	case !retryable(err) || entry.Attempts >= o.opts.MaxAttempts:
+		entry.Status = StatusFailed
+		entry.LastError = err.Error()
+	default:
+		entry.LastError = err.Error()
+		entry.NextAttemptAt = now.Add(o.retryDelay(entry.Attempts, err))
+	}
+
+	if err := o.store.Put(ctx, entry); err != nil {
+		return err
+	}
+	if entry.Status != StatusPending && o.opts.OnDone != nil {
+		o.opts.OnDone(entry)
+	}
+	return nil
+}
+
+// retryDelay returns how long to wait before attempt+1, honouring how long
+// the API or the client's circuit breaker asked callers to wait
+func (o *Outbox) retryDelay(attempt int, err error) time.Duration {
+	backoff := api.RetryConfig{
+		BackoffStrategy: api.BackoffEqualJitter,
+		BaseDelay:       o.opts.BaseDelay,
+		MaxDelay:        o.opts.MaxDelay,
+	}
+	delay := backoff.GetDelay(attempt)
+
+	var apiErr *api.APIError
+	var openErr *api.CircuitOpenError
+	switch {
+	case errors.As(err, &apiErr):
+		if wait := time.Duration(apiErr.RetryAfter) * time.Second; wait > delay {
+			delay = wait
+		}
+	case errors.As(err, &openErr):
+		if openErr.RetryAfter > delay {
+			delay = openErr.RetryAfter
+		}
+	}
+	return delay
+}
+
+// retryable reports whether a failed attempt should be tried again. Only an
+// API error the API says is final is not; network errors, 5xx responses,
+// rate limits and requests still in flight under the same key all are.
+func retryable(err error) bool {
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.IsRetryable()
+	}
+	return true
+}
@@ -0,0 +1,271 @@
+package outbox
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/ahasendtest"
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) (*ahasendtest.Server, *api.APIClient) {
+	t.Helper()
+
+	server := ahasendtest.NewServer()
+	t.Cleanup(server.Close)
+	return server, server.Client(api.WithRetryConfig(api.RetryConfig{Enabled: false}))
+}
+
+func welcomeMessage(recipient string) requests.CreateMessageRequest {
+	return requests.CreateMessageRequest{
+		From:        common.SenderAddress{Email: "noreply@example.com"},
+		Recipients:  []common.Recipient{{Email: recipient}},
+		Subject:     "Welcome",
+		TextContent: ahasend.String("Hello"),
+	}
+}
+
+// fastRetries makes failed entries due again almost at once
+var fastRetries = Options{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+func TestOutboxSendsEnqueuedMessages(t *testing.T) {
+	server, client := newTestServer(t)
+	box := New(client, NewMemoryStore(), Options{})
+	ctx := context.Background()
+
+	first, err := box.Enqueue(ctx, server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+	second, err := box.Enqueue(ctx, server.AccountID(), welcomeMessage("b@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, first.Status)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	require.NoError(t, box.Flush(ctx))
+
+	server.AssertMessageCount(t, 2)
+	server.AssertMessageSent(t, "a@example.com", "Welcome")
+	for _, id := range []string{first.ID, second.ID} {
+		entry, err := box.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, StatusSent, entry.Status)
+		assert.Equal(t, 1, entry.Attempts)
+		assert.Len(t, entry.MessageIDs(), 1)
+	}
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, first.ID, requests[0].Header.Get("Idempotency-Key"))
+}
+
+func TestOutboxRetriesTransientFailures(t *testing.T) {
+	server, client := newTestServer(t)
+	box := New(client, NewMemoryStore(), fastRetries)
+	ctx := context.Background()
+
+	server.FailNext(1, http.StatusServiceUnavailable)
+	entry, err := box.Enqueue(ctx, server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+
+	require.NoError(t, box.Flush(ctx))
+	entry, err = box.Get(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, entry.Status)
+	assert.Equal(t, 1, entry.Attempts)
+	assert.NotEmpty(t, entry.LastError)
+	server.AssertMessageCount(t, 0)
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, box.Flush(ctx))
+	entry, err = box.Get(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, entry.Status)
+	assert.Equal(t, 2, entry.Attempts)
+	assert.Empty(t, entry.LastError)
+	server.AssertMessageCount(t, 1)
+}
+
+func TestOutboxHonoursRetryAfter(t *testing.T) {
+	server, client := newTestServer(t)
+	box := New(client, NewMemoryStore(), fastRetries)
+	ctx := context.Background()
+
+	server.RateLimitNext(1, time.Minute)
+	entry, err := box.Enqueue(ctx, server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+	require.NoError(t, box.Flush(ctx))
+
+	entry, err = box.Get(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, entry.Status)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), entry.NextAttemptAt, 5*time.Second)
+
+	// Not due yet
+	require.NoError(t, box.Flush(ctx))
+	server.AssertMessageCount(t, 0)
+}
+
+func TestOutboxMarksPermanentFailures(t *testing.T) {
+	server, client := newTestServer(t)
+	var done []Entry
+	box := New(client, NewMemoryStore(), Options{OnDone: func(e Entry) { done = append(done, e) }})
+	ctx := context.Background()
+
+	server.FailNext(1, http.StatusBadRequest)
+	entry, err := box.Enqueue(ctx, server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+	require.NoError(t, box.Flush(ctx))
+
+	entry, err = box.Get(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, entry.Status)
+	assert.Contains(t, entry.LastError, "400")
+	require.Len(t, done, 1)
+	assert.Equal(t, entry.ID, done[0].ID)
+
+	// Failed entries are not sent again
+	require.NoError(t, box.Flush(ctx))
+	assert.Len(t, server.Requests(), 1)
+}
+
+func TestOutboxGivesUpAfterMaxAttempts(t *testing.T) {
+	server, client := newTestServer(t)
+	opts := fastRetries
+	opts.MaxAttempts = 2
+	box := New(client, NewMemoryStore(), opts)
+	ctx := context.Background()
+
+	server.AddFault(ahasendtest.Fault{Status: http.StatusBadGateway})
+	entry, err := box.Enqueue(ctx, server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, box.Flush(ctx))
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	entry, err = box.Get(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, entry.Status)
+	assert.Equal(t, 2, entry.Attempts)
+	assert.Len(t, server.Requests(), 2)
+}
+
+func TestOutboxEnqueueWithKeyIsIdempotent(t *testing.T) {
+	server, client := newTestServer(t)
+	box := New(client, NewMemoryStore(), Options{})
+	ctx := context.Background()
+
+	first, err := box.EnqueueWithKey(ctx, "order-123-receipt", server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+	require.NoError(t, box.Flush(ctx))
+
+	again, err := box.EnqueueWithKey(ctx, "order-123-receipt", server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Equal(t, StatusSent, again.Status)
+
+	require.NoError(t, box.Flush(ctx))
+	server.AssertMessageCount(t, 1)
+
+	_, err = box.EnqueueWithKey(ctx, "", server.AccountID(), welcomeMessage("a@example.com"))
+	assert.Error(t, err)
+}
+
+func TestOutboxSurvivesRestart(t *testing.T) {
+	server, client := newTestServer(t)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+	entry, err := New(client, store, Options{}).Enqueue(ctx, server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+
+	// A new process picks the entry up
+	store, err = NewFileStore(dir)
+	require.NoError(t, err)
+	box := New(client, store, Options{})
+	require.NoError(t, box.Flush(ctx))
+
+	sent, err := box.Get(ctx, entry.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusSent, sent.Status)
+	ids := sent.MessageIDs()
+	require.Len(t, ids, 1)
+
+	// The process died after sending but before storing the result: the
+	// entry is sent again with the same key and the API replays the result
+	crashed := sent
+	crashed.Status = StatusPending
+	crashed.Response = nil
+	require.NoError(t, store.Put(ctx, crashed))
+
+	store, err = NewFileStore(dir)
+	require.NoError(t, err)
+	box = New(client, store, Options{})
+	require.NoError(t, box.Flush(ctx))
+
+	resent, err := box.Get(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, resent.Status)
+	assert.Equal(t, ids, resent.MessageIDs())
+	server.AssertMessageCount(t, 1)
+}
+
+func TestOutboxRunSendsInBackground(t *testing.T) {
+	server, client := newTestServer(t)
+	sent := make(chan string, 1)
+	box := New(client, NewMemoryStore(), Options{
+		Interval: time.Hour,
+		OnDone:   func(e Entry) { sent <- e.ID },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- box.Run(ctx) }()
+
+	entry, err := box.Enqueue(ctx, server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+
+	select {
+	case id := <-sent:
+		assert.Equal(t, entry.ID, id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("enqueued entry was not sent")
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-stopped, context.Canceled)
+}
+
+func TestOutboxPrune(t *testing.T) {
+	server, client := newTestServer(t)
+	box := New(client, NewMemoryStore(), Options{})
+	ctx := context.Background()
+
+	sent, err := box.Enqueue(ctx, server.AccountID(), welcomeMessage("a@example.com"))
+	require.NoError(t, err)
+	require.NoError(t, box.Flush(ctx))
+	pending, err := box.Enqueue(ctx, server.AccountID(), welcomeMessage("b@example.com"))
+	require.NoError(t, err)
+
+	deleted, err := box.Prune(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	deleted, err = box.Prune(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	_, err = box.Get(ctx, sent.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = box.Get(ctx, pending.ID)
+	assert.NoError(t, err, "pending entries are never pruned")
+}
@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned by Store.Get for an unknown entry ID
+var ErrNotFound = errors.New("outbox: entry not found")
+
+// Status is where an entry is in its delivery
+type Status string
+
+const (
+	StatusPending Status = "pending" // Waiting to be sent, or to be retried
+	StatusSent    Status = "sent"    // Accepted by AhaSend
+	StatusFailed  Status = "failed"  // Rejected by AhaSend, or out of attempts
+)
+
+// Entry is a CreateMessage request in the outbox
+type Entry struct {
+	// ID is the idempotency key the request is sent with
+	ID        string                        `json:"id"`
+	AccountID uuid.UUID                     `json:"account_id"`
+	Request   requests.CreateMessageRequest `json:"request"`
+
+	Status   Status `json:"status"`
+	Attempts int    `json:"attempts"`
+	// LastError is the error of the most recent failed attempt
+	LastError string `json:"last_error,omitempty"`
+	// Response is what AhaSend returned once the entry was sent
+	Response *responses.CreateMessageResponse `json:"response,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// NextAttemptAt is when a pending entry is next due to be sent
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
+
+// MessageIDs returns the IDs of the messages AhaSend created for a sent entry
+func (e Entry) MessageIDs() []string {
+	if e.Response == nil {
+		return nil
+	}
+	var ids []string
+	for _, message := range e.Response.Data {
+		if message.ID != nil {
+			ids = append(ids, *message.ID)
+		}
+	}
+	return ids
+}
+
+// Store persists outbox entries. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	// Put saves entry, replacing any entry with the same ID
+	Put(ctx context.Context, entry Entry) error
+	// Get returns the entry with the given ID, or ErrNotFound
+	Get(ctx context.Context, id string) (Entry, error)
+	// List returns the entries with the given status, oldest first
+	List(ctx context.Context, status Status) ([]Entry, error)
+	// Delete removes the entry with the given ID. Deleting an entry that does
+	// not exist is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore keeps entries in memory. Entries do not survive a restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]Entry)}
+}
+
+// Put implements Store
+func (m *MemoryStore) Put(_ context.Context, entry Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[entry.ID] = entry
+	return nil
+}
+
+// Get implements Store
+func (m *MemoryStore) Get(_ context.Context, id string) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[id]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return entry, nil
+}
+
+// List implements Store
+func (m *MemoryStore) List(_ context.Context, status Status) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []Entry
+	for _, entry := range m.entries {
+		if entry.Status == status {
+			entries = append(entries, entry)
+		}
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// Delete implements Store
+func (m *MemoryStore) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, id)
+	return nil
+}
+
+// sortEntries orders entries oldest first
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"file": func(t *testing.T) Store {
+			store, err := NewFileStore(t.TempDir())
+			require.NoError(t, err)
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			ctx := context.Background()
+			now := time.Now().UTC().Truncate(time.Millisecond)
+
+			_, err := store.Get(ctx, "missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			older := Entry{ID: "key/with:odd chars", AccountID: uuid.New(), Status: StatusPending, CreatedAt: now}
+			newer := Entry{ID: "key-2", AccountID: uuid.New(), Status: StatusPending, CreatedAt: now.Add(time.Second)}
+			sent := Entry{ID: "key-3", Status: StatusSent, CreatedAt: now}
+			older.Request = welcomeMessage("a@example.com")
+			for _, entry := range []Entry{newer, sent, older} {
+				require.NoError(t, store.Put(ctx, entry))
+			}
+
+			got, err := store.Get(ctx, older.ID)
+			require.NoError(t, err)
+			assert.Equal(t, older.AccountID, got.AccountID)
+			assert.Equal(t, "a@example.com", got.Request.Recipients[0].Email)
+			assert.True(t, got.CreatedAt.Equal(now))
+
+			pending, err := store.List(ctx, StatusPending)
+			require.NoError(t, err)
+			require.Len(t, pending, 2)
+			assert.Equal(t, older.ID, pending[0].ID, "oldest first")
+			assert.Equal(t, newer.ID, pending[1].ID)
+
+			older.Status = StatusSent
+			require.NoError(t, store.Put(ctx, older))
+			pending, err = store.List(ctx, StatusPending)
+			require.NoError(t, err)
+			assert.Len(t, pending, 1)
+
+			require.NoError(t, store.Delete(ctx, older.ID))
+			require.NoError(t, store.Delete(ctx, older.ID))
+			_, err = store.Get(ctx, older.ID)
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+func TestFileStoreRemovesTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	leftover := filepath.Join(dir, ".tmp-123")
+	require.NoError(t, os.WriteFile(leftover, []byte(`{"id":`), 0o600))
+
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+	_, err = os.Stat(leftover)
+	assert.True(t, os.IsNotExist(err))
+
+	entries, err := store.List(context.Background(), StatusPending)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
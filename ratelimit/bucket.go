@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// bucket is the state of one token bucket, shared by MemoryBackend and
+// FileBackend. Times are Unix nanoseconds.
+type bucket struct {
+	Tokens      float64 `json:"tokens"`
+	Updated     int64   `json:"updated"`
+	PausedUntil int64   `json:"paused_until,omitempty"`
+}
+
+// take tries to take a token from a bucket that refills at rate tokens per
+// second up to burst, and returns how long to wait if none is available
+func (b *bucket) take(now time.Time, rate float64, burst int) time.Duration {
+	n := now.UnixNano()
+	if b.Updated == 0 {
+		b.Tokens = float64(burst)
+		b.Updated = n
+	}
+	if n < b.PausedUntil {
+		return time.Duration(b.PausedUntil - n)
+	}
+	if n > b.Updated {
+		b.Tokens += float64(n-b.Updated) / float64(time.Second) * rate
+		b.Updated = n
+	}
+	b.Tokens = math.Min(b.Tokens, float64(burst))
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return 0
+	}
+	if rate <= 0 {
+		return time.Second
+	}
+	return time.Duration(math.Ceil((1 - b.Tokens) / rate * float64(time.Second)))
+}
+
+// pause empties the bucket and stops it refilling for d
+func (b *bucket) pause(now time.Time, d time.Duration) {
+	resume := now.Add(d).UnixNano()
+	if resume > b.PausedUntil {
+		b.PausedUntil = resume
+	}
+	b.Tokens = 0
+	if b.PausedUntil > b.Updated {
+		b.Updated = b.PausedUntil
+	}
+}
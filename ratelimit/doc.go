@@ -0,0 +1,28 @@
+// Package ratelimit provides shared stores for the AhaSend API client's rate
+// limiter, so that processes sending for the same account stay within one
+// rate limit between them instead of each assuming the whole quota.
+//
+// Each store implements api.RateLimiterBackend:
+//
+//   - RedisBackend keeps buckets in Redis, or any server speaking the Redis
+//     protocol, for processes spread over several hosts
+//   - FileBackend keeps buckets in files guarded by flock(2), for processes on
+//     a single host
+//   - MemoryBackend keeps buckets in memory, for clients within one process
+//
+// Install one on a client with api.WithRateLimiterBackend:
+//
+//	backend := ratelimit.NewRedisBackend(ratelimit.RedisOptions{Addr: "redis:6379"})
+//	defer backend.Close()
+//
+//	client := api.NewAPIClient(
+//		api.WithAPIKey(apiKey),
+//		api.WithRateLimiterBackend(backend),
+//	)
+//
+// The client namespaces its bucket keys by a hash of its API key, or by
+// api.WithRateLimiterNamespace, so clients for different accounts never share
+// a bucket. RedisOptions.KeyPrefix and FileOptions.KeyPrefix further separate
+// applications sharing one store. Processes that share a bucket should
+// configure the same rate limits.
+package ratelimit
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// validKey restricts bucket keys to names that are safe as file names
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// FileBackend keeps each bucket in a file in one directory, locked with
+// flock(2) while it is updated, so that processes on the same host share rate
+// limits. The directory must be on a local file system; locks on network file
+// systems are not reliable.
+//
+// FileBackend is not available on Windows, where Take and Pause return an
+// error and the rate limiter falls back to in-process buckets.
+type FileBackend struct {
+	dir  string
+	opts FileOptions
+}
+
+// FileOptions configures a FileBackend
+type FileOptions struct {
+	// KeyPrefix is prepended to bucket file names, separating applications
+	// that share the directory. It may only contain letters, digits and
+	// "_.:-".
+	KeyPrefix string
+}
+
+// NewFileBackend returns a FileBackend that keeps buckets in dir, creating it
+// if necessary
+func NewFileBackend(dir string) (*FileBackend, error) {
+	return NewFileBackendWithOptions(dir, FileOptions{})
+}
+
+// NewFileBackendWithOptions is NewFileBackend with options
+func NewFileBackendWithOptions(dir string, opts FileOptions) (*FileBackend, error) {
+	if opts.KeyPrefix != "" && !validKey.MatchString(opts.KeyPrefix) {
+		return nil, fmt.Errorf("ratelimit: invalid key prefix %q", opts.KeyPrefix)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("ratelimit: creating bucket directory: %w", err)
+	}
+	return &FileBackend{dir: dir, opts: opts}, nil
+}
+
+// Take implements api.RateLimiterBackend
+func (f *FileBackend) Take(_ context.Context, key string, rate float64, burst int) (time.Duration, error) {
+	var wait time.Duration
+	err := f.update(key, func(b *bucket) {
+		wait = b.take(time.Now(), rate, burst)
+	})
+	return wait, err
+}
+
+// Pause implements api.RateLimiterBackend
+func (f *FileBackend) Pause(_ context.Context, key string, d time.Duration) error {
+	return f.update(key, func(b *bucket) {
+		b.pause(time.Now(), d)
+	})
+}
+
+// update applies fn to the bucket stored for key while holding its lock
+func (f *FileBackend) update(key string, fn func(*bucket)) (err error) {
+	if !validKey.MatchString(key) {
+		return fmt.Errorf("ratelimit: invalid bucket key %q", key)
+	}
+
+	file, err := os.OpenFile(filepath.Join(f.dir, f.opts.KeyPrefix+key+".bucket"), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("ratelimit: opening bucket: %w", err)
+	}
+	defer file.Close()
+
+	if err := lockFile(file); err != nil {
+		return fmt.Errorf("ratelimit: locking bucket: %w", err)
+	}
+	defer func() {
+		if unlockErr := unlockFile(file); unlockErr != nil && err == nil {
+			err = fmt.Errorf("ratelimit: unlocking bucket: %w", unlockErr)
+		}
+	}()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("ratelimit: reading bucket: %w", err)
+	}
+	var b bucket
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &b); err != nil {
+			// A damaged bucket starts over full rather than failing forever
+			b = bucket{}
+		}
+	}
+
+	fn(&b)
+
+	if data, err = json.Marshal(b); err != nil {
+		return fmt.Errorf("ratelimit: encoding bucket: %w", err)
+	}
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("ratelimit: writing bucket: %w", err)
+	}
+	if _, err := file.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("ratelimit: writing bucket: %w", err)
+	}
+	return nil
+}
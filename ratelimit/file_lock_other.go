@@ -0,0 +1,18 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package ratelimit
+
+import (
+	"errors"
+	"os"
+)
+
+var errFileLockUnsupported = errors.New("file locking is not supported on this platform")
+
+func lockFile(*os.File) error {
+	return errFileLockUnsupported
+}
+
+func unlockFile(*os.File) error {
+	return errFileLockUnsupported
+}
@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps buckets in memory. It lets several API clients in one
+// process share a rate limit.
+type MemoryBackend struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewMemoryBackend returns an empty MemoryBackend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{buckets: make(map[string]*bucket)}
+}
+
+// Take implements api.RateLimiterBackend
+func (m *MemoryBackend) Take(_ context.Context, key string, rate float64, burst int) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.bucket(key).take(time.Now(), rate, burst), nil
+}
+
+// Pause implements api.RateLimiterBackend
+func (m *MemoryBackend) Pause(_ context.Context, key string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bucket(key).pause(time.Now(), d)
+	return nil
+}
+
+func (m *MemoryBackend) bucket(key string) *bucket {
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{}
+		m.buckets[key] = b
+	}
+	return b
+}
@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ api.RateLimiterBackend = (*MemoryBackend)(nil)
+	_ api.RateLimiterBackend = (*FileBackend)(nil)
+	_ api.RateLimiterBackend = (*RedisBackend)(nil)
+)
+
+func TestBucketTakeAndRefill(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	var b bucket
+
+	assert.Zero(t, b.take(start, 2, 2), "a new bucket starts full")
+	assert.Zero(t, b.take(start, 2, 2))
+	assert.Equal(t, 500*time.Millisecond, b.take(start, 2, 2))
+	assert.Zero(t, b.take(start.Add(500*time.Millisecond), 2, 2), "refills at the given rate")
+
+	// Refilling never exceeds the burst, even after a long idle period
+	later := start.Add(time.Hour)
+	assert.Zero(t, b.take(later, 2, 2))
+	assert.Zero(t, b.take(later, 2, 2))
+	assert.NotZero(t, b.take(later, 2, 2))
+}
+
+func TestBucketPause(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	var b bucket
+	require.Zero(t, b.take(now, 100, 100))
+
+	b.pause(now, 2*time.Second)
+	assert.Equal(t, 2*time.Second, b.take(now, 100, 100))
+	assert.Equal(t, time.Second, b.take(now.Add(time.Second), 100, 100))
+
+	// A shorter pause does not cut a longer one short
+	b.pause(now, time.Second)
+	assert.Equal(t, 2*time.Second, b.take(now, 100, 100))
+
+	// Tokens only start to refill once the pause is over
+	assert.Zero(t, b.take(now.Add(2*time.Second+10*time.Millisecond), 100, 100))
+	assert.NotZero(t, b.take(now.Add(2*time.Second+10*time.Millisecond), 100, 100))
+}
+
+// countImmediate counts how many of n concurrent takes succeed without waiting
+func countImmediate(t *testing.T, n int, take func() (time.Duration, error)) int {
+	t.Helper()
+
+	var granted int64
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := take()
+			assert.NoError(t, err)
+			if wait == 0 {
+				atomic.AddInt64(&granted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(granted)
+}
+
+func TestMemoryBackendSharesBuckets(t *testing.T) {
+	backend := NewMemoryBackend()
+	ctx := context.Background()
+
+	granted := countImmediate(t, 20, func() (time.Duration, error) {
+		return backend.Take(ctx, "send_message", 0.001, 5)
+	})
+	assert.Equal(t, 5, granted)
+
+	wait, err := backend.Take(ctx, "general", 0.001, 5)
+	require.NoError(t, err)
+	assert.Zero(t, wait, "buckets are independent")
+}
+
+func TestFileBackendSharesBucketsBetweenInstances(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// Separate instances stand in for separate processes: each opens and
+	// locks the bucket file on its own
+	backends := make([]*FileBackend, 4)
+	for i := range backends {
+		backend, err := NewFileBackend(dir)
+		require.NoError(t, err)
+		backends[i] = backend
+	}
+
+	var next int64
+	granted := countImmediate(t, 40, func() (time.Duration, error) {
+		backend := backends[atomic.AddInt64(&next, 1)%int64(len(backends))]
+		return backend.Take(ctx, "send_message", 0.001, 10)
+	})
+	assert.Equal(t, 10, granted)
+
+	require.NoError(t, backends[0].Pause(ctx, "general", time.Hour))
+	wait, err := backends[1].Take(ctx, "general", 100, 100)
+	require.NoError(t, err)
+	assert.Greater(t, wait, 59*time.Minute)
+}
+
+func TestFileBackendRejectsUnsafeKeys(t *testing.T) {
+	backend, err := NewFileBackend(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = backend.Take(context.Background(), "../escape", 1, 1)
+	assert.Error(t, err)
+}
+
+func TestFileBackendKeyPrefix(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	first, err := NewFileBackendWithOptions(dir, FileOptions{KeyPrefix: "app-a:"})
+	require.NoError(t, err)
+	second, err := NewFileBackendWithOptions(dir, FileOptions{KeyPrefix: "app-b:"})
+	require.NoError(t, err)
+
+	require.NoError(t, first.Pause(ctx, "general", time.Hour))
+	wait, err := second.Take(ctx, "general", 1, 1)
+	require.NoError(t, err)
+	assert.Zero(t, wait, "prefixes keep the buckets apart")
+	assert.FileExists(t, dir+"/app-a:general.bucket")
+
+	_, err = NewFileBackendWithOptions(dir, FileOptions{KeyPrefix: "../"})
+	assert.Error(t, err)
+}
+
+func TestFileBackendRecoversFromDamagedBucket(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewFileBackend(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dir+"/general.bucket", []byte("not json"), 0o600))
+
+	wait, err := backend.Take(context.Background(), "general", 1, 1)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestSharedBackendAcrossClients(t *testing.T) {
+	backend := NewMemoryBackend()
+
+	limiters := []*api.RateLimiter{api.NewRateLimiter(), api.NewRateLimiter()}
+	for _, rl := range limiters {
+		rl.SetRateLimit(api.SendMessageAPI, 1, 2)
+		rl.SetBackend(backend, "account-1")
+	}
+
+	ctx := context.Background()
+	require.NoError(t, limiters[0].GetBucket(api.SendMessageAPI).WaitForTokenWithContext(ctx))
+	require.NoError(t, limiters[1].GetBucket(api.SendMessageAPI).WaitForTokenWithContext(ctx))
+
+	// Each limiter has tokens of its own, but the shared bucket is empty
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiters[0].GetBucket(api.SendMessageAPI).WaitForTokenWithContext(ctx), context.DeadlineExceeded)
+}
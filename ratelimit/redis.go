@@ -0,0 +1,317 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// takeScript takes a token from the bucket at KEYS[1], which refills at
+// ARGV[1] tokens per second up to ARGV[2], and returns the milliseconds to
+// wait if none is available. It uses the server clock so that processes with
+// skewed clocks still agree.
+const takeScript = `
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+if redis.replicate_commands then redis.replicate_commands() end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', key, 'tokens', 'updated', 'paused_until')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+local paused = tonumber(state[3]) or 0
+if tokens == nil or updated == nil then
+  tokens = burst
+  updated = now
+end
+if now < paused then
+  return paused - now
+end
+if now > updated then
+  tokens = tokens + (now - updated) * rate / 1000
+  updated = now
+end
+tokens = math.min(tokens, burst)
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+elseif rate > 0 then
+  wait = math.ceil((1 - tokens) * 1000 / rate)
+else
+  wait = 1000
+end
+redis.call('HSET', key, 'tokens', tostring(tokens), 'updated', updated, 'paused_until', paused)
+redis.call('PEXPIRE', key, ttl)
+return wait
+`
+
+// pauseScript empties the bucket at KEYS[1] and stops it refilling for
+// ARGV[1] milliseconds
+const pauseScript = `
+local key = KEYS[1]
+local delay = tonumber(ARGV[1])
+local ttl = tonumber(ARGV[2])
+if redis.replicate_commands then redis.replicate_commands() end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local paused = tonumber(redis.call('HGET', key, 'paused_until')) or 0
+if now + delay > paused then
+  paused = now + delay
+end
+redis.call('HSET', key, 'tokens', '0', 'updated', paused, 'paused_until', paused)
+redis.call('PEXPIRE', key, ttl)
+return paused - now
+`
+
+var (
+	takeScriptSHA  = scriptSHA(takeScript)
+	pauseScriptSHA = scriptSHA(pauseScript)
+)
+
+func scriptSHA(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// minBucketTTL is the shortest time an idle bucket is kept
+const minBucketTTL = time.Minute
+
+// RedisOptions configures a RedisBackend
+type RedisOptions struct {
+	// Addr is the host:port of the server. Defaults to localhost:6379.
+	Addr string
+
+	// Username and Password authenticate with AUTH when Password is set.
+	// Username is only needed with Redis 6 ACLs.
+	Username string
+	Password string
+
+	// DB is the database selected on each connection
+	DB int
+
+	// TLSConfig enables TLS when set
+	TLSConfig *tls.Config
+
+	// KeyPrefix is prepended to bucket keys. Defaults to "ahasend:ratelimit:".
+	// Processes sending for different accounts should use different prefixes.
+	KeyPrefix string
+
+	// DialTimeout bounds connecting to the server. Defaults to 5 seconds.
+	DialTimeout time.Duration
+
+	// Timeout bounds each operation when the context has no earlier deadline.
+	// Defaults to 2 seconds.
+	Timeout time.Duration
+
+	// PoolSize is the number of idle connections kept open. Defaults to 10.
+	PoolSize int
+}
+
+// RedisBackend keeps buckets in Redis, or in any server that speaks the Redis
+// protocol and supports Lua scripting, such as Valkey or KeyDB. Buckets are
+// updated atomically by scripts run on the server, which require Redis 4.0 or
+// later. It is safe for concurrent use.
+type RedisBackend struct {
+	opts RedisOptions
+	idle chan *redisConn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewRedisBackend returns a RedisBackend for the server described by opts.
+// Connections are made as they are needed.
+func NewRedisBackend(opts RedisOptions) *RedisBackend {
+	if opts.Addr == "" {
+		opts.Addr = "localhost:6379"
+	}
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = "ahasend:ratelimit:"
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	return &RedisBackend{opts: opts, idle: make(chan *redisConn, opts.PoolSize)}
+}
+
+// Take implements api.RateLimiterBackend
+func (r *RedisBackend) Take(ctx context.Context, key string, rate float64, burst int) (time.Duration, error) {
+	ttl := minBucketTTL
+	if rate > 0 {
+		if refill := time.Duration(float64(burst) / rate * float64(time.Second)); 2*refill > ttl {
+			ttl = 2 * refill
+		}
+	}
+
+	reply, err := r.eval(ctx, takeScript, takeScriptSHA, r.opts.KeyPrefix+key,
+		strconv.FormatFloat(rate, 'f', -1, 64),
+		strconv.Itoa(burst),
+		strconv.FormatInt(ttl.Milliseconds(), 10),
+	)
+	if err != nil {
+		return 0, err
+	}
+	ms, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("ratelimit: unexpected reply %v from Redis", reply)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// Pause implements api.RateLimiterBackend
+func (r *RedisBackend) Pause(ctx context.Context, key string, d time.Duration) error {
+	delay := int64(math.Ceil(float64(d) / float64(time.Millisecond)))
+	_, err := r.eval(ctx, pauseScript, pauseScriptSHA, r.opts.KeyPrefix+key,
+		strconv.FormatInt(delay, 10),
+		strconv.FormatInt((d+minBucketTTL).Milliseconds(), 10),
+	)
+	return err
+}
+
+// Close closes the idle connections. Operations started after Close fail.
+func (r *RedisBackend) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	for {
+		select {
+		case conn := <-r.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// eval runs a script by its SHA, loading it with EVAL the first time the
+// server has not seen it
+func (r *RedisBackend) eval(ctx context.Context, script, sha, key string, args ...string) (interface{}, error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(r.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ratelimit: %w", err)
+	}
+
+	command := append([]string{"EVALSHA", sha, "1", key}, args...)
+	reply, err := conn.do(command...)
+	var replyErr redisError
+	if errors.As(err, &replyErr) && strings.HasPrefix(string(replyErr), "NOSCRIPT") {
+		command[0], command[1] = "EVAL", script
+		reply, err = conn.do(command...)
+	}
+	if err != nil && !errors.As(err, &replyErr) {
+		// The connection is in an unknown state
+		conn.Close()
+		return nil, fmt.Errorf("ratelimit: %w", err)
+	}
+	r.put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: %w", err)
+	}
+	return reply, nil
+}
+
+// get returns an idle connection, or dials a new one
+func (r *RedisBackend) get(ctx context.Context) (*redisConn, error) {
+	r.mu.Lock()
+	closed := r.closed
+	r.mu.Unlock()
+	if closed {
+		return nil, errors.New("ratelimit: Redis backend is closed")
+	}
+
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+
+	conn, err := r.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: connecting to Redis: %w", err)
+	}
+	return conn, nil
+}
+
+// put returns a connection to the pool, or closes it if the pool is full
+func (r *RedisBackend) put(conn *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		conn.Close()
+		return
+	}
+	select {
+	case r.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// dial connects, authenticates and selects the database
+func (r *RedisBackend) dial(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: r.opts.DialTimeout}
+	var netConn net.Conn
+	var err error
+	if r.opts.TLSConfig != nil {
+		netConn, err = (&tls.Dialer{NetDialer: dialer, Config: r.opts.TLSConfig}).DialContext(ctx, "tcp", r.opts.Addr)
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", r.opts.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn := newRedisConn(netConn)
+	if err := conn.SetDeadline(time.Now().Add(r.opts.DialTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if r.opts.Password != "" {
+		auth := []string{"AUTH", r.opts.Password}
+		if r.opts.Username != "" {
+			auth = []string{"AUTH", r.opts.Username, r.opts.Password}
+		}
+		if _, err := conn.do(auth...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if r.opts.DB != 0 {
+		if _, err := conn.do("SELECT", strconv.Itoa(r.opts.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
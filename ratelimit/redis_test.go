@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis is a Redis protocol server that runs the backend's scripts with
+// their Go equivalents, so the client can be tested without a Redis server
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	loaded   map[string]bool
+	buckets  map[string]*bucket
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeRedis{
+		listener: listener,
+		password: password,
+		loaded:   map[string]bool{},
+		buckets:  map[string]*bucket{},
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authenticated := f.password == ""
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, args[0])
+		f.mu.Unlock()
+
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[len(args)-1] != f.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authenticated = true
+			fmt.Fprint(conn, "+OK\r\n")
+		case "SELECT":
+			fmt.Fprint(conn, "+OK\r\n")
+		case "EVALSHA", "EVAL":
+			if !authenticated {
+				fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+				continue
+			}
+			fmt.Fprint(conn, f.eval(args))
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+	}
+}
+
+func (f *fakeRedis) eval(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sha := args[1]
+	if strings.ToUpper(args[0]) == "EVAL" {
+		sha = scriptSHA(args[1])
+		f.loaded[sha] = true
+	} else if !f.loaded[sha] {
+		return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+	}
+
+	key := args[3]
+	b, ok := f.buckets[key]
+	if !ok {
+		b = &bucket{}
+		f.buckets[key] = b
+	}
+	now := time.Now()
+	switch sha {
+	case takeScriptSHA:
+		rate, _ := strconv.ParseFloat(args[4], 64)
+		burst, _ := strconv.Atoi(args[5])
+		wait := b.take(now, rate, burst)
+		return fmt.Sprintf(":%d\r\n", (wait+time.Millisecond-1)/time.Millisecond)
+	case pauseScriptSHA:
+		delay, _ := strconv.Atoi(args[4])
+		b.pause(now, time.Duration(delay)*time.Millisecond)
+		return fmt.Sprintf(":%d\r\n", delay)
+	}
+	return "-ERR unknown script\r\n"
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedisBackendTakeAndPause(t *testing.T) {
+	server := newFakeRedis(t, "hunter2")
+	backend := NewRedisBackend(RedisOptions{Addr: server.addr(), Password: "hunter2", DB: 2, KeyPrefix: "test:"})
+	defer backend.Close()
+	ctx := context.Background()
+
+	granted := countImmediate(t, 12, func() (time.Duration, error) {
+		return backend.Take(ctx, "send_message", 0.001, 4)
+	})
+	assert.Equal(t, 4, granted)
+
+	require.NoError(t, backend.Pause(ctx, "general", time.Minute))
+	wait, err := backend.Take(ctx, "general", 100, 100)
+	require.NoError(t, err)
+	assert.Greater(t, wait, 59*time.Second)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Contains(t, server.buckets, "test:send_message")
+	assert.Contains(t, server.buckets, "test:general")
+	assert.Contains(t, server.commands, "AUTH")
+	assert.Contains(t, server.commands, "SELECT")
+	assert.Contains(t, server.commands, "EVAL", "scripts are loaded when the server does not know them")
+}
+
+func TestRedisBackendErrors(t *testing.T) {
+	server := newFakeRedis(t, "hunter2")
+	ctx := context.Background()
+
+	backend := NewRedisBackend(RedisOptions{Addr: server.addr(), Password: "wrong"})
+	_, err := backend.Take(ctx, "general", 1, 1)
+	assert.ErrorContains(t, err, "WRONGPASS")
+	backend.Close()
+
+	backend = NewRedisBackend(RedisOptions{Addr: server.addr(), Password: "hunter2"})
+	require.NoError(t, backend.Close())
+	_, err = backend.Take(ctx, "general", 1, 1)
+	assert.ErrorContains(t, err, "closed")
+
+	unreachable := NewRedisBackend(RedisOptions{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond})
+	defer unreachable.Close()
+	_, err = unreachable.Take(ctx, "general", 1, 1)
+	assert.Error(t, err)
+}
+
+// TestRedisBackendAgainstServer runs the scripts on a real server when
+// AHASEND_TEST_REDIS_ADDR is set
+func TestRedisBackendAgainstServer(t *testing.T) {
+	addr := os.Getenv("AHASEND_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("AHASEND_TEST_REDIS_ADDR not set")
+	}
+
+	prefix := fmt.Sprintf("ahasend:test:%d:", time.Now().UnixNano())
+	backend := NewRedisBackend(RedisOptions{Addr: addr, KeyPrefix: prefix})
+	defer backend.Close()
+	ctx := context.Background()
+
+	granted := countImmediate(t, 12, func() (time.Duration, error) {
+		return backend.Take(ctx, "send_message", 0.001, 4)
+	})
+	assert.Equal(t, 4, granted)
+
+	require.NoError(t, backend.Pause(ctx, "general", time.Minute))
+	wait, err := backend.Take(ctx, "general", 100, 100)
+	require.NoError(t, err)
+	assert.Greater(t, wait, 59*time.Second)
+}
@@ -0,0 +1,16 @@
+// Package templates renders message substitutions locally.
+//
+// AhaSend renders the subject and content of a message with Jinja2, using the
+// global CreateMessageRequest.Substitutions merged with each recipient's
+// common.Recipient.Substitutions (recipient values win). This package
+// implements the commonly used subset of that language - `{{ }}` output with
+// attribute access and filters, `{% if %}`, `{% for %}`, `{% set %}`,
+// `{% raw %}`, comments and whitespace control - so that a message can be
+// previewed per recipient, snapshot-tested, and checked for missing or unused
+// variables before it is sent.
+//
+// Rendering follows Jinja2's defaults: an undefined variable renders as an
+// empty string and is falsy in conditions, and output is not HTML-escaped
+// unless the template uses the escape filter. Every undefined variable that
+// reaches the output is reported as missing.
+package templates
@@ -0,0 +1,370 @@
+package templates
+
+import (
+	"html"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// filterArgs holds the evaluated arguments of a filter call
+type filterArgs struct {
+	positional []interface{}
+	keyword    map[string]interface{}
+}
+
+// get returns the argument at position i or named name, or fallback when the
+// call passed neither
+func (a filterArgs) get(i int, name string, fallback interface{}) interface{} {
+	if i < len(a.positional) {
+		return a.positional[i]
+	}
+	if value, ok := a.keyword[name]; ok {
+		return value
+	}
+	return fallback
+}
+
+type filterFunc func(r *renderer, value interface{}, args filterArgs) (interface{}, error)
+
+// filters are the Jinja2 built-in filters this package supports. Using any
+// other filter is a syntax error, so a template that would fail on the server
+// fails to parse here too.
+var filters = map[string]filterFunc{
+	"default":    filterDefault,
+	"d":          filterDefault,
+	"upper":      stringFilter(strings.ToUpper),
+	"lower":      stringFilter(strings.ToLower),
+	"title":      stringFilter(title),
+	"capitalize": stringFilter(capitalize),
+	"trim":       stringFilter(strings.TrimSpace),
+	"escape":     stringFilter(html.EscapeString),
+	"e":          stringFilter(html.EscapeString),
+	"safe":       filterSafe,
+	"length":     filterLength,
+	"count":      filterLength,
+	"join":       filterJoin,
+	"replace":    filterReplace,
+	"round":      filterRound,
+	"int":        filterInt,
+	"float":      filterFloat,
+	"string":     stringFilter(func(s string) string { return s }),
+	"first":      filterFirst,
+	"last":       filterLast,
+	"abs":        filterAbs,
+	"truncate":   filterTruncate,
+	"urlencode":  stringFilter(urlencode),
+}
+
+// stringFilter adapts a string transformation into a filter
+func stringFilter(fn func(string) string) filterFunc {
+	return func(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+		return fn(toString(value)), nil
+	}
+}
+
+func filterDefault(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	fallback := args.get(0, "default_value", "")
+	if truthy(args.get(1, "boolean", false)) {
+		if !truthy(value) {
+			return fallback, nil
+		}
+		return value, nil
+	}
+	if _, ok := value.(undefined); ok {
+		return fallback, nil
+	}
+	return value, nil
+}
+
+func filterSafe(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	if _, ok := value.(undefined); ok {
+		return value, nil
+	}
+	return toString(value), nil
+}
+
+func filterLength(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	switch v := value.(type) {
+	case undefined:
+		return int64(0), nil
+	case string:
+		return int64(utf8.RuneCountInString(v)), nil
+	case []interface{}:
+		return int64(len(v)), nil
+	case map[string]interface{}:
+		return int64(len(v)), nil
+	}
+	return nil, r.errorf("object of type %s has no len()", typeName(value))
+}
+
+func filterJoin(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	items, err := r.iterate(value)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		if attribute, ok := args.get(1, "attribute", nil).(string); ok {
+			item = getItem(item, attribute)
+		}
+		parts[i] = r.str(item)
+	}
+	return strings.Join(parts, toString(args.get(0, "d", ""))), nil
+}
+
+func filterReplace(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	count := int64(-1)
+	if n, ok := toInt(args.get(2, "count", nil)); ok {
+		count = n
+	}
+	return strings.Replace(toString(value), toString(args.get(0, "old", "")), toString(args.get(1, "new", "")), int(count)), nil
+}
+
+func filterRound(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	f, ok := toNumber(value)
+	if !ok {
+		return nil, r.errorf("round filter requires a number, not %s", typeName(value))
+	}
+	precision, _ := toInt(args.get(0, "precision", int64(0)))
+	shift := math.Pow(10, float64(precision))
+	switch method := toString(args.get(1, "method", "common")); method {
+	case "common":
+		f = math.Round(f*shift) / shift
+	case "ceil":
+		f = math.Ceil(f*shift) / shift
+	case "floor":
+		f = math.Floor(f*shift) / shift
+	default:
+		return nil, r.errorf("round method must be 'common', 'ceil' or 'floor', not %q", method)
+	}
+	return f, nil
+}
+
+func filterInt(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	fallback := args.get(0, "default", int64(0))
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case bool:
+		n, _ := toInt(v)
+		return n, nil
+	case float64:
+		return int64(v), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return int64(f), nil
+		}
+	}
+	return fallback, nil
+}
+
+func filterFloat(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	fallback := args.get(0, "default", 0.0)
+	if f, ok := toNumber(value); ok {
+		return f, nil
+	}
+	if s, ok := value.(string); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return f, nil
+		}
+	}
+	return fallback, nil
+}
+
+func filterFirst(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	items, err := r.iterate(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return undefined{}, nil
+	}
+	return items[0], nil
+}
+
+func filterLast(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	items, err := r.iterate(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return undefined{}, nil
+	}
+	return items[len(items)-1], nil
+}
+
+func filterAbs(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return -v, nil
+		}
+		return v, nil
+	case float64:
+		return math.Abs(v), nil
+	}
+	return nil, r.errorf("bad operand type for abs(): %s", typeName(value))
+}
+
+// filterTruncate mirrors Jinja2's truncate, including its leeway: text at
+// most length+leeway characters long is left alone.
+func filterTruncate(r *renderer, value interface{}, args filterArgs) (interface{}, error) {
+	s := []rune(toString(value))
+	length, _ := toInt(args.get(0, "length", int64(255)))
+	killwords := truthy(args.get(1, "killwords", false))
+	end := []rune(toString(args.get(2, "end", "...")))
+	leeway, _ := toInt(args.get(3, "leeway", int64(5)))
+
+	if int64(len(s)) <= length+leeway {
+		return string(s), nil
+	}
+	cut := int(length) - len(end)
+	if cut < 0 {
+		cut = 0
+	}
+	if killwords {
+		return string(s[:cut]) + string(end), nil
+	}
+	kept := string(s[:cut])
+	if idx := strings.LastIndex(kept, " "); idx >= 0 {
+		kept = kept[:idx]
+	}
+	return kept + string(end), nil
+}
+
+// title upper-cases the first letter of every word and lower-cases the rest
+func title(s string) string {
+	var sb strings.Builder
+	start := true
+	for _, c := range s {
+		if start {
+			sb.WriteRune(unicode.ToUpper(c))
+		} else {
+			sb.WriteRune(unicode.ToLower(c))
+		}
+		start = !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '\''
+	}
+	return sb.String()
+}
+
+func capitalize(s string) string {
+	c, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(c)) + strings.ToLower(s[size:])
+}
+
+// urlencode quotes a string for use in a URL, keeping slashes as Jinja2 does
+func urlencode(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "%2F", "/")
+}
+
+type testFunc func(value interface{}) bool
+
+// tests are the Jinja2 tests usable with `is` and `is not`
+var tests = map[string]testFunc{
+	"defined": func(value interface{}) bool {
+		_, ok := value.(undefined)
+		return !ok
+	},
+	"undefined": func(value interface{}) bool {
+		_, ok := value.(undefined)
+		return ok
+	},
+	"none": func(value interface{}) bool { return value == nil },
+	"number": func(value interface{}) bool {
+		switch value.(type) {
+		case int64, float64:
+			return true
+		}
+		return false
+	},
+	"string": func(value interface{}) bool {
+		_, ok := value.(string)
+		return ok
+	},
+	"mapping": func(value interface{}) bool {
+		_, ok := value.(map[string]interface{})
+		return ok
+	},
+	"even": func(value interface{}) bool {
+		n, ok := value.(int64)
+		return ok && n%2 == 0
+	},
+	"odd": func(value interface{}) bool {
+		n, ok := value.(int64)
+		return ok && n%2 != 0
+	},
+}
+
+type methodFunc func(r *renderer, obj interface{}, args []interface{}) (interface{}, error)
+
+// methods are the Python dict and str methods templates commonly call
+var methods = map[string]methodFunc{
+	"items": func(r *renderer, obj interface{}, args []interface{}) (interface{}, error) {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil, r.errorf("%s has no method items", typeName(obj))
+		}
+		items := make([]interface{}, 0, len(m))
+		for _, key := range mapKeys(m) {
+			items = append(items, []interface{}{key, m[key]})
+		}
+		return items, nil
+	},
+	"keys": func(r *renderer, obj interface{}, args []interface{}) (interface{}, error) {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil, r.errorf("%s has no method keys", typeName(obj))
+		}
+		keys := make([]interface{}, 0, len(m))
+		for _, key := range mapKeys(m) {
+			keys = append(keys, key)
+		}
+		return keys, nil
+	},
+	"values": func(r *renderer, obj interface{}, args []interface{}) (interface{}, error) {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil, r.errorf("%s has no method values", typeName(obj))
+		}
+		values := make([]interface{}, 0, len(m))
+		for _, key := range mapKeys(m) {
+			values = append(values, m[key])
+		}
+		return values, nil
+	},
+	"get": func(r *renderer, obj interface{}, args []interface{}) (interface{}, error) {
+		m, ok := obj.(map[string]interface{})
+		if !ok || len(args) == 0 {
+			return nil, r.errorf("get requires a dict and a key")
+		}
+		if key, ok := args[0].(string); ok {
+			if value, found := m[key]; found {
+				return value, nil
+			}
+		}
+		if len(args) > 1 {
+			return args[1], nil
+		}
+		return nil, nil
+	},
+	"upper": func(r *renderer, obj interface{}, args []interface{}) (interface{}, error) {
+		return strings.ToUpper(toString(obj)), nil
+	},
+	"lower": func(r *renderer, obj interface{}, args []interface{}) (interface{}, error) {
+		return strings.ToLower(toString(obj)), nil
+	},
+	"strip": func(r *renderer, obj interface{}, args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(toString(obj)), nil
+	},
+}
@@ -0,0 +1,146 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// segmentKind identifies the kind of a template segment
+type segmentKind int
+
+const (
+	segmentText segmentKind = iota
+	segmentOutput
+	segmentTag
+)
+
+// segment is a run of literal text, an `{{ }}` expression or a `{% %}` tag
+type segment struct {
+	kind    segmentKind
+	content string
+	line    int
+}
+
+// SyntaxError reports a template that cannot be parsed
+type SyntaxError struct {
+	Template string
+	Line     int
+	Message  string
+}
+
+// Error implements the error interface
+func (e *SyntaxError) Error() string {
+	if e.Template != "" {
+		return fmt.Sprintf("template %s: line %d: %s", e.Template, e.Line, e.Message)
+	}
+	return fmt.Sprintf("template line %d: %s", e.Line, e.Message)
+}
+
+// delimiters maps each opening delimiter to its closing one
+var delimiters = map[string]string{"{{": "}}", "{%": "%}", "{#": "#}"}
+
+// splitSegments cuts src into text, output and tag segments, dropping
+// comments, applying `-` whitespace control and keeping `{% raw %}` blocks as
+// literal text.
+func splitSegments(name, src string) ([]segment, error) {
+	var segments []segment
+	line := 1
+	trimNext := false
+
+	for {
+		start := nextDelimiter(src)
+		if start < 0 {
+			segments = appendText(segments, src, line, trimNext)
+			return segments, nil
+		}
+
+		text := src[:start]
+		opener := src[start : start+2]
+		body := src[start+2:]
+		if strings.HasPrefix(body, "-") {
+			body = body[1:]
+			text = strings.TrimRightFunc(text, unicode.IsSpace)
+		}
+		segments = appendText(segments, text, line, trimNext)
+		line += strings.Count(src[:start], "\n")
+
+		end := strings.Index(body, delimiters[opener])
+		if end < 0 {
+			return nil, &SyntaxError{Template: name, Line: line, Message: fmt.Sprintf("unclosed %s", opener)}
+		}
+		content := body[:end]
+		rest := body[end+2:]
+		trimNext = strings.HasSuffix(content, "-")
+		content = strings.TrimSpace(strings.TrimSuffix(content, "-"))
+		tagLine := line
+		line += strings.Count(body[:end+2], "\n")
+
+		switch {
+		case opener == "{{":
+			segments = append(segments, segment{kind: segmentOutput, content: content, line: tagLine})
+		case opener == "{%" && content == "raw":
+			raw, after, trimAfter, err := readRaw(name, rest, tagLine)
+			if err != nil {
+				return nil, err
+			}
+			segments = appendText(segments, raw, line, trimNext)
+			line += strings.Count(rest[:len(rest)-len(after)], "\n")
+			rest = after
+			trimNext = trimAfter
+		case opener == "{%":
+			segments = append(segments, segment{kind: segmentTag, content: content, line: tagLine})
+		}
+		src = rest
+	}
+}
+
+// nextDelimiter returns the offset of the next `{{`, `{%` or `{#` in src, or
+// -1 when there is none.
+func nextDelimiter(src string) int {
+	for i := 0; i+1 < len(src); i++ {
+		if src[i] == '{' && strings.IndexByte("{%#", src[i+1]) >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// readRaw returns the literal text up to the matching `{% endraw %}`, the
+// source after it, and whether the endraw tag asked for trailing whitespace to
+// be trimmed.
+func readRaw(name, src string, line int) (string, string, bool, error) {
+	offset := 0
+	for {
+		idx := strings.Index(src[offset:], "{%")
+		if idx < 0 {
+			return "", "", false, &SyntaxError{Template: name, Line: line, Message: "unclosed raw block"}
+		}
+		idx += offset
+		end := strings.Index(src[idx:], "%}")
+		if end < 0 {
+			return "", "", false, &SyntaxError{Template: name, Line: line, Message: "unclosed raw block"}
+		}
+		inner := src[idx+2 : idx+end]
+		if strings.Trim(inner, "- \t\r\n") == "endraw" {
+			raw := src[:idx]
+			if strings.HasPrefix(inner, "-") {
+				raw = strings.TrimRightFunc(raw, unicode.IsSpace)
+			}
+			return raw, src[idx+end+2:], strings.HasSuffix(inner, "-"), nil
+		}
+		offset = idx + 2
+	}
+}
+
+// appendText adds a text segment, trimming its leading whitespace when the
+// preceding tag ended with `-`.
+func appendText(segments []segment, text string, line int, trimLeading bool) []segment {
+	if trimLeading {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	}
+	if text == "" {
+		return segments
+	}
+	return append(segments, segment{kind: segmentText, content: text, line: line})
+}
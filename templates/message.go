@@ -0,0 +1,225 @@
+package templates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+)
+
+// MessageTemplates holds the parsed subject and content templates of a
+// message. Fields for content the message does not have are nil.
+type MessageTemplates struct {
+	Subject *Template
+	HTML    *Template
+	Text    *Template
+	AMP     *Template
+}
+
+// ParseMessage parses the subject and content of a message
+func ParseMessage(request *requests.CreateMessageRequest) (*MessageTemplates, error) {
+	var (
+		m   MessageTemplates
+		err error
+	)
+	if m.Subject, err = Parse("subject", request.Subject); err != nil {
+		return nil, err
+	}
+	if m.HTML, err = parseOptional("html_content", request.HtmlContent); err != nil {
+		return nil, err
+	}
+	if m.Text, err = parseOptional("text_content", request.TextContent); err != nil {
+		return nil, err
+	}
+	if m.AMP, err = parseOptional("amp_content", request.AmpContent); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func parseOptional(name string, src *string) (*Template, error) {
+	if src == nil {
+		return nil, nil
+	}
+	return Parse(name, *src)
+}
+
+// Variables returns the sorted names of the variables any of the templates
+// read
+func (m *MessageTemplates) Variables() []string {
+	found := make(map[string]bool)
+	for _, t := range m.all() {
+		for _, name := range t.Variables() {
+			found[name] = true
+		}
+	}
+	return sortedKeys(found)
+}
+
+func (m *MessageTemplates) all() []*Template {
+	var all []*Template
+	for _, t := range []*Template{m.Subject, m.HTML, m.Text, m.AMP} {
+		if t != nil {
+			all = append(all, t)
+		}
+	}
+	return all
+}
+
+// RecipientPreview is a message as one recipient will receive it
+type RecipientPreview struct {
+	Recipient common.Recipient
+
+	// Rendered subject and content. HTML, Text and AMP are nil when the
+	// message has no such content.
+	Subject string
+	HTML    *string
+	Text    *string
+	AMP     *string
+
+	// Missing lists the undefined variables the rendered output used
+	Missing []string
+	// Unused lists the substitutions no template refers to
+	Unused []string
+}
+
+// Render renders the templates for one recipient, merging the message-level
+// substitutions with the recipient's own, which take precedence
+func (m *MessageTemplates) Render(global map[string]interface{}, recipient common.Recipient) (*RecipientPreview, error) {
+	data := mergeSubstitutions(global, recipient.Substitutions)
+	preview := &RecipientPreview{Recipient: recipient}
+	missing := make(map[string]bool)
+
+	render := func(t *Template) (*string, error) {
+		if t == nil {
+			return nil, nil
+		}
+		output, names, err := t.Render(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			missing[name] = true
+		}
+		return &output, nil
+	}
+
+	subject, err := render(m.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if subject != nil {
+		preview.Subject = *subject
+	}
+	if preview.HTML, err = render(m.HTML); err != nil {
+		return nil, err
+	}
+	if preview.Text, err = render(m.Text); err != nil {
+		return nil, err
+	}
+	if preview.AMP, err = render(m.AMP); err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	for _, name := range m.Variables() {
+		used[name] = true
+	}
+	for name := range data {
+		if !used[name] {
+			preview.Unused = append(preview.Unused, name)
+		}
+	}
+	sort.Strings(preview.Unused)
+	preview.Missing = sortedKeys(missing)
+	return preview, nil
+}
+
+// Preview renders a message for each of its recipients, in order
+func Preview(request *requests.CreateMessageRequest) ([]RecipientPreview, error) {
+	m, err := ParseMessage(request)
+	if err != nil {
+		return nil, err
+	}
+	previews := make([]RecipientPreview, 0, len(request.Recipients))
+	for _, recipient := range request.Recipients {
+		preview, err := m.Render(request.Substitutions, recipient)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %s: %w", recipient.Email, err)
+		}
+		previews = append(previews, *preview)
+	}
+	return previews, nil
+}
+
+// MissingVariablesError reports recipients whose message would render with
+// undefined variables
+type MissingVariablesError struct {
+	// Missing maps a recipient's email address to the variables it lacks
+	Missing map[string][]string
+}
+
+// Error implements the error interface
+func (e *MissingVariablesError) Error() string {
+	emails := make([]string, 0, len(e.Missing))
+	for email := range e.Missing {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	parts := make([]string, len(emails))
+	for i, email := range emails {
+		parts[i] = fmt.Sprintf("%s (%s)", email, strings.Join(e.Missing[email], ", "))
+	}
+	return fmt.Sprintf("missing substitutions for %d recipient(s): %s", len(emails), strings.Join(parts, "; "))
+}
+
+// Check renders a message for every recipient and returns a
+// *MissingVariablesError when any of them would receive output with undefined
+// variables. Syntax and render errors are returned as they are.
+func Check(request *requests.CreateMessageRequest) error {
+	previews, err := Preview(request)
+	if err != nil {
+		return err
+	}
+	missing := make(map[string][]string)
+	for _, preview := range previews {
+		if len(preview.Missing) > 0 {
+			missing[preview.Recipient.Email] = preview.Missing
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingVariablesError{Missing: missing}
+	}
+	return nil
+}
+
+// PreflightCheck runs Check on a CreateMessageRequest body and accepts any
+// other body. It is meant for api.WithPreflightCheck:
+//
+//	client.MessagesAPI.CreateMessage(ctx, accountID, request,
+//		api.WithPreflightCheck(templates.PreflightCheck))
+func PreflightCheck(body interface{}) error {
+	switch request := body.(type) {
+	case requests.CreateMessageRequest:
+		return Check(&request)
+	case *requests.CreateMessageRequest:
+		return Check(request)
+	}
+	return nil
+}
+
+// mergeSubstitutions combines message-level and recipient substitutions,
+// recipient values winning
+func mergeSubstitutions(global, recipient map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(global)+len(recipient))
+	for key, value := range global {
+		merged[key] = value
+	}
+	for key, value := range recipient {
+		merged[key] = value
+	}
+	return merged
+}
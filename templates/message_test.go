@@ -0,0 +1,69 @@
+package templates
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequest() requests.CreateMessageRequest {
+	return requests.CreateMessageRequest{
+		From:        common.SenderAddress{Email: "sender@example.com"},
+		Subject:     "Order {{ order_id }} for {{ first_name }}",
+		HtmlContent: ahasend.String("<p>Hi {{ first_name }} from {{ company }}</p>"),
+		TextContent: ahasend.String("Hi {{ first_name }}"),
+		Substitutions: map[string]interface{}{
+			"company":  "Example",
+			"order_id": "X-1",
+			"unused":   true,
+		},
+		Recipients: []common.Recipient{
+			{Email: "ada@example.com", Substitutions: map[string]interface{}{"first_name": "Ada", "order_id": "A-7"}},
+			{Email: "bob@example.com"},
+		},
+	}
+}
+
+func TestPreview(t *testing.T) {
+	request := newRequest()
+	previews, err := Preview(&request)
+	require.NoError(t, err)
+	require.Len(t, previews, 2)
+
+	ada := previews[0]
+	assert.Equal(t, "ada@example.com", ada.Recipient.Email)
+	assert.Equal(t, "Order A-7 for Ada", ada.Subject, "recipient substitutions win")
+	assert.Equal(t, "<p>Hi Ada from Example</p>", *ada.HTML)
+	assert.Equal(t, "Hi Ada", *ada.Text)
+	assert.Nil(t, ada.AMP)
+	assert.Empty(t, ada.Missing)
+	assert.Equal(t, []string{"unused"}, ada.Unused)
+
+	bob := previews[1]
+	assert.Equal(t, "Order X-1 for ", bob.Subject)
+	assert.Equal(t, []string{"first_name"}, bob.Missing)
+}
+
+func TestCheck(t *testing.T) {
+	request := newRequest()
+	err := Check(&request)
+
+	var missingErr *MissingVariablesError
+	require.True(t, errors.As(err, &missingErr))
+	assert.Equal(t, map[string][]string{"bob@example.com": {"first_name"}}, missingErr.Missing)
+	assert.Contains(t, err.Error(), "bob@example.com (first_name)")
+
+	request.Substitutions["first_name"] = "there"
+	assert.NoError(t, Check(&request))
+	assert.NoError(t, PreflightCheck(request), "request values are accepted")
+	assert.NoError(t, PreflightCheck(map[string]string{}), "other bodies are ignored")
+
+	request.Subject = "{{ broken"
+	var syntaxErr *SyntaxError
+	assert.True(t, errors.As(PreflightCheck(&request), &syntaxErr))
+}
@@ -0,0 +1,790 @@
+package templates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// node is a statement in a parsed template
+type node interface{}
+
+type textNode struct {
+	text string
+}
+
+type outputNode struct {
+	expr expr
+	line int
+}
+
+type ifBranch struct {
+	cond expr
+	body []node
+}
+
+type ifNode struct {
+	branches []ifBranch
+	elseBody []node
+}
+
+type forNode struct {
+	targets  []string
+	iter     expr
+	body     []node
+	elseBody []node
+	line     int
+}
+
+type setNode struct {
+	name string
+	expr expr
+}
+
+// expr is an expression inside `{{ }}` or a tag
+type expr interface{}
+
+type literalExpr struct {
+	value interface{}
+}
+
+type nameExpr struct {
+	name string
+}
+
+type attrExpr struct {
+	obj  expr
+	name string
+}
+
+type indexExpr struct {
+	obj expr
+	key expr
+}
+
+type callExpr struct {
+	obj    expr
+	method string
+	args   []expr
+}
+
+type filterExpr struct {
+	value  expr
+	name   string
+	args   []expr
+	kwargs map[string]expr
+}
+
+type testExpr struct {
+	value  expr
+	name   string
+	negate bool
+}
+
+type unaryExpr struct {
+	op    string
+	value expr
+}
+
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+type condExpr struct {
+	cond, then, otherwise expr
+}
+
+type listExpr struct {
+	items []expr
+}
+
+// tokenKind identifies the kind of an expression token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenName
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+// operators lists multi-character operators before their prefixes
+var operators = []string{"==", "!=", "<=", ">=", "//", "<", ">", "+", "-", "*", "/", "%", "~", "|", ".", ",", "(", ")", "[", "]", "=", ":"}
+
+// tokenize splits an expression into tokens
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, value: src[start:i]})
+		case isDigit(c):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '_') {
+				i++
+			}
+			if i+1 < len(src) && src[i] == '.' && isDigit(src[i+1]) {
+				i++
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: strings.ReplaceAll(src[start:i], "_", "")})
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(src) {
+				if src[i] == '\\' && i+1 < len(src) {
+					sb.WriteByte(unescapeChar(src[i+1]))
+					i += 2
+					continue
+				}
+				if src[i] == c {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, value: sb.String()})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, value: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func unescapeChar(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	}
+	return c
+}
+
+// exprParser is a recursive-descent parser over the tokens of one tag
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func newExprParser(src string) (*exprParser, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	return &exprParser{tokens: tokens}, nil
+}
+
+func (p *exprParser) peek() token { return p.tokens[p.pos] }
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token when it is the given operator or keyword
+func (p *exprParser) accept(value string) bool {
+	tok := p.peek()
+	if (tok.kind == tokenOperator || tok.kind == tokenName) && tok.value == value {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(value string) error {
+	if !p.accept(value) {
+		return fmt.Errorf("expected %q, found %s", value, describe(p.peek()))
+	}
+	return nil
+}
+
+func (p *exprParser) expectName() (string, error) {
+	tok := p.next()
+	if tok.kind != tokenName {
+		return "", fmt.Errorf("expected a name, found %s", describe(tok))
+	}
+	return tok.value, nil
+}
+
+func (p *exprParser) expectEnd() error {
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return fmt.Errorf("unexpected %s", describe(tok))
+	}
+	return nil
+}
+
+func describe(tok token) string {
+	switch tok.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(tok.value)
+	}
+	return fmt.Sprintf("%q", tok.value)
+}
+
+// parseExpr parses a full expression, including the `a if b else c` form
+func (p *exprParser) parseExpr() (expr, error) {
+	value, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("if") {
+		return value, nil
+	}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	var otherwise expr = literalExpr{value: undefined{}}
+	if p.accept("else") {
+		if otherwise, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	return condExpr{cond: cond, then: value, otherwise: otherwise}, nil
+}
+
+func (p *exprParser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (expr, error) {
+	if p.accept("not") {
+		value, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op: "not", value: value}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (expr, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch tok := p.peek(); {
+		case tok.kind == tokenOperator && strings.Contains(" == != < > <= >= ", " "+tok.value+" "):
+			op = p.next().value
+		case tok.kind == tokenName && tok.value == "in":
+			p.next()
+			op = "in"
+		case tok.kind == tokenName && tok.value == "not" && p.tokens[p.pos+1].kind == tokenName && p.tokens[p.pos+1].value == "in":
+			p.pos += 2
+			op = "not in"
+		case tok.kind == tokenName && tok.value == "is":
+			p.next()
+			negate := p.accept("not")
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := tests[name]; !ok {
+				return nil, fmt.Errorf("unknown test %q", name)
+			}
+			left = testExpr{value: left, name: name, negate: negate}
+			continue
+		default:
+			return left, nil
+		}
+		right, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseConcat() (expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("~") {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: "~", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAdditive() (expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokenOperator || (tok.value != "+" && tok.value != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: tok.value, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseMultiplicative() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokenOperator || !strings.Contains(" * / // % ", " "+tok.value+" ") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: tok.value, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (expr, error) {
+	if p.accept("-") {
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op: "-", value: value}, nil
+	}
+	if p.accept("+") {
+		return p.parseUnary()
+	}
+	return p.parseFiltered()
+}
+
+func (p *exprParser) parseFiltered() (expr, error) {
+	value, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := filters[name]; !ok {
+			return nil, fmt.Errorf("unknown filter %q", name)
+		}
+		f := filterExpr{value: value, name: name}
+		if p.accept("(") {
+			if f.args, f.kwargs, err = p.parseArgs(); err != nil {
+				return nil, err
+			}
+		}
+		value = f
+	}
+	return value, nil
+}
+
+// parseArgs parses a call's arguments after the opening parenthesis
+func (p *exprParser) parseArgs() ([]expr, map[string]expr, error) {
+	var args []expr
+	var kwargs map[string]expr
+	for !p.accept(")") {
+		if len(args)+len(kwargs) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, nil, err
+			}
+			if p.accept(")") {
+				break
+			}
+		}
+		if tok := p.peek(); tok.kind == tokenName && p.tokens[p.pos+1].value == "=" && p.tokens[p.pos+1].kind == tokenOperator {
+			p.pos += 2
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, err
+			}
+			if kwargs == nil {
+				kwargs = make(map[string]expr)
+			}
+			kwargs[tok.value] = value
+			continue
+		}
+		if kwargs != nil {
+			return nil, nil, fmt.Errorf("positional argument follows keyword argument")
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, value)
+	}
+	return args, kwargs, nil
+}
+
+func (p *exprParser) parsePostfix() (expr, error) {
+	value, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if p.accept("(") {
+				if _, ok := methods[name]; !ok {
+					return nil, fmt.Errorf("unknown method %q", name)
+				}
+				args, kwargs, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				if kwargs != nil {
+					return nil, fmt.Errorf("method %q does not take keyword arguments", name)
+				}
+				value = callExpr{obj: value, method: name, args: args}
+				continue
+			}
+			value = attrExpr{obj: value, name: name}
+		case p.accept("["):
+			key, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			value = indexExpr{obj: value, key: key}
+		default:
+			return value, nil
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary() (expr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		if strings.Contains(tok.value, ".") {
+			f, err := strconv.ParseFloat(tok.value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", tok.value)
+			}
+			return literalExpr{value: f}, nil
+		}
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.value)
+		}
+		return literalExpr{value: n}, nil
+	case tokenString:
+		return literalExpr{value: tok.value}, nil
+	case tokenName:
+		switch tok.value {
+		case "true", "True":
+			return literalExpr{value: true}, nil
+		case "false", "False":
+			return literalExpr{value: false}, nil
+		case "none", "None":
+			return literalExpr{value: nil}, nil
+		}
+		return nameExpr{name: tok.value}, nil
+	case tokenOperator:
+		switch tok.value {
+		case "(":
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return value, nil
+		case "[":
+			var items []expr
+			for !p.accept("]") {
+				if len(items) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+					if p.accept("]") {
+						break
+					}
+				}
+				item, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			return listExpr{items: items}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s", describe(tok))
+}
+
+// templateParser builds the statement tree from a template's segments
+type templateParser struct {
+	name     string
+	segments []segment
+	pos      int
+}
+
+// parseBody parses statements until one of the given end tags, returning the
+// tag keyword and remaining content of the tag that stopped it
+func (p *templateParser) parseBody(endTags ...string) ([]node, string, string, error) {
+	var nodes []node
+	for p.pos < len(p.segments) {
+		seg := p.segments[p.pos]
+		p.pos++
+
+		switch seg.kind {
+		case segmentText:
+			nodes = append(nodes, textNode{text: seg.content})
+		case segmentOutput:
+			e, err := p.parseExpression(seg, seg.content)
+			if err != nil {
+				return nil, "", "", err
+			}
+			nodes = append(nodes, outputNode{expr: e, line: seg.line})
+		case segmentTag:
+			keyword, rest := splitKeyword(seg.content)
+			for _, end := range endTags {
+				if keyword == end {
+					return nodes, keyword, rest, nil
+				}
+			}
+			n, err := p.parseTag(seg, keyword, rest)
+			if err != nil {
+				return nil, "", "", err
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	if len(endTags) > 0 {
+		return nil, "", "", p.errorf(p.lastLine(), "missing {%% %s %%}", endTags[len(endTags)-1])
+	}
+	return nodes, "", "", nil
+}
+
+func (p *templateParser) parseTag(seg segment, keyword, rest string) (node, error) {
+	switch keyword {
+	case "if":
+		return p.parseIf(seg, rest)
+	case "for":
+		return p.parseFor(seg, rest)
+	case "set":
+		return p.parseSet(seg, rest)
+	case "":
+		return nil, p.errorf(seg.line, "empty tag")
+	}
+	return nil, p.errorf(seg.line, "unknown tag %q", keyword)
+}
+
+func (p *templateParser) parseIf(seg segment, rest string) (node, error) {
+	var n ifNode
+	cond, err := p.parseExpression(seg, rest)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		body, end, endRest, err := p.parseBody("elif", "else", "endif")
+		if err != nil {
+			return nil, err
+		}
+		n.branches = append(n.branches, ifBranch{cond: cond, body: body})
+		switch end {
+		case "elif":
+			if cond, err = p.parseExpression(seg, endRest); err != nil {
+				return nil, err
+			}
+		case "else":
+			if n.elseBody, _, _, err = p.parseBody("endif"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *templateParser) parseFor(seg segment, rest string) (node, error) {
+	ep, err := newExprParser(rest)
+	if err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	n := forNode{line: seg.line}
+	for {
+		target, err := ep.expectName()
+		if err != nil {
+			return nil, p.errorf(seg.line, "%v", err)
+		}
+		n.targets = append(n.targets, target)
+		if !ep.accept(",") {
+			break
+		}
+	}
+	if len(n.targets) > 2 {
+		return nil, p.errorf(seg.line, "for loops unpack at most two targets")
+	}
+	if err := ep.expect("in"); err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	if n.iter, err = ep.parseOr(); err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	if err := ep.expectEnd(); err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+
+	body, end, _, err := p.parseBody("else", "endfor")
+	if err != nil {
+		return nil, err
+	}
+	n.body = body
+	if end == "else" {
+		if n.elseBody, _, _, err = p.parseBody("endfor"); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (p *templateParser) parseSet(seg segment, rest string) (node, error) {
+	ep, err := newExprParser(rest)
+	if err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	name, err := ep.expectName()
+	if err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	if err := ep.expect("="); err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	value, err := ep.parseExpr()
+	if err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	if err := ep.expectEnd(); err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	return setNode{name: name, expr: value}, nil
+}
+
+// parseExpression parses src as a complete expression
+func (p *templateParser) parseExpression(seg segment, src string) (expr, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, p.errorf(seg.line, "missing expression")
+	}
+	ep, err := newExprParser(src)
+	if err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	e, err := ep.parseExpr()
+	if err == nil {
+		err = ep.expectEnd()
+	}
+	if err != nil {
+		return nil, p.errorf(seg.line, "%v", err)
+	}
+	return e, nil
+}
+
+func (p *templateParser) errorf(line int, format string, args ...interface{}) error {
+	return &SyntaxError{Template: p.name, Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *templateParser) lastLine() int {
+	if len(p.segments) == 0 {
+		return 1
+	}
+	return p.segments[len(p.segments)-1].line
+}
+
+// splitKeyword separates a tag's leading keyword from the rest of its content
+func splitKeyword(content string) (string, string) {
+	idx := strings.IndexAny(content, " \t\r\n")
+	if idx < 0 {
+		return content, ""
+	}
+	return content[:idx], strings.TrimSpace(content[idx:])
+}
@@ -0,0 +1,741 @@
+package templates
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// undefined is the value of a variable, attribute or key that does not exist.
+// path names it for the missing-variable report.
+type undefined struct {
+	path string
+}
+
+// renderer holds the state of a single Render call
+type renderer struct {
+	template string
+	scopes   []map[string]interface{}
+	missing  map[string]bool
+	line     int
+}
+
+// RenderError reports a template that failed while rendering, such as a type
+// mismatch in an expression
+type RenderError struct {
+	Template string
+	Line     int
+	Message  string
+}
+
+// Error implements the error interface
+func (e *RenderError) Error() string {
+	if e.Template != "" {
+		return fmt.Sprintf("template %s: line %d: %s", e.Template, e.Line, e.Message)
+	}
+	return fmt.Sprintf("template line %d: %s", e.Line, e.Message)
+}
+
+func (r *renderer) errorf(format string, args ...interface{}) error {
+	return &RenderError{Template: r.template, Line: r.line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (r *renderer) renderNodes(out *strings.Builder, nodes []node) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case textNode:
+			out.WriteString(n.text)
+		case outputNode:
+			r.line = n.line
+			value, err := r.eval(n.expr)
+			if err != nil {
+				return err
+			}
+			out.WriteString(r.str(value))
+		case ifNode:
+			rendered := false
+			for _, branch := range n.branches {
+				cond, err := r.eval(branch.cond)
+				if err != nil {
+					return err
+				}
+				if truthy(cond) {
+					if err := r.renderNodes(out, branch.body); err != nil {
+						return err
+					}
+					rendered = true
+					break
+				}
+			}
+			if !rendered {
+				if err := r.renderNodes(out, n.elseBody); err != nil {
+					return err
+				}
+			}
+		case forNode:
+			if err := r.renderFor(out, n); err != nil {
+				return err
+			}
+		case setNode:
+			value, err := r.eval(n.expr)
+			if err != nil {
+				return err
+			}
+			r.scopes[len(r.scopes)-1][n.name] = value
+		}
+	}
+	return nil
+}
+
+func (r *renderer) renderFor(out *strings.Builder, n forNode) error {
+	r.line = n.line
+	iterable, err := r.eval(n.iter)
+	if err != nil {
+		return err
+	}
+	items, err := r.iterate(iterable)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return r.renderNodes(out, n.elseBody)
+	}
+
+	scope := make(map[string]interface{})
+	r.scopes = append(r.scopes, scope)
+	defer func() { r.scopes = r.scopes[:len(r.scopes)-1] }()
+
+	for i, item := range items {
+		if len(n.targets) == 2 {
+			pair, ok := item.([]interface{})
+			if !ok || len(pair) != 2 {
+				r.line = n.line
+				return r.errorf("cannot unpack %s into two loop variables", typeName(item))
+			}
+			scope[n.targets[0]], scope[n.targets[1]] = pair[0], pair[1]
+		} else {
+			scope[n.targets[0]] = item
+		}
+		scope["loop"] = map[string]interface{}{
+			"index":     int64(i + 1),
+			"index0":    int64(i),
+			"revindex":  int64(len(items) - i),
+			"revindex0": int64(len(items) - i - 1),
+			"first":     i == 0,
+			"last":      i == len(items)-1,
+			"length":    int64(len(items)),
+		}
+		if err := r.renderNodes(out, n.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterate returns the items a for loop visits. Maps yield their keys in
+// sorted order.
+func (r *renderer) iterate(value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case undefined:
+		r.use(v)
+		return nil, nil
+	case nil:
+		return nil, r.errorf("None is not iterable")
+	case []interface{}:
+		return v, nil
+	case map[string]interface{}:
+		keys := make([]interface{}, 0, len(v))
+		for _, key := range mapKeys(v) {
+			keys = append(keys, key)
+		}
+		return keys, nil
+	case string:
+		chars := make([]interface{}, 0, len(v))
+		for _, c := range v {
+			chars = append(chars, string(c))
+		}
+		return chars, nil
+	}
+	return nil, r.errorf("%s is not iterable", typeName(value))
+}
+
+// use records an undefined value that reached the output
+func (r *renderer) use(value interface{}) {
+	if u, ok := value.(undefined); ok && u.path != "" {
+		r.missing[u.path] = true
+	}
+}
+
+// str converts a value to its output form, recording undefined values
+func (r *renderer) str(value interface{}) string {
+	r.use(value)
+	return toString(value)
+}
+
+func (r *renderer) lookup(name string) interface{} {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if value, ok := r.scopes[i][name]; ok {
+			return value
+		}
+	}
+	return undefined{path: name}
+}
+
+func (r *renderer) eval(e expr) (interface{}, error) {
+	switch e := e.(type) {
+	case literalExpr:
+		return e.value, nil
+	case nameExpr:
+		return r.lookup(e.name), nil
+	case listExpr:
+		items := make([]interface{}, 0, len(e.items))
+		for _, item := range e.items {
+			value, err := r.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	case attrExpr:
+		obj, err := r.eval(e.obj)
+		if err != nil {
+			return nil, err
+		}
+		return withPath(getItem(obj, e.name), e), nil
+	case indexExpr:
+		obj, err := r.eval(e.obj)
+		if err != nil {
+			return nil, err
+		}
+		key, err := r.eval(e.key)
+		if err != nil {
+			return nil, err
+		}
+		return withPath(getItem(obj, key), e), nil
+	case callExpr:
+		return r.evalCall(e)
+	case filterExpr:
+		return r.evalFilter(e)
+	case testExpr:
+		value, err := r.eval(e.value)
+		if err != nil {
+			return nil, err
+		}
+		return tests[e.name](value) != e.negate, nil
+	case unaryExpr:
+		value, err := r.eval(e.value)
+		if err != nil {
+			return nil, err
+		}
+		if e.op == "not" {
+			return !truthy(value), nil
+		}
+		r.use(value)
+		switch v := value.(type) {
+		case int64:
+			return -v, nil
+		case float64:
+			return -v, nil
+		}
+		return nil, r.errorf("bad operand type for unary -: %s", typeName(value))
+	case binaryExpr:
+		return r.evalBinary(e)
+	case condExpr:
+		cond, err := r.eval(e.cond)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return r.eval(e.then)
+		}
+		return r.eval(e.otherwise)
+	}
+	return nil, r.errorf("unsupported expression")
+}
+
+func (r *renderer) evalBinary(e binaryExpr) (interface{}, error) {
+	left, err := r.eval(e.left)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "and":
+		if !truthy(left) {
+			return left, nil
+		}
+		return r.eval(e.right)
+	case "or":
+		if truthy(left) {
+			return left, nil
+		}
+		return r.eval(e.right)
+	}
+
+	right, err := r.eval(e.right)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", ">", "<=", ">=":
+		return r.compare(e.op, left, right)
+	case "in", "not in":
+		found, err := r.contains(right, left)
+		if err != nil {
+			return nil, err
+		}
+		return found != (e.op == "not in"), nil
+	case "~":
+		return r.str(left) + r.str(right), nil
+	}
+
+	r.use(left)
+	r.use(right)
+	return r.arithmetic(e.op, left, right)
+}
+
+func (r *renderer) compare(op string, left, right interface{}) (interface{}, error) {
+	var cmp int
+	if ls, ok := left.(string); ok {
+		rs, ok := right.(string)
+		if !ok {
+			return nil, r.errorf("'%s' not supported between %s and %s", op, typeName(left), typeName(right))
+		}
+		cmp = strings.Compare(ls, rs)
+	} else {
+		lf, lok := toNumber(left)
+		rf, rok := toNumber(right)
+		if !lok || !rok {
+			return nil, r.errorf("'%s' not supported between %s and %s", op, typeName(left), typeName(right))
+		}
+		switch {
+		case lf < rf:
+			cmp = -1
+		case lf > rf:
+			cmp = 1
+		}
+	}
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+func (r *renderer) contains(container, item interface{}) (bool, error) {
+	switch c := container.(type) {
+	case undefined:
+		return false, nil
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, r.errorf("'in <string>' requires string as left operand, not %s", typeName(item))
+		}
+		return strings.Contains(c, s), nil
+	case []interface{}:
+		for _, value := range c {
+			if equal(value, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := item.(string)
+		if !ok {
+			return false, nil
+		}
+		_, found := c[key]
+		return found, nil
+	}
+	return false, r.errorf("argument of type %s is not iterable", typeName(container))
+}
+
+func (r *renderer) arithmetic(op string, left, right interface{}) (interface{}, error) {
+	if _, ok := left.(undefined); ok {
+		left = ""
+		if op != "+" {
+			left = int64(0)
+		}
+	}
+	if _, ok := right.(undefined); ok {
+		right = ""
+		if _, isString := left.(string); !isString {
+			right = int64(0)
+		}
+	}
+
+	switch l := left.(type) {
+	case string:
+		if s, ok := right.(string); ok && op == "+" {
+			return l + s, nil
+		}
+		if n, ok := right.(int64); ok && op == "*" {
+			return strings.Repeat(l, int(math.Max(0, float64(n)))), nil
+		}
+	case []interface{}:
+		if items, ok := right.([]interface{}); ok && op == "+" {
+			return append(append([]interface{}{}, l...), items...), nil
+		}
+	}
+
+	li, lInt := toInt(left)
+	ri, rInt := toInt(right)
+	if lInt && rInt {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "//", "%":
+			if ri == 0 {
+				return nil, r.errorf("integer division or modulo by zero")
+			}
+			div, mod := li/ri, li%ri
+			if mod != 0 && (mod < 0) != (ri < 0) {
+				div--
+				mod += ri
+			}
+			if op == "//" {
+				return div, nil
+			}
+			return mod, nil
+		}
+	}
+
+	lf, lok := toNumber(left)
+	rf, rok := toNumber(right)
+	if !lok || !rok {
+		return nil, r.errorf("unsupported operand types for %s: %s and %s", op, typeName(left), typeName(right))
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	}
+	if rf == 0 {
+		return nil, r.errorf("division by zero")
+	}
+	switch op {
+	case "/":
+		return lf / rf, nil
+	case "//":
+		return math.Floor(lf / rf), nil
+	}
+	return lf - math.Floor(lf/rf)*rf, nil
+}
+
+func (r *renderer) evalArgs(args []expr) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		value, err := r.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func (r *renderer) evalCall(e callExpr) (interface{}, error) {
+	obj, err := r.eval(e.obj)
+	if err != nil {
+		return nil, err
+	}
+	args, err := r.evalArgs(e.args)
+	if err != nil {
+		return nil, err
+	}
+	r.use(obj)
+	return methods[e.method](r, obj, args)
+}
+
+func (r *renderer) evalFilter(e filterExpr) (interface{}, error) {
+	value, err := r.eval(e.value)
+	if err != nil {
+		return nil, err
+	}
+	args, err := r.evalArgs(e.args)
+	if err != nil {
+		return nil, err
+	}
+	var kwargs map[string]interface{}
+	if len(e.kwargs) > 0 {
+		kwargs = make(map[string]interface{}, len(e.kwargs))
+		for name, arg := range e.kwargs {
+			if kwargs[name], err = r.eval(arg); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if e.name != "default" && e.name != "d" {
+		r.use(value)
+	}
+	return filters[e.name](r, value, filterArgs{positional: args, keyword: kwargs})
+}
+
+// getItem returns obj[key], or an undefined value naming the path
+func getItem(obj interface{}, key interface{}) interface{} {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		if name, ok := key.(string); ok {
+			if value, found := o[name]; found {
+				return value
+			}
+		}
+	case []interface{}:
+		if i, ok := key.(int64); ok {
+			if i < 0 {
+				i += int64(len(o))
+			}
+			if i >= 0 && i < int64(len(o)) {
+				return o[i]
+			}
+		}
+	case string:
+		if i, ok := key.(int64); ok {
+			runes := []rune(o)
+			if i < 0 {
+				i += int64(len(runes))
+			}
+			if i >= 0 && i < int64(len(runes)) {
+				return string(runes[i])
+			}
+		}
+	}
+	return undefined{path: childPath(obj, key)}
+}
+
+// withPath names an undefined attribute or item after the expression that
+// produced it, such as "order.total", when the expression is a plain chain of
+// lookups
+func withPath(value interface{}, e expr) interface{} {
+	if u, ok := value.(undefined); ok {
+		if path := exprPath(e); path != "" {
+			u.path = path
+		}
+		return u
+	}
+	return value
+}
+
+// exprPath returns the dotted path of a chain of lookups, or "" for any other
+// expression
+func exprPath(e expr) string {
+	switch e := e.(type) {
+	case nameExpr:
+		return e.name
+	case attrExpr:
+		if parent := exprPath(e.obj); parent != "" {
+			return parent + "." + e.name
+		}
+	case indexExpr:
+		parent := exprPath(e.obj)
+		key, ok := e.key.(literalExpr)
+		if parent == "" || !ok {
+			return ""
+		}
+		if name, ok := key.value.(string); ok {
+			return parent + "." + name
+		}
+		return fmt.Sprintf("%s[%s]", parent, toString(key.value))
+	}
+	return ""
+}
+
+// childPath names obj[key] for the missing-variable report
+func childPath(obj interface{}, key interface{}) string {
+	parent := ""
+	if u, ok := obj.(undefined); ok {
+		if u.path == "" {
+			return ""
+		}
+		parent = u.path
+	}
+	if name, ok := key.(string); ok {
+		if parent == "" {
+			return name
+		}
+		return parent + "." + name
+	}
+	if parent == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s[%s]", parent, toString(key))
+}
+
+// truthy reports whether a value is true in a condition
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil, undefined:
+		return false
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// equal compares two values, treating integers and floats as numbers
+func equal(left, right interface{}) bool {
+	lf, lok := toNumber(left)
+	rf, rok := toNumber(right)
+	if lok && rok {
+		return lf == rf
+	}
+	if _, ok := left.(undefined); ok {
+		_, ok = right.(undefined)
+		return ok
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// toNumber converts numeric values, including booleans as Python does, to
+// float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func toInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// toString formats a value the way Python's str does
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case undefined:
+		return ""
+	case string:
+		return v
+	}
+	return repr(value)
+}
+
+// repr formats a value the way Python's repr does, which is also how
+// containers print their items
+func repr(value interface{}) string {
+	switch v := value.(type) {
+	case undefined:
+		return ""
+	case nil:
+		return "None"
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatFloat(v)
+	case string:
+		return "'" + strings.ReplaceAll(strings.ReplaceAll(v, `\`, `\\`), "'", `\'`) + "'"
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = repr(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]interface{}:
+		parts := make([]string, 0, len(v))
+		for _, key := range mapKeys(v) {
+			parts = append(parts, repr(key)+": "+repr(v[key]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprint(value)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	abs := math.Abs(f)
+	if abs != 0 && (abs < 1e-4 || abs >= 1e16) {
+		return strconv.FormatFloat(f, 'e', -1, 64)
+	}
+	if f == math.Trunc(f) {
+		return strconv.FormatFloat(f, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case undefined:
+		return "Undefined"
+	case nil:
+		return "NoneType"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "str"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "dict"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func mapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
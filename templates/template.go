@@ -0,0 +1,201 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Template is a parsed template, safe for concurrent use
+type Template struct {
+	name  string
+	nodes []node
+}
+
+// Parse parses src as a template. name is only used in error messages.
+func Parse(name, src string) (*Template, error) {
+	segments, err := splitSegments(name, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &templateParser{name: name, segments: segments}
+	nodes, _, _, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	return &Template{name: name, nodes: nodes}, nil
+}
+
+// MustParse is like Parse but panics if the template cannot be parsed. It
+// simplifies initialisation of package-level templates.
+func MustParse(name, src string) *Template {
+	t, err := Parse(name, src)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Name returns the name the template was parsed with
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Render renders the template with data and returns the output together with
+// the sorted names of the undefined variables that reached it, such as
+// "first_name" or "order.total". A variable that is only tested in a
+// condition or given a default is not reported.
+func (t *Template) Render(data map[string]interface{}) (string, []string, error) {
+	root, err := normalize(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("template %s: %w", t.name, err)
+	}
+	r := &renderer{
+		template: t.name,
+		scopes:   []map[string]interface{}{root},
+		missing:  make(map[string]bool),
+	}
+	var out strings.Builder
+	if err := r.renderNodes(&out, t.nodes); err != nil {
+		return "", nil, err
+	}
+	return out.String(), sortedKeys(r.missing), nil
+}
+
+// Variables returns the sorted names of the top-level variables the template
+// reads from its data, excluding loop variables and names it sets itself.
+func (t *Template) Variables() []string {
+	found := make(map[string]bool)
+	collectNodes(t.nodes, map[string]bool{}, found)
+	return sortedKeys(found)
+}
+
+// normalize converts data into the plain JSON value tree the server renders,
+// so structs, typed slices and numeric types behave as they do once sent.
+func normalize(data map[string]interface{}) (map[string]interface{}, error) {
+	if data == nil {
+		return map[string]interface{}{}, nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode substitutions: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded map[string]interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode substitutions: %w", err)
+	}
+	return convertNumbers(decoded).(map[string]interface{}), nil
+}
+
+// convertNumbers replaces json.Number values with int64 or float64
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	}
+	return value
+}
+
+// collectNodes records the free variables of nodes into found. bound holds
+// the names assigned by enclosing loops and earlier set tags.
+func collectNodes(nodes []node, bound, found map[string]bool) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case outputNode:
+			collectExpr(n.expr, bound, found)
+		case ifNode:
+			for _, branch := range n.branches {
+				collectExpr(branch.cond, bound, found)
+				collectNodes(branch.body, bound, found)
+			}
+			collectNodes(n.elseBody, bound, found)
+		case forNode:
+			collectExpr(n.iter, bound, found)
+			inner := copyBound(bound)
+			inner["loop"] = true
+			for _, target := range n.targets {
+				inner[target] = true
+			}
+			collectNodes(n.body, inner, found)
+			collectNodes(n.elseBody, bound, found)
+		case setNode:
+			collectExpr(n.expr, bound, found)
+			bound[n.name] = true
+		}
+	}
+}
+
+func collectExpr(e expr, bound, found map[string]bool) {
+	switch e := e.(type) {
+	case nameExpr:
+		if !bound[e.name] {
+			found[e.name] = true
+		}
+	case attrExpr:
+		collectExpr(e.obj, bound, found)
+	case indexExpr:
+		collectExpr(e.obj, bound, found)
+		collectExpr(e.key, bound, found)
+	case callExpr:
+		collectExpr(e.obj, bound, found)
+		for _, arg := range e.args {
+			collectExpr(arg, bound, found)
+		}
+	case filterExpr:
+		collectExpr(e.value, bound, found)
+		for _, arg := range e.args {
+			collectExpr(arg, bound, found)
+		}
+		for _, arg := range e.kwargs {
+			collectExpr(arg, bound, found)
+		}
+	case testExpr:
+		collectExpr(e.value, bound, found)
+	case unaryExpr:
+		collectExpr(e.value, bound, found)
+	case binaryExpr:
+		collectExpr(e.left, bound, found)
+		collectExpr(e.right, bound, found)
+	case condExpr:
+		collectExpr(e.cond, bound, found)
+		collectExpr(e.then, bound, found)
+		collectExpr(e.otherwise, bound, found)
+	case listExpr:
+		for _, item := range e.items {
+			collectExpr(item, bound, found)
+		}
+	}
+}
+
+func copyBound(bound map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(bound)+2)
+	for name := range bound {
+		copied[name] = true
+	}
+	return copied
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,144 @@
+package templates
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, src string, data map[string]interface{}) (string, []string) {
+	t.Helper()
+	tmpl, err := Parse("test", src)
+	require.NoError(t, err)
+	output, missing, err := tmpl.Render(data)
+	require.NoError(t, err)
+	return output, missing
+}
+
+func TestRenderExpressions(t *testing.T) {
+	data := map[string]interface{}{
+		"name":  "ada lovelace",
+		"count": 3,
+		"price": 9.5,
+		"user":  map[string]interface{}{"plan": "pro", "tags": []string{"a", "b"}},
+		"items": []interface{}{"x", "y", "z"},
+	}
+
+	cases := map[string]string{
+		`Hi {{ name }}`:                                    "Hi ada lovelace",
+		`{{ name|title }}`:                                 "Ada Lovelace",
+		`{{ name|upper|replace("A", "4") }}`:               "4D4 LOVEL4CE",
+		`{{ user.plan }} {{ user["plan"] }}`:               "pro pro",
+		`{{ user.tags[1] }} {{ items[-1] }}`:               "b z",
+		`{{ count + 2 }} {{ count / 2 }}`:                  "5 1.5",
+		`{{ count // 2 }} {{ -7 % 3 }}`:                    "1 2",
+		`{{ price * 2 }} {{ price|round }}`:                "19.0 10.0",
+		`{{ "n=" ~ count }}`:                               "n=3",
+		`{{ items|join(", ") }}`:                           "x, y, z",
+		`{{ items|length }} {{ items|first }}`:             "3 x",
+		`{{ user.tags }} {{ none }} {{ true }}`:            "['a', 'b'] None True",
+		`{{ "yes" if count > 2 else "no" }}`:               "yes",
+		`{{ "b" in items }} {{ "x" in items }}`:            "False True",
+		`{{ "<b>"|e }}`:                                    "&lt;b&gt;",
+		`{{ "one two three four"|truncate(9, leeway=0) }}`: "one...",
+		`{{ "a b/c"|urlencode }}`:                          "a%20b/c",
+		`{{ user.get("plan") }} {{ user.get("x", 1) }}`:    "pro 1",
+	}
+	for src, want := range cases {
+		output, missing := render(t, src, data)
+		assert.Equal(t, want, output, src)
+		assert.Empty(t, missing, src)
+	}
+}
+
+func TestRenderStatements(t *testing.T) {
+	data := map[string]interface{}{
+		"items":  []interface{}{map[string]interface{}{"name": "Tea", "qty": 2}, map[string]interface{}{"name": "Cake", "qty": 1}},
+		"prices": map[string]interface{}{"tea": 3, "cake": 4},
+		"vip":    true,
+	}
+
+	output, _ := render(t, `{% for item in items %}{{ loop.index }}.{{ item.name }} x{{ item.qty }}{% if not loop.last %}, {% endif %}{% endfor %}`, data)
+	assert.Equal(t, "1.Tea x2, 2.Cake x1", output)
+
+	output, _ = render(t, `{% for k, v in prices.items() %}{{ k }}={{ v }};{% endfor %}`, data)
+	assert.Equal(t, "cake=4;tea=3;", output)
+
+	output, _ = render(t, `{% for x in [] %}{{ x }}{% else %}empty{% endfor %}`, data)
+	assert.Equal(t, "empty", output)
+
+	output, _ = render(t, `{% if vip %}VIP{% elif items %}regular{% else %}none{% endif %}`, data)
+	assert.Equal(t, "VIP", output)
+
+	output, _ = render(t, `{% set total = 0 %}{% set total = total + 5 %}{{ total }}`, data)
+	assert.Equal(t, "5", output)
+
+	output, _ = render(t, "<ul>\n  {%- for item in items %}\n  <li>{{ item.name }}</li>\n  {%- endfor %}\n</ul>", data)
+	assert.Equal(t, "<ul>\n  <li>Tea</li>\n  <li>Cake</li>\n</ul>", output)
+
+	output, _ = render(t, `{# note #}{% raw %}{{ not rendered }}{% endraw %}`, data)
+	assert.Equal(t, "{{ not rendered }}", output)
+}
+
+func TestRenderReportsMissingVariables(t *testing.T) {
+	output, missing := render(t, `Hi {{ first_name }}, total {{ order.total }}{% for x in lines %}{{ x }}{% endfor %}`,
+		map[string]interface{}{"order": map[string]interface{}{}})
+	assert.Equal(t, "Hi , total ", output)
+	assert.Equal(t, []string{"first_name", "lines", "order.total"}, missing)
+
+	output, missing = render(t, `{% if nickname %}{{ nickname }}{% endif %}{{ title|default("friend") }}{% if promo is defined %}!{% endif %}`, nil)
+	assert.Equal(t, "friend", output)
+	assert.Empty(t, missing, "conditions, tests and defaults do not count as missing")
+}
+
+func TestRenderNormalizesData(t *testing.T) {
+	type order struct {
+		ID    string  `json:"id"`
+		Total float64 `json:"total"`
+	}
+	output, _ := render(t, `{{ order.id }} {{ order.total }} {{ n }}`, map[string]interface{}{
+		"order": order{ID: "A1", Total: 12.25},
+		"n":     int32(7),
+	})
+	assert.Equal(t, "A1 12.25 7", output)
+}
+
+func TestVariables(t *testing.T) {
+	tmpl, err := Parse("test", `{{ greeting }} {% set x = base %}{{ x }}{% for item in items %}{{ item.name }}{{ loop.index }}{{ currency }}{% endfor %}{{ item }}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"base", "currency", "greeting", "item", "items"}, tmpl.Variables())
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"Hi {{ name ":                               "unclosed {{",
+		"{% if x %}open":                            "missing {% endif %}",
+		"{{ name|nosuchfilter }}":                   `unknown filter "nosuchfilter"`,
+		"{% include 'x' %}":                         `unknown tag "include"`,
+		"line one\n{{ a + }}":                       "line 2",
+		"{% for a, b, c in x %}{% endfor %}":        "at most two",
+		"{{ 'unterminated }}":                       "unterminated string",
+		"{% raw %}never closed":                     "unclosed raw block",
+		"{% if x %}{% else %}{% else %}{% endif %}": `unknown tag "else"`,
+	}
+	for src, want := range cases {
+		_, err := Parse("test", src)
+		require.Error(t, err, src)
+		var syntaxErr *SyntaxError
+		assert.True(t, errors.As(err, &syntaxErr), src)
+		assert.Contains(t, err.Error(), want, src)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tmpl, err := Parse("test", "\n{{ 1 / zero }}")
+	require.NoError(t, err)
+	_, _, err = tmpl.Render(map[string]interface{}{"zero": 0})
+
+	var renderErr *RenderError
+	require.True(t, errors.As(err, &renderErr))
+	assert.Equal(t, 2, renderErr.Line)
+	assert.Contains(t, err.Error(), "division by zero")
+}
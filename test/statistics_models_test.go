@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AhaSend/ahasend-go/models/common"
 	"github.com/AhaSend/ahasend-go/models/responses"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -58,7 +59,7 @@ func TestStatisticsModelsUpdatedFields(t *testing.T) {
 		assert.Equal(t, fromTime, stats.FromTimestamp)
 		assert.Equal(t, toTime, stats.ToTimestamp)
 		assert.Equal(t, bounces, stats.Bounces)
-		assert.Equal(t, "hard", stats.Bounces[0].Classification)
+		assert.Equal(t, common.BounceClassification("hard"), stats.Bounces[0].Classification)
 		assert.Equal(t, 10, stats.Bounces[0].Count)
 
 		// Test JSON serialization contains correct fields
@@ -147,9 +148,9 @@ func TestStatisticsModelsJSONDeserialization(t *testing.T) {
 
 		bounces := stats.Bounces
 		assert.Len(t, bounces, 2)
-		assert.Equal(t, "hard", bounces[0].Classification)
+		assert.Equal(t, common.BounceClassification("hard"), bounces[0].Classification)
 		assert.Equal(t, 10, bounces[0].Count)
-		assert.Equal(t, "soft", bounces[1].Classification)
+		assert.Equal(t, common.BounceClassification("soft"), bounces[1].Classification)
 		assert.Equal(t, 5, bounces[1].Count)
 	})
 
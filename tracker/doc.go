@@ -0,0 +1,34 @@
+// Package tracker follows sent messages until they reach a final status, such
+// as delivered, bounced or failed.
+//
+// CreateMessage only reports that AhaSend accepted a message. A Tracker takes
+// the IDs it returns and learns what became of each message, either by
+// polling MessagesAPIService.GetMessageByAPIID with backoff or from webhook
+// events:
+//
+//	resp, _, err := client.MessagesAPI.CreateMessage(ctx, accountID, request)
+//	if err != nil {
+//		return err
+//	}
+//
+//	t := tracker.New(client, accountID, tracker.Options{})
+//	go t.Run(ctx) // polls
+//	defer t.Close()
+//
+//	for _, handle := range t.TrackResponse(resp) {
+//		result, err := handle.Wait(ctx)
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(result.Status, len(result.DeliveryAttempts))
+//	}
+//
+// To follow many messages at once, receive from Updates, which reports every
+// status change of every tracked message.
+//
+// When a webhooks.Handler receives AhaSend's message events, pass them to
+// HandleEvent: register it with handler.OnEvent(t.HandleEvent), or call it
+// from the message callbacks you register yourself. Statuses are then picked
+// up as soon as they happen, and Run is only needed as a fallback for events
+// that never arrive.
+package tracker
@@ -0,0 +1,490 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/AhaSend/ahasend-go/webhooks"
+	"github.com/google/uuid"
+)
+
+// ErrClosed is returned by Wait once the Tracker has been closed
+var ErrClosed = errors.New("tracker: closed")
+
+// eventStatuses maps message webhook event types to the status they report
+var eventStatuses = map[string]common.MessageStatus{
+	"message.reception":       common.MessageStatusReceived,
+	"message.transient_error": common.MessageStatusDeferred,
+	"message.delivered":       common.MessageStatusDelivered,
+	"message.failed":          common.MessageStatusFailed,
+	"message.bounced":         common.MessageStatusBounced,
+	"message.suppressed":      common.MessageStatusSuppressed,
+}
+
+// Options configures a Tracker
+type Options struct {
+	// PollInterval is how long Run waits before first polling a message, and
+	// the interval between polls to begin with. Defaults to 5 seconds.
+	PollInterval time.Duration
+
+	// MaxPollInterval caps the interval between polls, which doubles after
+	// every poll. Defaults to 5 minutes.
+	MaxPollInterval time.Duration
+
+	// UpdateBuffer is the capacity of the Updates channel. Defaults to 64.
+	UpdateBuffer int
+
+	// RequestOptions are applied to every GetMessageByAPIID call
+	RequestOptions []api.RequestOption
+
+	// OnError is called when polling a message fails; the message is polled
+	// again later regardless (optional)
+	OnError func(id string, err error)
+}
+
+// Result is what became of a tracked message
+type Result struct {
+	// ID is the ID the message was tracked with
+	ID     string
+	Status common.MessageStatus
+	// DeliveryAttempts and Message come from the last time the message was
+	// fetched. They are empty if it could not be fetched.
+	DeliveryAttempts []responses.DeliveryEvent
+	Message          *responses.Message
+}
+
+// Update is a change in the status of a tracked message
+type Update struct {
+	ID   string
+	From common.MessageStatus // Empty when the message's status was not known yet
+	To   common.MessageStatus
+	// Final is set when To is a terminal status. The message is no longer
+	// tracked once this update has been sent; its Handles keep the result.
+	Final bool
+	Time  time.Time
+}
+
+// Tracker follows messages sent from one account until each reaches a final
+// status, and then releases it, so a long-lived Tracker only holds the
+// messages still in flight. It is safe for concurrent use.
+type Tracker struct {
+	client    *api.APIClient
+	accountID uuid.UUID
+	opts      Options
+	wake      chan struct{}
+	closed    chan struct{}
+
+	mu       sync.Mutex
+	messages map[string]*message // By messageKey; messages not yet final
+	order    []*message
+	updates  chan Update // Created by the first call to Updates
+	isClosed bool
+	senders  sync.WaitGroup // Goroutines sending on updates
+}
+
+// message is the state of one tracked message
+type message struct {
+	id       string
+	status   common.MessageStatus
+	latest   *responses.Message
+	interval time.Duration
+	nextPoll time.Time
+	done     chan struct{} // Closed once status is final
+}
+
+// New returns a Tracker for messages sent from accountID
+func New(client *api.APIClient, accountID uuid.UUID, opts Options) *Tracker {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.MaxPollInterval <= 0 {
+		opts.MaxPollInterval = 5 * time.Minute
+	}
+	if opts.MaxPollInterval < opts.PollInterval {
+		opts.MaxPollInterval = opts.PollInterval
+	}
+	if opts.UpdateBuffer <= 0 {
+		opts.UpdateBuffer = 64
+	}
+	return &Tracker{
+		client:    client,
+		accountID: accountID,
+		opts:      opts,
+		wake:      make(chan struct{}, 1),
+		closed:    make(chan struct{}),
+		messages:  make(map[string]*message),
+	}
+}
+
+// Handle waits for one tracked message
+type Handle struct {
+	t *Tracker
+	m *message
+}
+
+// ID returns the ID the message is tracked with
+func (h *Handle) ID() string {
+	return h.m.id
+}
+
+// Wait blocks until the message reaches a final status, ctx is done or the
+// Tracker is closed. It keeps returning the result after the Tracker has
+// released the message.
+func (h *Handle) Wait(ctx context.Context) (Result, error) {
+	select {
+	case <-h.m.done:
+		return h.t.result(h.m), nil
+	default:
+	}
+	select {
+	case <-h.m.done:
+		return h.t.result(h.m), nil
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	case <-h.t.closed:
+		return Result{}, ErrClosed
+	}
+}
+
+// Track starts tracking the message with the given ID, as returned by
+// CreateMessage. Either form of a message ID is accepted, and tracking a
+// message twice returns a Handle for the same message until it reaches a
+// final status; after that it is tracked afresh.
+func (t *Tracker) Track(id string) *Handle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := messageKey(id)
+	if m, ok := t.messages[key]; ok {
+		return &Handle{t: t, m: m}
+	}
+	m := &message{
+		id:       id,
+		interval: t.opts.PollInterval,
+		nextPoll: time.Now().Add(t.opts.PollInterval),
+		done:     make(chan struct{}),
+	}
+	t.messages[key] = m
+	t.order = append(t.order, m)
+
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+	return &Handle{t: t, m: m}
+}
+
+// TrackResponse tracks every message CreateMessage created. Recipients the
+// API returned an error for are skipped.
+func (t *Tracker) TrackResponse(resp *responses.CreateMessageResponse) []*Handle {
+	if resp == nil {
+		return nil
+	}
+	var handles []*Handle
+	for _, single := range resp.Data {
+		if single.ID == nil || single.Error != nil {
+			continue
+		}
+		handles = append(handles, t.Track(*single.ID))
+	}
+	return handles
+}
+
+// Wait blocks until every message still being tracked reaches a final
+// status, and returns their results in the order they were tracked. Messages
+// that were already final when Wait was called have been released; their
+// Handles return their results.
+func (t *Tracker) Wait(ctx context.Context) ([]Result, error) {
+	t.mu.Lock()
+	pending := append([]*message(nil), t.order...)
+	t.mu.Unlock()
+
+	results := make([]Result, 0, len(pending))
+	for _, m := range pending {
+		result, err := (&Handle{t: t, m: m}).Wait(ctx)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Updates returns a channel that receives every status change of every
+// tracked message from now on. Updates are only sent once Updates has been
+// called; after that the channel must be drained, or polling and HandleEvent
+// block until it has room. The channel is closed by Close.
+func (t *Tracker) Updates() <-chan Update {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.updates == nil {
+		t.updates = make(chan Update, t.opts.UpdateBuffer)
+		if t.isClosed {
+			close(t.updates)
+		}
+	}
+	return t.updates
+}
+
+// Close stops tracking. Pending Waits return ErrClosed, Run returns and the
+// Updates channel is closed.
+func (t *Tracker) Close() {
+	t.mu.Lock()
+	if t.isClosed {
+		t.mu.Unlock()
+		return
+	}
+	t.isClosed = true
+	close(t.closed)
+	updates := t.updates
+	t.mu.Unlock()
+
+	t.senders.Wait()
+	if updates != nil {
+		close(updates)
+	}
+}
+
+// Run polls tracked messages as they fall due until ctx is done or the
+// Tracker is closed. It returns ctx's error, or nil after Close.
+func (t *Tracker) Run(ctx context.Context) error {
+	for {
+		t.poll(ctx, t.due(time.Now()))
+
+		timer := time.NewTimer(t.untilNextPoll())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-t.closed:
+			timer.Stop()
+			return nil
+		case <-t.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Poll polls every tracked message that has not reached a final status yet,
+// whether or not it is due
+func (t *Tracker) Poll(ctx context.Context) error {
+	t.poll(ctx, t.due(time.Time{}))
+	return ctx.Err()
+}
+
+// HandleEvent updates tracked messages from an AhaSend webhook event. Its
+// signature matches webhooks.Handler's callbacks, so it can be registered
+// with OnEvent, or called from message event callbacks. Events for other
+// messages are ignored.
+func (t *Tracker) HandleEvent(ctx context.Context, event webhooks.WebhookEvent) error {
+	var data webhooks.MessageEventData
+	switch e := event.(type) {
+	case *webhooks.MessageReceptionEvent:
+		data = e.Data
+	case *webhooks.MessageTransientErrorEvent:
+		data = e.Data
+	case *webhooks.MessageDeliveredEvent:
+		data = e.Data
+	case *webhooks.MessageFailedEvent:
+		data = e.Data
+	case *webhooks.MessageBouncedEvent:
+		data = e.Data
+	case *webhooks.MessageSuppressedEvent:
+		data = e.Data
+	default:
+		return nil
+	}
+	status, ok := eventStatuses[event.GetType()]
+	if !ok || (data.AccountID != "" && data.AccountID != t.accountID.String()) {
+		return nil
+	}
+
+	t.mu.Lock()
+	m, ok := t.messages[messageKey(data.MessageIDHeader)]
+	if !ok {
+		m, ok = t.messages[messageKey(data.ID)]
+	}
+	t.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	// Fetch the delivery attempts of a message that is done. The event is
+	// authoritative for the status, as the API may not have caught up yet.
+	var latest *responses.Message
+	if status.IsTerminal() {
+		if msg, _, err := t.client.MessagesAPI.GetMessageByAPIID(ctx, t.accountID, m.id, t.opts.RequestOptions...); err == nil {
+			latest = msg
+		}
+	}
+	t.setStatus(ctx, m, status, latest)
+	return nil
+}
+
+// due returns the messages that are not final and due to be polled at now. A
+// zero now returns all of them.
+func (t *Tracker) due(now time.Time) []*message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var due []*message
+	for _, m := range t.order {
+		if m.status.IsTerminal() {
+			continue
+		}
+		if now.IsZero() || !m.nextPoll.After(now) {
+			due = append(due, m)
+		}
+	}
+	return due
+}
+
+// untilNextPoll returns how long Run can sleep before a message is due
+func (t *Tracker) untilNextPoll() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	wait := t.opts.MaxPollInterval
+	for _, m := range t.order {
+		if m.status.IsTerminal() {
+			continue
+		}
+		if until := time.Until(m.nextPoll); until < wait {
+			wait = until
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// poll fetches each message and records its status, backing off its next
+// poll
+func (t *Tracker) poll(ctx context.Context, messages []*message) {
+	for _, m := range messages {
+		if ctx.Err() != nil {
+			return
+		}
+
+		msg, _, err := t.client.MessagesAPI.GetMessageByAPIID(ctx, t.accountID, m.id, t.opts.RequestOptions...)
+
+		t.mu.Lock()
+		m.interval *= 2
+		if m.interval > t.opts.MaxPollInterval {
+			m.interval = t.opts.MaxPollInterval
+		}
+		m.nextPoll = time.Now().Add(m.interval)
+		t.mu.Unlock()
+
+		if err != nil {
+			// A message may not be visible yet right after it was sent
+			if ctx.Err() == nil && t.opts.OnError != nil {
+				t.opts.OnError(m.id, err)
+			}
+			continue
+		}
+		t.setStatus(ctx, m, msg.Status, msg)
+	}
+}
+
+// setStatus records a message's status, and latest if not nil, and reports
+// the change. A final status is never changed.
+func (t *Tracker) setStatus(ctx context.Context, m *message, status common.MessageStatus, latest *responses.Message) {
+	t.mu.Lock()
+	if m.status.IsTerminal() {
+		t.mu.Unlock()
+		return
+	}
+	if latest != nil {
+		m.latest = latest
+	}
+	if status == "" || status == m.status {
+		t.mu.Unlock()
+		return
+	}
+	update := Update{ID: m.id, From: m.status, To: status, Final: status.IsTerminal(), Time: time.Now()}
+	m.status = status
+	if update.Final {
+		close(m.done)
+	}
+	t.mu.Unlock()
+
+	t.emit(ctx, update)
+	if update.Final {
+		t.release(m)
+	}
+}
+
+// release stops tracking a final message. Its Handles still hold it, so
+// their Waits keep returning its result.
+func (t *Tracker) release(m *message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := messageKey(m.id)
+	if t.messages[key] == m {
+		delete(t.messages, key)
+	}
+	for i, tracked := range t.order {
+		if tracked == m {
+			copy(t.order[i:], t.order[i+1:])
+			t.order[len(t.order)-1] = nil
+			t.order = t.order[:len(t.order)-1]
+			break
+		}
+	}
+}
+
+// emit sends update on the Updates channel, if anyone asked for it
+func (t *Tracker) emit(ctx context.Context, update Update) {
+	t.mu.Lock()
+	updates := t.updates
+	if updates == nil || t.isClosed {
+		t.mu.Unlock()
+		return
+	}
+	t.senders.Add(1)
+	t.mu.Unlock()
+	defer t.senders.Done()
+
+	select {
+	case updates <- update:
+	case <-ctx.Done():
+	case <-t.closed:
+	}
+}
+
+// result returns the result of a final message
+func (t *Tracker) result(m *message) Result {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := Result{ID: m.id, Status: m.status, Message: m.latest}
+	if m.latest != nil {
+		result.DeliveryAttempts = m.latest.DeliveryAttempts
+	}
+	return result
+}
+
+// messageKey normalizes a message ID. AhaSend identifies a message both by
+// its Message-ID, such as "<uuid@example.com>", and by the bare UUID, which
+// webhook events carry without dashes.
+func messageKey(id string) string {
+	value := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+	if at := strings.Index(value, "@"); at >= 0 {
+		value = value[:at]
+	}
+	if parsed, err := uuid.Parse(value); err == nil {
+		return strings.ReplaceAll(parsed.String(), "-", "")
+	}
+	return strings.ToLower(value)
+}
@@ -0,0 +1,232 @@
+package tracker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/ahasendtest"
+	"github.com/AhaSend/ahasend-go/api"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+	"github.com/AhaSend/ahasend-go/models/responses"
+	"github.com/AhaSend/ahasend-go/webhooks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastPolling polls almost continuously
+var fastPolling = Options{PollInterval: time.Millisecond, MaxPollInterval: 5 * time.Millisecond}
+
+// sendScheduled sends a message scheduled for tomorrow to each recipient, so
+// that it stays in the Scheduled status until the test changes it
+func sendScheduled(t *testing.T, server *ahasendtest.Server, client *api.APIClient, recipients ...string) *responses.CreateMessageResponse {
+	t.Helper()
+
+	firstAttempt := time.Now().Add(24 * time.Hour)
+	request := requests.CreateMessageRequest{
+		From:        common.SenderAddress{Email: "noreply@example.com"},
+		Subject:     "Welcome",
+		TextContent: ahasend.String("Hello"),
+		Schedule:    &common.MessageSchedule{FirstAttempt: &firstAttempt},
+	}
+	for _, recipient := range recipients {
+		request.Recipients = append(request.Recipients, common.Recipient{Email: recipient})
+	}
+	resp, _, err := client.MessagesAPI.CreateMessage(context.Background(), server.AccountID(), request)
+	require.NoError(t, err)
+	require.Len(t, resp.Data, len(recipients))
+	return resp
+}
+
+func newTestServer(t *testing.T) (*ahasendtest.Server, *api.APIClient) {
+	t.Helper()
+
+	server := ahasendtest.NewServer()
+	t.Cleanup(server.Close)
+	return server, server.Client(api.WithRetryConfig(api.RetryConfig{Enabled: false}))
+}
+
+func TestTrackerPollsUntilFinal(t *testing.T) {
+	server, client := newTestServer(t)
+	resp := sendScheduled(t, server, client, "a@example.com", "b@example.com")
+
+	tr := New(client, server.AccountID(), fastPolling)
+	defer tr.Close()
+	updates := tr.Updates()
+	handles := tr.TrackResponse(resp)
+	require.Len(t, handles, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Wait covers the messages tracked when it is called, so the messages
+	// only become final once it is waiting
+	delivered := make(chan struct{})
+	go func() {
+		defer close(delivered)
+		go tr.Run(ctx)
+
+		// Both messages are seen as scheduled first
+		seen := map[string]Update{}
+		for len(seen) < 2 {
+			select {
+			case update := <-updates:
+				assert.Empty(t, update.From)
+				assert.Equal(t, ahasendtest.StatusScheduled, update.To)
+				assert.False(t, update.Final)
+				seen[update.ID] = update
+			case <-ctx.Done():
+				t.Error("no updates")
+				return
+			}
+		}
+
+		assert.True(t, server.SetMessageStatus(handles[0].ID(), "Bounced"))
+		assert.True(t, server.SetMessageStatus(handles[1].ID(), ahasendtest.StatusDelivered))
+	}()
+
+	results, err := tr.Wait(ctx)
+	<-delivered
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, handles[0].ID(), results[0].ID)
+	assert.Equal(t, common.MessageStatusBounced, results[0].Status)
+	assert.Equal(t, ahasendtest.StatusDelivered, results[1].Status)
+	require.NotNil(t, results[0].Message)
+	assert.Equal(t, "a@example.com", results[0].Message.Recipient)
+	assert.NotNil(t, results[0].DeliveryAttempts)
+
+	for i := 0; i < 2; i++ {
+		update := <-updates
+		assert.Equal(t, ahasendtest.StatusScheduled, update.From)
+		assert.True(t, update.Final)
+	}
+}
+
+func TestTrackerFollowsWebhookEvents(t *testing.T) {
+	server, client := newTestServer(t)
+	resp := sendScheduled(t, server, client, "a@example.com")
+
+	// No Run: the webhook events drive the tracker
+	tr := New(client, server.AccountID(), Options{})
+	defer tr.Close()
+	handle := tr.TrackResponse(resp)[0]
+	id, err := uuid.Parse(strings.TrimPrefix(strings.SplitN(handle.ID(), "@", 2)[0], "<"))
+	require.NoError(t, err)
+
+	deferred := webhooks.NewMessageTransientErrorEvent()
+	deferred.Data.AccountID = server.AccountID().String()
+	deferred.Data.MessageIDHeader = handle.ID()
+	require.NoError(t, tr.HandleEvent(context.Background(), deferred))
+
+	// Events for other messages and accounts are ignored
+	other := webhooks.NewMessageFailedEvent()
+	require.NoError(t, tr.HandleEvent(context.Background(), other))
+	otherAccount := webhooks.NewMessageFailedEvent()
+	otherAccount.Data.ID = strings.ReplaceAll(id.String(), "-", "")
+	require.NoError(t, tr.HandleEvent(context.Background(), otherAccount))
+
+	// Webhook events carry the bare ID without dashes
+	bounced := webhooks.NewMessageBouncedEvent()
+	bounced.Data.AccountID = server.AccountID().String()
+	bounced.Data.ID = strings.ReplaceAll(id.String(), "-", "")
+	require.NoError(t, tr.HandleEvent(context.Background(), bounced))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result, err := handle.Wait(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, common.MessageStatusBounced, result.Status, "the event wins over the status the API still reports")
+	require.NotNil(t, result.Message)
+	assert.NotNil(t, result.DeliveryAttempts)
+
+	// A final status does not change
+	delivered := webhooks.NewMessageDeliveredEvent()
+	delivered.Data.AccountID = server.AccountID().String()
+	delivered.Data.MessageIDHeader = handle.ID()
+	require.NoError(t, tr.HandleEvent(context.Background(), delivered))
+	result, err = handle.Wait(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, common.MessageStatusBounced, result.Status)
+}
+
+func TestTrackerReleasesFinalMessages(t *testing.T) {
+	server, client := newTestServer(t)
+	resp := sendScheduled(t, server, client, "a@example.com", "b@example.com")
+
+	tr := New(client, server.AccountID(), Options{})
+	defer tr.Close()
+	handles := tr.TrackResponse(resp)
+	require.Len(t, handles, 2)
+
+	require.True(t, server.SetMessageStatus(handles[0].ID(), ahasendtest.StatusDelivered))
+	require.NoError(t, tr.Poll(context.Background()))
+
+	tr.mu.Lock()
+	assert.Len(t, tr.messages, 1, "the delivered message is released")
+	require.Len(t, tr.order, 1)
+	assert.Same(t, handles[1].m, tr.order[0])
+	tr.mu.Unlock()
+	assert.Len(t, tr.due(time.Time{}), 1)
+
+	// Its Handle still has the result
+	result, err := handles[0].Wait(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ahasendtest.StatusDelivered, result.Status)
+	require.NotNil(t, result.Message)
+}
+
+func TestTrackerTrackSameMessageTwice(t *testing.T) {
+	server, client := newTestServer(t)
+	tr := New(client, server.AccountID(), Options{})
+	defer tr.Close()
+
+	id := uuid.New()
+	first := tr.Track("<" + id.String() + "@example.com>")
+	second := tr.Track(id.String())
+	assert.Same(t, first.m, second.m)
+}
+
+func TestTrackerClose(t *testing.T) {
+	server, client := newTestServer(t)
+	resp := sendScheduled(t, server, client, "a@example.com")
+
+	tr := New(client, server.AccountID(), fastPolling)
+	updates := tr.Updates()
+	handle := tr.TrackResponse(resp)[0]
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- tr.Run(context.Background()) }()
+
+	// Nobody drains updates, so Run blocks sending one until Close
+	time.Sleep(20 * time.Millisecond)
+	tr.Close()
+
+	_, err := handle.Wait(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.NoError(t, <-stopped)
+	for range updates {
+	}
+	tr.Close()
+}
+
+func TestTrackerPollReportsErrors(t *testing.T) {
+	server, client := newTestServer(t)
+	var failed []string
+	opts := Options{OnError: func(id string, err error) { failed = append(failed, id) }}
+	tr := New(client, server.AccountID(), opts)
+	defer tr.Close()
+
+	handle := tr.Track(uuid.New().String())
+	require.NoError(t, tr.Poll(context.Background()))
+	assert.Equal(t, []string{handle.ID()}, failed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := handle.Wait(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,210 @@
+package webhooks
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The constructors below build events with realistic placeholder data, fresh
+// IDs and the current time, for use with Signer and Simulator. Adjust the
+// returned event's fields to fit a test case before sending it.
+
+const (
+	fakeAccountID = "4cdd7bdd-294e-4762-892f-83d40abf5a87"
+	fakeSender    = "sender@example.com"
+	fakeRecipient = "recipient@example.com"
+	fakeSubject   = "Welcome to our service"
+	fakeUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"
+	fakeIP        = "192.168.1.100"
+)
+
+// newFakeMessageEventData returns message event data for the given event name
+func newFakeMessageEventData(event string) MessageEventData {
+	return MessageEventData{
+		AccountID:       fakeAccountID,
+		Event:           event,
+		From:            fakeSender,
+		Recipient:       fakeRecipient,
+		Subject:         fakeSubject,
+		MessageIDHeader: "<" + randomHex(8) + "@example.com>",
+		ID:              randomHex(16),
+	}
+}
+
+// fakeWebhookID returns a webhook ID in the format of the webhook-id header.
+// Signer and Simulator sign an event with the webhook ID it carries, so that
+// the header and the payload agree as they do in production.
+func fakeWebhookID() *string {
+	id := NewWebhookID()
+	return &id
+}
+
+func fakeRouteID() *string {
+	id := uuid.NewString()
+	return &id
+}
+
+func fakeTimestamp() time.Time {
+	return time.Now().UTC()
+}
+
+// NewMessageReceptionEvent returns a message.reception event with placeholder data
+func NewMessageReceptionEvent() *MessageReceptionEvent {
+	return &MessageReceptionEvent{
+		Type:      "message.reception",
+		WebhookID: fakeWebhookID(),
+		Timestamp: fakeTimestamp(),
+		Data:      newFakeMessageEventData("on_reception"),
+	}
+}
+
+// NewMessageDeliveredEvent returns a message.delivered event with placeholder data
+func NewMessageDeliveredEvent() *MessageDeliveredEvent {
+	return &MessageDeliveredEvent{
+		Type:      "message.delivered",
+		WebhookID: fakeWebhookID(),
+		Timestamp: fakeTimestamp(),
+		Data:      newFakeMessageEventData("on_delivered"),
+	}
+}
+
+// NewMessageTransientErrorEvent returns a message.transient_error event with placeholder data
+func NewMessageTransientErrorEvent() *MessageTransientErrorEvent {
+	return &MessageTransientErrorEvent{
+		Type:      "message.transient_error",
+		WebhookID: fakeWebhookID(),
+		Timestamp: fakeTimestamp(),
+		Data:      newFakeMessageEventData("on_transient_error"),
+	}
+}
+
+// NewMessageFailedEvent returns a message.failed event with placeholder data
+func NewMessageFailedEvent() *MessageFailedEvent {
+	return &MessageFailedEvent{
+		Type:      "message.failed",
+		WebhookID: fakeWebhookID(),
+		Timestamp: fakeTimestamp(),
+		Data:      newFakeMessageEventData("on_failed"),
+	}
+}
+
+// NewMessageBouncedEvent returns a message.bounced event with placeholder data
+func NewMessageBouncedEvent() *MessageBouncedEvent {
+	return &MessageBouncedEvent{
+		Type:      "message.bounced",
+		WebhookID: fakeWebhookID(),
+		Timestamp: fakeTimestamp(),
+		Data:      newFakeMessageEventData("on_bounced"),
+	}
+}
+
+// NewMessageSuppressedEvent returns a message.suppressed event with placeholder data
+func NewMessageSuppressedEvent() *MessageSuppressedEvent {
+	data := newFakeMessageEventData("on_suppressed")
+	data.Recipient = "bounced@example.com"
+	return &MessageSuppressedEvent{
+		Type:      "message.suppressed",
+		WebhookID: fakeWebhookID(),
+		Timestamp: fakeTimestamp(),
+		Data:      data,
+	}
+}
+
+// NewMessageOpenedEvent returns a message.opened event with placeholder data
+func NewMessageOpenedEvent() *MessageOpenedEvent {
+	data := newFakeMessageEventData("on_opened")
+	userAgent, ip := fakeUserAgent, fakeIP
+	data.UserAgent = &userAgent
+	data.IP = &ip
+	return &MessageOpenedEvent{
+		Type:      "message.opened",
+		WebhookID: fakeWebhookID(),
+		Timestamp: fakeTimestamp(),
+		Data:      data,
+	}
+}
+
+// NewMessageClickedEvent returns a message.clicked event with placeholder data
+func NewMessageClickedEvent() *MessageClickedEvent {
+	return &MessageClickedEvent{
+		Type:      "message.clicked",
+		WebhookID: fakeWebhookID(),
+		Timestamp: fakeTimestamp(),
+		Data: MessageClickedEventData{
+			AccountID:       fakeAccountID,
+			Event:           "on_clicked",
+			From:            fakeSender,
+			Recipient:       fakeRecipient,
+			Subject:         fakeSubject,
+			MessageIDHeader: "<" + randomHex(8) + "@example.com>",
+			URL:             "https://example.com/landing-page",
+			UserAgent:       fakeUserAgent,
+			IP:              fakeIP,
+			ID:              randomHex(16),
+		},
+	}
+}
+
+// NewSuppressionCreatedEvent returns a suppression.created event with placeholder data
+func NewSuppressionCreatedEvent() *SuppressionCreatedEvent {
+	now := fakeTimestamp()
+	return &SuppressionCreatedEvent{
+		Type:      "suppression.created",
+		WebhookID: fakeWebhookID(),
+		Timestamp: now,
+		Data: SuppressionEventData{
+			AccountID:     fakeAccountID,
+			Recipient:     "bounced@example.com",
+			CreatedAt:     now,
+			ExpiresAt:     now.Add(30 * 24 * time.Hour),
+			Reason:        "Too many hard bounces",
+			SendingDomain: "example.com",
+		},
+	}
+}
+
+// NewDomainDNSErrorEvent returns a domain.dns_error event with placeholder data
+func NewDomainDNSErrorEvent() *DomainDNSErrorEvent {
+	now := fakeTimestamp()
+	return &DomainDNSErrorEvent{
+		Type:      "domain.dns_error",
+		WebhookID: fakeWebhookID(),
+		Timestamp: now,
+		Data: DomainEventData{
+			Domain:           "example.com",
+			AccountID:        fakeAccountID,
+			SPFValid:         true,
+			DKIMValid:        false,
+			DMARCValid:       true,
+			DNSLastCheckedAt: now,
+		},
+	}
+}
+
+// NewRouteMessageEvent returns a message.routing event with placeholder data
+func NewRouteMessageEvent() *RouteMessageEvent {
+	now := fakeTimestamp()
+	replyTo := "customer@example.net"
+	date := now.Format(time.RFC1123Z)
+	spamScore := float32(0.1)
+	return &RouteMessageEvent{
+		Type:      "message.routing",
+		RouteID:   fakeRouteID(),
+		Timestamp: now,
+		Data: RouteEventData{
+			ID:        "route-msg-" + randomHex(6),
+			From:      "Customer <customer@example.net>",
+			ReplyTo:   &replyTo,
+			To:        "support@example.com",
+			Subject:   "Help with my account",
+			MessageID: "<" + randomHex(8) + "@example.net>",
+			Size:      2048,
+			SpamScore: &spamScore,
+			Date:      &date,
+			HTMLBody:  "<p>I need help with my account settings.</p>",
+			PlainBody: "I need help with my account settings.",
+			Headers:   map[string]string{"X-Mailer": "Example Mail"},
+		},
+	}
+}
@@ -0,0 +1,357 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxBodyBytes is the default limit on a webhook request body. It
+	// leaves room for route events, which carry inbound attachments inline.
+	DefaultMaxBodyBytes int64 = 32 << 20
+
+	// DefaultSeenTTL is how long MemorySeenStore remembers a webhook ID,
+	// covering the window in which AhaSend redelivers a failed webhook
+	DefaultSeenTTL = 24 * time.Hour
+)
+
+// ErrBodyTooLarge is returned when a webhook request body exceeds the
+// handler's limit
+var ErrBodyTooLarge = errors.New("webhook body too large")
+
+// SeenStore records the webhook IDs a Handler has processed so that
+// redelivered events are acknowledged without running their callback again.
+// Implementations must be safe for concurrent use.
+type SeenStore interface {
+	// MarkSeen records id and reports whether it had already been recorded.
+	// Recording must be atomic, so that of two concurrent deliveries of the
+	// same event only one is processed.
+	MarkSeen(ctx context.Context, id string) (bool, error)
+
+	// Forget removes id, so that a delivery whose callback failed is
+	// processed again when it is redelivered
+	Forget(ctx context.Context, id string) error
+}
+
+// MemorySeenStore is an in-process SeenStore whose entries expire after a
+// TTL. It only deduplicates deliveries reaching the same process; use a
+// shared store when running several replicas.
+type MemorySeenStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]time.Time
+	expiry  []seenEntry // Oldest first; entries expire in the order they were added
+	now     func() time.Time
+}
+
+// seenEntry is an ID in the expiry queue of a MemorySeenStore
+type seenEntry struct {
+	id        string
+	expiresAt time.Time
+}
+
+// NewMemorySeenStore creates a MemorySeenStore that remembers IDs for ttl, or
+// for DefaultSeenTTL when ttl is not positive
+func NewMemorySeenStore(ttl time.Duration) *MemorySeenStore {
+	if ttl <= 0 {
+		ttl = DefaultSeenTTL
+	}
+	return &MemorySeenStore{
+		ttl:     ttl,
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// MarkSeen implements SeenStore
+func (s *MemorySeenStore) MarkSeen(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+
+	if _, ok := s.entries[id]; ok {
+		return true, nil
+	}
+	expiresAt := now.Add(s.ttl)
+	s.entries[id] = expiresAt
+	s.expiry = append(s.expiry, seenEntry{id: id, expiresAt: expiresAt})
+	return false, nil
+}
+
+// expire removes the entries that have expired at now. As every entry lives
+// for the same TTL, only the front of the queue needs to be looked at. The
+// caller must hold s.mu.
+func (s *MemorySeenStore) expire(now time.Time) {
+	n := 0
+	for ; n < len(s.expiry) && !now.Before(s.expiry[n].expiresAt); n++ {
+		entry := s.expiry[n]
+		// The ID may have been forgotten, and seen again since
+		if expiresAt, ok := s.entries[entry.id]; ok && expiresAt.Equal(entry.expiresAt) {
+			delete(s.entries, entry.id)
+		}
+		s.expiry[n] = seenEntry{}
+	}
+	s.expiry = s.expiry[n:]
+}
+
+// Forget implements SeenStore
+func (s *MemorySeenStore) Forget(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, id)
+	return nil
+}
+
+// Handler is an http.Handler that verifies webhook deliveries, parses them
+// into typed events and dispatches each one to the callback registered for
+// its type.
+//
+// Responses follow what AhaSend expects of a webhook endpoint: 204 once an
+// event has been handled, ignored or recognised as a redelivery; 4xx for
+// requests that will never succeed, such as a bad signature or an oversized
+// body; and 500 when a callback fails, so that the event is delivered again.
+type Handler struct {
+	verifier     *WebhookVerifier
+	maxBodyBytes int64
+	seen         SeenStore
+	onError      func(r *http.Request, err error)
+
+	onMessageReception      func(context.Context, *MessageReceptionEvent) error
+	onMessageDelivered      func(context.Context, *MessageDeliveredEvent) error
+	onMessageTransientError func(context.Context, *MessageTransientErrorEvent) error
+	onMessageFailed         func(context.Context, *MessageFailedEvent) error
+	onMessageBounced        func(context.Context, *MessageBouncedEvent) error
+	onMessageSuppressed     func(context.Context, *MessageSuppressedEvent) error
+	onMessageOpened         func(context.Context, *MessageOpenedEvent) error
+	onMessageClicked        func(context.Context, *MessageClickedEvent) error
+	onSuppressionCreated    func(context.Context, *SuppressionCreatedEvent) error
+	onDomainDNSError        func(context.Context, *DomainDNSErrorEvent) error
+	onRouteMessage          func(context.Context, *RouteMessageEvent) error
+	onEvent                 func(context.Context, WebhookEvent) error
+}
+
+// NewHandler creates a Handler that verifies deliveries with verifier. Until
+// a SeenStore is set, redelivered events are processed again.
+func NewHandler(verifier *WebhookVerifier) *Handler {
+	return &Handler{
+		verifier:     verifier,
+		maxBodyBytes: DefaultMaxBodyBytes,
+	}
+}
+
+// SetMaxBodyBytes sets the largest request body the handler accepts
+func (h *Handler) SetMaxBodyBytes(n int64) {
+	h.maxBodyBytes = n
+}
+
+// SetSeenStore sets the store used to drop redelivered events
+func (h *Handler) SetSeenStore(store SeenStore) {
+	h.seen = store
+}
+
+// SetErrorHandler sets a function called with every error that leads to a
+// non-2xx response, for logging
+func (h *Handler) SetErrorHandler(fn func(r *http.Request, err error)) {
+	h.onError = fn
+}
+
+// OnMessageReception registers the callback for message.reception events
+func (h *Handler) OnMessageReception(fn func(context.Context, *MessageReceptionEvent) error) {
+	h.onMessageReception = fn
+}
+
+// OnMessageDelivered registers the callback for message.delivered events
+func (h *Handler) OnMessageDelivered(fn func(context.Context, *MessageDeliveredEvent) error) {
+	h.onMessageDelivered = fn
+}
+
+// OnMessageTransientError registers the callback for message.transient_error events
+func (h *Handler) OnMessageTransientError(fn func(context.Context, *MessageTransientErrorEvent) error) {
+	h.onMessageTransientError = fn
+}
+
+// OnMessageFailed registers the callback for message.failed events
+func (h *Handler) OnMessageFailed(fn func(context.Context, *MessageFailedEvent) error) {
+	h.onMessageFailed = fn
+}
+
+// OnMessageBounced registers the callback for message.bounced events
+func (h *Handler) OnMessageBounced(fn func(context.Context, *MessageBouncedEvent) error) {
+	h.onMessageBounced = fn
+}
+
+// OnMessageSuppressed registers the callback for message.suppressed events
+func (h *Handler) OnMessageSuppressed(fn func(context.Context, *MessageSuppressedEvent) error) {
+	h.onMessageSuppressed = fn
+}
+
+// OnMessageOpened registers the callback for message.opened events
+func (h *Handler) OnMessageOpened(fn func(context.Context, *MessageOpenedEvent) error) {
+	h.onMessageOpened = fn
+}
+
+// OnMessageClicked registers the callback for message.clicked events
+func (h *Handler) OnMessageClicked(fn func(context.Context, *MessageClickedEvent) error) {
+	h.onMessageClicked = fn
+}
+
+// OnSuppressionCreated registers the callback for suppression.created events
+func (h *Handler) OnSuppressionCreated(fn func(context.Context, *SuppressionCreatedEvent) error) {
+	h.onSuppressionCreated = fn
+}
+
+// OnDomainDNSError registers the callback for domain.dns_error events
+func (h *Handler) OnDomainDNSError(fn func(context.Context, *DomainDNSErrorEvent) error) {
+	h.onDomainDNSError = fn
+}
+
+// OnRouteMessage registers the callback for inbound route events
+func (h *Handler) OnRouteMessage(fn func(context.Context, *RouteMessageEvent) error) {
+	h.onRouteMessage = fn
+}
+
+// OnEvent registers the callback for events whose type has no callback of
+// its own. Without it such events are acknowledged and dropped.
+func (h *Handler) OnEvent(fn func(context.Context, WebhookEvent) error) {
+	h.onEvent = fn
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.fail(w, r, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, h.maxBodyBytes+1))
+	if err != nil {
+		h.fail(w, r, http.StatusBadRequest, fmt.Errorf("failed to read request body: %w", err))
+		return
+	}
+	if int64(len(body)) > h.maxBodyBytes {
+		h.fail(w, r, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+		return
+	}
+
+	event, err := h.verifier.Parse(body, r.Header)
+	switch {
+	case errors.Is(err, ErrUnknownEventType):
+		// Event types added after this SDK version are acknowledged so the
+		// sender does not retry them forever
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrExpiredTimestamp):
+		h.fail(w, r, http.StatusUnauthorized, err)
+		return
+	case err != nil:
+		h.fail(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	webhookID := r.Header.Get(HeaderWebhookID)
+	if h.seen != nil {
+		seen, err := h.seen.MarkSeen(ctx, webhookID)
+		if err != nil {
+			h.fail(w, r, http.StatusInternalServerError, fmt.Errorf("failed to record webhook id: %w", err))
+			return
+		}
+		if seen {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	if err := h.dispatch(ctx, event, webhookID); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// dispatch runs the callback for event. When it fails or panics the webhook
+// ID is forgotten again, so the redelivery is processed.
+func (h *Handler) dispatch(ctx context.Context, event WebhookEvent, webhookID string) (err error) {
+	if h.seen != nil {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				_ = h.seen.Forget(ctx, webhookID)
+				panic(recovered)
+			}
+			if err != nil {
+				if forgetErr := h.seen.Forget(ctx, webhookID); forgetErr != nil {
+					err = fmt.Errorf("%w (and failed to forget webhook id: %v)", err, forgetErr)
+				}
+			}
+		}()
+	}
+
+	switch e := event.(type) {
+	case *MessageReceptionEvent:
+		if h.onMessageReception != nil {
+			return h.onMessageReception(ctx, e)
+		}
+	case *MessageDeliveredEvent:
+		if h.onMessageDelivered != nil {
+			return h.onMessageDelivered(ctx, e)
+		}
+	case *MessageTransientErrorEvent:
+		if h.onMessageTransientError != nil {
+			return h.onMessageTransientError(ctx, e)
+		}
+	case *MessageFailedEvent:
+		if h.onMessageFailed != nil {
+			return h.onMessageFailed(ctx, e)
+		}
+	case *MessageBouncedEvent:
+		if h.onMessageBounced != nil {
+			return h.onMessageBounced(ctx, e)
+		}
+	case *MessageSuppressedEvent:
+		if h.onMessageSuppressed != nil {
+			return h.onMessageSuppressed(ctx, e)
+		}
+	case *MessageOpenedEvent:
+		if h.onMessageOpened != nil {
+			return h.onMessageOpened(ctx, e)
+		}
+	case *MessageClickedEvent:
+		if h.onMessageClicked != nil {
+			return h.onMessageClicked(ctx, e)
+		}
+	case *SuppressionCreatedEvent:
+		if h.onSuppressionCreated != nil {
+			return h.onSuppressionCreated(ctx, e)
+		}
+	case *DomainDNSErrorEvent:
+		if h.onDomainDNSError != nil {
+			return h.onDomainDNSError(ctx, e)
+		}
+	case *RouteMessageEvent:
+		if h.onRouteMessage != nil {
+			return h.onRouteMessage(ctx, e)
+		}
+	}
+
+	if h.onEvent != nil {
+		return h.onEvent(ctx, event)
+	}
+	return nil
+}
+
+// fail reports err to the error handler and writes status
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if h.onError != nil {
+		h.onError(r, err)
+	}
+	http.Error(w, http.StatusText(status), status)
+}
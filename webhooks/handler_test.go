@@ -0,0 +1,171 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const deliveredPayload = `{"type":"message.delivered","timestamp":"2024-05-06T09:50:16.687031577Z","data":{"account_id":"4cdd7bdd-294e-4762-892f-83d40abf5a87","event":"on_delivered","from":"sender@example.com","recipient":"recipient@example.com","subject":"Test Email","message_id_header":"<message-id-12345@localhost>","id":"407926766d2711f09b30960002cafe7c"}}`
+
+func newSignedRequest(t *testing.T, webhookID, payload string) *http.Request {
+	t.Helper()
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(payload))
+	req.Header.Set(HeaderWebhookID, webhookID)
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, signWithSecret(t, testWebhookSecret, webhookID, timestamp, payload))
+	return req
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	verifier, err := NewWebhookVerifier(testWebhookSecret)
+	require.NoError(t, err)
+	return NewHandler(verifier)
+}
+
+func serve(h http.Handler, req *http.Request) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestHandlerDispatchesTypedEvents(t *testing.T) {
+	h := newTestHandler(t)
+
+	var delivered *MessageDeliveredEvent
+	h.OnMessageDelivered(func(ctx context.Context, event *MessageDeliveredEvent) error {
+		delivered = event
+		return nil
+	})
+	var fallback []string
+	h.OnEvent(func(ctx context.Context, event WebhookEvent) error {
+		fallback = append(fallback, event.GetType())
+		return nil
+	})
+
+	assert.Equal(t, http.StatusNoContent, serve(h, newSignedRequest(t, "msg_1", deliveredPayload)))
+	require.NotNil(t, delivered)
+	assert.Equal(t, "recipient@example.com", delivered.Data.Recipient)
+
+	opened := strings.Replace(deliveredPayload, "message.delivered", "message.opened", 1)
+	assert.Equal(t, http.StatusNoContent, serve(h, newSignedRequest(t, "msg_2", opened)))
+	assert.Equal(t, []string{"message.opened"}, fallback, "events without their own callback go to OnEvent")
+
+	unknown := strings.Replace(deliveredPayload, "message.delivered", "message.teleported", 1)
+	assert.Equal(t, http.StatusNoContent, serve(h, newSignedRequest(t, "msg_3", unknown)), "unknown types are acknowledged")
+}
+
+func TestHandlerStatusCodes(t *testing.T) {
+	h := newTestHandler(t)
+	var reported []error
+	h.SetErrorHandler(func(r *http.Request, err error) { reported = append(reported, err) })
+	h.OnMessageDelivered(func(ctx context.Context, event *MessageDeliveredEvent) error {
+		return errors.New("database unavailable")
+	})
+
+	get := httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(h, get))
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(deliveredPayload))
+	assert.Equal(t, http.StatusBadRequest, serve(h, unsigned))
+
+	tampered := newSignedRequest(t, "msg_1", deliveredPayload)
+	tampered.Header.Set(HeaderWebhookSignature, "v1,invalid")
+	assert.Equal(t, http.StatusUnauthorized, serve(h, tampered))
+
+	assert.Equal(t, http.StatusBadRequest, serve(h, newSignedRequest(t, "msg_1", `{"type":`)))
+
+	assert.Equal(t, http.StatusInternalServerError, serve(h, newSignedRequest(t, "msg_1", deliveredPayload)),
+		"a failing callback must make AhaSend retry")
+
+	h.SetMaxBodyBytes(16)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(h, newSignedRequest(t, "msg_1", deliveredPayload)))
+
+	require.Len(t, reported, 6)
+	assert.ErrorIs(t, reported[2], ErrInvalidSignature)
+	assert.ErrorIs(t, reported[5], ErrBodyTooLarge)
+}
+
+func TestHandlerDropsRedeliveries(t *testing.T) {
+	h := newTestHandler(t)
+	h.SetSeenStore(NewMemorySeenStore(time.Hour))
+
+	calls := 0
+	fail := true
+	h.OnMessageDelivered(func(ctx context.Context, event *MessageDeliveredEvent) error {
+		calls++
+		if fail {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, serve(h, newSignedRequest(t, "msg_1", deliveredPayload)))
+	fail = false
+	assert.Equal(t, http.StatusNoContent, serve(h, newSignedRequest(t, "msg_1", deliveredPayload)),
+		"a failed delivery is processed again")
+	assert.Equal(t, http.StatusNoContent, serve(h, newSignedRequest(t, "msg_1", deliveredPayload)))
+	assert.Equal(t, 2, calls, "a successful delivery is not processed twice")
+}
+
+func TestMemorySeenStore(t *testing.T) {
+	store := NewMemorySeenStore(time.Minute)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	seen, err := store.MarkSeen(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, seen)
+	seen, _ = store.MarkSeen(ctx, "a")
+	assert.True(t, seen)
+
+	now = now.Add(2 * time.Minute)
+	seen, _ = store.MarkSeen(ctx, "a")
+	assert.False(t, seen, "entries expire after the TTL")
+
+	require.NoError(t, store.Forget(ctx, "a"))
+	seen, _ = store.MarkSeen(ctx, "a")
+	assert.False(t, seen)
+
+	// An ID forgotten and seen again keeps its new expiry
+	now = now.Add(30 * time.Second)
+	require.NoError(t, store.Forget(ctx, "a"))
+	seen, _ = store.MarkSeen(ctx, "a")
+	assert.False(t, seen)
+	now = now.Add(45 * time.Second)
+	seen, _ = store.MarkSeen(ctx, "a")
+	assert.True(t, seen, "the earlier entry's expiry does not remove it")
+	assert.Len(t, store.entries, 1)
+	assert.Len(t, store.expiry, 1, "expired entries leave the queue")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	fresh := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if seen, _ := store.MarkSeen(ctx, "concurrent"); !seen {
+				mu.Lock()
+				fresh++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, fresh)
+}
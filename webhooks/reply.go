@@ -0,0 +1,382 @@
+package webhooks
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"net/mail"
+	"strings"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/AhaSend/ahasend-go/models/requests"
+)
+
+// ReplyOptions configures Reply, ReplyAll and Forward
+type ReplyOptions struct {
+	// Text and HTML are the new content written above the quoted message.
+	// When only Text is set, the HTML part uses an escaped copy of it.
+	Text string
+	HTML string
+
+	// IncludeAttachments re-attaches the attachments of the received message.
+	// Forward re-attaches all of them; Reply and ReplyAll only re-attach the
+	// inline parts the quoted HTML body refers to, so that its images still
+	// show.
+	IncludeAttachments bool
+}
+
+// Reply returns a request that replies to the sender of a routed message, in
+// the same thread. The reply goes to the message's Reply-To address when it
+// has one, and to its From address otherwise:
+//
+//	handler.OnRouteMessage(func(ctx context.Context, event *webhooks.RouteMessageEvent) error {
+//		reply, err := webhooks.Reply(event.Data, support, webhooks.ReplyOptions{
+//			Text: "Thanks, we are looking into it.",
+//		})
+//		if err != nil {
+//			return err
+//		}
+//		_, _, err = client.MessagesAPI.CreateConversationMessage(ctx, accountID, reply)
+//		return err
+//	})
+//
+// The subject gets a "Re:" prefix, and the In-Reply-To and References headers
+// point at the received message.
+func Reply(data RouteEventData, from common.SenderAddress, opts ReplyOptions) (requests.CreateConversationMessageRequest, error) {
+	to, err := replyTarget(data)
+	if err != nil {
+		return requests.CreateConversationMessageRequest{}, err
+	}
+	return newReply(data, from, []common.SenderAddress{to}, nil, opts)
+}
+
+// ReplyAll is Reply that also copies the other recipients of the routed
+// message, its To and Cc addresses, except from and the address replied to
+func ReplyAll(data RouteEventData, from common.SenderAddress, opts ReplyOptions) (requests.CreateConversationMessageRequest, error) {
+	to, err := replyTarget(data)
+	if err != nil {
+		return requests.CreateConversationMessageRequest{}, err
+	}
+
+	seen := map[string]bool{
+		strings.ToLower(from.Email): true,
+		strings.ToLower(to.Email):   true,
+	}
+	var cc []common.SenderAddress
+	for _, field := range []struct {
+		name  string
+		value string
+	}{{"To", data.To}, {"Cc", stringValue(data.CC)}} {
+		addresses, err := parseAddressList(field.name, field.value)
+		if err != nil {
+			return requests.CreateConversationMessageRequest{}, err
+		}
+		for _, address := range addresses {
+			if key := strings.ToLower(address.Email); !seen[key] {
+				seen[key] = true
+				cc = append(cc, address)
+			}
+		}
+	}
+	return newReply(data, from, []common.SenderAddress{to}, cc, opts)
+}
+
+// Forward returns a request that forwards a routed message to new
+// recipients. The subject gets a "Fwd:" prefix and the body quotes the
+// message below a summary of its headers. Forwarding starts a new thread, so
+// only the References header points at the received message.
+func Forward(data RouteEventData, from common.SenderAddress, to []common.SenderAddress, opts ReplyOptions) (requests.CreateConversationMessageRequest, error) {
+	if len(to) == 0 {
+		return requests.CreateConversationMessageRequest{}, errors.New("at least one forward recipient is required")
+	}
+
+	request := requests.CreateConversationMessageRequest{
+		From:    from,
+		To:      to,
+		Subject: prefixSubject("Fwd:", data.Subject, "fwd:", "fw:"),
+	}
+	if references := threadReferences(data); references != "" {
+		request.Headers = map[string]string{"References": references}
+	}
+
+	summary := [][2]string{
+		{"From", data.From},
+		{"Date", stringValue(data.Date)},
+		{"Subject", data.Subject},
+		{"To", data.To},
+		{"Cc", stringValue(data.CC)},
+	}
+
+	var text strings.Builder
+	if opts.Text != "" {
+		text.WriteString(opts.Text)
+		text.WriteString("\n\n")
+	}
+	text.WriteString("---------- Forwarded message ---------\n")
+	for _, line := range summary {
+		if line[1] != "" {
+			fmt.Fprintf(&text, "%s: %s\n", line[0], line[1])
+		}
+	}
+	text.WriteString("\n")
+	text.WriteString(data.PlainBody)
+	request.TextContent = ahasend.String(text.String())
+
+	if htmlContent := replyHTML(data, opts); htmlContent != "" || data.HTMLBody != "" {
+		var body strings.Builder
+		if htmlContent != "" {
+			body.WriteString(htmlContent)
+			body.WriteString("<br><br>")
+		}
+		body.WriteString(`<div class="forwarded">---------- Forwarded message ---------<br>`)
+		for _, line := range summary {
+			if line[1] != "" {
+				fmt.Fprintf(&body, "%s: %s<br>", line[0], html.EscapeString(line[1]))
+			}
+		}
+		body.WriteString("<br>")
+		body.WriteString(quotedHTML(data))
+		body.WriteString("</div>")
+		request.HtmlContent = ahasend.String(body.String())
+	}
+
+	if opts.IncludeAttachments {
+		attachments, err := convertAttachments(data.Attachments, func(RouteAttachment) bool { return true })
+		if err != nil {
+			return requests.CreateConversationMessageRequest{}, err
+		}
+		request.Attachments = attachments
+	}
+	return request, nil
+}
+
+// Bytes returns the decoded content of the attachment
+func (a RouteAttachment) Bytes() ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(a.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data for attachment %q: %w", a.Filename, err)
+	}
+	return data, nil
+}
+
+// Attachment returns the attachment as a message attachment, ready to be
+// sent again. Parts with a Content-ID stay inline, so that cid: references to
+// them keep working; everything else is sent as a regular attachment.
+func (a RouteAttachment) Attachment() (common.Attachment, error) {
+	data, err := a.Bytes()
+	if err != nil {
+		return common.Attachment{}, err
+	}
+	attachment := common.Attachment{
+		Base64:             true,
+		Data:               base64.StdEncoding.EncodeToString(data),
+		ContentType:        a.ContentType,
+		ContentDisposition: "attachment",
+		FileName:           a.Filename,
+	}
+	if contentID := a.contentID(); contentID != "" {
+		wrapped := "<" + contentID + ">"
+		attachment.ContentDisposition = "inline"
+		attachment.ContentID = &wrapped
+	}
+	return attachment, nil
+}
+
+// contentID returns the attachment's Content-ID without the angle brackets it
+// may be given with, or "" if it has none
+func (a RouteAttachment) contentID() string {
+	if a.ContentID == nil {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(*a.ContentID), "<>")
+}
+
+// newReply builds the reply shared by Reply and ReplyAll
+func newReply(data RouteEventData, from common.SenderAddress, to, cc []common.SenderAddress, opts ReplyOptions) (requests.CreateConversationMessageRequest, error) {
+	request := requests.CreateConversationMessageRequest{
+		From:    from,
+		To:      to,
+		CC:      cc,
+		Subject: prefixSubject("Re:", data.Subject, "re:"),
+	}
+	if messageID := angleID(data.MessageID); messageID != "" {
+		request.Headers = map[string]string{
+			"In-Reply-To": messageID,
+			"References":  threadReferences(data),
+		}
+	}
+
+	attribution := data.From + " wrote:"
+	if data.Date != nil && *data.Date != "" {
+		attribution = "On " + *data.Date + ", " + attribution
+	}
+
+	var text strings.Builder
+	if opts.Text != "" {
+		text.WriteString(opts.Text)
+		text.WriteString("\n\n")
+	}
+	text.WriteString(attribution)
+	text.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimRight(data.PlainBody, "\r\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || strings.HasPrefix(line, ">") {
+			text.WriteString(">" + line + "\n")
+		} else {
+			text.WriteString("> " + line + "\n")
+		}
+	}
+	request.TextContent = ahasend.String(text.String())
+
+	if htmlContent := replyHTML(data, opts); htmlContent != "" || data.HTMLBody != "" {
+		var body strings.Builder
+		if htmlContent != "" {
+			body.WriteString(htmlContent)
+			body.WriteString("<br><br>")
+		}
+		fmt.Fprintf(&body, `<div class="quote">%s<br><blockquote type="cite" style="margin:0 0 0 .8ex;border-left:1px solid #ccc;padding-left:1ex">`, html.EscapeString(attribution))
+		body.WriteString(quotedHTML(data))
+		body.WriteString("</blockquote></div>")
+		request.HtmlContent = ahasend.String(body.String())
+	}
+
+	if opts.IncludeAttachments {
+		attachments, err := convertAttachments(data.Attachments, func(a RouteAttachment) bool {
+			contentID := a.contentID()
+			return contentID != "" && strings.Contains(data.HTMLBody, "cid:"+contentID)
+		})
+		if err != nil {
+			return requests.CreateConversationMessageRequest{}, err
+		}
+		request.Attachments = attachments
+	}
+	return request, nil
+}
+
+// replyTarget returns the address a reply to data goes to
+func replyTarget(data RouteEventData) (common.SenderAddress, error) {
+	if data.ReplyTo != nil && strings.TrimSpace(*data.ReplyTo) != "" {
+		addresses, err := parseAddressList("Reply-To", *data.ReplyTo)
+		if err != nil {
+			return common.SenderAddress{}, err
+		}
+		return addresses[0], nil
+	}
+	addresses, err := parseAddressList("From", data.From)
+	if err != nil {
+		return common.SenderAddress{}, err
+	}
+	if len(addresses) == 0 {
+		return common.SenderAddress{}, errors.New("message has no From address to reply to")
+	}
+	return addresses[0], nil
+}
+
+// parseAddressList parses the value of an address header; an empty value is
+// an empty list
+func parseAddressList(header, value string) ([]common.SenderAddress, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	parsed, err := mail.ParseAddressList(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s address %q: %w", header, value, err)
+	}
+	addresses := make([]common.SenderAddress, 0, len(parsed))
+	for _, address := range parsed {
+		sender := common.SenderAddress{Email: address.Address}
+		if address.Name != "" {
+			name := address.Name
+			sender.Name = &name
+		}
+		addresses = append(addresses, sender)
+	}
+	return addresses, nil
+}
+
+// prefixSubject adds prefix to subject unless it already starts with one of
+// existing, compared case-insensitively
+func prefixSubject(prefix, subject string, existing ...string) string {
+	subject = strings.TrimSpace(subject)
+	lower := strings.ToLower(subject)
+	for _, p := range existing {
+		if strings.HasPrefix(lower, p) {
+			return subject
+		}
+	}
+	if subject == "" {
+		return prefix
+	}
+	return prefix + " " + subject
+}
+
+// threadReferences returns the References header of a message in the thread
+// of data: the references of data followed by its own Message-ID. When data
+// has no References header, its In-Reply-To stands in for it, as RFC 5322
+// describes.
+func threadReferences(data RouteEventData) string {
+	references := strings.Fields(stringValue(data.References))
+	if len(references) == 0 {
+		references = strings.Fields(stringValue(data.InReplyTo))
+	}
+	if messageID := angleID(data.MessageID); messageID != "" {
+		references = append(references, messageID)
+	}
+	return strings.Join(references, " ")
+}
+
+// angleID returns a Message-ID in angle brackets
+func angleID(id string) string {
+	id = strings.Trim(strings.TrimSpace(id), "<>")
+	if id == "" {
+		return ""
+	}
+	return "<" + id + ">"
+}
+
+// replyHTML returns the new HTML content of a reply or forward
+func replyHTML(data RouteEventData, opts ReplyOptions) string {
+	if opts.HTML != "" {
+		return opts.HTML
+	}
+	return textToHTML(opts.Text)
+}
+
+// quotedHTML returns the body of data to quote in an HTML part
+func quotedHTML(data RouteEventData) string {
+	if data.HTMLBody != "" {
+		return data.HTMLBody
+	}
+	return textToHTML(data.PlainBody)
+}
+
+// textToHTML escapes text for an HTML body, keeping its line breaks
+func textToHTML(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// convertAttachments converts the attachments keep selects
+func convertAttachments(attachments []RouteAttachment, keep func(RouteAttachment) bool) ([]common.Attachment, error) {
+	var converted []common.Attachment
+	for _, a := range attachments {
+		if !keep(a) {
+			continue
+		}
+		attachment, err := a.Attachment()
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, attachment)
+	}
+	return converted, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
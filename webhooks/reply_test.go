@@ -0,0 +1,166 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/AhaSend/ahasend-go"
+	"github.com/AhaSend/ahasend-go/models/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var support = common.SenderAddress{Email: "support@example.com", Name: ahasend.String("Support")}
+
+func routedMessage() RouteEventData {
+	return RouteEventData{
+		ID:         "route-msg-1",
+		From:       "Customer <customer@example.net>",
+		To:         "Support <support@example.com>, sales@example.com",
+		CC:         ahasend.String("Manager <Manager@example.net>, customer@example.net"),
+		Subject:    "Help with my account",
+		MessageID:  "<second@example.net>",
+		Date:       ahasend.String("Mon, 06 May 2024 13:15:46 +0000"),
+		InReplyTo:  ahasend.String("<first@example.com>"),
+		References: ahasend.String("<root@example.com> <first@example.com>"),
+		HTMLBody:   `<p>I need help with my account settings.</p><img src="cid:logo-123">`,
+		PlainBody:  "I need help with my account settings.\n\n> Earlier reply\n",
+		Attachments: []RouteAttachment{
+			{Filename: "logo.png", ContentType: "image/png", ContentID: ahasend.String("logo-123"), Disposition: "inline", Data: "aW1hZ2UtYnl0ZXM="},
+			{Filename: "articles_data.csv", ContentType: "text/csv", ContentID: ahasend.String(""), Data: "YSxiCjEsMgo="},
+		},
+	}
+}
+
+func TestReply(t *testing.T) {
+	request, err := Reply(routedMessage(), support, ReplyOptions{Text: "Thanks, we are on it.\nSupport"})
+	require.NoError(t, err)
+
+	assert.Equal(t, support, request.From)
+	assert.Equal(t, []common.SenderAddress{{Email: "customer@example.net", Name: ahasend.String("Customer")}}, request.To)
+	assert.Empty(t, request.CC)
+	assert.Equal(t, "Re: Help with my account", request.Subject)
+	assert.Equal(t, map[string]string{
+		"In-Reply-To": "<second@example.net>",
+		"References":  "<root@example.com> <first@example.com> <second@example.net>",
+	}, request.Headers)
+
+	require.NotNil(t, request.TextContent)
+	assert.Equal(t, "Thanks, we are on it.\nSupport\n\n"+
+		"On Mon, 06 May 2024 13:15:46 +0000, Customer <customer@example.net> wrote:\n"+
+		"> I need help with my account settings.\n"+
+		">\n"+
+		">> Earlier reply\n", *request.TextContent)
+
+	require.NotNil(t, request.HtmlContent)
+	assert.Contains(t, *request.HtmlContent, "Thanks, we are on it.<br>Support<br><br>")
+	assert.Contains(t, *request.HtmlContent, "Customer &lt;customer@example.net&gt; wrote:")
+	assert.Contains(t, *request.HtmlContent, `<blockquote type="cite"`)
+	assert.Contains(t, *request.HtmlContent, routedMessage().HTMLBody)
+	assert.Empty(t, request.Attachments)
+}
+
+func TestReplyUsesReplyToAndKeepsSubjectPrefix(t *testing.T) {
+	data := routedMessage()
+	data.ReplyTo = ahasend.String("tickets@example.net")
+	data.Subject = "RE: Help with my account"
+	data.References = nil
+	data.HTMLBody = ""
+
+	request, err := Reply(data, support, ReplyOptions{HTML: "<p>Thanks</p>"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []common.SenderAddress{{Email: "tickets@example.net"}}, request.To)
+	assert.Equal(t, "RE: Help with my account", request.Subject)
+	assert.Equal(t, "<first@example.com> <second@example.net>", request.Headers["References"], "In-Reply-To stands in for missing References")
+	require.NotNil(t, request.HtmlContent)
+	assert.Contains(t, *request.HtmlContent, "<p>Thanks</p><br><br>")
+	assert.Contains(t, *request.HtmlContent, "I need help with my account settings.<br><br>&gt; Earlier reply")
+}
+
+func TestReplyAll(t *testing.T) {
+	request, err := ReplyAll(routedMessage(), support, ReplyOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "customer@example.net", request.To[0].Email)
+	assert.Equal(t, []common.SenderAddress{
+		{Email: "sales@example.com"},
+		{Email: "Manager@example.net", Name: ahasend.String("Manager")},
+	}, request.CC, "the replying address and the sender are not copied")
+}
+
+func TestReplyIncludesReferencedInlineParts(t *testing.T) {
+	request, err := Reply(routedMessage(), support, ReplyOptions{IncludeAttachments: true})
+	require.NoError(t, err)
+
+	require.Len(t, request.Attachments, 1)
+	assert.Equal(t, "logo.png", request.Attachments[0].FileName)
+	assert.Equal(t, "inline", request.Attachments[0].ContentDisposition)
+	assert.Equal(t, ahasend.String("<logo-123>"), request.Attachments[0].ContentID)
+}
+
+func TestReplyIncludesInlinePartsWithBracketedContentID(t *testing.T) {
+	data := routedMessage()
+	data.Attachments[0].ContentID = ahasend.String("<logo-123>")
+
+	request, err := Reply(data, support, ReplyOptions{IncludeAttachments: true})
+	require.NoError(t, err)
+
+	require.Len(t, request.Attachments, 1)
+	assert.Equal(t, ahasend.String("<logo-123>"), request.Attachments[0].ContentID)
+}
+
+func TestReplyRejectsInvalidSender(t *testing.T) {
+	data := routedMessage()
+	data.From = "not an address"
+
+	_, err := Reply(data, support, ReplyOptions{})
+	assert.ErrorContains(t, err, "invalid From address")
+}
+
+func TestForward(t *testing.T) {
+	to := []common.SenderAddress{{Email: "billing@example.com"}}
+	request, err := Forward(routedMessage(), support, to, ReplyOptions{Text: "Can you take this one?", IncludeAttachments: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, to, request.To)
+	assert.Equal(t, "Fwd: Help with my account", request.Subject)
+	assert.Equal(t, map[string]string{"References": "<root@example.com> <first@example.com> <second@example.net>"}, request.Headers)
+
+	require.NotNil(t, request.TextContent)
+	assert.Equal(t, "Can you take this one?\n\n"+
+		"---------- Forwarded message ---------\n"+
+		"From: Customer <customer@example.net>\n"+
+		"Date: Mon, 06 May 2024 13:15:46 +0000\n"+
+		"Subject: Help with my account\n"+
+		"To: Support <support@example.com>, sales@example.com\n"+
+		"Cc: Manager <Manager@example.net>, customer@example.net\n"+
+		"\n"+
+		routedMessage().PlainBody, *request.TextContent)
+	require.NotNil(t, request.HtmlContent)
+	assert.Contains(t, *request.HtmlContent, "From: Customer &lt;customer@example.net&gt;<br>")
+
+	require.Len(t, request.Attachments, 2)
+	assert.Equal(t, common.Attachment{
+		Base64:             true,
+		Data:               "YSxiCjEsMgo=",
+		ContentType:        "text/csv",
+		ContentDisposition: "attachment",
+		FileName:           "articles_data.csv",
+	}, request.Attachments[1])
+}
+
+func TestForwardErrors(t *testing.T) {
+	_, err := Forward(routedMessage(), support, nil, ReplyOptions{})
+	assert.ErrorContains(t, err, "forward recipient")
+
+	data := routedMessage()
+	data.Attachments[1].Data = "not base64!"
+	_, err = Forward(data, support, []common.SenderAddress{{Email: "billing@example.com"}}, ReplyOptions{IncludeAttachments: true})
+	assert.ErrorContains(t, err, `invalid data for attachment "articles_data.csv"`)
+}
+
+func TestRouteAttachmentBytes(t *testing.T) {
+	data, err := routedMessage().Attachments[1].Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n1,2\n", string(data))
+}
@@ -0,0 +1,95 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Signer signs webhook payloads the way AhaSend does, producing headers that
+// WebhookVerifier accepts. It is meant for testing webhook consumers.
+type Signer struct {
+	key []byte
+	now func() time.Time
+}
+
+// NewSigner creates a signer for the given secret. Secrets are interpreted as
+// by NewWebhookVerifier.
+func NewSigner(secret string) (*Signer, error) {
+	key, err := secretKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{key: key, now: time.Now}, nil
+}
+
+// Sign returns the webhook-signature header value for a payload
+func (s *Signer) Sign(msgID string, timestamp time.Time, payload []byte) string {
+	signedContent := fmt.Sprintf("%s.%d.%s", msgID, timestamp.Unix(), string(payload))
+	return SignatureVersion + "," + sign(s.key, []byte(signedContent))
+}
+
+// Headers returns the webhook-id, webhook-timestamp and webhook-signature
+// headers for a payload
+func (s *Signer) Headers(msgID string, timestamp time.Time, payload []byte) http.Header {
+	headers := http.Header{}
+	headers.Set(HeaderWebhookID, msgID)
+	headers.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	headers.Set(HeaderWebhookSignature, s.Sign(msgID, timestamp, payload))
+	return headers
+}
+
+// NewRequest builds a signed POST request delivering event to url, with the
+// current time and the event's webhook ID, or a new one if it has none
+func (s *Signer) NewRequest(ctx context.Context, url string, event WebhookEvent) (*http.Request, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	return s.newRequest(ctx, url, payloadWebhookID(payload), payload)
+}
+
+// newRequest builds a signed POST request for an encoded payload
+func (s *Signer) newRequest(ctx context.Context, url, msgID string, payload []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range s.Headers(msgID, s.now(), payload) {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// payloadWebhookID returns the webhook_id of an encoded event, so that the
+// webhook-id header matches it, or a new ID if the event has none
+func payloadWebhookID(payload []byte) string {
+	var event struct {
+		WebhookID *string `json:"webhook_id"`
+	}
+	if json.Unmarshal(payload, &event) == nil && event.WebhookID != nil && *event.WebhookID != "" {
+		return *event.WebhookID
+	}
+	return NewWebhookID()
+}
+
+// NewWebhookID returns a new unique value for the webhook-id header
+func NewWebhookID() string {
+	return "msg_" + randomHex(12)
+}
+
+// randomHex returns n random bytes, hex-encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("webhooks: failed to read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,139 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultRetrySchedule approximates AhaSend's retry policy: after the first
+// attempt a failed webhook is retried 6 times over a little more than 16
+// minutes. Tests usually want a much shorter schedule; see
+// Simulator.SetRetrySchedule.
+var DefaultRetrySchedule = []time.Duration{
+	5 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+}
+
+// ErrDeliveryFailed is returned when no attempt to deliver a webhook got a
+// 2xx response
+var ErrDeliveryFailed = errors.New("webhook delivery failed")
+
+// DeliveryAttempt records one attempt to deliver a webhook
+type DeliveryAttempt struct {
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+}
+
+// Delivery records every attempt to deliver a webhook
+type Delivery struct {
+	WebhookID string
+	Attempts  []DeliveryAttempt
+	Delivered bool
+}
+
+// Simulator delivers signed webhooks to a URL the way AhaSend does: every
+// attempt carries the same webhook-id with a fresh timestamp and signature,
+// only a 2xx response counts as success, and failures are retried on a
+// schedule.
+type Simulator struct {
+	url        string
+	signer     *Signer
+	httpClient *http.Client
+	schedule   []time.Duration
+}
+
+// NewSimulator creates a simulator that delivers webhooks signed by signer to url
+func NewSimulator(url string, signer *Signer) *Simulator {
+	return &Simulator{
+		url:        url,
+		signer:     signer,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		schedule:   DefaultRetrySchedule,
+	}
+}
+
+// SetHTTPClient sets the client used to deliver webhooks
+func (s *Simulator) SetHTTPClient(client *http.Client) {
+	s.httpClient = client
+}
+
+// SetRetrySchedule sets the delays before each retry. An empty schedule
+// disables retries.
+func (s *Simulator) SetRetrySchedule(schedule []time.Duration) {
+	s.schedule = schedule
+}
+
+// Deliver sends event until it is acknowledged or the retry schedule is
+// exhausted. The returned Delivery is never nil.
+func (s *Simulator) Deliver(ctx context.Context, event WebhookEvent) (*Delivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return &Delivery{}, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	return s.DeliverPayload(ctx, payload)
+}
+
+// DeliverPayload is like Deliver for an already encoded payload, such as an
+// example copied from the webhook documentation. The webhook-id header is the
+// payload's webhook_id, or a new ID if it has none.
+func (s *Simulator) DeliverPayload(ctx context.Context, payload []byte) (*Delivery, error) {
+	delivery := &Delivery{WebhookID: payloadWebhookID(payload)}
+
+	for attempt := 0; ; attempt++ {
+		result := s.attempt(ctx, delivery.WebhookID, payload)
+		delivery.Attempts = append(delivery.Attempts, result)
+		if result.Err == nil && result.StatusCode >= 200 && result.StatusCode < 300 {
+			delivery.Delivered = true
+			return delivery, nil
+		}
+
+		if attempt >= len(s.schedule) {
+			return delivery, fmt.Errorf("%w after %d attempts: %s", ErrDeliveryFailed, len(delivery.Attempts), describeAttempt(result))
+		}
+
+		timer := time.NewTimer(s.schedule[attempt])
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return delivery, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt makes a single delivery attempt
+func (s *Simulator) attempt(ctx context.Context, msgID string, payload []byte) DeliveryAttempt {
+	start := time.Now()
+
+	req, err := s.signer.newRequest(ctx, s.url, msgID, payload)
+	if err != nil {
+		return DeliveryAttempt{Err: err}
+	}
+	req.Header.Set("User-Agent", "AhaSend-Webhook-Simulator")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return DeliveryAttempt{Err: err, Duration: time.Since(start)}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return DeliveryAttempt{StatusCode: resp.StatusCode, Duration: time.Since(start)}
+}
+
+func describeAttempt(attempt DeliveryAttempt) string {
+	if attempt.Err != nil {
+		return attempt.Err.Error()
+	}
+	return fmt.Sprintf("last response %d", attempt.StatusCode)
+}
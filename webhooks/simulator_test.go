@@ -0,0 +1,141 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerMatchesVerifier(t *testing.T) {
+	signer, err := NewSigner(testWebhookSecret)
+	require.NoError(t, err)
+	verifier, err := NewWebhookVerifier(testWebhookSecret)
+	require.NoError(t, err)
+
+	events := []WebhookEvent{
+		NewMessageReceptionEvent(),
+		NewMessageDeliveredEvent(),
+		NewMessageTransientErrorEvent(),
+		NewMessageFailedEvent(),
+		NewMessageBouncedEvent(),
+		NewMessageSuppressedEvent(),
+		NewMessageOpenedEvent(),
+		NewMessageClickedEvent(),
+		NewSuppressionCreatedEvent(),
+		NewDomainDNSErrorEvent(),
+		NewRouteMessageEvent(),
+	}
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		require.NoError(t, err)
+
+		headers := signer.Headers(NewWebhookID(), time.Now(), payload)
+		parsed, err := verifier.Parse(payload, headers)
+		require.NoError(t, err, event.GetType())
+		assert.IsType(t, event, parsed)
+		assert.Equal(t, event.GetType(), parsed.GetType())
+	}
+
+	// The signature matches the one computed independently by the tests
+	payload := []byte(deliveredPayload)
+	timestamp := time.Now()
+	assert.Equal(t,
+		signWithSecret(t, testWebhookSecret, "msg_1", strconv.FormatInt(timestamp.Unix(), 10), string(payload)),
+		signer.Sign("msg_1", timestamp, payload))
+
+	// whsec_ secrets are signed with their literal bytes too
+	const standardSecret = "whsec_c3RhbmRhcmQtd2ViaG9va3Mta2V5"
+	standardSigner, err := NewSigner(standardSecret)
+	require.NoError(t, err)
+	assert.Equal(t,
+		signWithSecret(t, standardSecret, "msg_1", strconv.FormatInt(timestamp.Unix(), 10), string(payload)),
+		standardSigner.Sign("msg_1", timestamp, payload))
+}
+
+func TestSimulatorDeliversToHandler(t *testing.T) {
+	signer, err := NewSigner(testWebhookSecret)
+	require.NoError(t, err)
+
+	h := newTestHandler(t)
+	var received *MessageBouncedEvent
+	h.OnMessageBounced(func(ctx context.Context, event *MessageBouncedEvent) error {
+		received = event
+		return nil
+	})
+	var headerID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headerID = r.Header.Get(HeaderWebhookID)
+		h.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	event := NewMessageBouncedEvent()
+	event.Data.Recipient = "gone@example.com"
+
+	delivery, err := NewSimulator(server.URL, signer).Deliver(context.Background(), event)
+	require.NoError(t, err)
+	assert.True(t, delivery.Delivered)
+	assert.Len(t, delivery.Attempts, 1)
+	require.NotNil(t, received)
+	assert.Equal(t, "gone@example.com", received.Data.Recipient)
+	require.NotNil(t, event.WebhookID)
+	assert.Equal(t, *event.WebhookID, headerID, "the header carries the event's webhook id")
+	assert.Equal(t, *event.WebhookID, delivery.WebhookID)
+
+	req, err := signer.NewRequest(context.Background(), server.URL, event)
+	require.NoError(t, err)
+	assert.Equal(t, *event.WebhookID, req.Header.Get(HeaderWebhookID))
+}
+
+func TestSimulatorRetriesFailures(t *testing.T) {
+	signer, err := NewSigner(testWebhookSecret)
+	require.NoError(t, err)
+	verifier, err := NewWebhookVerifier(testWebhookSecret)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var ids []string
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, verifier.VerifyRequest(r))
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, r.Header.Get(HeaderWebhookID))
+		if len(ids) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	simulator := NewSimulator(server.URL, signer)
+	simulator.SetRetrySchedule([]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond})
+
+	delivery, err := simulator.DeliverPayload(context.Background(), []byte(deliveredPayload))
+	require.NoError(t, err)
+	assert.True(t, delivery.Delivered)
+	require.Len(t, delivery.Attempts, 3)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.Attempts[0].StatusCode)
+	assert.Equal(t, http.StatusOK, delivery.Attempts[2].StatusCode)
+	assert.Equal(t, []string{delivery.WebhookID, delivery.WebhookID, delivery.WebhookID}, ids,
+		"retries reuse the webhook id")
+
+	mu.Lock()
+	failures = 10
+	ids = nil
+	mu.Unlock()
+	delivery, err = simulator.DeliverPayload(context.Background(), []byte(deliveredPayload))
+	assert.True(t, errors.Is(err, ErrDeliveryFailed))
+	assert.False(t, delivery.Delivered)
+	assert.Len(t, delivery.Attempts, 4, "the first attempt plus one per scheduled retry")
+}
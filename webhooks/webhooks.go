@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -43,42 +45,163 @@ var (
 	ErrUnknownEventType = errors.New("unknown webhook event type")
 )
 
+// WebhookSecret is one of the secrets a WebhookVerifier accepts. Keeping the
+// old and new secret side by side while a rotation propagates means no
+// delivery fails verification in between.
+type WebhookSecret struct {
+	// ID names the secret in verification results. When empty, a fingerprint
+	// of the secret is used.
+	ID string
+
+	// Secret is the signing secret as issued. Its UTF-8 bytes are the HMAC
+	// key as they are, prefix included and never base64-decoded, whatever
+	// the prefix ("aha-whsec-...", "whsec_..." or none).
+	Secret string
+
+	// NotAfter, when set, is the time after which the secret is no longer
+	// accepted, typically shortly after the new secret was put in place
+	NotAfter time.Time
+}
+
+// verifierSecret is a WebhookSecret with its decoded HMAC key
+type verifierSecret struct {
+	id       string
+	key      []byte
+	notAfter time.Time
+}
+
 // WebhookVerifier verifies webhook signatures according to the Standard Webhooks specification
 type WebhookVerifier struct {
-	secret    []byte
-	tolerance time.Duration
+	mu          sync.Mutex
+	secrets     []verifierSecret
+	lastMatched map[string]time.Time
+	tolerance   time.Duration
 }
 
 // NewWebhookVerifier creates a new webhook verifier with the given secret
 func NewWebhookVerifier(secret string) (*WebhookVerifier, error) {
-	return &WebhookVerifier{
-		secret:    []byte(secret),
-		tolerance: DefaultTolerance,
-	}, nil
+	return NewWebhookVerifierWithSecrets(WebhookSecret{Secret: secret})
+}
+
+// NewWebhookVerifierWithSecrets creates a webhook verifier that accepts a
+// signature made with any of the given secrets
+func NewWebhookVerifierWithSecrets(secrets ...WebhookSecret) (*WebhookVerifier, error) {
+	if len(secrets) == 0 {
+		return nil, errors.New("at least one webhook secret is required")
+	}
+
+	v := &WebhookVerifier{
+		lastMatched: make(map[string]time.Time),
+		tolerance:   DefaultTolerance,
+	}
+	for _, secret := range secrets {
+		if err := v.AddSecret(secret); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// AddSecret adds a secret to the ones the verifier accepts
+func (v *WebhookVerifier) AddSecret(secret WebhookSecret) error {
+	key, err := secretKey(secret.Secret)
+	if err != nil {
+		return err
+	}
+	id := secret.ID
+	if id == "" {
+		id = secretFingerprint(key)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, existing := range v.secrets {
+		if existing.id == id {
+			return fmt.Errorf("duplicate webhook secret %q", id)
+		}
+	}
+	v.secrets = append(v.secrets, verifierSecret{id: id, key: key, notAfter: secret.NotAfter})
+	return nil
+}
+
+// RemoveSecret stops accepting the secret with the given ID and reports
+// whether it was present
+func (v *WebhookVerifier) RemoveSecret(id string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for i, secret := range v.secrets {
+		if secret.id == id {
+			v.secrets = append(v.secrets[:i:i], v.secrets[i+1:]...)
+			delete(v.lastMatched, id)
+			return true
+		}
+	}
+	return false
+}
+
+// SecretIDs returns the IDs of the secrets the verifier accepts, in the order
+// they were added
+func (v *WebhookVerifier) SecretIDs() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	ids := make([]string, len(v.secrets))
+	for i, secret := range v.secrets {
+		ids[i] = secret.id
+	}
+	return ids
+}
+
+// LastMatched returns when the secret with the given ID last verified a
+// webhook. Once an old secret has stopped matching for longer than the
+// sender's retry window, it is safe to retire.
+func (v *WebhookVerifier) LastMatched(id string) (time.Time, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	at, ok := v.lastMatched[id]
+	return at, ok
 }
 
 // SetTolerance sets the time tolerance for webhook verification
 func (v *WebhookVerifier) SetTolerance(tolerance time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
 	v.tolerance = tolerance
 }
 
 // Verify verifies a webhook payload with the given headers
 func (v *WebhookVerifier) Verify(payload []byte, headers http.Header) error {
+	_, err := v.VerifyWithSecret(payload, headers)
+	return err
+}
+
+// VerifyWithSecret verifies a webhook payload with the given headers and
+// returns the ID of the secret whose signature matched
+func (v *WebhookVerifier) VerifyWithSecret(payload []byte, headers http.Header) (string, error) {
 	// Get required headers
 	msgID := headers.Get(HeaderWebhookID)
 	msgTimestamp := headers.Get(HeaderWebhookTimestamp)
 	msgSignature := headers.Get(HeaderWebhookSignature)
 
 	if msgID == "" || msgTimestamp == "" || msgSignature == "" {
-		return ErrMissingHeaders
+		return "", ErrMissingHeaders
 	}
 
 	// Parse and validate timestamp
 	timestamp, err := strconv.ParseInt(msgTimestamp, 10, 64)
 	if err != nil {
-		return fmt.Errorf("invalid timestamp: %w", err)
+		return "", fmt.Errorf("invalid timestamp: %w", err)
 	}
 
+	v.mu.Lock()
+	tolerance := v.tolerance
+	secrets := append([]verifierSecret(nil), v.secrets...)
+	v.mu.Unlock()
+
 	// Standard Webhooks requires the timestamp to be within tolerance of now in
 	// either direction, so a far-future timestamp is rejected just like a stale
 	// one. The bounds are compared as instants rather than as a signed duration:
@@ -87,31 +210,40 @@ func (v *WebhookVerifier) Verify(payload []byte, headers http.Header) error {
 	// far future through.
 	webhookTime := time.Unix(timestamp, 0)
 	now := time.Now()
-	if webhookTime.Before(now.Add(-v.tolerance)) || webhookTime.After(now.Add(v.tolerance)) {
-		return ErrExpiredTimestamp
+	if webhookTime.Before(now.Add(-tolerance)) || webhookTime.After(now.Add(tolerance)) {
+		return "", ErrExpiredTimestamp
 	}
 
 	// Build the signed content
-	signedContent := fmt.Sprintf("%s.%s.%s", msgID, msgTimestamp, string(payload))
-
-	// Calculate expected signature
-	expectedSignature := v.sign([]byte(signedContent))
+	signedContent := []byte(fmt.Sprintf("%s.%s.%s", msgID, msgTimestamp, string(payload)))
 
 	// Parse signatures from header (space-delimited list)
 	signatures := strings.Split(msgSignature, " ")
 
-	// Check if any signature matches
-	for _, sig := range signatures {
-		// Remove version prefix if present
-		sig = strings.TrimPrefix(sig, SignatureVersion+",")
+	for _, secret := range secrets {
+		if !secret.notAfter.IsZero() && now.After(secret.notAfter) {
+			continue
+		}
 
-		// Constant-time comparison
-		if hmac.Equal([]byte(sig), []byte(expectedSignature)) {
-			return nil
+		// Calculate expected signature
+		expectedSignature := sign(secret.key, signedContent)
+
+		// Check if any signature matches
+		for _, sig := range signatures {
+			// Remove version prefix if present
+			sig = strings.TrimPrefix(sig, SignatureVersion+",")
+
+			// Constant-time comparison
+			if hmac.Equal([]byte(sig), []byte(expectedSignature)) {
+				v.mu.Lock()
+				v.lastMatched[secret.id] = now
+				v.mu.Unlock()
+				return secret.id, nil
+			}
 		}
 	}
 
-	return ErrInvalidSignature
+	return "", ErrInvalidSignature
 }
 
 // VerifyRequest verifies a webhook from an HTTP request
@@ -240,12 +372,27 @@ func (v *WebhookVerifier) ParseRequest(r *http.Request) (WebhookEvent, error) {
 }
 
 // sign calculates the HMAC-SHA256 signature for the given data
-func (v *WebhookVerifier) sign(data []byte) string {
-	h := hmac.New(sha256.New, v.secret)
+func sign(key, data []byte) string {
+	h := hmac.New(sha256.New, key)
 	h.Write(data)
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
+// secretKey returns the HMAC key for a secret as issued: its literal bytes,
+// as the webhook spec requires
+func secretKey(secret string) ([]byte, error) {
+	if secret == "" {
+		return nil, errors.New("webhook secret is empty")
+	}
+	return []byte(secret), nil
+}
+
+// secretFingerprint identifies a secret without revealing it
+func secretFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
 // WebhookEvent is the interface for all webhook events
 type WebhookEvent interface {
 	GetType() string
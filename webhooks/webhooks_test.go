@@ -661,6 +661,84 @@ func TestWebhookHelperFunctions(t *testing.T) {
 	})
 }
 
+func TestWebhookSecretRotation(t *testing.T) {
+	const oldSecret = "aha-whsec-old-secret"
+	const newSecret = "aha-whsec-new-secret"
+	payload := `{"type":"message.delivered","timestamp":"2024-05-06T09:50:16.687031577Z","data":{}}`
+
+	signedHeaders := func(secret string) http.Header {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers := http.Header{}
+		headers.Set("webhook-id", "msg_2Ej8Gx5VCOPKUhbMr9Zw7qvxPtt")
+		headers.Set("webhook-timestamp", timestamp)
+		headers.Set("webhook-signature", signWithSecret(t, secret, headers.Get("webhook-id"), timestamp, payload))
+		return headers
+	}
+
+	t.Run("accepts every active secret and reports the match", func(t *testing.T) {
+		verifier, err := NewWebhookVerifierWithSecrets(
+			WebhookSecret{ID: "old", Secret: oldSecret},
+			WebhookSecret{ID: "new", Secret: newSecret},
+		)
+		require.NoError(t, err)
+
+		id, err := verifier.VerifyWithSecret([]byte(payload), signedHeaders(oldSecret))
+		require.NoError(t, err)
+		assert.Equal(t, "old", id)
+
+		id, err = verifier.VerifyWithSecret([]byte(payload), signedHeaders(newSecret))
+		require.NoError(t, err)
+		assert.Equal(t, "new", id)
+
+		_, matched := verifier.LastMatched("old")
+		assert.True(t, matched)
+
+		assert.True(t, verifier.RemoveSecret("old"))
+		assert.Equal(t, []string{"new"}, verifier.SecretIDs())
+		assert.ErrorIs(t, verifier.Verify([]byte(payload), signedHeaders(oldSecret)), ErrInvalidSignature)
+		_, matched = verifier.LastMatched("old")
+		assert.False(t, matched)
+	})
+
+	t.Run("expired secrets are ignored", func(t *testing.T) {
+		verifier, err := NewWebhookVerifierWithSecrets(
+			WebhookSecret{ID: "old", Secret: oldSecret, NotAfter: time.Now().Add(-time.Minute)},
+			WebhookSecret{ID: "new", Secret: newSecret},
+		)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, verifier.Verify([]byte(payload), signedHeaders(oldSecret)), ErrInvalidSignature)
+		assert.NoError(t, verifier.Verify([]byte(payload), signedHeaders(newSecret)))
+	})
+
+	t.Run("whsec_ secrets are used as literal bytes", func(t *testing.T) {
+		secret := "whsec_" + base64.StdEncoding.EncodeToString([]byte("standard-webhooks-key"))
+		verifier, err := NewWebhookVerifier(secret)
+		require.NoError(t, err)
+
+		// Signed with the literal string, as AhaSend does
+		assert.NoError(t, verifier.Verify([]byte(payload), signedHeaders(secret)))
+		assert.ErrorIs(t, verifier.Verify([]byte(payload), signedHeaders("standard-webhooks-key")), ErrInvalidSignature,
+			"the secret is never base64-decoded")
+		assert.Len(t, verifier.SecretIDs(), 1)
+		assert.Contains(t, verifier.SecretIDs()[0], "sha256:", "unnamed secrets are identified by fingerprint")
+
+		_, err = NewWebhookVerifier("whsec_not base64!")
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid secrets are rejected", func(t *testing.T) {
+		_, err := NewWebhookVerifier("")
+		assert.Error(t, err)
+
+		_, err = NewWebhookVerifierWithSecrets()
+		assert.Error(t, err)
+
+		_, err = NewWebhookVerifierWithSecrets(WebhookSecret{ID: "a", Secret: oldSecret}, WebhookSecret{ID: "a", Secret: newSecret})
+		assert.ErrorContains(t, err, "duplicate")
+	})
+}
+
 // Helper function to generate a valid signature for testing
 func generateSignature(t *testing.T, verifier *WebhookVerifier, msgID, timestamp, payload string) string {
 	t.Helper()